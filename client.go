@@ -1,6 +1,8 @@
 package cliaas
 
 import (
+	"fmt"
+
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/pivotal-cf/cliaas/iaas"
 	"github.com/pivotal-cf/cliaas/iaas/aws"
@@ -8,71 +10,315 @@ import (
 
 type Client interface {
 	Delete(vmIdentifier string) error
+	Stop(vmIdentifier string, wait bool) error
+	Start(vmIdentifier string, wait bool) error
 	Replace(vmIdentifier string, imageIdentifier string, diskSizeGB int64) error
+	Create(imageIdentifier string, spec iaas.VMSpec) (iaas.VM, error)
 	GetDisk(vmIdentifier string) (iaas.Disk, error)
+	GetVM(vmIdentifier string) (iaas.VM, error)
+	DescribeVM(vmIdentifier string) (iaas.VMSpec, error)
+	CheckPermissions(vmIdentifier string, imageIdentifier string) []iaas.PermissionCheck
 }
 
-func NewAWSAPIClient(client aws.AWSClient) Client {
+func NewAWSAPIClient(client aws.AWSClient, waiter iaas.Waiter, progress iaas.ProgressReporter, namer iaas.Namer, migrateDataDisk bool, hooks *iaas.HookRunner) Client {
 	return &awsAPIClient{
-		client: client,
+		client:          client,
+		waiter:          waiter,
+		progress:        progress,
+		namer:           namer,
+		migrateDataDisk: migrateDataDisk,
+		hooks:           hooks,
 	}
 }
 
 type awsAPIClient struct {
-	client aws.AWSClient
+	client          aws.AWSClient
+	waiter          iaas.Waiter
+	progress        iaas.ProgressReporter
+	namer           iaas.Namer
+	migrateDataDisk bool
+	hooks           *iaas.HookRunner
 }
 
 func (c *awsAPIClient) Delete(identifier string) error {
 	return c.client.DeleteVM(identifier)
 }
 
-func (c *awsAPIClient) Replace(identifier string, ami string, diskSizeGB int64) error {
+func (c *awsAPIClient) Stop(identifier string, wait bool) error {
+	vmInfo, err := c.client.GetVMInfo(identifier + "*")
+	if err != nil {
+		return err
+	}
+
+	iaas.ReportStep(c.progress, iaas.StepStopping, vmInfo.InstanceID)
+	err = c.client.ShutdownVM(vmInfo.InstanceID)
+	if err != nil || !wait {
+		return err
+	}
+
+	err = c.client.WaitForStatus(vmInfo.InstanceID, ec2.InstanceStateNameStopped, c.waiter.Timeouts.Stop)
+	if err != nil {
+		return err
+	}
+	iaas.ReportStep(c.progress, iaas.StepStopped, vmInfo.InstanceID)
+	return nil
+}
+
+// Start starts a stopped instance. An elastic ip stays associated with the
+// instance while it is stopped, so there is nothing to restore.
+func (c *awsAPIClient) Start(identifier string, wait bool) error {
+	vmInfo, err := c.client.GetStoppedVMInfo(identifier + "*")
+	if err != nil {
+		return err
+	}
+
+	iaas.ReportStep(c.progress, iaas.StepStarting, vmInfo.InstanceID)
+	err = c.client.StartVM(vmInfo.InstanceID)
+	if err != nil || !wait {
+		return err
+	}
+
+	err = c.client.WaitForStatus(vmInfo.InstanceID, ec2.InstanceStateNameRunning, c.waiter.Timeouts.Start)
+	if err != nil {
+		return err
+	}
+	iaas.ReportStep(c.progress, iaas.StepRunning, vmInfo.InstanceID)
+	return nil
+}
+
+func (c *awsAPIClient) Replace(identifier string, ami string, diskSizeGB int64) (err error) {
 	vmInfo, err := c.client.GetVMInfo(identifier + "*")
 	if err != nil {
 		return err
 	}
+	iaas.ReportStep(c.progress, iaas.StepFoundVM, vmInfo.InstanceID)
+
+	// name the new vm before anything changes, so a bad template fails early
+	oldName := vmInfo.Name
+	if oldName == "" {
+		oldName = identifier
+	}
+	name, err := c.namer.Name(c.namer.NameData(oldName, ami), iaas.SuffixVM)
+	if err != nil {
+		return err
+	}
+	err = aws.ValidateName(name)
+	if err != nil {
+		return err
+	}
 
+	// in migrate mode the new vm only gets a root volume, and the old vm's
+	// data disks are moved over to it
+	var dataDisks []aws.BlockDeviceMapping
+	newVMInfo := vmInfo
+	if c.migrateDataDisk {
+		dataDisks = vmInfo.DataDisks()
+		if len(dataDisks) == 0 {
+			return fmt.Errorf("instance %s has no data disk to migrate", vmInfo.InstanceID)
+		}
+		newVMInfo = vmInfo.WithoutDataDisks()
+	}
+
+	hookData := iaas.HookData{
+		Identifier: identifier,
+		OldVM:      iaas.VM{ID: vmInfo.InstanceID, Name: vmInfo.Name, Image: vmInfo.ImageID},
+		Image:      ami,
+		PublicIP:   vmInfo.PublicIP,
+	}
+	err = c.hooks.Run(iaas.PhasePreStop, hookData)
+	if err != nil {
+		return err
+	}
+
+	// every failure from here on rolls back to the old vm
+	defer func() {
+		if err != nil {
+			rollbackData := hookData
+			rollbackData.Error = err.Error()
+			_ = c.hooks.Run(iaas.PhaseOnRollback, rollbackData)
+		}
+	}()
+
+	iaas.ReportStep(c.progress, iaas.StepStopping, vmInfo.InstanceID)
 	err = c.client.StopVM(vmInfo.InstanceID)
 	if err != nil {
 		_ = c.client.StartVM(vmInfo.InstanceID)
 		return err
 	}
 
-	err = c.client.WaitForStatus(vmInfo.InstanceID, ec2.InstanceStateNameStopped)
+	err = c.client.WaitForStatus(vmInfo.InstanceID, ec2.InstanceStateNameStopped, c.waiter.Timeouts.Stop)
+	if err != nil {
+		_ = c.client.StartVM(vmInfo.InstanceID)
+		return err
+	}
+	iaas.ReportStep(c.progress, iaas.StepStopped, vmInfo.InstanceID)
+	_ = c.hooks.Run(iaas.PhasePostStop, hookData)
+
+	// detached only holds the disks that have left the old vm, so that a
+	// rollback moves back just those
+	var detached []aws.BlockDeviceMapping
+	for _, disk := range dataDisks {
+		iaas.ReportStep(c.progress, iaas.StepDetachingDisk, disk.EBS.VolumeID)
+		err = c.client.DetachVolume(disk.EBS.VolumeID)
+		if err != nil {
+			c.reattachDataDisks(vmInfo.InstanceID, detached)
+			_ = c.client.StartVM(vmInfo.InstanceID)
+			return err
+		}
+		detached = append(detached, disk)
+
+		err = c.client.WaitForVolumeStatus(disk.EBS.VolumeID, ec2.VolumeStateAvailable, c.waiter.Timeouts.DiskAttachment)
+		if err != nil {
+			c.reattachDataDisks(vmInfo.InstanceID, detached)
+			_ = c.client.StartVM(vmInfo.InstanceID)
+			return err
+		}
+	}
+
+	err = c.hooks.Run(iaas.PhasePreCreate, hookData)
 	if err != nil {
+		c.reattachDataDisks(vmInfo.InstanceID, detached)
 		_ = c.client.StartVM(vmInfo.InstanceID)
 		return err
 	}
 
+	iaas.ReportStep(c.progress, iaas.StepCreating, name)
+
 	instanceID, err := c.client.CreateVM(
 		ami,
-		identifier,
-		vmInfo,
+		name,
+		newVMInfo,
 	)
 	if err != nil {
+		c.reattachDataDisks(vmInfo.InstanceID, detached)
 		_ = c.client.StartVM(vmInfo.InstanceID)
 		return err
 	}
+	hookData.NewVM = iaas.VM{ID: instanceID, Name: name, Image: ami}
+	_ = c.hooks.Run(iaas.PhasePostCreate, hookData)
 
-	err = c.client.WaitForStatus(instanceID, ec2.InstanceStateNameRunning)
+	iaas.ReportStep(c.progress, iaas.StepWaitingForRunning, instanceID)
+	err = c.client.WaitForStatus(instanceID, ec2.InstanceStateNameRunning, c.waiter.Timeouts.Create)
 	if err != nil {
 		_ = c.client.DeleteVM(instanceID)
+		c.reattachDataDisks(vmInfo.InstanceID, detached)
+		_ = c.client.StartVM(vmInfo.InstanceID)
 		return err
 	}
+	iaas.ReportStep(c.progress, iaas.StepRunning, instanceID)
+
+	for _, disk := range dataDisks {
+		iaas.ReportStep(c.progress, iaas.StepAttachingDisk, disk.EBS.VolumeID)
+		err = c.client.AttachVolume(instanceID, disk.EBS.VolumeID, disk.DeviceName)
+		if err == nil {
+			err = c.client.WaitForVolumeStatus(disk.EBS.VolumeID, ec2.VolumeStateInUse, c.waiter.Timeouts.DiskAttachment)
+		}
+		if err != nil {
+			c.reattachDataDisks(vmInfo.InstanceID, detached)
+			_ = c.client.DeleteVM(instanceID)
+			_ = c.client.StartVM(vmInfo.InstanceID)
+			return err
+		}
+	}
 
 	if vmInfo.PublicIP != "" {
-		err = c.client.AssignPublicIP(instanceID, vmInfo.PublicIP)
+		// a freshly running instance is not always ready for the address yet
+		err = c.waiter.Retry("associating "+vmInfo.PublicIP, c.waiter.Timeouts.IPAssociation, func() error {
+			return c.client.AssignPublicIP(instanceID, vmInfo.PublicIP)
+		})
 		if err != nil {
+			c.reattachDataDisks(vmInfo.InstanceID, detached)
 			_ = c.client.DeleteVM(instanceID)
 			_ = c.client.AssignPublicIP(vmInfo.InstanceID, vmInfo.PublicIP)
 			_ = c.client.StartVM(vmInfo.InstanceID)
 			return err
 		}
+		iaas.ReportStep(c.progress, iaas.StepIPAssociated, vmInfo.PublicIP)
 	}
 
+	_ = c.hooks.Run(iaas.PhasePostReady, hookData)
+	iaas.ReportStep(c.progress, iaas.StepDone, instanceID)
 	return nil
 }
 
+// Create launches a new instance from ami as the spec describes, and
+// associates the spec's elastic ip with it once it is running. The instance
+// is terminated if it does not get that far.
+func (c *awsAPIClient) Create(ami string, spec iaas.VMSpec) (iaas.VM, error) {
+	err := aws.ValidateName(spec.Name)
+	if err != nil {
+		return iaas.VM{}, err
+	}
+
+	iaas.ReportStep(c.progress, iaas.StepCreating, spec.Name)
+	instanceID, err := c.client.CreateVM(ami, spec.Name, aws.SpecVMInfo(spec))
+	if err != nil {
+		return iaas.VM{}, err
+	}
+
+	iaas.ReportStep(c.progress, iaas.StepWaitingForRunning, instanceID)
+	err = c.client.WaitForStatus(instanceID, ec2.InstanceStateNameRunning, c.waiter.Timeouts.Create)
+	if err != nil {
+		_ = c.client.DeleteVM(instanceID)
+		return iaas.VM{}, err
+	}
+	iaas.ReportStep(c.progress, iaas.StepRunning, instanceID)
+
+	if spec.Network.PublicIP != "" {
+		err = c.waiter.Retry("associating "+spec.Network.PublicIP, c.waiter.Timeouts.IPAssociation, func() error {
+			return c.client.AssignPublicIP(instanceID, spec.Network.PublicIP)
+		})
+		if err != nil {
+			_ = c.client.DeleteVM(instanceID)
+			return iaas.VM{}, err
+		}
+		iaas.ReportStep(c.progress, iaas.StepIPAssociated, spec.Network.PublicIP)
+	}
+
+	iaas.ReportStep(c.progress, iaas.StepDone, instanceID)
+	return iaas.VM{ID: instanceID, Name: spec.Name, Image: ami}, nil
+}
+
+// reattachDataDisks moves disks back to the old vm after a failed replace.
+// A disk may still be attached to the new vm, or still detaching, so each is
+// detached and waited on before it is attached again.
+func (c *awsAPIClient) reattachDataDisks(instanceID string, disks []aws.BlockDeviceMapping) {
+	for _, disk := range disks {
+		_ = c.client.DetachVolume(disk.EBS.VolumeID)
+		_ = c.client.WaitForVolumeStatus(disk.EBS.VolumeID, ec2.VolumeStateAvailable, c.waiter.Timeouts.DiskAttachment)
+		_ = c.client.AttachVolume(instanceID, disk.EBS.VolumeID, disk.DeviceName)
+	}
+}
+
+func (c *awsAPIClient) CheckPermissions(identifier string, ami string) []iaas.PermissionCheck {
+	describe := iaas.PermissionCheck{Permission: "ec2:DescribeInstances"}
+
+	vmInfo, err := c.client.GetVMInfo(identifier + "*")
+	if err != nil {
+		describe.Err = err
+		return []iaas.PermissionCheck{describe}
+	}
+
+	return append([]iaas.PermissionCheck{describe}, c.client.CheckPermissions(ami, vmInfo)...)
+}
+
+func (c *awsAPIClient) GetVM(identifier string) (iaas.VM, error) {
+	vmInfo, err := c.client.GetVMInfo(identifier + "*")
+	if err != nil {
+		return iaas.VM{}, err
+	}
+
+	return iaas.VM{
+		ID:    vmInfo.InstanceID,
+		Name:  vmInfo.Name,
+		Image: vmInfo.ImageID,
+	}, nil
+}
+
+func (c *awsAPIClient) DescribeVM(identifier string) (iaas.VMSpec, error) {
+	return c.client.DescribeVM(identifier + "*")
+}
+
 func (c *awsAPIClient) GetDisk(identifier string) (iaas.Disk, error) {
 	return iaas.Disk{SizeGB: int64(0)}, nil
 }
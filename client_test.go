@@ -1,13 +1,24 @@
 package cliaas_test
 
 import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/clock/fakeclock"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	. "github.com/pivotal-cf/cliaas"
 
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pivotal-cf/cliaas/iaas"
 	"github.com/pivotal-cf/cliaas/iaas/aws"
 	"github.com/pivotal-cf/cliaas/iaas/aws/awsfakes"
+	"github.com/pivotal-cf/cliaas/iaas/iaasfakes"
 )
 
 var _ = Describe("test for unexported features", func() {
@@ -15,6 +26,7 @@ var _ = Describe("test for unexported features", func() {
 		Context("when calling Replace on a running VM with valid arguments", func() {
 			var client Client
 			var fakeAPIClient *awsfakes.FakeAWSClient
+			var fakeProgress *iaasfakes.FakeProgressReporter
 			var callIndex = map[string]int{
 				"old-vm-shutdown": 0,
 				"new-vm-startup":  1,
@@ -23,6 +35,7 @@ var _ = Describe("test for unexported features", func() {
 			var expectedIdentifier = "abc"
 			var expectedDiskSizeGB = int64(10)
 			var expectedVMInfo = aws.VMInfo{
+				Name:         "ops-manager-2017-02-01-10-00-00",
 				InstanceID:   "1234",
 				InstanceType: "abc",
 				BlockDeviceMappings: []aws.BlockDeviceMapping{
@@ -40,11 +53,13 @@ var _ = Describe("test for unexported features", func() {
 
 			BeforeEach(func() {
 				fakeAPIClient = new(awsfakes.FakeAWSClient)
+				fakeProgress = new(iaasfakes.FakeProgressReporter)
 				fakeAPIClient.GetVMInfoReturns(expectedVMInfo, nil)
 				fakeAPIClient.StopVMReturns(nil)
 				fakeAPIClient.WaitForStatusReturns(nil)
 				fakeAPIClient.CreateVMReturns("1234", nil)
-				client = NewAWSAPIClient(fakeAPIClient)
+				namer := iaas.Namer{Clock: fakeclock.NewFakeClock(time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC))}
+				client = NewAWSAPIClient(fakeAPIClient, iaas.NewWaiter(clock.NewClock(), iaas.Timeouts{}), fakeProgress, namer, false, nil)
 
 				err := client.Replace(expectedIdentifier, expectedAMI, expectedDiskSizeGB)
 				Expect(err).ShouldNot(HaveOccurred())
@@ -55,21 +70,339 @@ var _ = Describe("test for unexported features", func() {
 			})
 
 			It("should wait for vm stopping after stopping the old vm", func() {
-				_, state := fakeAPIClient.WaitForStatusArgsForCall(callIndex["old-vm-shutdown"])
+				_, state, timeout := fakeAPIClient.WaitForStatusArgsForCall(callIndex["old-vm-shutdown"])
 				Expect(state).Should(Equal(ec2.InstanceStateNameStopped))
+				Expect(timeout).Should(Equal(iaas.DefaultTimeouts().Stop))
 			})
 
 			It("should wait for vm starting after starting the new vm", func() {
-				_, state := fakeAPIClient.WaitForStatusArgsForCall(callIndex["new-vm-startup"])
+				_, state, timeout := fakeAPIClient.WaitForStatusArgsForCall(callIndex["new-vm-startup"])
 				Expect(state).Should(Equal(ec2.InstanceStateNameRunning))
+				Expect(timeout).Should(Equal(iaas.DefaultTimeouts().Create))
+			})
+
+			It("should report each step", func() {
+				var steps []string
+				for i := 0; i < fakeProgress.ReportCallCount(); i++ {
+					steps = append(steps, fakeProgress.ReportArgsForCall(i).Step)
+				}
+				Expect(steps).To(Equal([]string{
+					iaas.StepFoundVM,
+					iaas.StepStopping,
+					iaas.StepStopped,
+					iaas.StepCreating,
+					iaas.StepWaitingForRunning,
+					iaas.StepRunning,
+					iaas.StepDone,
+				}))
 			})
 
 			It("should make a complete copy from old vm to new vm", func() {
-				ami, identifier, vmInfo := fakeAPIClient.CreateVMArgsForCall(0)
+				ami, name, vmInfo := fakeAPIClient.CreateVMArgsForCall(0)
 				Expect(ami).To(Equal(expectedAMI))
-				Expect(identifier).To(Equal(expectedIdentifier))
+				Expect(name).To(Equal("ops-manager-2017-03-01-12-00-00"))
 				Expect(vmInfo).To(Equal(expectedVMInfo))
 			})
 		})
+
+		Context("when the name template is invalid", func() {
+			It("should fail before stopping the old vm", func() {
+				fakeAPIClient := new(awsfakes.FakeAWSClient)
+				fakeAPIClient.GetVMInfoReturns(aws.VMInfo{Name: "ops-manager"}, nil)
+				client := NewAWSAPIClient(fakeAPIClient, iaas.NewWaiter(clock.NewClock(), iaas.Timeouts{}), nil, iaas.Namer{Template: "{{.Name}}"}, false, nil)
+
+				err := client.Replace("abc", "xyz", 10)
+				Expect(err).To(MatchError(ContainSubstring("invalid name template")))
+				Expect(fakeAPIClient.StopVMCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when migrating the data disk", func() {
+			var client Client
+			var fakeAPIClient *awsfakes.FakeAWSClient
+			var vmInfo = aws.VMInfo{
+				Name:           "ops-manager",
+				InstanceID:     "i-old",
+				RootDeviceName: "/dev/sda1",
+				BlockDeviceMappings: []aws.BlockDeviceMapping{
+					{DeviceName: "/dev/sda1", EBS: aws.EBS{VolumeID: "vol-root"}},
+					{DeviceName: "/dev/sdf", EBS: aws.EBS{VolumeID: "vol-data"}},
+				},
+			}
+
+			BeforeEach(func() {
+				fakeAPIClient = new(awsfakes.FakeAWSClient)
+				fakeAPIClient.GetVMInfoReturns(vmInfo, nil)
+				fakeAPIClient.CreateVMReturns("i-new", nil)
+				client = NewAWSAPIClient(fakeAPIClient, iaas.NewWaiter(clock.NewClock(), iaas.Timeouts{}), nil, iaas.Namer{}, true, nil)
+			})
+
+			It("should move the data disk to the new vm at the same device", func() {
+				Expect(client.Replace("ops-manager", "ami-new", 10)).To(Succeed())
+
+				Expect(fakeAPIClient.DetachVolumeArgsForCall(0)).To(Equal("vol-data"))
+				_, _, newVMInfo := fakeAPIClient.CreateVMArgsForCall(0)
+				Expect(newVMInfo.BlockDeviceMappings).To(Equal(vmInfo.BlockDeviceMappings[:1]))
+
+				Expect(fakeAPIClient.AttachVolumeCallCount()).To(Equal(1))
+				instanceID, volumeID, deviceName := fakeAPIClient.AttachVolumeArgsForCall(0)
+				Expect([]string{instanceID, volumeID, deviceName}).To(Equal([]string{"i-new", "vol-data", "/dev/sdf"}))
+			})
+
+			It("should move the data disk back to the old vm when attaching fails", func() {
+				fakeAPIClient.AttachVolumeStub = func(instanceID, volumeID, deviceName string) error {
+					if instanceID == "i-new" {
+						return errors.New("IncorrectState")
+					}
+					return nil
+				}
+
+				Expect(client.Replace("ops-manager", "ami-new", 10)).To(MatchError("IncorrectState"))
+
+				Expect(fakeAPIClient.AttachVolumeCallCount()).To(Equal(2))
+				instanceID, volumeID, deviceName := fakeAPIClient.AttachVolumeArgsForCall(1)
+				Expect([]string{instanceID, volumeID, deviceName}).To(Equal([]string{"i-old", "vol-data", "/dev/sdf"}))
+				Expect(fakeAPIClient.DeleteVMArgsForCall(0)).To(Equal("i-new"))
+				Expect(fakeAPIClient.StartVMArgsForCall(0)).To(Equal("i-old"))
+			})
+
+			It("should move the data disk back and start the old vm when the new vm does not come up", func() {
+				fakeAPIClient.WaitForStatusStub = func(instanceID string, status string, timeout time.Duration) error {
+					if instanceID == "i-new" {
+						return errors.New("timed out")
+					}
+					return nil
+				}
+
+				Expect(client.Replace("ops-manager", "ami-new", 10)).To(MatchError("timed out"))
+
+				Expect(fakeAPIClient.DeleteVMArgsForCall(0)).To(Equal("i-new"))
+				instanceID, volumeID, _ := fakeAPIClient.AttachVolumeArgsForCall(0)
+				Expect([]string{instanceID, volumeID}).To(Equal([]string{"i-old", "vol-data"}))
+				Expect(fakeAPIClient.StartVMArgsForCall(0)).To(Equal("i-old"))
+			})
+
+			It("should fail before stopping a vm without a data disk", func() {
+				fakeAPIClient.GetVMInfoReturns(vmInfo.WithoutDataDisks(), nil)
+
+				Expect(client.Replace("ops-manager", "ami-new", 10)).To(MatchError(ContainSubstring("no data disk to migrate")))
+				Expect(fakeAPIClient.StopVMCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when hooks are configured", func() {
+			var client Client
+			var fakeAPIClient *awsfakes.FakeAWSClient
+			var logFile string
+			var hooks iaas.Hooks
+
+			BeforeEach(func() {
+				dir, err := ioutil.TempDir("", "hooks")
+				Expect(err).NotTo(HaveOccurred())
+				logFile = filepath.Join(dir, "phases")
+
+				record := "echo $CLIAAS_PHASE $CLIAAS_OLD_VM_ID $CLIAAS_NEW_VM_ID >> " + logFile
+				hooks = iaas.Hooks{
+					PreStop:    record,
+					PostStop:   record,
+					PreCreate:  record,
+					PostCreate: record,
+					PostReady:  record,
+					OnRollback: record,
+				}
+
+				fakeAPIClient = new(awsfakes.FakeAWSClient)
+				fakeAPIClient.GetVMInfoReturns(aws.VMInfo{Name: "ops-manager", InstanceID: "i-old"}, nil)
+				fakeAPIClient.CreateVMReturns("i-new", nil)
+			})
+
+			AfterEach(func() {
+				os.RemoveAll(filepath.Dir(logFile))
+			})
+
+			newClient := func() Client {
+				runner := &iaas.HookRunner{Hooks: hooks, IaaS: "aws", Output: GinkgoWriter}
+				return NewAWSAPIClient(fakeAPIClient, iaas.NewWaiter(clock.NewClock(), iaas.Timeouts{}), nil, iaas.Namer{}, false, runner)
+			}
+
+			phases := func() string {
+				contents, err := ioutil.ReadFile(logFile)
+				if os.IsNotExist(err) {
+					return ""
+				}
+				Expect(err).NotTo(HaveOccurred())
+				return string(contents)
+			}
+
+			It("should run each hook at its phase", func() {
+				client = newClient()
+				err := client.Replace("ops-manager", "xyz", 10)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(phases()).To(Equal("pre-stop i-old\n" +
+					"post-stop i-old\n" +
+					"pre-create i-old\n" +
+					"post-create i-old i-new\n" +
+					"post-ready i-old i-new\n"))
+			})
+
+			It("should abort without touching the vm when the pre-stop hook fails", func() {
+				hooks.PreStop = "exit 1"
+				client = newClient()
+
+				err := client.Replace("ops-manager", "xyz", 10)
+				Expect(err).To(MatchError(ContainSubstring("pre-stop hook failed")))
+				Expect(fakeAPIClient.StopVMCallCount()).To(Equal(0))
+				Expect(phases()).NotTo(ContainSubstring("on-rollback"))
+			})
+
+			It("should restart the old vm and run the rollback hook when the pre-create hook fails", func() {
+				hooks.PreCreate = "exit 1"
+				client = newClient()
+
+				err := client.Replace("ops-manager", "xyz", 10)
+				Expect(err).To(MatchError(ContainSubstring("pre-create hook failed")))
+				Expect(fakeAPIClient.CreateVMCallCount()).To(Equal(0))
+				Expect(fakeAPIClient.StartVMArgsForCall(0)).To(Equal("i-old"))
+				Expect(phases()).To(HaveSuffix("on-rollback i-old\n"))
+			})
+
+			It("should not fail the replace when a post hook fails", func() {
+				hooks.PostReady = "exit 1"
+				client = newClient()
+
+				err := client.Replace("ops-manager", "xyz", 10)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(phases()).NotTo(ContainSubstring("on-rollback"))
+			})
+		})
+
+		Context("when stopping and starting the vm", func() {
+			var client Client
+			var fakeAPIClient *awsfakes.FakeAWSClient
+
+			BeforeEach(func() {
+				fakeAPIClient = new(awsfakes.FakeAWSClient)
+				fakeAPIClient.GetVMInfoReturns(aws.VMInfo{Name: "ops-manager", InstanceID: "i-running"}, nil)
+				fakeAPIClient.GetStoppedVMInfoReturns(aws.VMInfo{Name: "ops-manager", InstanceID: "i-stopped"}, nil)
+				client = NewAWSAPIClient(fakeAPIClient, iaas.NewWaiter(clock.NewClock(), iaas.Timeouts{}), nil, iaas.Namer{}, false, nil)
+			})
+
+			It("should shut the running instance down without waiting by default", func() {
+				Expect(client.Stop("ops-manager", false)).To(Succeed())
+				Expect(fakeAPIClient.GetVMInfoArgsForCall(0)).To(Equal("ops-manager*"))
+				Expect(fakeAPIClient.ShutdownVMArgsForCall(0)).To(Equal("i-running"))
+				Expect(fakeAPIClient.StopVMCallCount()).To(Equal(0))
+				Expect(fakeAPIClient.WaitForStatusCallCount()).To(Equal(0))
+			})
+
+			It("should wait for the stopped instance to be running", func() {
+				Expect(client.Start("ops-manager", true)).To(Succeed())
+				Expect(fakeAPIClient.GetStoppedVMInfoArgsForCall(0)).To(Equal("ops-manager*"))
+				Expect(fakeAPIClient.StartVMArgsForCall(0)).To(Equal("i-stopped"))
+				instanceID, state, timeout := fakeAPIClient.WaitForStatusArgsForCall(0)
+				Expect(instanceID).To(Equal("i-stopped"))
+				Expect(state).To(Equal(ec2.InstanceStateNameRunning))
+				Expect(timeout).To(Equal(iaas.DefaultTimeouts().Start))
+			})
+		})
+
+		Context("when creating a vm from a spec", func() {
+			var client Client
+			var fakeAPIClient *awsfakes.FakeAWSClient
+			var spec iaas.VMSpec
+
+			BeforeEach(func() {
+				fakeAPIClient = new(awsfakes.FakeAWSClient)
+				fakeAPIClient.CreateVMReturns("i-new", nil)
+				client = NewAWSAPIClient(fakeAPIClient, iaas.NewWaiter(clock.NewClock(), iaas.Timeouts{}), nil, iaas.Namer{}, false, nil)
+				spec = iaas.VMSpec{
+					Name:         "ops-manager",
+					InstanceType: "m4.large",
+					Network: iaas.NetworkSpec{
+						Subnet:    "subnet-1",
+						PrivateIP: "10.0.0.5",
+						PublicIP:  "54.0.0.5",
+					},
+					Disks: []iaas.DiskSpec{{Name: "/dev/sda1", Boot: true, SizeGB: 100}},
+					Tags:  map[string]string{"env": "prod"},
+				}
+			})
+
+			It("should launch the instance and associate its elastic ip", func() {
+				vm, err := client.Create("ami-1", spec)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(vm).To(Equal(iaas.VM{ID: "i-new", Name: "ops-manager", Image: "ami-1"}))
+
+				ami, name, vmInfo := fakeAPIClient.CreateVMArgsForCall(0)
+				Expect(ami).To(Equal("ami-1"))
+				Expect(name).To(Equal("ops-manager"))
+				Expect(vmInfo.PrivateIP).To(Equal("10.0.0.5"))
+				Expect(vmInfo.Tags).To(Equal(map[string]string{"env": "prod"}))
+				Expect(vmInfo.BlockDeviceMappings).To(Equal([]aws.BlockDeviceMapping{
+					{DeviceName: "/dev/sda1", EBS: aws.EBS{DeleteOnTermination: true, VolumeSize: 100, VolumeType: "gp2"}},
+				}))
+
+				instanceID, ip := fakeAPIClient.AssignPublicIPArgsForCall(0)
+				Expect(instanceID).To(Equal("i-new"))
+				Expect(ip).To(Equal("54.0.0.5"))
+			})
+
+			It("should terminate the instance when it does not start", func() {
+				fakeAPIClient.WaitForStatusReturns(errors.New("timed out"))
+
+				_, err := client.Create("ami-1", spec)
+				Expect(err).To(MatchError("timed out"))
+				Expect(fakeAPIClient.DeleteVMArgsForCall(0)).To(Equal("i-new"))
+				Expect(fakeAPIClient.AssignPublicIPCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when getting the vm", func() {
+			It("should return the matching instance and its ami", func() {
+				fakeAPIClient := new(awsfakes.FakeAWSClient)
+				fakeAPIClient.GetVMInfoReturns(aws.VMInfo{Name: "ops-manager", InstanceID: "1234", ImageID: "ami-019e4617"}, nil)
+				client := NewAWSAPIClient(fakeAPIClient, iaas.NewWaiter(clock.NewClock(), iaas.Timeouts{}), nil, iaas.Namer{}, false, nil)
+
+				vm, err := client.GetVM("ops-manager")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(vm).To(Equal(iaas.VM{ID: "1234", Name: "ops-manager", Image: "ami-019e4617"}))
+				Expect(fakeAPIClient.GetVMInfoArgsForCall(0)).To(Equal("ops-manager*"))
+			})
+		})
+
+		Context("when checking permissions", func() {
+			var client Client
+			var fakeAPIClient *awsfakes.FakeAWSClient
+
+			BeforeEach(func() {
+				fakeAPIClient = new(awsfakes.FakeAWSClient)
+				client = NewAWSAPIClient(fakeAPIClient, iaas.NewWaiter(clock.NewClock(), iaas.Timeouts{}), nil, iaas.Namer{}, false, nil)
+			})
+
+			It("should dry run the calls against the matching vm", func() {
+				vmInfo := aws.VMInfo{InstanceID: "1234"}
+				fakeAPIClient.GetVMInfoReturns(vmInfo, nil)
+				fakeAPIClient.CheckPermissionsReturns([]iaas.PermissionCheck{{Permission: "ec2:RunInstances"}})
+
+				checks := client.CheckPermissions("abc", "xyz")
+				Expect(checks).To(Equal([]iaas.PermissionCheck{
+					{Permission: "ec2:DescribeInstances"},
+					{Permission: "ec2:RunInstances"},
+				}))
+				Expect(fakeAPIClient.GetVMInfoArgsForCall(0)).To(Equal("abc*"))
+				ami, actualVMInfo := fakeAPIClient.CheckPermissionsArgsForCall(0)
+				Expect(ami).To(Equal("xyz"))
+				Expect(actualVMInfo).To(Equal(vmInfo))
+			})
+
+			It("should stop at a failing vm lookup", func() {
+				controlErr := errors.New("UnauthorizedOperation")
+				fakeAPIClient.GetVMInfoReturns(aws.VMInfo{}, controlErr)
+
+				checks := client.CheckPermissions("abc", "xyz")
+				Expect(checks).To(Equal([]iaas.PermissionCheck{{Permission: "ec2:DescribeInstances", Err: controlErr}}))
+				Expect(fakeAPIClient.CheckPermissionsCallCount()).To(Equal(0))
+			})
+		})
 	})
 })
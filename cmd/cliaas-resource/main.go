@@ -0,0 +1,13 @@
+package main
+
+import (
+	"os"
+
+	"github.com/pivotal-cf/cliaas/resource"
+)
+
+// main is installed as /opt/resource/check, in and out, or run with the
+// script name as its first argument
+func main() {
+	os.Exit(resource.Main(os.Args, os.Stdin, os.Stdout, os.Stderr))
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"log"
+	"os"
 
 	flags "github.com/jessevdk/go-flags"
 	"github.com/pivotal-cf/cliaas/commands"
@@ -13,6 +14,7 @@ func main() {
 
 	_, err := parser.Parse()
 	if err != nil {
-		log.Fatalf("error: %s", err)
+		log.Printf("error: %s", commands.Cliaas.Redact(err.Error()))
+		os.Exit(commands.ExitCode(err))
 	}
 }
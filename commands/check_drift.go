@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/pivotal-cf/cliaas/iaas"
+	yaml "gopkg.in/yaml.v2"
+)
+
+type CheckDriftCommand struct {
+	Identifier string `short:"i" long:"identifier" required:"true" description:"Identifier of the VM to check"`
+	Spec       string `long:"spec" required:"true" description:"Path to the spec the VM should match, as printed by describe-vm"`
+}
+
+func (c *CheckDriftCommand) Execute([]string) error {
+	contents, err := ioutil.ReadFile(c.Spec)
+	if err != nil {
+		return fmt.Errorf("failed to read spec: %s", err)
+	}
+
+	var desired iaas.VMSpec
+	err = yaml.Unmarshal(contents, &desired)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal spec: %s", err)
+	}
+
+	config, err := Cliaas.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	client, err := config.NewClient()
+	if err != nil {
+		return err
+	}
+
+	live, err := client.DescribeVM(c.Identifier)
+	if err != nil {
+		return err
+	}
+
+	drifts := desired.Drift(live)
+	if len(drifts) > 0 {
+		return &iaas.DriftError{Drifts: drifts}
+	}
+
+	fmt.Println("no drift")
+	return nil
+}
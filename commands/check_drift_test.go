@@ -0,0 +1,26 @@
+package commands_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/pivotal-cf/cliaas/commands"
+)
+
+var _ = Describe("CheckDrift", func() {
+	It("errors if the identifier or spec is not provided", func() {
+		_, err := flags.ParseArgs(&commands.CheckDriftCommand{}, []string{"--spec", "vm.yml"})
+		Expect(err).To(HaveOccurred())
+
+		_, err = flags.ParseArgs(&commands.CheckDriftCommand{}, []string{"-i", "ops-manager"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("reads the spec path", func() {
+		command := commands.CheckDriftCommand{}
+		_, err := flags.ParseArgs(&command, []string{"-i", "ops-manager", "--spec", "vm.yml"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(command.Spec).To(Equal("vm.yml"))
+	})
+})
@@ -1,11 +1,15 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
-	"log"
+	"os"
 
+	"code.cloudfoundry.org/clock"
 	"github.com/pivotal-cf/cliaas"
+	"github.com/pivotal-cf/cliaas/iaas"
 
 	yaml "gopkg.in/yaml.v2"
 )
@@ -13,41 +17,189 @@ import (
 type ConfigFilePath string
 
 func (c *ConfigFilePath) UnmarshalFlag(value string) error {
-	bs, err := ioutil.ReadFile(value)
+	_, err := ioutil.ReadFile(value)
 	if err != nil {
 		return fmt.Errorf("failed to read config: %s", err)
 	}
 
-	var multiConfig cliaas.MultiConfig
-	err = yaml.Unmarshal(bs, &multiConfig)
+	*c = ConfigFilePath(value)
+	return nil
+}
+
+// VarsFile is a --vars-file flag, parsed as the flag is read so that a broken
+// file is reported before any command runs
+type VarsFile struct {
+	Path string
+	Vars map[string]interface{}
+}
+
+func (v *VarsFile) UnmarshalFlag(value string) error {
+	vars, err := cliaas.LoadVarsFile(value)
 	if err != nil {
-		log.Fatalf("failed to unmarshal config: %s", err)
+		return err
 	}
 
-	completeConfigs := multiConfig.CompleteConfigs()
+	v.Path = value
+	v.Vars = vars
+	return nil
+}
 
-	if len(completeConfigs) == 0 {
-		log.Fatalf("zero iaas configurations exists in config")
+type CliaasCommand struct {
+	Config   cliaas.Config
+	Redactor *cliaas.Redactor
+
+	ConfigFile ConfigFilePath `short:"c" long:"config" required:"true" description:"Path to config file"`
+	VarsFiles  []VarsFile     `long:"vars-file" description:"Path to a YAML file of values for ((placeholders)) in the config file, may be given more than once"`
+	StrictVars bool           `long:"strict-vars" description:"Fail when a placeholder in the config file cannot be resolved"`
+	Target     string         `short:"t" long:"target" description:"Name of the target to use from a config file with multiple targets"`
+
+	Progress  string `long:"progress" choice:"text" choice:"json" choice:"none" default:"text" description:"How to report the steps of a replace on stderr"`
+	LogLevel  string `long:"log-level" choice:"debug" choice:"info" choice:"warn" choice:"error" default:"info" description:"Least severe log lines to write on stderr, debug includes every IaaS API call"`
+	LogFormat string `long:"log-format" choice:"text" choice:"json" default:"text" description:"How to write log lines on stderr"`
+
+	NameTemplate string `long:"name-template" description:"Template naming the VMs, images and disks a replace creates, overriding name_template in the config file"`
+
+	Timeouts iaas.Timeouts `group:"Timeouts"`
+
+	CreateVM       CreateVMCommand       `command:"create-vm" description:"Create a new VM from the image in the config file and a spec"`
+	ReplaceVM      ReplaceVMCommand      `command:"replace-vm" description:"Create a new VM with the old VM's IP"`
+	ReplaceMany    ReplaceManyCommand    `command:"replace-many" description:"Replace the VMs listed in an inventory, canaries first"`
+	UpgradeOpsMan  UpgradeOpsManCommand  `command:"upgrade-opsman" description:"Export the Ops Manager installation, replace the VM and import the installation into the new VM"`
+	DeleteVM       DeleteVMCommand       `command:"delete-vm" description:"Delete the VM that has the specified identifier"`
+	StopVM         StopVMCommand         `command:"stop-vm" description:"Stop the VM that has the specified identifier"`
+	StartVM        StartVMCommand        `command:"start-vm" description:"Start the stopped VM that has the specified identifier"`
+	DescribeVM     DescribeVMCommand     `command:"describe-vm" description:"Print the spec of the VM that has the specified identifier as yaml"`
+	CheckDrift     CheckDriftCommand     `command:"check-drift" description:"Compare the VM that has the specified identifier with a spec file"`
+	GetVMDiskSize  GetVMDiskSizeCommand  `command:"get-vm-disk-size" description:"Get disk size for VM that has the specified identifier"`
+	Doctor         DoctorCommand         `command:"doctor" description:"Check that the credentials allow everything replace-vm and delete-vm need"`
+	Serve          ServeCommand          `command:"serve" description:"Serve replace, delete and get-disk over an HTTP API"`
+	Targets        TargetsCommand        `command:"targets" description:"List the targets configured in the config file"`
+	ValidateConfig ValidateConfigCommand `command:"validate-config" description:"List every missing, unknown or malformed field in the config file"`
+	Version        VersionCommand        `command:"version" description:"Display the current version of the CLI"`
+}
+
+var Cliaas CliaasCommand
+
+// LoadConfig reads the config file once every flag has been parsed, so vars
+// files, strict mode and the target apply regardless of their position on the
+// command line
+func (c *CliaasCommand) LoadConfig() (cliaas.Config, error) {
+	if c.Config != nil {
+		return c.Config, nil
 	}
 
-	if len(completeConfigs) > 1 {
-		log.Fatalf("more than one iaas configuration exists in config")
+	if c.NameTemplate != "" {
+		err := iaas.ValidateNameTemplate(c.NameTemplate)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	Cliaas.Config = completeConfigs[0]
+	configFile, err := c.LoadConfigFile()
+	if err != nil {
+		return nil, err
+	}
 
-	return nil
+	c.Config, err = configFile.Target(c.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Redactor.Add(c.Config.Secrets()...)
+	c.ApplyFlags(c.Config)
+
+	return c.Config, nil
 }
 
-type CliaasCommand struct {
-	Config cliaas.Config
+// ApplyFlags overrides the shared settings of config with the global flags,
+// and points its progress and logs at stderr
+func (c *CliaasCommand) ApplyFlags(config cliaas.Config) {
+	options := config.Options()
+	options.Timeouts = options.Timeouts.Merge(c.Timeouts)
+	if c.NameTemplate != "" {
+		options.NameTemplate = c.NameTemplate
+	}
+	options.Progress = iaas.NewProgressReporter(c.Progress, os.Stderr, clock.NewClock(), c.Redact)
+	options.Logger = c.NewLogger(os.Stderr, clock.NewClock())
+}
 
-	ConfigFile ConfigFilePath `short:"c" long:"config" required:"true" description:"Path to config file"`
+// NewLogger returns a logger for the --log-level and --log-format flags that
+// redacts every secret in the config
+func (c *CliaasCommand) NewLogger(w io.Writer, clock clock.Clock) *iaas.Logger {
+	level, err := iaas.ParseLogLevel(c.LogLevel)
+	if err != nil {
+		level = iaas.LogInfo
+	}
+	if c.Redactor == nil {
+		c.Redactor = cliaas.NewRedactor()
+	}
 
-	ReplaceVM     ReplaceVMCommand     `command:"replace-vm" description:"Create a new VM with the old VM's IP"`
-	DeleteVM      DeleteVMCommand      `command:"delete-vm" description:"Delete the VM that has the specified identifier"`
-	GetVMDiskSize GetVMDiskSizeCommand `command:"get-vm-disk-size" description:"Get disk size for VM that has the specified identifier"`
-	Version       VersionCommand       `command:"version" description:"Display the current version of the CLI"`
+	return &iaas.Logger{
+		Writer:     w,
+		Level:      level,
+		Format:     c.LogFormat,
+		Clock:      clock,
+		Redact:     c.Redact,
+		AddSecrets: c.Redactor.Add,
+	}
 }
 
-var Cliaas CliaasCommand
+// LoadConfigFile reads and interpolates the whole config file
+func (c *CliaasCommand) LoadConfigFile() (*cliaas.ConfigFile, error) {
+	contents, err := c.InterpolatedConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var configFile cliaas.ConfigFile
+	err = yaml.Unmarshal(contents, &configFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %s", c.Redact(err.Error()))
+	}
+
+	return &configFile, nil
+}
+
+// InterpolatedConfig returns the config file contents with every placeholder
+// resolved
+func (c *CliaasCommand) InterpolatedConfig() ([]byte, error) {
+	return c.InterpolateFile(string(c.ConfigFile))
+}
+
+// InterpolateFile resolves the placeholders of another config file with the
+// same vars files, and adds the values it resolves to the redactor
+func (c *CliaasCommand) InterpolateFile(path string) ([]byte, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %s", err)
+	}
+
+	vars := map[string]interface{}{}
+	for _, varsFile := range c.VarsFiles {
+		for name, value := range varsFile.Vars {
+			vars[name] = value
+		}
+	}
+
+	interpolator := cliaas.Interpolator{
+		Vars:      vars,
+		LookupEnv: os.LookupEnv,
+		Strict:    c.StrictVars,
+	}
+
+	contents, secrets, err := interpolator.Interpolate(contents)
+	if c.Redactor == nil {
+		c.Redactor = cliaas.NewRedactor()
+	}
+	c.Redactor.Add(secrets...)
+	if err != nil {
+		return nil, errors.New(c.Redact(err.Error()))
+	}
+
+	return contents, nil
+}
+
+// Redact hides every value interpolated into the config
+func (c *CliaasCommand) Redact(s string) string {
+	return c.Redactor.Redact(s)
+}
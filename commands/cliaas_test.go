@@ -0,0 +1,220 @@
+package commands_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/cliaas"
+	"github.com/pivotal-cf/cliaas/commands"
+	"github.com/pivotal-cf/cliaas/iaas"
+)
+
+var _ = Describe("CliaasCommand", func() {
+	Describe("LoadConfig", func() {
+		var (
+			tmpDir  string
+			command commands.CliaasCommand
+		)
+
+		writeFile := func(name string, contents string) string {
+			path := filepath.Join(tmpDir, name)
+			Expect(ioutil.WriteFile(path, []byte(contents), 0600)).To(Succeed())
+			return path
+		}
+
+		varsFile := func(contents string) commands.VarsFile {
+			var flag commands.VarsFile
+			Expect(flag.UnmarshalFlag(writeFile("vars.yml", contents))).To(Succeed())
+			return flag
+		}
+
+		BeforeEach(func() {
+			var err error
+			tmpDir, err = ioutil.TempDir("", "commands")
+			Expect(err).NotTo(HaveOccurred())
+
+			command = commands.CliaasCommand{
+				ConfigFile: commands.ConfigFilePath(writeFile("config.yml", `
+aws:
+  access_key_id: ((access_key_id))
+  secret_access_key: ${CLIAAS_TEST_SECRET_ACCESS_KEY}
+  region: us-east-1
+  vpc: vpc-yyyyyyyy
+  ami: ami-nnnnnnnn
+`)),
+			}
+			os.Setenv("CLIAAS_TEST_SECRET_ACCESS_KEY", "secret-from-env")
+		})
+
+		AfterEach(func() {
+			os.Unsetenv("CLIAAS_TEST_SECRET_ACCESS_KEY")
+			os.RemoveAll(tmpDir)
+		})
+
+		It("resolves placeholders from vars files and the environment", func() {
+			command.VarsFiles = []commands.VarsFile{varsFile("access_key_id: key-from-vars-file\n")}
+
+			config, err := command.LoadConfig()
+			Expect(err).NotTo(HaveOccurred())
+
+			awsConfig, ok := config.(*cliaas.AWSConfig)
+			Expect(ok).To(BeTrue())
+			Expect(awsConfig.AccessKeyID).To(Equal("key-from-vars-file"))
+			Expect(awsConfig.SecretAccessKey).To(Equal("secret-from-env"))
+		})
+
+		It("reports a broken vars file as the flag is read", func() {
+			var flag commands.VarsFile
+			err := flag.UnmarshalFlag(writeFile("vars.yml", "access_key_id: [unclosed\n"))
+			Expect(err).To(MatchError(ContainSubstring("failed to parse vars file")))
+		})
+
+		It("redacts resolved values", func() {
+			command.VarsFiles = []commands.VarsFile{varsFile("access_key_id: key-from-vars-file\n")}
+
+			_, err := command.LoadConfig()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(command.Redact("failed with key-from-vars-file and secret-from-env")).To(Equal("failed with [REDACTED] and [REDACTED]"))
+		})
+
+		It("overrides the config timeouts with the timeout flags", func() {
+			command.ConfigFile = commands.ConfigFilePath(writeFile("config.yml", `
+aws:
+  access_key_id: some-access-key-id
+  secret_access_key: some-secret-access-key
+  region: us-east-1
+  vpc: vpc-yyyyyyyy
+  ami: ami-nnnnnnnn
+  timeouts:
+    stop: 5m
+    create: 5m
+`))
+			command.Timeouts = iaas.Timeouts{Create: 20 * time.Minute}
+
+			config, err := command.LoadConfig()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.Options().Timeouts).To(Equal(iaas.Timeouts{
+				Stop:   5 * time.Minute,
+				Create: 20 * time.Minute,
+			}))
+		})
+
+		It("overrides the config name template with the name template flag", func() {
+			command.ConfigFile = commands.ConfigFilePath(writeFile("config.yml", `
+aws:
+  access_key_id: some-access-key-id
+  secret_access_key: some-secret-access-key
+  region: us-east-1
+  vpc: vpc-yyyyyyyy
+  ami: ami-nnnnnnnn
+  name_template: "{{.Identifier}}-{{.Timestamp}}"
+`))
+			command.NameTemplate = "{{.Identifier}}-{{.Version}}-{{.Timestamp}}{{.Suffix}}"
+
+			config, err := command.LoadConfig()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.Options().NameTemplate).To(Equal("{{.Identifier}}-{{.Version}}-{{.Timestamp}}{{.Suffix}}"))
+		})
+
+		It("rejects a name template flag that does not name the vm after the identifier", func() {
+			command.ConfigFile = commands.ConfigFilePath(writeFile("config.yml", `
+aws:
+  access_key_id: some-access-key-id
+  secret_access_key: some-secret-access-key
+  region: us-east-1
+  vpc: vpc-yyyyyyyy
+  ami: ami-nnnnnnnn
+`))
+			command.NameTemplate = "opsman-{{.Version}}-{{.Timestamp}}{{.Suffix}}"
+
+			_, err := command.LoadConfig()
+			Expect(err).To(MatchError(ContainSubstring("does not start with")))
+		})
+
+		It("redacts the secrets written literally into the config from log lines", func() {
+			command.ConfigFile = commands.ConfigFilePath(writeFile("config.yml", `
+aws:
+  access_key_id: some-access-key-id
+  secret_access_key: some-secret-access-key
+  region: us-east-1
+  vpc: vpc-yyyyyyyy
+  ami: ami-nnnnnnnn
+`))
+			command.LogLevel = "warn"
+			command.LogFormat = iaas.LogFormatJSON
+
+			config, err := command.LoadConfig()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.Options().Logger.Level).To(Equal(iaas.LogWarn))
+
+			output := new(bytes.Buffer)
+			logger := command.NewLogger(output, fakeclock.NewFakeClock(time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC)))
+			logger.Info("not written", nil)
+			logger.Warn("signing with some-secret-access-key failed", iaas.Fields{"user": "some-access-key-id"})
+			Expect(output.String()).To(Equal(`{"level":"warn","message":"signing with [REDACTED] failed","time":"2017-03-01T12:00:00Z","user":"[REDACTED]"}` + "\n"))
+		})
+
+		Context("in strict mode", func() {
+			BeforeEach(func() {
+				command.StrictVars = true
+			})
+
+			It("errors on unresolved placeholders", func() {
+				_, err := command.LoadConfig()
+				Expect(err).To(MatchError(ContainSubstring("access_key_id")))
+			})
+		})
+
+		Context("when the config has named targets", func() {
+			BeforeEach(func() {
+				command.ConfigFile = commands.ConfigFilePath(writeFile("config.yml", `
+targets:
+  prod-east:
+    aws:
+      access_key_id: some-access-key-id
+      secret_access_key: some-secret-access-key
+      region: us-east-1
+      vpc: vpc-yyyyyyyy
+      ami: ((prod_ami))
+  dev-east:
+    aws:
+      access_key_id: some-access-key-id
+      secret_access_key: some-secret-access-key
+      region: us-east-1
+      vpc: vpc-zzzzzzzz
+      ami: ami-dev
+`))
+				command.VarsFiles = []commands.VarsFile{varsFile("prod_ami: ami-prod\n")}
+			})
+
+			It("loads the selected target", func() {
+				command.Target = "prod-east"
+
+				config, err := command.LoadConfig()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.Image()).To(Equal("ami-prod"))
+			})
+
+			It("errors when no target is selected", func() {
+				_, err := command.LoadConfig()
+				Expect(err).To(MatchError(ContainSubstring("a target is required")))
+			})
+		})
+
+		Context("when the placeholders leave the config incomplete", func() {
+			It("errors without exiting", func() {
+				command.ConfigFile = commands.ConfigFilePath(writeFile("config.yml", "aws:\n  access_key_id: ((missing))\n"))
+
+				_, err := command.LoadConfig()
+				Expect(err).To(MatchError("zero iaas configurations exists in config"))
+			})
+		})
+	})
+})
@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/pivotal-cf/cliaas/iaas"
+	yaml "gopkg.in/yaml.v2"
+)
+
+type CreateVMCommand struct {
+	Spec string `long:"spec" required:"true" description:"Path to the spec of the VM to create, as printed by describe-vm"`
+	Name string `long:"name" description:"Name of the new VM, instead of the name in the spec"`
+}
+
+func (c *CreateVMCommand) Execute([]string) error {
+	contents, err := ioutil.ReadFile(c.Spec)
+	if err != nil {
+		return fmt.Errorf("failed to read spec: %s", err)
+	}
+
+	var spec iaas.VMSpec
+	err = yaml.Unmarshal(contents, &spec)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal spec: %s", err)
+	}
+	if c.Name != "" {
+		spec.Name = c.Name
+	}
+	if spec.Name == "" || spec.InstanceType == "" {
+		return fmt.Errorf("the spec needs a name and an instance_type")
+	}
+
+	config, err := Cliaas.LoadConfig()
+	if err != nil {
+		return err
+	}
+	if spec.IaaS != "" && spec.IaaS != config.IaaS() {
+		return fmt.Errorf("the spec is for %s, but the config is for %s", spec.IaaS, config.IaaS())
+	}
+
+	client, err := config.NewClient()
+	if err != nil {
+		return err
+	}
+
+	vm, err := client.Create(config.Image(), spec)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(vm.Name)
+	return nil
+}
@@ -0,0 +1,23 @@
+package commands_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/pivotal-cf/cliaas/commands"
+)
+
+var _ = Describe("CreateVM", func() {
+	It("errors if the spec is not provided", func() {
+		_, err := flags.ParseArgs(&commands.CreateVMCommand{}, []string{"--name", "ops-manager"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("takes the name from the spec unless one is given", func() {
+		command := commands.CreateVMCommand{}
+		_, err := flags.ParseArgs(&command, []string{"--spec", "vm.yml"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(command.Name).To(BeEmpty())
+	})
+})
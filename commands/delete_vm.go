@@ -5,7 +5,12 @@ type DeleteVMCommand struct {
 }
 
 func (c *DeleteVMCommand) Execute([]string) error {
-	client, err := Cliaas.Config.NewClient()
+	config, err := Cliaas.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	client, err := config.NewClient()
 	if err != nil {
 		return err
 	}
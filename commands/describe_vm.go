@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pivotal-cf/cliaas/iaas"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// rawVMDescription is the spec with the iaas's own description of the vm
+// under raw
+type rawVMDescription struct {
+	iaas.VMSpec `yaml:",inline"`
+	Raw         interface{} `yaml:"raw"`
+}
+
+type DescribeVMCommand struct {
+	Identifier string `short:"i" long:"identifier" required:"true" description:"Identifier of the VM to describe"`
+	Raw        bool   `long:"raw" description:"Also print the IaaS's own description of the VM under raw"`
+}
+
+func (c *DescribeVMCommand) Execute([]string) error {
+	config, err := Cliaas.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	client, err := config.NewClient()
+	if err != nil {
+		return err
+	}
+
+	spec, err := client.DescribeVM(c.Identifier)
+	if err != nil {
+		return err
+	}
+
+	var description interface{} = spec
+	if c.Raw {
+		raw, err := toPlainData(spec.Raw)
+		if err != nil {
+			return fmt.Errorf("failed to marshal the description of %s: %s", c.Identifier, err)
+		}
+		description = rawVMDescription{VMSpec: spec, Raw: raw}
+	}
+
+	output, err := yaml.Marshal(description)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the description of %s: %s", c.Identifier, err)
+	}
+
+	fmt.Print(string(output))
+	return nil
+}
+
+// toPlainData turns the sdk's structs into maps and lists, so that they are
+// printed with their json field names
+func toPlainData(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var plain interface{}
+	err = json.Unmarshal(data, &plain)
+	return plain, err
+}
@@ -0,0 +1,27 @@
+package commands_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/pivotal-cf/cliaas/commands"
+)
+
+var _ = Describe("DescribeVM", func() {
+	It("errors if the identifier is not provided", func() {
+		_, err := flags.ParseArgs(&commands.DescribeVMCommand{}, []string{})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("prints the spec unless asked for the raw description", func() {
+		command := commands.DescribeVMCommand{}
+		_, err := flags.ParseArgs(&command, []string{"-i", "ops-manager"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(command.Raw).To(BeFalse())
+
+		_, err = flags.ParseArgs(&command, []string{"-i", "ops-manager", "--raw"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(command.Raw).To(BeTrue())
+	})
+})
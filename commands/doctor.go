@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/pivotal-cf/cliaas/iaas"
+)
+
+type DoctorCommand struct {
+	Identifier string `short:"i" long:"identifier" required:"true" description:"Identifier of the VM that will be replaced or deleted"`
+}
+
+func (c *DoctorCommand) Execute([]string) error {
+	config, err := Cliaas.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	client, err := config.NewClient()
+	if err != nil {
+		return err
+	}
+
+	checks := client.CheckPermissions(c.Identifier, config.Image())
+	err = PrintPermissionChecks(os.Stdout, config.IaaS(), checks)
+	if err != nil {
+		return err
+	}
+
+	return FailedPermissionChecks(checks)
+}
+
+// PrintPermissionChecks writes a pass/fail matrix of the checks
+func PrintPermissionChecks(w io.Writer, iaasName string, checks []iaas.PermissionCheck) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "IAAS\tPERMISSION\tRESULT\tDETAIL")
+	for _, check := range checks {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", iaasName, check.Permission, check.Result(), Cliaas.Redact(check.Detail()))
+	}
+	return tw.Flush()
+}
+
+// FailedPermissionChecks returns an error counting the failed checks, if any
+func FailedPermissionChecks(checks []iaas.PermissionCheck) error {
+	var failed int
+	for _, check := range checks {
+		if check.Err != nil {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d permission checks failed", failed, len(checks))
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package commands_test
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/jessevdk/go-flags"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/cliaas/commands"
+	"github.com/pivotal-cf/cliaas/iaas"
+)
+
+var _ = Describe("Doctor", func() {
+	It("errors if the identifier is not provided", func() {
+		r := commands.DoctorCommand{}
+		_, err := flags.ParseArgs(&r, []string{})
+		Expect(err).To(HaveOccurred())
+	})
+
+	checks := []iaas.PermissionCheck{
+		{Permission: "ec2:RunInstances"},
+		{Permission: "ec2:AssociateAddress", SkipReason: "the vm has no public ip"},
+		{Permission: "ec2:TerminateInstances", Err: errors.New("UnauthorizedOperation")},
+	}
+
+	It("prints a pass/fail matrix", func() {
+		var out bytes.Buffer
+		Expect(commands.PrintPermissionChecks(&out, "aws", checks)).To(Succeed())
+		Expect(out.String()).To(Equal(
+			"IAAS  PERMISSION              RESULT  DETAIL\n" +
+				"aws   ec2:RunInstances        PASS    \n" +
+				"aws   ec2:AssociateAddress    SKIP    the vm has no public ip\n" +
+				"aws   ec2:TerminateInstances  FAIL    UnauthorizedOperation\n"))
+	})
+
+	It("fails when any check fails", func() {
+		Expect(commands.FailedPermissionChecks(checks)).To(MatchError("1 of 3 permission checks failed"))
+		Expect(commands.FailedPermissionChecks(checks[:2])).To(Succeed())
+	})
+})
@@ -0,0 +1,46 @@
+package commands
+
+import (
+	flags "github.com/jessevdk/go-flags"
+	"github.com/pivotal-cf/cliaas/iaas"
+	errwrap "github.com/pkg/errors"
+)
+
+// Exit codes, so that scripts can tell a run worth retrying from one that
+// needs fixing
+const (
+	ExitFailure   = 1
+	ExitUsage     = 2
+	ExitNotFound  = 3
+	ExitRetryable = 4
+	ExitTimeout   = 5
+	ExitDrift     = 6
+)
+
+// ExitCode maps the error a command failed with to the process exit code
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	if _, ok := err.(*flags.Error); ok {
+		return ExitUsage
+	}
+
+	if _, ok := errwrap.Cause(err).(*iaas.DriftError); ok {
+		return ExitDrift
+	}
+
+	if _, ok := errwrap.Cause(err).(*iaas.TimeoutError); ok {
+		return ExitTimeout
+	}
+
+	switch iaas.ClassOf(err) {
+	case iaas.NotFound:
+		return ExitNotFound
+	case iaas.Retryable:
+		return ExitRetryable
+	default:
+		return ExitFailure
+	}
+}
@@ -0,0 +1,34 @@
+package commands_test
+
+import (
+	"errors"
+	"time"
+
+	flags "github.com/jessevdk/go-flags"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	errwrap "github.com/pkg/errors"
+
+	"github.com/pivotal-cf/cliaas/commands"
+	"github.com/pivotal-cf/cliaas/iaas"
+)
+
+var _ = Describe("ExitCode", func() {
+	It("tells the classes of iaas errors apart", func() {
+		Expect(commands.ExitCode(nil)).To(Equal(0))
+		Expect(commands.ExitCode(errors.New("some error"))).To(Equal(commands.ExitFailure))
+		Expect(commands.ExitCode(&flags.Error{Type: flags.ErrRequired})).To(Equal(commands.ExitUsage))
+		Expect(commands.ExitCode(errwrap.Wrap(iaas.NewNotFoundError(errors.New("gone")), "stop failed"))).To(Equal(commands.ExitNotFound))
+		Expect(commands.ExitCode(iaas.NewRetryableError(errors.New("throttled"), 0))).To(Equal(commands.ExitRetryable))
+		Expect(commands.ExitCode(iaas.NewFatalError(errors.New("forbidden")))).To(Equal(commands.ExitFailure))
+	})
+
+	It("reports timeouts on their own", func() {
+		err := errwrap.Wrap(&iaas.TimeoutError{Description: "waiting", Timeout: time.Minute}, "last status was stopping")
+		Expect(commands.ExitCode(err)).To(Equal(commands.ExitTimeout))
+	})
+
+	It("reports drift on its own", func() {
+		Expect(commands.ExitCode(&iaas.DriftError{})).To(Equal(commands.ExitDrift))
+	})
+})
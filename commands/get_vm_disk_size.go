@@ -7,7 +7,12 @@ type GetVMDiskSizeCommand struct {
 }
 
 func (c *GetVMDiskSizeCommand) Execute([]string) error {
-	client, err := Cliaas.Config.NewClient()
+	config, err := Cliaas.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	client, err := config.NewClient()
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,169 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"github.com/pivotal-cf/cliaas"
+	"github.com/pivotal-cf/cliaas/iaas"
+	yaml "gopkg.in/yaml.v2"
+)
+
+type ReplaceManyCommand struct {
+	Inventory   string `long:"inventory" required:"true" description:"Path to a YAML file listing the VMs to replace"`
+	Parallelism int    `long:"parallelism" default:"1" description:"Number of replaces to run at once"`
+	MaxFailures int    `long:"max-failures" default:"1" description:"Stop starting replaces once this many have failed"`
+	Report      string `long:"report" description:"Path to write a JSON report of every replace to"`
+}
+
+// replaceReport is one line of the --report file
+type replaceReport struct {
+	cliaas.InventoryEntry
+	Status          string  `json:"status"`
+	Error           string  `json:"error,omitempty"`
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+func (c *ReplaceManyCommand) Execute([]string) error {
+	inventory, err := cliaas.LoadInventory(c.Inventory)
+	if err != nil {
+		return err
+	}
+
+	// every config is read and checked before the first replace starts, so
+	// that a typo in the inventory does not stop a wave half way
+	contents := map[string][]byte{}
+	for _, entry := range inventory.Replaces {
+		path := c.configPath(entry)
+		if _, ok := contents[path]; !ok {
+			contents[path], err = Cliaas.InterpolateFile(path)
+			if err != nil {
+				return err
+			}
+		}
+
+		config, err := loadTarget(contents[path], entry.Target)
+		if err != nil {
+			return fmt.Errorf("%s: %s", entry.Name, err)
+		}
+		Cliaas.Redactor.Add(config.Secrets()...)
+	}
+
+	batch := cliaas.ReplaceBatch{
+		Parallelism: c.Parallelism,
+		MaxFailures: c.MaxFailures,
+		Clock:       clock.NewClock(),
+		Replace: func(entry cliaas.InventoryEntry) error {
+			config, err := loadTarget(contents[c.configPath(entry)], entry.Target)
+			if err != nil {
+				return err
+			}
+			Cliaas.ApplyFlags(config)
+			config.Options().Progress = iaas.NewLabeledProgressReporter(Cliaas.Progress, entry.Name, os.Stderr, clock.NewClock(), Cliaas.Redact)
+
+			client, err := config.NewClient()
+			if err != nil {
+				return err
+			}
+
+			image := entry.Image
+			if image == "" {
+				image = config.Image()
+			}
+			return client.Replace(entry.Identifier, image, entry.DiskSizeGB)
+		},
+	}
+
+	results := batch.Run(inventory.Replaces)
+	printReplaceResults(os.Stdout, results)
+
+	if c.Report != "" {
+		err = writeReplaceReport(c.Report, results)
+		if err != nil {
+			return err
+		}
+	}
+
+	var failed, skipped int
+	for _, result := range results {
+		switch result.Status {
+		case cliaas.ReplaceFailed:
+			failed++
+		case cliaas.ReplaceSkipped:
+			skipped++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d replaces failed, %d skipped", failed, len(results), skipped)
+	}
+	return nil
+}
+
+// configPath is the config file of an entry, the --config file by default
+func (c *ReplaceManyCommand) configPath(entry cliaas.InventoryEntry) string {
+	if entry.Config == "" {
+		return string(Cliaas.ConfigFile)
+	}
+	return entry.Config
+}
+
+// loadTarget unmarshals a fresh config for every replace, so that the
+// settings and progress of one replace are not shared with another
+func loadTarget(contents []byte, target string) (cliaas.Config, error) {
+	var configFile cliaas.ConfigFile
+	err := yaml.Unmarshal(contents, &configFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %s", Cliaas.Redact(err.Error()))
+	}
+	return configFile.Target(target)
+}
+
+func printReplaceResults(w io.Writer, results []cliaas.ReplaceResult) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tIDENTIFIER\tRESULT\tDURATION\tERROR")
+	for _, result := range results {
+		errorMessage := ""
+		if result.Err != nil {
+			errorMessage = Cliaas.Redact(result.Err.Error())
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			result.Entry.Name,
+			result.Entry.Identifier,
+			result.Status,
+			result.Duration/time.Second*time.Second,
+			errorMessage,
+		)
+	}
+	tw.Flush()
+}
+
+func writeReplaceReport(path string, results []cliaas.ReplaceResult) error {
+	var report []replaceReport
+	for _, result := range results {
+		line := replaceReport{
+			InventoryEntry:  result.Entry,
+			Status:          result.Status,
+			DurationSeconds: result.Duration.Seconds(),
+		}
+		if result.Err != nil {
+			line.Error = Cliaas.Redact(result.Err.Error())
+		}
+		report = append(report, line)
+	}
+
+	contents, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to render report: %s", err)
+	}
+	err = ioutil.WriteFile(path, contents, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write report: %s", err)
+	}
+	return nil
+}
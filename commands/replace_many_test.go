@@ -0,0 +1,26 @@
+package commands_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/pivotal-cf/cliaas/commands"
+)
+
+var _ = Describe("ReplaceMany", func() {
+	It("errors if the inventory is not provided", func() {
+		r := commands.ReplaceManyCommand{}
+		_, err := flags.ParseArgs(&r, []string{})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("runs one replace at a time and stops at the first failure by default", func() {
+		r := commands.ReplaceManyCommand{}
+		_, err := flags.ParseArgs(&r, []string{"--inventory", "inventory.yml"})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(r.Parallelism).To(Equal(1))
+		Expect(r.MaxFailures).To(Equal(1))
+	})
+})
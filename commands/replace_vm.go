@@ -3,13 +3,21 @@ package commands
 type ReplaceVMCommand struct {
 	Identifier string `long:"identifier" required:"true" description:"Identifier of the VM that is being replaced"`
 	DiskSizeGB int64  `long:"disk-size-gb" required:"false" default:"100" description:"Disk size of the VM that is being replaced"`
+
+	MigrateDataDisk bool `long:"migrate-data-disk" description:"Move the old VM's data disk to the new VM at the same device or LUN, and back on failure"`
 }
 
 func (r *ReplaceVMCommand) Execute([]string) error {
-	client, err := Cliaas.Config.NewClient()
+	config, err := Cliaas.LoadConfig()
+	if err != nil {
+		return err
+	}
+	config.Options().MigrateDataDisk = r.MigrateDataDisk
+
+	client, err := config.NewClient()
 	if err != nil {
 		return err
 	}
 
-	return client.Replace(r.Identifier, Cliaas.Config.Image(), r.DiskSizeGB)
+	return client.Replace(r.Identifier, config.Image(), r.DiskSizeGB)
 }
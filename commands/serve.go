@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/pivotal-cf/cliaas"
+	"github.com/pivotal-cf/cliaas/server"
+	yaml "gopkg.in/yaml.v2"
+)
+
+type ServeCommand struct {
+	Listen  string `long:"listen" default:"127.0.0.1:8080" description:"Address to serve the API on"`
+	Token   string `long:"token" env:"CLIAAS_SERVER_TOKEN" required:"true" description:"Token clients send in an Authorization: Bearer header"`
+	TLSCert string `long:"tls-cert" description:"Path to a certificate to serve the API over https with"`
+	TLSKey  string `long:"tls-key" description:"Path to the private key of the certificate"`
+
+	JobRetention time.Duration `long:"job-retention" default:"24h" description:"How long a finished job can still be looked up"`
+}
+
+func (c *ServeCommand) Execute([]string) error {
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		return fmt.Errorf("--tls-cert and --tls-key must be given together")
+	}
+
+	contents, err := Cliaas.InterpolatedConfig()
+	if err != nil {
+		return err
+	}
+
+	var configFile cliaas.ConfigFile
+	err = yaml.Unmarshal(contents, &configFile)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal config: %s", Cliaas.Redact(err.Error()))
+	}
+
+	Cliaas.Redactor.Add(c.Token)
+	for _, config := range configFile.Configs() {
+		Cliaas.Redactor.Add(config.Secrets()...)
+	}
+	for _, multiConfig := range configFile.Targets {
+		if multiConfig != nil {
+			for _, config := range multiConfig.Configs() {
+				Cliaas.Redactor.Add(config.Secrets()...)
+			}
+		}
+	}
+
+	// every job gets its own copy of the config, so that the progress of one
+	// job is not reported on another
+	s := server.New(c.Token, func(target string) (cliaas.Config, error) {
+		config, err := loadTarget(contents, target)
+		if err != nil {
+			return nil, err
+		}
+		Cliaas.ApplyFlags(config)
+		return config, nil
+	})
+	s.TargetNames = configFile.TargetNames()
+	s.DefaultTarget = Cliaas.Target
+	s.Redact = Cliaas.Redact
+	s.JobRetention = c.JobRetention
+
+	// jobs run in the background, so no request takes long; the timeouts only
+	// keep slow or idle clients from holding connections open
+	httpServer := &http.Server{
+		Addr:              c.Listen,
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       time.Minute,
+		IdleTimeout:       2 * time.Minute,
+	}
+
+	fmt.Fprintf(os.Stderr, "serving on %s\n", c.Listen)
+	if c.TLSCert != "" {
+		return httpServer.ListenAndServeTLS(c.TLSCert, c.TLSKey)
+	}
+	return httpServer.ListenAndServe()
+}
@@ -0,0 +1,20 @@
+package commands
+
+type StartVMCommand struct {
+	Identifier string `short:"i" long:"identifier" required:"true" description:"Identifier of the stopped VM to start"`
+	Wait       bool   `long:"wait" description:"Wait until the VM is running"`
+}
+
+func (c *StartVMCommand) Execute([]string) error {
+	config, err := Cliaas.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	client, err := config.NewClient()
+	if err != nil {
+		return err
+	}
+
+	return client.Start(c.Identifier, c.Wait)
+}
@@ -0,0 +1,20 @@
+package commands
+
+type StopVMCommand struct {
+	Identifier string `short:"i" long:"identifier" required:"true" description:"Identifier of the VM to stop"`
+	Wait       bool   `long:"wait" description:"Wait until the VM has stopped"`
+}
+
+func (c *StopVMCommand) Execute([]string) error {
+	config, err := Cliaas.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	client, err := config.NewClient()
+	if err != nil {
+		return err
+	}
+
+	return client.Stop(c.Identifier, c.Wait)
+}
@@ -0,0 +1,31 @@
+package commands_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/pivotal-cf/cliaas/commands"
+)
+
+var _ = Describe("StopVM and StartVM", func() {
+	It("errors if the identifier is not provided", func() {
+		_, err := flags.ParseArgs(&commands.StopVMCommand{}, []string{})
+		Expect(err).To(HaveOccurred())
+
+		_, err = flags.ParseArgs(&commands.StartVMCommand{}, []string{})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("does not wait unless asked to", func() {
+		stop := commands.StopVMCommand{}
+		_, err := flags.ParseArgs(&stop, []string{"-i", "ops-manager"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stop.Wait).To(BeFalse())
+
+		start := commands.StartVMCommand{}
+		_, err = flags.ParseArgs(&start, []string{"-i", "ops-manager", "--wait"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(start.Wait).To(BeTrue())
+	})
+})
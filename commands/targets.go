@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pivotal-cf/cliaas"
+)
+
+type TargetsCommand struct {
+}
+
+func (c *TargetsCommand) Execute([]string) error {
+	configFile, err := Cliaas.LoadConfigFile()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "TARGET\tIAAS\tCOMPLETE")
+
+	if len(configFile.Configs()) > 0 {
+		printTarget(w, "(default)", &configFile.MultiConfig)
+	}
+
+	for _, name := range configFile.TargetNames() {
+		printTarget(w, name, configFile.Targets[name])
+	}
+
+	return w.Flush()
+}
+
+func printTarget(w *tabwriter.Writer, name string, multiConfig *cliaas.MultiConfig) {
+	if multiConfig == nil {
+		multiConfig = &cliaas.MultiConfig{}
+	}
+
+	var iaases []string
+	for _, config := range multiConfig.Configs() {
+		iaases = append(iaases, config.IaaS())
+	}
+
+	complete := "no"
+	if len(multiConfig.CompleteConfigs()) == 1 {
+		complete = "yes"
+	}
+
+	fmt.Fprintf(w, "%s\t%s\t%s\n", name, strings.Join(iaases, ","), complete)
+}
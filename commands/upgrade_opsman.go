@@ -0,0 +1,53 @@
+package commands
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"github.com/pivotal-cf/cliaas/iaas"
+	"github.com/pivotal-cf/cliaas/opsman"
+)
+
+type UpgradeOpsManCommand struct {
+	Identifier string `short:"i" long:"identifier" required:"true" description:"Identifier of the Ops Manager VM that is being replaced"`
+	DiskSizeGB int64  `long:"disk-size-gb" default:"100" description:"Disk size of the new VM"`
+
+	OpsManURL            string `long:"opsman-url" env:"OPSMAN_URL" required:"true" description:"URL of Ops Manager, which must reach the new VM once it has replaced the old one"`
+	Username             string `long:"username" env:"OPSMAN_USERNAME" description:"Ops Manager user to export the installation as"`
+	Password             string `long:"password" env:"OPSMAN_PASSWORD" description:"Password of the Ops Manager user"`
+	ClientID             string `long:"client-id" env:"OPSMAN_CLIENT_ID" description:"UAA client to export the installation as, instead of a user"`
+	ClientSecret         string `long:"client-secret" env:"OPSMAN_CLIENT_SECRET" description:"Secret of the UAA client"`
+	DecryptionPassphrase string `long:"decryption-passphrase" env:"OPSMAN_DECRYPTION_PASSPHRASE" required:"true" description:"Passphrase the installation is encrypted with"`
+	SkipSSLValidation    bool   `long:"skip-ssl-validation" description:"Accept Ops Manager's certificate without validating it"`
+	InstallationFile     string `long:"installation" default:"installation.zip" description:"Path to save the exported installation to, which is kept after the import"`
+
+	RequestTimeout time.Duration `long:"request-timeout" default:"1h" description:"How long a request to Ops Manager, such as the export or the import, may take"`
+}
+
+func (c *UpgradeOpsManCommand) Execute([]string) error {
+	config, err := Cliaas.LoadConfig()
+	if err != nil {
+		return err
+	}
+	Cliaas.Redactor.Add(c.Password, c.ClientSecret, c.DecryptionPassphrase)
+
+	client, err := config.NewClient()
+	if err != nil {
+		return err
+	}
+
+	options := config.Options()
+	opsmanClient := opsman.NewClient(opsman.Config{
+		URL:               c.OpsManURL,
+		Username:          c.Username,
+		Password:          c.Password,
+		ClientID:          c.ClientID,
+		ClientSecret:      c.ClientSecret,
+		SkipSSLValidation: c.SkipSSLValidation,
+		RequestTimeout:    c.RequestTimeout,
+	}, iaas.NewWaiter(clock.NewClock(), options.Timeouts), options.Progress)
+
+	return opsmanClient.Upgrade(c.InstallationFile, c.DecryptionPassphrase, func() error {
+		return client.Replace(c.Identifier, config.Image(), c.DiskSizeGB)
+	})
+}
@@ -0,0 +1,44 @@
+package commands_test
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/pivotal-cf/cliaas/commands"
+)
+
+var _ = Describe("UpgradeOpsMan", func() {
+	AfterEach(func() {
+		os.Unsetenv("OPSMAN_URL")
+		os.Unsetenv("OPSMAN_DECRYPTION_PASSPHRASE")
+	})
+
+	It("errors if the ops manager url is not provided", func() {
+		u := commands.UpgradeOpsManCommand{}
+		_, err := flags.ParseArgs(&u, []string{"--identifier", "an-identifier", "--decryption-passphrase", "a-passphrase"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("errors if the decryption passphrase is not provided", func() {
+		u := commands.UpgradeOpsManCommand{}
+		_, err := flags.ParseArgs(&u, []string{"--identifier", "an-identifier", "--opsman-url", "https://opsman"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("reads the url and passphrase from the environment", func() {
+		os.Setenv("OPSMAN_URL", "https://opsman")
+		os.Setenv("OPSMAN_DECRYPTION_PASSPHRASE", "a-passphrase")
+
+		u := commands.UpgradeOpsManCommand{}
+		_, err := flags.ParseArgs(&u, []string{"--identifier", "an-identifier"})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(u.OpsManURL).To(Equal("https://opsman"))
+		Expect(u.DecryptionPassphrase).To(Equal("a-passphrase"))
+		Expect(u.InstallationFile).To(Equal("installation.zip"))
+		Expect(u.DiskSizeGB).To(Equal(int64(100)))
+	})
+})
@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/pivotal-cf/cliaas"
+)
+
+type ValidateConfigCommand struct {
+	Online bool `long:"online" description:"Also check the credentials and that the referenced VPC, project or resource group exists"`
+}
+
+func (c *ValidateConfigCommand) Execute([]string) error {
+	err := Cliaas.ValidateConfigFile(c.Online)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("config is valid")
+	return nil
+}
+
+// ValidateConfigFile checks every config in the config file and returns a
+// *cliaas.ValidationError listing all of the problems found
+func (c *CliaasCommand) ValidateConfigFile(online bool) error {
+	contents, err := c.InterpolatedConfig()
+	if err != nil {
+		return err
+	}
+
+	fieldErrors := cliaas.UnknownFields(contents)
+
+	configFile, err := c.LoadConfigFile()
+	if err != nil {
+		return err
+	}
+
+	err = configFile.Validate()
+	if err != nil {
+		validationError, ok := err.(*cliaas.ValidationError)
+		if !ok {
+			return err
+		}
+		fieldErrors = append(fieldErrors, validationError.Errors...)
+	}
+
+	if online && len(fieldErrors) == 0 {
+		fieldErrors = append(fieldErrors, validateOnline("", &configFile.MultiConfig)...)
+		for _, name := range configFile.TargetNames() {
+			fieldErrors = append(fieldErrors, validateOnline("targets."+name+".", configFile.Targets[name])...)
+		}
+	}
+
+	if len(fieldErrors) == 0 {
+		return nil
+	}
+
+	for i := range fieldErrors {
+		fieldErrors[i].Message = c.Redact(fieldErrors[i].Message)
+	}
+	return &cliaas.ValidationError{Errors: fieldErrors}
+}
+
+func validateOnline(prefix string, multiConfig *cliaas.MultiConfig) []cliaas.FieldError {
+	var fieldErrors []cliaas.FieldError
+	for _, config := range multiConfig.Configs() {
+		err := config.ValidateOnline()
+		if err != nil {
+			fieldErrors = append(fieldErrors, cliaas.FieldError{
+				Field:   prefix + config.IaaS(),
+				Message: err.Error(),
+			})
+		}
+	}
+	return fieldErrors
+}
@@ -0,0 +1,87 @@
+package commands_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/cliaas"
+	"github.com/pivotal-cf/cliaas/commands"
+)
+
+var _ = Describe("ValidateConfig", func() {
+	var (
+		tmpDir  string
+		command commands.CliaasCommand
+	)
+
+	writeConfig := func(contents string) {
+		path := filepath.Join(tmpDir, "config.yml")
+		Expect(ioutil.WriteFile(path, []byte(contents), 0600)).To(Succeed())
+		command.ConfigFile = commands.ConfigFilePath(path)
+	}
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "commands")
+		Expect(err).NotTo(HaveOccurred())
+		command = commands.CliaasCommand{}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	It("accepts a valid config", func() {
+		writeConfig(`
+aws:
+  access_key_id: some-access-key-id
+  secret_access_key: some-secret-access-key
+  region: us-east-1
+  vpc: vpc-0123abcd
+  ami: ami-0123abcd
+`)
+		Expect(command.ValidateConfigFile(false)).To(Succeed())
+	})
+
+	It("lists unknown and malformed fields together", func() {
+		writeConfig(`
+aws:
+  access_key_id: some-access-key-id
+  secret_access_key: some-secret-access-key
+  region: us-east-1
+  vcp: vpc-0123abcd
+  ami: ami-0123abcd
+`)
+		err := command.ValidateConfigFile(false)
+		Expect(err).To(HaveOccurred())
+
+		validationError, ok := err.(*cliaas.ValidationError)
+		Expect(ok).To(BeTrue())
+		Expect(validationError.Errors).To(HaveLen(2))
+		Expect(validationError.Errors[0].Message).To(ContainSubstring("vcp"))
+		Expect(validationError.Errors[1]).To(Equal(cliaas.FieldError{Field: "aws.vpc", Message: "is required"}))
+	})
+
+	It("redacts interpolated values from the errors", func() {
+		writeConfig(`
+aws:
+  access_key_id: some-access-key-id
+  secret_access_key: some-secret-access-key
+  region: us-east-1
+  vpc: vpc-0123abcd
+  ami: ((ami))
+`)
+		varsPath := filepath.Join(tmpDir, "vars.yml")
+		Expect(ioutil.WriteFile(varsPath, []byte("ami: not-an-ami-secret\n"), 0600)).To(Succeed())
+		var varsFile commands.VarsFile
+		Expect(varsFile.UnmarshalFlag(varsPath)).To(Succeed())
+		command.VarsFiles = []commands.VarsFile{varsFile}
+
+		err := command.ValidateConfigFile(false)
+		Expect(err).To(MatchError(ContainSubstring(`aws.ami: "[REDACTED]" is not an AMI id`)))
+	})
+})
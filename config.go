@@ -1,10 +1,11 @@
 package cliaas
 
 import (
-	"os"
+	"strings"
 
 	"code.cloudfoundry.org/clock"
 
+	"github.com/pivotal-cf/cliaas/iaas"
 	"github.com/pivotal-cf/cliaas/iaas/aws"
 	"github.com/pivotal-cf/cliaas/iaas/azure"
 	"github.com/pivotal-cf/cliaas/iaas/gcp"
@@ -12,11 +13,50 @@ import (
 )
 
 type Config interface {
+	IaaS() string
+	Options() *ClientOptions
+	Secrets() []string
 	Image() string
 	Complete() bool
+	Validate() error
+	ValidateOnline() error
 	NewClient() (Client, error)
 }
 
+// ClientOptions are the settings every iaas config shares
+type ClientOptions struct {
+	Timeouts     iaas.Timeouts         `yaml:"timeouts"`
+	NameTemplate string                `yaml:"name_template"`
+	Hooks        iaas.Hooks            `yaml:"hooks"`
+	Progress     iaas.ProgressReporter `yaml:"-"`
+	Logger       *iaas.Logger          `yaml:"-"`
+
+	// MigrateDataDisk moves the old vm's data disks to the new vm on replace
+	MigrateDataDisk bool `yaml:"-"`
+}
+
+// Namer names what a replace creates after the name template
+func (o *ClientOptions) Namer() iaas.Namer {
+	return iaas.Namer{Template: o.NameTemplate, Clock: clock.NewClock()}
+}
+
+// HookRunner runs the hooks of a replace on the iaas
+func (o *ClientOptions) HookRunner(iaasName string) *iaas.HookRunner {
+	return &iaas.HookRunner{
+		Hooks:    o.Hooks,
+		IaaS:     iaasName,
+		Timeout:  iaas.DefaultTimeouts().Merge(o.Timeouts).Hook,
+		Logger:   o.Logger,
+		Progress: o.Progress,
+		Clock:    clock.NewClock(),
+	}
+}
+
+// Options gives access to the shared settings, so that flags can override them
+func (o *ClientOptions) Options() *ClientOptions {
+	return o
+}
+
 type MultiConfig struct {
 	AWS   *AWSConfig   `yaml:"aws"`
 	GCP   *GCPConfig   `yaml:"gcp"`
@@ -55,13 +95,17 @@ func (c *MultiConfig) CompleteConfigs() []Config {
 }
 
 type AzureConfig struct {
+	ClientOptions `yaml:",inline"`
+
 	VHDImageURL             string `yaml:"vhd_image_url"`
 	SubscriptionID          string `yaml:"subscription_id"`
 	ClientID                string `yaml:"client_id"`
 	ClientSecret            string `yaml:"client_secret"`
 	TenantID                string `yaml:"tenant_id"`
 	ResourceGroupName       string `yaml:"resource_group_name"`
+	Environment             string `yaml:"environment"`
 	ResourceManagerEndpoint string `yaml:"resource_manager_endpoint"`
+	MetadataEndpoint        string `yaml:"metadata_endpoint"`
 	StorageAccountName      string `yaml:"storage_account_name"`
 	StorageAccountKey       string `yaml:"storage_account_key"`
 	StorageContainerName    string `yaml:"storage_container_name"`
@@ -69,10 +113,19 @@ type AzureConfig struct {
 	VMAdminPassword         string `yaml:"vm_admin_password"`
 }
 
+func (c *AzureConfig) IaaS() string {
+	return "azure"
+}
+
 func (c *AzureConfig) Image() string {
 	return c.VHDImageURL
 }
 
+// Secrets are the values that must never be logged or printed
+func (c *AzureConfig) Secrets() []string {
+	return []string{c.ClientSecret, c.StorageAccountKey, c.VMAdminPassword}
+}
+
 func (c *AzureConfig) Complete() bool {
 	return c.SubscriptionID != "" &&
 		c.ClientID != "" &&
@@ -82,17 +135,37 @@ func (c *AzureConfig) Complete() bool {
 		c.StorageAccountName != "" &&
 		c.StorageAccountKey != "" &&
 		c.VHDImageURL != "" &&
-		c.StorageContainerName != ""
+		c.StorageContainerName != "" &&
+		(!strings.EqualFold(c.Environment, azure.AzureStack) || c.ResourceManagerEndpoint != "")
 }
 
 func (c *AzureConfig) NewClient() (Client, error) {
-	client, err := azure.NewClient(c.SubscriptionID, c.ClientID, c.ClientSecret, c.TenantID, c.ResourceGroupName, c.ResourceManagerEndpoint)
+	client, err := c.newClient()
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+func (c *AzureConfig) newClient() (*azure.Client, error) {
+	environment, err := azure.NewEnvironment(c.Environment, c.ResourceManagerEndpoint, c.MetadataEndpoint)
+	if err != nil {
+		return nil, errwrap.Wrap(err, "failed to resolve azure environment")
+	}
+
+	client, err := azure.NewClient(c.SubscriptionID, c.ClientID, c.ClientSecret, c.TenantID, c.ResourceGroupName, environment)
 	if err != nil {
 		return nil, errwrap.Wrap(err, "azure newclient failed to create a client")
 	}
 
+	client.SetWaiter(iaas.NewWaiter(clock.NewClock(), c.Timeouts))
+	client.SetProgressReporter(c.Progress)
+	client.SetLogger(c.Logger)
+	client.SetNamer(c.Namer())
+	client.SetMigrateDataDisk(c.MigrateDataDisk)
+	client.SetHookRunner(c.HookRunner(c.IaaS()))
 	if c.StorageURL == "" {
-		c.StorageURL = azure.DefaultBaseURL
+		c.StorageURL = environment.StorageEndpointSuffix
 	}
 	client.SetStorageContainerName(c.StorageContainerName)
 	client.SetStorageAccountName(c.StorageAccountName)
@@ -107,60 +180,112 @@ func (c *AzureConfig) NewClient() (Client, error) {
 }
 
 type AWSConfig struct {
+	ClientOptions `yaml:",inline"`
+
 	AMI             string `yaml:"ami"`
 	AccessKeyID     string `yaml:"access_key_id"`
 	SecretAccessKey string `yaml:"secret_access_key"`
+	SessionToken    string `yaml:"session_token"`
+	Profile         string `yaml:"profile"`
+	RoleARN         string `yaml:"role_arn"`
+	ExternalID      string `yaml:"external_id"`
+	RoleSessionName string `yaml:"role_session_name"`
 	Region          string `yaml:"region"`
 	VPCID           string `yaml:"vpc"`
 }
 
+func (c *AWSConfig) IaaS() string {
+	return "aws"
+}
+
 func (c *AWSConfig) Image() string {
 	return c.AMI
 }
 
+// Secrets are the values that must never be logged or printed
+func (c *AWSConfig) Secrets() []string {
+	return []string{c.AccessKeyID, c.SecretAccessKey, c.SessionToken}
+}
+
+func (c *AWSConfig) AuthMode() string {
+	return c.credentialsConfig().AuthMode()
+}
+
 func (c *AWSConfig) Complete() bool {
-	return c.AccessKeyID != "" &&
-		c.SecretAccessKey != "" &&
+	return c.credentialsConfig().Complete() &&
 		c.VPCID != "" &&
 		c.AMI != "" &&
 		c.Region != ""
 }
 
 func (c *AWSConfig) NewClient() (Client, error) {
-	ec2Client, err := aws.NewEC2Client(c.AccessKeyID, c.SecretAccessKey, c.Region)
+	ec2Client, err := aws.NewEC2Client(c.credentialsConfig(), c.Region, c.Logger)
 	if err != nil {
 		return nil, errwrap.Wrap(err, "failed to make ec2 client")
 	}
 
+	waiter := iaas.NewWaiter(clock.NewClock(), c.Timeouts)
 	return NewAWSAPIClient(
-		aws.NewAWSClient(ec2Client, c.VPCID, clock.NewClock())), nil
+		aws.NewAWSClient(ec2Client, c.VPCID, waiter), waiter, c.Progress, c.Namer(), c.MigrateDataDisk, c.HookRunner(c.IaaS())), nil
+}
+
+func (c *AWSConfig) credentialsConfig() aws.CredentialsConfig {
+	return aws.CredentialsConfig{
+		AccessKeyID:     c.AccessKeyID,
+		SecretAccessKey: c.SecretAccessKey,
+		SessionToken:    c.SessionToken,
+		Profile:         c.Profile,
+		RoleARN:         c.RoleARN,
+		ExternalID:      c.ExternalID,
+		RoleSessionName: c.RoleSessionName,
+	}
 }
 
 type GCPConfig struct {
-	CredfilePath string `yaml:"credfile"`
-	Zone         string `yaml:"zone"`
-	Project      string `yaml:"project"`
-	DiskImageURL string `yaml:"disk_image_url"`
+	ClientOptions `yaml:",inline"`
+
+	CredfilePath              string   `yaml:"credfile"`
+	CredentialsJSON           string   `yaml:"credentials_json"`
+	ImpersonateServiceAccount string   `yaml:"impersonate_service_account"`
+	ImpersonationDelegates    []string `yaml:"impersonation_delegates"`
+	Zone                      string   `yaml:"zone"`
+	Project                   string   `yaml:"project"`
+	DiskImageURL              string   `yaml:"disk_image_url"`
+}
+
+func (c *GCPConfig) IaaS() string {
+	return "gcp"
 }
 
 func (c *GCPConfig) Image() string {
 	return c.DiskImageURL
 }
 
-func (c *GCPConfig) Complete() bool {
-	_, err := os.Stat(c.CredfilePath)
-	if err != nil {
-		return false
-	}
+// Secrets are the values that must never be logged or printed
+func (c *GCPConfig) Secrets() []string {
+	return append([]string{c.CredentialsJSON}, gcp.CredentialsSecrets(c.CredentialsJSON)...)
+}
+
+func (c *GCPConfig) CredentialsSource() string {
+	return c.credentialsConfig().Source()
+}
 
-	return c.CredfilePath != "" &&
-		c.Zone != "" &&
+func (c *GCPConfig) Complete() bool {
+	return c.Zone != "" &&
 		c.Project != "" &&
 		c.DiskImageURL != ""
 }
 
 func (c *GCPConfig) NewClient() (Client, error) {
-	computeClient, err := gcp.NewDefaultGoogleComputeClient(c.CredfilePath)
+	client, err := c.newClient()
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+func (c *GCPConfig) newClient() (*gcp.Client, error) {
+	computeClient, err := gcp.NewGoogleComputeClient(c.credentialsConfig())
 	if err != nil {
 		return nil, errwrap.Wrap(err, "failed to create gcp default client")
 	}
@@ -169,10 +294,24 @@ func (c *GCPConfig) NewClient() (Client, error) {
 		gcp.ConfigGoogleClient(computeClient),
 		gcp.ConfigZoneName(c.Zone),
 		gcp.ConfigProjectName(c.Project),
-		gcp.ConfigTimeout(600),
+		gcp.ConfigWaiter(iaas.NewWaiter(clock.NewClock(), c.Timeouts)),
+		gcp.ConfigProgressReporter(c.Progress),
+		gcp.ConfigLogger(c.Logger),
+		gcp.ConfigNamer(c.Namer()),
+		gcp.ConfigMigrateDataDisk(c.MigrateDataDisk),
+		gcp.ConfigHookRunner(c.HookRunner(c.IaaS())),
 	)
 	if err != nil {
 		return nil, errwrap.Wrap(err, "failed to create gcp client api")
 	}
 	return gcpClientAPI, err
 }
+
+func (c *GCPConfig) credentialsConfig() gcp.CredentialsConfig {
+	return gcp.CredentialsConfig{
+		CredfilePath:              c.CredfilePath,
+		CredentialsJSON:           c.CredentialsJSON,
+		ImpersonateServiceAccount: c.ImpersonateServiceAccount,
+		Delegates:                 c.ImpersonationDelegates,
+	}
+}
@@ -2,12 +2,16 @@ package cliaas_test
 
 import (
 	"io/ioutil"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	yaml "gopkg.in/yaml.v2"
 
 	"github.com/pivotal-cf/cliaas"
+	"github.com/pivotal-cf/cliaas/iaas"
+	"github.com/pivotal-cf/cliaas/iaas/aws"
+	"github.com/pivotal-cf/cliaas/iaas/gcp"
 )
 
 var _ = Describe("Config", func() {
@@ -87,6 +91,39 @@ var _ = Describe("Config", func() {
 			})
 		})
 
+		Context("when the multi config has an AWS config without static keys", func() {
+			var awsConfig *cliaas.AWSConfig
+
+			BeforeEach(func() {
+				awsConfig = &cliaas.AWSConfig{
+					RoleARN: "some-role-arn",
+					Region:  "some-region",
+					VPCID:   "some-vpc-id",
+					AMI:     "some-ami",
+				}
+
+				multiConfig = cliaas.MultiConfig{
+					AWS: awsConfig,
+				}
+			})
+
+			It("uses the default credential chain", func() {
+				Expect(awsConfig.AuthMode()).To(Equal(aws.AuthModeDefaultChain))
+				Expect(multiConfig.CompleteConfigs()).To(Equal([]cliaas.Config{awsConfig}))
+			})
+
+			Context("when only an access key id is given", func() {
+				BeforeEach(func() {
+					awsConfig.AccessKeyID = "some-access-key-id"
+				})
+
+				It("is an incomplete static config", func() {
+					Expect(awsConfig.AuthMode()).To(Equal(aws.AuthModeStatic))
+					Expect(multiConfig.CompleteConfigs()).To(BeEmpty())
+				})
+			})
+		})
+
 		Context("when the multi config has a complete GCP config", func() {
 			var gcpConfig *cliaas.GCPConfig
 
@@ -106,6 +143,30 @@ var _ = Describe("Config", func() {
 			It("returns a slice of the GCP config", func() {
 				Expect(multiConfig.CompleteConfigs()).To(Equal([]cliaas.Config{gcpConfig}))
 			})
+
+			Context("when the credfile does not exist", func() {
+				BeforeEach(func() {
+					gcpConfig.CredfilePath = "testdata/does-not-exist.json"
+				})
+
+				It("is still complete but fails to create a client", func() {
+					Expect(multiConfig.CompleteConfigs()).To(Equal([]cliaas.Config{gcpConfig}))
+
+					_, err := gcpConfig.NewClient()
+					Expect(err).To(MatchError(ContainSubstring("does-not-exist.json")))
+				})
+			})
+
+			Context("when no credentials are given", func() {
+				BeforeEach(func() {
+					gcpConfig.CredfilePath = ""
+				})
+
+				It("uses application default credentials", func() {
+					Expect(gcpConfig.CredentialsSource()).To(Equal(gcp.CredentialsSourceApplicationDefault))
+					Expect(multiConfig.CompleteConfigs()).To(Equal([]cliaas.Config{gcpConfig}))
+				})
+			})
 		})
 
 		Describe("Azure Config", func() {
@@ -137,6 +198,28 @@ var _ = Describe("Config", func() {
 				})
 			})
 
+			Context("when the multi config targets Azure Stack without a resource manager endpoint", func() {
+
+				BeforeEach(func() {
+					azureConfig = &cliaas.AzureConfig{
+						SubscriptionID:       "asdfasd",
+						ClientID:             "klasdjfas",
+						ClientSecret:         "asdfas",
+						TenantID:             "asdfasd",
+						ResourceGroupName:    "asdfasd",
+						StorageAccountName:   "sadfasdf",
+						StorageAccountKey:    "asdfasdf",
+						StorageContainerName: "asdfasdf",
+						VHDImageURL:          "https://some.url",
+						Environment:          "AzureStack",
+					}
+				})
+
+				It("does not consider the Azure config complete", func() {
+					Expect(multiConfig.CompleteConfigs()).To(BeEmpty())
+				})
+			})
+
 			Context("when the multi config DOES NOT have a complete Azure config", func() {
 
 				BeforeEach(func() {
@@ -149,4 +232,25 @@ var _ = Describe("Config", func() {
 			})
 		})
 	})
+
+	Describe("Timeouts", func() {
+		It("reads the timeouts section of an iaas config", func() {
+			var multiConfig cliaas.MultiConfig
+			err := yaml.Unmarshal([]byte(`
+gcp:
+  zone: us-east1-b
+  timeouts:
+    stop: 5m
+    image_import: 1h
+    poll_interval: 500ms
+`), &multiConfig)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			Expect(multiConfig.GCP.Options().Timeouts).To(Equal(iaas.Timeouts{
+				Stop:         5 * time.Minute,
+				ImageImport:  time.Hour,
+				PollInterval: 500 * time.Millisecond,
+			}))
+		})
+	})
 })
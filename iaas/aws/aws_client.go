@@ -2,12 +2,13 @@ package aws
 
 import (
 	"fmt"
+	"sort"
 	"time"
 
-	"code.cloudfoundry.org/clock"
-
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/google/uuid"
+	"github.com/pivotal-cf/cliaas/iaas"
 	errwrap "github.com/pkg/errors"
 )
 
@@ -17,34 +18,38 @@ type AWSClient interface {
 	CreateVM(ami, name string, vmInfo VMInfo) (string, error)
 	DeleteVM(instanceID string) error
 	GetVMInfo(name string) (VMInfo, error)
+	GetStoppedVMInfo(name string) (VMInfo, error)
+	DescribeVM(name string) (iaas.VMSpec, error)
 	GetDisk(name string) (EBS, error)
 	StartVM(instanceID string) error
 	StopVM(instanceID string) error
+	ShutdownVM(instanceID string) error
 	AssignPublicIP(instance, ip string) error
-	WaitForStatus(instanceID string, status string) error
+	WaitForStatus(instanceID string, status string, timeout time.Duration) error
+	DetachVolume(volumeID string) error
+	AttachVolume(instanceID, volumeID, deviceName string) error
+	WaitForVolumeStatus(volumeID string, status string, timeout time.Duration) error
+	VerifyVPC() error
+	CheckPermissions(ami string, vmInfo VMInfo) []iaas.PermissionCheck
 }
 
 type client struct {
 	ec2Client EC2Client
 	vpcID     string
-	timeout   time.Duration
-	clock     clock.Clock
+	waiter    iaas.Waiter
 }
 
-func NewAWSClient(ec2Client EC2Client, vpcID string, clock clock.Clock) AWSClient {
+func NewAWSClient(ec2Client EC2Client, vpcID string, waiter iaas.Waiter) AWSClient {
 	client := &client{
-		ec2Client: ec2Client,
+		ec2Client: retryingEC2Client{ec2Client: ec2Client, waiter: waiter},
 		vpcID:     vpcID,
-		timeout:   60 * time.Second,
-		clock:     clock,
+		waiter:    waiter,
 	}
 
 	return client
 }
 
-func (c *client) WaitForStatus(instanceID string, status string) error {
-	doneCh := make(chan struct{})
-
+func (c *client) WaitForStatus(instanceID string, status string, timeout time.Duration) error {
 	input := &ec2.DescribeInstanceStatusInput{
 		IncludeAllInstances: aws.Bool(true),
 		InstanceIds: []*string{
@@ -54,36 +59,83 @@ func (c *client) WaitForStatus(instanceID string, status string) error {
 
 	var lastStatus string
 
-	go func() {
-		for {
-			<-c.clock.After(time.Second)
+	err := c.waiter.Wait(fmt.Sprintf("waiting for instance %s to become %s", instanceID, status), timeout, func() (bool, error) {
+		output, err := c.ec2Client.DescribeInstanceStatus(input)
+		if iaas.ClassOf(err) == iaas.NotFound {
+			// a new instance id takes a moment to be known to every endpoint
+			return false, nil
+		}
+		if err != nil {
+			return false, errwrap.Wrap(err, "describe instance status failed")
+		}
 
-			output, err := c.ec2Client.DescribeInstanceStatus(input)
-			if err != nil {
-				continue
-			}
+		if len(output.InstanceStatuses) != 1 {
+			return false, nil
+		}
 
-			if len(output.InstanceStatuses) != 1 {
-				continue
-			}
+		lastStatus = *output.InstanceStatuses[0].InstanceState.Name
+		return lastStatus == status, nil
+	})
+	if err != nil {
+		return errwrap.Wrapf(err, "last status was %s", lastStatus)
+	}
 
-			instanceStatus := *output.InstanceStatuses[0].InstanceState.Name
+	return nil
+}
 
-			if instanceStatus == status {
-				close(doneCh)
-				return
-			}
+func (c *client) WaitForVolumeStatus(volumeID string, status string, timeout time.Duration) error {
+	input := &ec2.DescribeVolumesInput{
+		VolumeIds: []*string{
+			aws.String(volumeID),
+		},
+	}
+
+	var lastStatus string
 
-			lastStatus = instanceStatus
+	err := c.waiter.Wait(fmt.Sprintf("waiting for volume %s to become %s", volumeID, status), timeout, func() (bool, error) {
+		output, err := c.ec2Client.DescribeVolumes(input)
+		if err != nil {
+			return false, errwrap.Wrap(err, "describe volumes failed")
+		}
+
+		if len(output.Volumes) != 1 {
+			return false, nil
 		}
-	}()
 
-	select {
-	case <-doneCh:
-		return nil
-	case <-c.clock.After(c.timeout):
-		return errwrap.New(fmt.Sprintf("timed out waiting for instance to become %s (last status was %s)", status, lastStatus))
+		lastStatus = aws.StringValue(output.Volumes[0].State)
+		return lastStatus == status, nil
+	})
+	if err != nil {
+		return errwrap.Wrapf(err, "last status was %s", lastStatus)
+	}
+
+	return nil
+}
+
+func (c *client) DetachVolume(volumeID string) error {
+	_, err := c.ec2Client.DetachVolume(&ec2.DetachVolumeInput{
+		VolumeId: aws.String(volumeID),
+	})
+
+	if err != nil {
+		return errwrap.Wrap(err, "detach volume failed")
+	}
+
+	return nil
+}
+
+func (c *client) AttachVolume(instanceID, volumeID, deviceName string) error {
+	_, err := c.ec2Client.AttachVolume(&ec2.AttachVolumeInput{
+		InstanceId: aws.String(instanceID),
+		VolumeId:   aws.String(volumeID),
+		Device:     aws.String(deviceName),
+	})
+
+	if err != nil {
+		return errwrap.Wrap(err, "attach volume failed")
 	}
+
+	return nil
 }
 
 func (c *client) AssignPublicIP(instanceID, ip string) error {
@@ -104,16 +156,65 @@ func (c *client) CreateVM(
 	name string,
 	vmInfo VMInfo,
 ) (string, error) {
+	runInput := runInstancesInput(ami, vmInfo)
+	// the client token makes a retried RunInstances return the first instance
+	// instead of launching another
+	runInput.ClientToken = aws.String(uuid.New().String())
+	runResult, err := c.ec2Client.RunInstances(runInput)
+	if err != nil {
+		return "", errwrap.Wrap(err, "run instances failed")
+	}
+
+	tags := []*ec2.Tag{
+		{
+			Key:   aws.String("Name"),
+			Value: aws.String(name),
+		},
+	}
+	var keys []string
+	for key := range vmInfo.Tags {
+		if key != "Name" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		tags = append(tags, &ec2.Tag{Key: aws.String(key), Value: aws.String(vmInfo.Tags[key])})
+	}
+
+	instanceID := *runResult.Instances[0].InstanceId
+	_, err = c.ec2Client.CreateTags(&ec2.CreateTagsInput{
+		Resources: []*string{runResult.Instances[0].InstanceId},
+		Tags:      tags,
+	})
+	if err != nil {
+		// an untagged instance cannot be found by its name, so it is not
+		// left running
+		_ = c.DeleteVM(instanceID)
+		return "", errwrap.Wrap(err, "create tags failed")
+	}
+
+	return instanceID, nil
+}
+
+func runInstancesInput(ami string, vmInfo VMInfo) *ec2.RunInstancesInput {
 	runInput := &ec2.RunInstancesInput{
 		ImageId:             aws.String(ami),
 		InstanceType:        aws.String(vmInfo.InstanceType),
 		BlockDeviceMappings: convertBlockDeviceMappings(vmInfo.BlockDeviceMappings),
-		IamInstanceProfile: &ec2.IamInstanceProfileSpecification{
+		MinCount:            aws.Int64(1),
+		MaxCount:            aws.Int64(1),
+		KeyName:             aws.String(vmInfo.KeyName),
+	}
+
+	if vmInfo.IAMInstanceProfileARN != "" {
+		runInput.IamInstanceProfile = &ec2.IamInstanceProfileSpecification{
 			Arn: aws.String(vmInfo.IAMInstanceProfileARN),
-		},
-		MinCount: aws.Int64(1),
-		MaxCount: aws.Int64(1),
-		KeyName:  aws.String(vmInfo.KeyName),
+		}
+	}
+
+	if vmInfo.PrivateIP != "" {
+		runInput.PrivateIpAddress = aws.String(vmInfo.PrivateIP)
 	}
 
 	if vmInfo.SubnetID != "" {
@@ -124,25 +225,7 @@ func (c *client) CreateVM(
 		runInput.SecurityGroupIds = aws.StringSlice(vmInfo.SecurityGroupIDs)
 	}
 
-	runResult, err := c.ec2Client.RunInstances(runInput)
-	if err != nil {
-		return "", errwrap.Wrap(err, "run instances failed")
-	}
-
-	_, err = c.ec2Client.CreateTags(&ec2.CreateTagsInput{
-		Resources: []*string{runResult.Instances[0].InstanceId},
-		Tags: []*ec2.Tag{
-			{
-				Key:   aws.String("Name"),
-				Value: aws.String(name),
-			},
-		},
-	})
-	if err != nil {
-		return "", err
-	}
-
-	return *runResult.Instances[0].InstanceId, nil
+	return runInput
 }
 
 func (c *client) DeleteVM(instanceID string) error {
@@ -160,13 +243,24 @@ func (c *client) DeleteVM(instanceID string) error {
 	return nil
 }
 
+// StopVM powers the instance off without waiting for its os, as a replace
+// does not need the old vm to shut down cleanly
 func (c *client) StopVM(instanceID string) error {
+	return c.stopInstance(instanceID, true)
+}
+
+// ShutdownVM stops the instance through its os, so that it shuts down cleanly
+func (c *client) ShutdownVM(instanceID string) error {
+	return c.stopInstance(instanceID, false)
+}
+
+func (c *client) stopInstance(instanceID string, force bool) error {
 	_, err := c.ec2Client.StopInstances(&ec2.StopInstancesInput{
 		InstanceIds: []*string{
 			aws.String(instanceID),
 		},
 		DryRun: aws.Bool(false),
-		Force:  aws.Bool(true),
+		Force:  aws.Bool(force),
 	})
 
 	if err != nil {
@@ -191,6 +285,24 @@ func (c *client) StartVM(instanceID string) error {
 	return nil
 }
 
+func (c *client) VerifyVPC() error {
+	output, err := c.ec2Client.DescribeVpcs(&ec2.DescribeVpcsInput{
+		VpcIds: []*string{
+			aws.String(c.vpcID),
+		},
+	})
+
+	if err != nil {
+		return errwrap.Wrap(err, "describe vpcs failed")
+	}
+
+	if len(output.Vpcs) != 1 {
+		return fmt.Errorf("vpc %s does not exist", c.vpcID)
+	}
+
+	return nil
+}
+
 func (c *client) GetDisk(name string) (EBS, error) {
 	params := &ec2.DescribeInstancesInput{
 		Filters: []*ec2.Filter{
@@ -218,7 +330,7 @@ func (c *client) GetDisk(name string) (EBS, error) {
 	}
 
 	if len(list) == 0 {
-		return EBS{}, errwrap.New("no matching instances found")
+		return EBS{}, iaas.NewNotFoundError(errwrap.New("no matching instances found"))
 	}
 
 	if len(list) > 1 {
@@ -236,14 +348,22 @@ func (c *client) GetDisk(name string) (EBS, error) {
 }
 
 type VMInfo struct {
+	Name                  string
 	InstanceID            string
 	InstanceType          string
+	ImageID               string
+	RootDeviceName        string
 	BlockDeviceMappings   []BlockDeviceMapping
 	IAMInstanceProfileARN string
 	KeyName               string
 	SubnetID              string
 	SecurityGroupIDs      []string
 	PublicIP              string
+
+	// PrivateIP and Tags are only launched with by a create, as a replaced
+	// vm is launched while the old vm still holds its private ip
+	PrivateIP string
+	Tags      map[string]string
 }
 
 type BlockDeviceMapping struct {
@@ -252,12 +372,84 @@ type BlockDeviceMapping struct {
 }
 
 type EBS struct {
+	VolumeID            string
 	DeleteOnTermination bool
 	VolumeSize          int64
 	VolumeType          string
 }
 
 func (c *client) GetVMInfo(name string) (VMInfo, error) {
+	return c.getVMInfo(name, ec2.InstanceStateNameRunning)
+}
+
+// GetStoppedVMInfo finds a stopped instance the way GetVMInfo finds a running
+// one, so that it can be started again
+func (c *client) GetStoppedVMInfo(name string) (VMInfo, error) {
+	return c.getVMInfo(name, ec2.InstanceStateNameStopped)
+}
+
+func (c *client) getVMInfo(name string, state string) (VMInfo, error) {
+	instance, err := c.findInstance(name, state)
+	if err != nil {
+		return VMInfo{}, err
+	}
+	return c.vmInfo(instance)
+}
+
+// DescribeVM describes the running instance as a spec, with the instance as
+// its raw description
+func (c *client) DescribeVM(name string) (iaas.VMSpec, error) {
+	instance, err := c.findInstance(name, ec2.InstanceStateNameRunning)
+	if err != nil {
+		return iaas.VMSpec{}, err
+	}
+	vmInfo, err := c.vmInfo(instance)
+	if err != nil {
+		return iaas.VMSpec{}, err
+	}
+
+	spec := iaas.VMSpec{
+		IaaS:         "aws",
+		Name:         vmInfo.Name,
+		ID:           vmInfo.InstanceID,
+		Image:        vmInfo.ImageID,
+		InstanceType: vmInfo.InstanceType,
+		Network: iaas.NetworkSpec{
+			Network:        aws.StringValue(instance.VpcId),
+			Subnet:         vmInfo.SubnetID,
+			PrivateIP:      aws.StringValue(instance.PrivateIpAddress),
+			PublicIP:       vmInfo.PublicIP,
+			SecurityGroups: vmInfo.SecurityGroupIDs,
+		},
+		KeyName:  vmInfo.KeyName,
+		Identity: vmInfo.IAMInstanceProfileARN,
+		Raw:      instance,
+	}
+	if instance.Placement != nil {
+		spec.Zone = aws.StringValue(instance.Placement.AvailabilityZone)
+	}
+	for _, blockDeviceMapping := range vmInfo.BlockDeviceMappings {
+		spec.Disks = append(spec.Disks, iaas.DiskSpec{
+			Name:   blockDeviceMapping.DeviceName,
+			Boot:   blockDeviceMapping.DeviceName == vmInfo.RootDeviceName,
+			SizeGB: blockDeviceMapping.EBS.VolumeSize,
+			Type:   blockDeviceMapping.EBS.VolumeType,
+			Source: blockDeviceMapping.EBS.VolumeID,
+		})
+	}
+	for _, tag := range instance.Tags {
+		if spec.Tags == nil {
+			spec.Tags = map[string]string{}
+		}
+		spec.Tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	spec.Normalize()
+	return spec, nil
+}
+
+// findInstance finds the single instance in the vpc in state whose name
+// matches
+func (c *client) findInstance(name string, state string) (*ec2.Instance, error) {
 	params := &ec2.DescribeInstancesInput{
 		Filters: []*ec2.Filter{
 			{
@@ -276,29 +468,31 @@ func (c *client) GetVMInfo(name string) (VMInfo, error) {
 	}
 	resp, err := c.ec2Client.DescribeInstances(params)
 	if err != nil {
-		return VMInfo{}, errwrap.Wrap(err, "describe instances failed")
+		return nil, errwrap.Wrap(err, "describe instances failed")
 	}
 
 	var list []*ec2.Instance
 
 	for idx := range resp.Reservations {
 		for _, instance := range resp.Reservations[idx].Instances {
-			if *instance.State.Name == ec2.InstanceStateNameRunning {
+			if *instance.State.Name == state {
 				list = append(list, instance)
 			}
 		}
 	}
 
 	if len(list) == 0 {
-		return VMInfo{}, errwrap.New("no matching instances found")
+		return nil, iaas.NewNotFoundError(errwrap.New("no matching instances found"))
 	}
 
 	if len(list) > 1 {
-		return VMInfo{}, errwrap.New("more than one matching instance found")
+		return nil, errwrap.New("more than one matching instance found")
 	}
 
-	instance := list[0]
+	return list[0], nil
+}
 
+func (c *client) vmInfo(instance *ec2.Instance) (VMInfo, error) {
 	var securityGroupIDs []string
 	for _, sg := range instance.SecurityGroups {
 		securityGroupIDs = append(securityGroupIDs, *sg.GroupId)
@@ -322,8 +516,11 @@ func (c *client) GetVMInfo(name string) (VMInfo, error) {
 	}
 
 	vmInfo := VMInfo{
+		Name:                  nameTag(instance.Tags),
 		InstanceID:            *instance.InstanceId,
 		InstanceType:          *instance.InstanceType,
+		ImageID:               aws.StringValue(instance.ImageId),
+		RootDeviceName:        aws.StringValue(instance.RootDeviceName),
 		KeyName:               *instance.KeyName,
 		SubnetID:              *instance.SubnetId,
 		SecurityGroupIDs:      securityGroupIDs,
@@ -335,6 +532,73 @@ func (c *client) GetVMInfo(name string) (VMInfo, error) {
 	return vmInfo, nil
 }
 
+// SpecVMInfo is the vm a spec describes, for launching it afresh. The boot
+// disk is deleted with the vm, any other disk is kept, and disks without a
+// type are gp2.
+func SpecVMInfo(spec iaas.VMSpec) VMInfo {
+	vmInfo := VMInfo{
+		Name:                  spec.Name,
+		InstanceType:          spec.InstanceType,
+		IAMInstanceProfileARN: spec.Identity,
+		KeyName:               spec.KeyName,
+		SubnetID:              spec.Network.Subnet,
+		SecurityGroupIDs:      spec.Network.SecurityGroups,
+		PublicIP:              spec.Network.PublicIP,
+		PrivateIP:             spec.Network.PrivateIP,
+		Tags:                  spec.Tags,
+	}
+	for _, disk := range spec.Disks {
+		if disk.Boot {
+			vmInfo.RootDeviceName = disk.Name
+		}
+		if disk.Type == "" {
+			disk.Type = ec2.VolumeTypeGp2
+		}
+		vmInfo.BlockDeviceMappings = append(vmInfo.BlockDeviceMappings, BlockDeviceMapping{
+			DeviceName: disk.Name,
+			EBS: EBS{
+				DeleteOnTermination: disk.Boot,
+				VolumeSize:          disk.SizeGB,
+				VolumeType:          disk.Type,
+			},
+		})
+	}
+	return vmInfo
+}
+
+// DataDisks are the volumes of the vm other than its root volume
+func (v VMInfo) DataDisks() []BlockDeviceMapping {
+	var dataDisks []BlockDeviceMapping
+	for _, blockDeviceMapping := range v.BlockDeviceMappings {
+		if blockDeviceMapping.DeviceName != v.RootDeviceName {
+			dataDisks = append(dataDisks, blockDeviceMapping)
+		}
+	}
+	return dataDisks
+}
+
+// WithoutDataDisks is the vm with only its root volume, for launching a vm
+// that the data disks are then moved to
+func (v VMInfo) WithoutDataDisks() VMInfo {
+	var rootDisks []BlockDeviceMapping
+	for _, blockDeviceMapping := range v.BlockDeviceMappings {
+		if blockDeviceMapping.DeviceName == v.RootDeviceName {
+			rootDisks = append(rootDisks, blockDeviceMapping)
+		}
+	}
+	v.BlockDeviceMappings = rootDisks
+	return v
+}
+
+func nameTag(tags []*ec2.Tag) string {
+	for _, tag := range tags {
+		if tag.Key != nil && *tag.Key == "Name" && tag.Value != nil {
+			return *tag.Value
+		}
+	}
+	return ""
+}
+
 func (c *client) describeVolumes(instanceBlockDeviceMappings []*ec2.InstanceBlockDeviceMapping) ([]BlockDeviceMapping, error) {
 	blockDeviceMappings := []BlockDeviceMapping{}
 	for _, blockDeviceMapping := range instanceBlockDeviceMappings {
@@ -357,6 +621,7 @@ func (c *client) describeVolumes(instanceBlockDeviceMappings []*ec2.InstanceBloc
 			blockDeviceMappings = append(blockDeviceMappings, BlockDeviceMapping{
 				DeviceName: aws.StringValue(blockDeviceMapping.DeviceName),
 				EBS: EBS{
+					VolumeID:            aws.StringValue(blockDeviceMapping.Ebs.VolumeId),
 					DeleteOnTermination: aws.BoolValue(blockDeviceMapping.Ebs.DeleteOnTermination),
 					VolumeSize:          aws.Int64Value(volume.Size),
 					VolumeType:          aws.StringValue(volume.VolumeType),
@@ -4,12 +4,15 @@ import (
 	"errors"
 	"time"
 
+	realclock "code.cloudfoundry.org/clock"
 	"code.cloudfoundry.org/clock/fakeclock"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/cliaas/iaas"
 	. "github.com/pivotal-cf/cliaas/iaas/aws"
 	"github.com/pivotal-cf/cliaas/iaas/aws/awsfakes"
 )
@@ -48,7 +51,7 @@ var _ = Describe("AWSClient", func() {
 		ec2Client = new(awsfakes.FakeEC2Client)
 		clock := fakeclock.NewFakeClock(time.Now())
 
-		client = NewAWSClient(ec2Client, "some vpc", clock)
+		client = NewAWSClient(ec2Client, "some vpc", iaas.NewWaiter(clock, iaas.Timeouts{}))
 	})
 
 	Describe("GetVMInfo", func() {
@@ -87,8 +90,11 @@ var _ = Describe("AWSClient", func() {
 				vmInfo, err := client.GetVMInfo("some-identifier")
 				Expect(err).NotTo(HaveOccurred())
 				Expect(vmInfo).To(Equal(VMInfo{
+					Name:             "some-identifier-2017-03-01-12-00-00",
 					InstanceID:       "some-instance-id",
 					InstanceType:     "some-instance-type",
+					ImageID:          "some-ami",
+					RootDeviceName:   "/dev/sda1",
 					KeyName:          "some-key-name",
 					SubnetID:         "some-subnet-id",
 					SecurityGroupIDs: []string{"some-group-id", "some-other-group-id"},
@@ -97,6 +103,7 @@ var _ = Describe("AWSClient", func() {
 						{
 							DeviceName: "/dev/sda1",
 							EBS: EBS{
+								VolumeID:            "some-root-volume-id",
 								DeleteOnTermination: true,
 								VolumeSize:          1,
 								VolumeType:          "some-volume-type",
@@ -105,6 +112,7 @@ var _ = Describe("AWSClient", func() {
 						{
 							DeviceName: "/dev/sda2",
 							EBS: EBS{
+								VolumeID:            "some-volume-id",
 								DeleteOnTermination: true,
 								VolumeSize:          1,
 								VolumeType:          "some-volume-type",
@@ -114,6 +122,38 @@ var _ = Describe("AWSClient", func() {
 					IAMInstanceProfileARN: "some-instance-profile-arn",
 				}))
 			})
+
+			It("describes the instance as a spec", func() {
+				spec, err := client.DescribeVM("some-identifier")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(spec.Raw).To(BeAssignableToTypeOf(&ec2.Instance{}))
+				spec.Raw = nil
+				Expect(spec).To(Equal(iaas.VMSpec{
+					IaaS:         "aws",
+					Name:         "some-identifier-2017-03-01-12-00-00",
+					ID:           "some-instance-id",
+					Image:        "some-ami",
+					InstanceType: "some-instance-type",
+					Network: iaas.NetworkSpec{
+						Subnet:         "some-subnet-id",
+						PublicIP:       "some-public-ip",
+						SecurityGroups: []string{"some-group-id", "some-other-group-id"},
+					},
+					Disks: []iaas.DiskSpec{
+						{Name: "/dev/sda1", Boot: true, SizeGB: 1, Type: "some-volume-type", Source: "some-root-volume-id"},
+						{Name: "/dev/sda2", SizeGB: 1, Type: "some-volume-type", Source: "some-volume-id"},
+					},
+					KeyName:  "some-key-name",
+					Identity: "some-instance-profile-arn",
+					Tags:     map[string]string{"Name": "some-identifier-2017-03-01-12-00-00"},
+				}))
+			})
+
+			It("finds the single `stopped` instance when asked for a stopped one", func() {
+				vmInfo, err := client.GetStoppedVMInfo("some-identifier")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(vmInfo.InstanceID).To(Equal("some-instance-id"))
+			})
 		})
 
 		Context("when more than one `running` instance is found", func() {
@@ -187,6 +227,15 @@ var _ = Describe("AWSClient", func() {
 			}))
 		})
 
+		It("lets the os shut down when asked to", func() {
+			err := client.ShutdownVM("foo")
+			Expect(err).NotTo(HaveOccurred())
+
+			input := ec2Client.StopInstancesArgsForCall(0)
+			Expect(*input.InstanceIds[0]).To(Equal("foo"))
+			Expect(*input.Force).To(BeFalse())
+		})
+
 		Context("when there is an api error", func() {
 			BeforeEach(func() {
 				ec2Client.StopInstancesReturns(&ec2.StopInstancesOutput{}, errors.New("an error"))
@@ -197,6 +246,34 @@ var _ = Describe("AWSClient", func() {
 				Expect(err).To(HaveOccurred())
 			})
 		})
+
+		Context("when the api is throttled", func() {
+			BeforeEach(func() {
+				client = NewAWSClient(ec2Client, "some vpc", iaas.NewWaiter(realclock.NewClock(), iaas.Timeouts{
+					PollInterval: time.Millisecond,
+				}))
+				ec2Client.StopInstancesReturnsOnCall(0, nil, awserr.New("RequestLimitExceeded", "Request limit exceeded.", nil))
+				ec2Client.StopInstancesReturnsOnCall(1, &ec2.StopInstancesOutput{}, nil)
+			})
+
+			It("retries the call", func() {
+				err := client.StopVM("foo")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ec2Client.StopInstancesCallCount()).To(Equal(2))
+			})
+		})
+
+		Context("when the instance does not exist", func() {
+			BeforeEach(func() {
+				ec2Client.StopInstancesReturns(nil, awserr.New("InvalidInstanceID.NotFound", "The instance ID 'foo' does not exist", nil))
+			})
+
+			It("returns a not found error without retrying", func() {
+				err := client.StopVM("foo")
+				Expect(iaas.ClassOf(err)).To(Equal(iaas.NotFound))
+				Expect(ec2Client.StopInstancesCallCount()).To(Equal(1))
+			})
+		})
 	})
 
 	Describe("Delete", func() {
@@ -227,6 +304,47 @@ var _ = Describe("AWSClient", func() {
 		})
 	})
 
+	Describe("VerifyVPC", func() {
+		It("looks up the configured vpc", func() {
+			ec2Client.DescribeVpcsReturns(&ec2.DescribeVpcsOutput{
+				Vpcs: []*ec2.Vpc{{VpcId: aws.String("some vpc")}},
+			}, nil)
+
+			err := client.VerifyVPC()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(ec2Client.DescribeVpcsCallCount()).To(Equal(1))
+			input := ec2Client.DescribeVpcsArgsForCall(0)
+			Expect(*input).To(Equal(ec2.DescribeVpcsInput{
+				VpcIds: []*string{
+					aws.String("some vpc"),
+				},
+			}))
+		})
+
+		Context("when the vpc is not found", func() {
+			BeforeEach(func() {
+				ec2Client.DescribeVpcsReturns(&ec2.DescribeVpcsOutput{}, nil)
+			})
+
+			It("returns an error", func() {
+				err := client.VerifyVPC()
+				Expect(err).To(MatchError("vpc some vpc does not exist"))
+			})
+		})
+
+		Context("when there is an api error", func() {
+			BeforeEach(func() {
+				ec2Client.DescribeVpcsReturns(nil, errors.New("an error"))
+			})
+
+			It("returns an error", func() {
+				err := client.VerifyVPC()
+				Expect(err.Error()).To(Equal("describe vpcs failed: an error"))
+			})
+		})
+	})
+
 	Describe("AssignPublicIP", func() {
 
 		It("tries to assign the public IP", func() {
@@ -254,6 +372,39 @@ var _ = Describe("AWSClient", func() {
 		})
 	})
 
+	Describe("AttachVolume", func() {
+		It("attaches the volume at the device name", func() {
+			err := client.AttachVolume("some-instance-id", "some-volume-id", "/dev/sdf")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(ec2Client.AttachVolumeCallCount()).To(Equal(1))
+			Expect(*ec2Client.AttachVolumeArgsForCall(0)).To(Equal(ec2.AttachVolumeInput{
+				InstanceId: aws.String("some-instance-id"),
+				VolumeId:   aws.String("some-volume-id"),
+				Device:     aws.String("/dev/sdf"),
+			}))
+		})
+	})
+
+	Describe("DataDisks", func() {
+		It("splits the data disks from the root volume", func() {
+			vmInfo := VMInfo{
+				RootDeviceName: "/dev/sda1",
+				BlockDeviceMappings: []BlockDeviceMapping{
+					{DeviceName: "/dev/sda1", EBS: EBS{VolumeID: "some-root-volume-id"}},
+					{DeviceName: "/dev/sdf", EBS: EBS{VolumeID: "some-data-volume-id"}},
+				},
+			}
+
+			Expect(vmInfo.DataDisks()).To(Equal([]BlockDeviceMapping{
+				{DeviceName: "/dev/sdf", EBS: EBS{VolumeID: "some-data-volume-id"}},
+			}))
+			Expect(vmInfo.WithoutDataDisks().BlockDeviceMappings).To(Equal([]BlockDeviceMapping{
+				{DeviceName: "/dev/sda1", EBS: EBS{VolumeID: "some-root-volume-id"}},
+			}))
+		})
+	})
+
 	Describe("Create", func() {
 		var (
 			reservation  *ec2.Reservation
@@ -284,6 +435,8 @@ var _ = Describe("AWSClient", func() {
 
 			Expect(ec2Client.RunInstancesCallCount()).To(Equal(1))
 			input := ec2Client.RunInstancesArgsForCall(0)
+			Expect(aws.StringValue(input.ClientToken)).NotTo(BeEmpty(), "retries must not launch a second instance")
+			input.ClientToken = nil
 			Expect(*input).To(Equal(ec2.RunInstancesInput{
 				ImageId:      aws.String(ami),
 				InstanceType: aws.String(vmInfoConfig.InstanceType),
@@ -340,6 +493,21 @@ var _ = Describe("AWSClient", func() {
 			})
 		})
 
+		It("launches with the private ip and tags of a spec", func() {
+			vmInfo := createVMInfo("/dev/sda1", "", true, vmInfoConfig)
+			vmInfo.PrivateIP = "10.0.0.5"
+			vmInfo.Tags = map[string]string{"team": "platform", "env": "prod", "Name": "ignored"}
+			_, err := client.CreateVM(ami, name, vmInfo)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(aws.StringValue(ec2Client.RunInstancesArgsForCall(0).PrivateIpAddress)).To(Equal("10.0.0.5"))
+			Expect(ec2Client.CreateTagsArgsForCall(0).Tags).To(Equal([]*ec2.Tag{
+				{Key: aws.String("Name"), Value: aws.String(name)},
+				{Key: aws.String("env"), Value: aws.String("prod")},
+				{Key: aws.String("team"), Value: aws.String("platform")},
+			}))
+		})
+
 		It("tries to create an instance with a blank security group when no security groups are set", func() {
 			_, err := client.CreateVM(ami, name, VMInfo{
 				KeyName:          vmInfoConfig.KeyName,
@@ -370,6 +538,21 @@ var _ = Describe("AWSClient", func() {
 				Expect(err.Error()).To(Equal("run instances failed: an error"))
 			})
 		})
+
+		Context("when tagging the instance fails", func() {
+			BeforeEach(func() {
+				ec2Client.CreateTagsReturns(nil, errors.New("an error"))
+			})
+
+			It("terminates the instance and returns an error", func() {
+				_, err := client.CreateVM(ami, name, createVMInfo("/dev/sda1", "", true, vmInfoConfig))
+				Expect(err).To(MatchError("create tags failed: an error"))
+
+				Expect(ec2Client.TerminateInstancesCallCount()).To(Equal(1))
+				input := ec2Client.TerminateInstancesArgsForCall(0)
+				Expect(aws.StringValueSlice(input.InstanceIds)).To(Equal([]string{"some-instance-id"}))
+			})
+		})
 	})
 
 	Describe("GetDisk", func() {
@@ -499,8 +682,12 @@ func createEC2Instance(state *ec2.InstanceState) *ec2.Instance {
 		State:        state,
 		InstanceId:   aws.String("some-instance-id"),
 		InstanceType: aws.String("some-instance-type"),
+		ImageId:      aws.String("some-ami"),
 		KeyName:      aws.String("some-key-name"),
 		SubnetId:     aws.String("some-subnet-id"),
+		Tags: []*ec2.Tag{
+			{Key: aws.String("Name"), Value: aws.String("some-identifier-2017-03-01-12-00-00")},
+		},
 		SecurityGroups: []*ec2.GroupIdentifier{
 			{
 				GroupId: aws.String("some-group-id"),
@@ -516,7 +703,7 @@ func createEC2Instance(state *ec2.InstanceState) *ec2.Instance {
 				},
 			},
 		},
-		RootDeviceName: aws.String("/dev/sda2"),
+		RootDeviceName: aws.String("/dev/sda1"),
 		BlockDeviceMappings: []*ec2.InstanceBlockDeviceMapping{
 			{
 				DeviceName: aws.String("/dev/sda1"),
@@ -3,7 +3,9 @@ package awsfakes
 
 import (
 	"sync"
+	"time"
 
+	"github.com/pivotal-cf/cliaas/iaas"
 	"github.com/pivotal-cf/cliaas/iaas/aws"
 )
 
@@ -47,6 +49,32 @@ type FakeAWSClient struct {
 		result1 aws.VMInfo
 		result2 error
 	}
+	GetStoppedVMInfoStub        func(name string) (aws.VMInfo, error)
+	getStoppedVMInfoMutex       sync.RWMutex
+	getStoppedVMInfoArgsForCall []struct {
+		name string
+	}
+	getStoppedVMInfoReturns struct {
+		result1 aws.VMInfo
+		result2 error
+	}
+	getStoppedVMInfoReturnsOnCall map[int]struct {
+		result1 aws.VMInfo
+		result2 error
+	}
+	DescribeVMStub        func(name string) (iaas.VMSpec, error)
+	describeVMMutex       sync.RWMutex
+	describeVMArgsForCall []struct {
+		name string
+	}
+	describeVMReturns struct {
+		result1 iaas.VMSpec
+		result2 error
+	}
+	describeVMReturnsOnCall map[int]struct {
+		result1 iaas.VMSpec
+		result2 error
+	}
 	GetDiskStub        func(name string) (aws.EBS, error)
 	getDiskMutex       sync.RWMutex
 	getDiskArgsForCall []struct {
@@ -82,6 +110,17 @@ type FakeAWSClient struct {
 	stopVMReturnsOnCall map[int]struct {
 		result1 error
 	}
+	ShutdownVMStub        func(instanceID string) error
+	shutdownVMMutex       sync.RWMutex
+	shutdownVMArgsForCall []struct {
+		instanceID string
+	}
+	shutdownVMReturns struct {
+		result1 error
+	}
+	shutdownVMReturnsOnCall map[int]struct {
+		result1 error
+	}
 	AssignPublicIPStub        func(instance, ip string) error
 	assignPublicIPMutex       sync.RWMutex
 	assignPublicIPArgsForCall []struct {
@@ -94,11 +133,12 @@ type FakeAWSClient struct {
 	assignPublicIPReturnsOnCall map[int]struct {
 		result1 error
 	}
-	WaitForStatusStub        func(instanceID string, status string) error
+	WaitForStatusStub        func(instanceID string, status string, timeout time.Duration) error
 	waitForStatusMutex       sync.RWMutex
 	waitForStatusArgsForCall []struct {
 		instanceID string
 		status     string
+		timeout    time.Duration
 	}
 	waitForStatusReturns struct {
 		result1 error
@@ -106,6 +146,64 @@ type FakeAWSClient struct {
 	waitForStatusReturnsOnCall map[int]struct {
 		result1 error
 	}
+	DetachVolumeStub        func(volumeID string) error
+	detachVolumeMutex       sync.RWMutex
+	detachVolumeArgsForCall []struct {
+		volumeID string
+	}
+	detachVolumeReturns struct {
+		result1 error
+	}
+	detachVolumeReturnsOnCall map[int]struct {
+		result1 error
+	}
+	AttachVolumeStub        func(instanceID, volumeID, deviceName string) error
+	attachVolumeMutex       sync.RWMutex
+	attachVolumeArgsForCall []struct {
+		instanceID string
+		volumeID   string
+		deviceName string
+	}
+	attachVolumeReturns struct {
+		result1 error
+	}
+	attachVolumeReturnsOnCall map[int]struct {
+		result1 error
+	}
+	WaitForVolumeStatusStub        func(volumeID string, status string, timeout time.Duration) error
+	waitForVolumeStatusMutex       sync.RWMutex
+	waitForVolumeStatusArgsForCall []struct {
+		volumeID string
+		status   string
+		timeout  time.Duration
+	}
+	waitForVolumeStatusReturns struct {
+		result1 error
+	}
+	waitForVolumeStatusReturnsOnCall map[int]struct {
+		result1 error
+	}
+	VerifyVPCStub        func() error
+	verifyVPCMutex       sync.RWMutex
+	verifyVPCArgsForCall []struct{}
+	verifyVPCReturns     struct {
+		result1 error
+	}
+	verifyVPCReturnsOnCall map[int]struct {
+		result1 error
+	}
+	CheckPermissionsStub        func(ami string, vmInfo aws.VMInfo) []iaas.PermissionCheck
+	checkPermissionsMutex       sync.RWMutex
+	checkPermissionsArgsForCall []struct {
+		ami    string
+		vmInfo aws.VMInfo
+	}
+	checkPermissionsReturns struct {
+		result1 []iaas.PermissionCheck
+	}
+	checkPermissionsReturnsOnCall map[int]struct {
+		result1 []iaas.PermissionCheck
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -262,6 +360,108 @@ func (fake *FakeAWSClient) GetVMInfoReturnsOnCall(i int, result1 aws.VMInfo, res
 	}{result1, result2}
 }
 
+func (fake *FakeAWSClient) GetStoppedVMInfo(name string) (aws.VMInfo, error) {
+	fake.getStoppedVMInfoMutex.Lock()
+	ret, specificReturn := fake.getStoppedVMInfoReturnsOnCall[len(fake.getStoppedVMInfoArgsForCall)]
+	fake.getStoppedVMInfoArgsForCall = append(fake.getStoppedVMInfoArgsForCall, struct {
+		name string
+	}{name})
+	fake.recordInvocation("GetStoppedVMInfo", []interface{}{name})
+	fake.getStoppedVMInfoMutex.Unlock()
+	if fake.GetStoppedVMInfoStub != nil {
+		return fake.GetStoppedVMInfoStub(name)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.getStoppedVMInfoReturns.result1, fake.getStoppedVMInfoReturns.result2
+}
+
+func (fake *FakeAWSClient) GetStoppedVMInfoCallCount() int {
+	fake.getStoppedVMInfoMutex.RLock()
+	defer fake.getStoppedVMInfoMutex.RUnlock()
+	return len(fake.getStoppedVMInfoArgsForCall)
+}
+
+func (fake *FakeAWSClient) GetStoppedVMInfoArgsForCall(i int) string {
+	fake.getStoppedVMInfoMutex.RLock()
+	defer fake.getStoppedVMInfoMutex.RUnlock()
+	return fake.getStoppedVMInfoArgsForCall[i].name
+}
+
+func (fake *FakeAWSClient) GetStoppedVMInfoReturns(result1 aws.VMInfo, result2 error) {
+	fake.GetStoppedVMInfoStub = nil
+	fake.getStoppedVMInfoReturns = struct {
+		result1 aws.VMInfo
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeAWSClient) GetStoppedVMInfoReturnsOnCall(i int, result1 aws.VMInfo, result2 error) {
+	fake.GetStoppedVMInfoStub = nil
+	if fake.getStoppedVMInfoReturnsOnCall == nil {
+		fake.getStoppedVMInfoReturnsOnCall = make(map[int]struct {
+			result1 aws.VMInfo
+			result2 error
+		})
+	}
+	fake.getStoppedVMInfoReturnsOnCall[i] = struct {
+		result1 aws.VMInfo
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeAWSClient) DescribeVM(name string) (iaas.VMSpec, error) {
+	fake.describeVMMutex.Lock()
+	ret, specificReturn := fake.describeVMReturnsOnCall[len(fake.describeVMArgsForCall)]
+	fake.describeVMArgsForCall = append(fake.describeVMArgsForCall, struct {
+		name string
+	}{name})
+	fake.recordInvocation("DescribeVM", []interface{}{name})
+	fake.describeVMMutex.Unlock()
+	if fake.DescribeVMStub != nil {
+		return fake.DescribeVMStub(name)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.describeVMReturns.result1, fake.describeVMReturns.result2
+}
+
+func (fake *FakeAWSClient) DescribeVMCallCount() int {
+	fake.describeVMMutex.RLock()
+	defer fake.describeVMMutex.RUnlock()
+	return len(fake.describeVMArgsForCall)
+}
+
+func (fake *FakeAWSClient) DescribeVMArgsForCall(i int) string {
+	fake.describeVMMutex.RLock()
+	defer fake.describeVMMutex.RUnlock()
+	return fake.describeVMArgsForCall[i].name
+}
+
+func (fake *FakeAWSClient) DescribeVMReturns(result1 iaas.VMSpec, result2 error) {
+	fake.DescribeVMStub = nil
+	fake.describeVMReturns = struct {
+		result1 iaas.VMSpec
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeAWSClient) DescribeVMReturnsOnCall(i int, result1 iaas.VMSpec, result2 error) {
+	fake.DescribeVMStub = nil
+	if fake.describeVMReturnsOnCall == nil {
+		fake.describeVMReturnsOnCall = make(map[int]struct {
+			result1 iaas.VMSpec
+			result2 error
+		})
+	}
+	fake.describeVMReturnsOnCall[i] = struct {
+		result1 iaas.VMSpec
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeAWSClient) GetDisk(name string) (aws.EBS, error) {
 	fake.getDiskMutex.Lock()
 	ret, specificReturn := fake.getDiskReturnsOnCall[len(fake.getDiskArgsForCall)]
@@ -409,6 +609,54 @@ func (fake *FakeAWSClient) StopVMReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeAWSClient) ShutdownVM(instanceID string) error {
+	fake.shutdownVMMutex.Lock()
+	ret, specificReturn := fake.shutdownVMReturnsOnCall[len(fake.shutdownVMArgsForCall)]
+	fake.shutdownVMArgsForCall = append(fake.shutdownVMArgsForCall, struct {
+		instanceID string
+	}{instanceID})
+	fake.recordInvocation("ShutdownVM", []interface{}{instanceID})
+	fake.shutdownVMMutex.Unlock()
+	if fake.ShutdownVMStub != nil {
+		return fake.ShutdownVMStub(instanceID)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.shutdownVMReturns.result1
+}
+
+func (fake *FakeAWSClient) ShutdownVMCallCount() int {
+	fake.shutdownVMMutex.RLock()
+	defer fake.shutdownVMMutex.RUnlock()
+	return len(fake.shutdownVMArgsForCall)
+}
+
+func (fake *FakeAWSClient) ShutdownVMArgsForCall(i int) string {
+	fake.shutdownVMMutex.RLock()
+	defer fake.shutdownVMMutex.RUnlock()
+	return fake.shutdownVMArgsForCall[i].instanceID
+}
+
+func (fake *FakeAWSClient) ShutdownVMReturns(result1 error) {
+	fake.ShutdownVMStub = nil
+	fake.shutdownVMReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeAWSClient) ShutdownVMReturnsOnCall(i int, result1 error) {
+	fake.ShutdownVMStub = nil
+	if fake.shutdownVMReturnsOnCall == nil {
+		fake.shutdownVMReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.shutdownVMReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeAWSClient) AssignPublicIP(instance string, ip string) error {
 	fake.assignPublicIPMutex.Lock()
 	ret, specificReturn := fake.assignPublicIPReturnsOnCall[len(fake.assignPublicIPArgsForCall)]
@@ -458,17 +706,18 @@ func (fake *FakeAWSClient) AssignPublicIPReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *FakeAWSClient) WaitForStatus(instanceID string, status string) error {
+func (fake *FakeAWSClient) WaitForStatus(instanceID string, status string, timeout time.Duration) error {
 	fake.waitForStatusMutex.Lock()
 	ret, specificReturn := fake.waitForStatusReturnsOnCall[len(fake.waitForStatusArgsForCall)]
 	fake.waitForStatusArgsForCall = append(fake.waitForStatusArgsForCall, struct {
 		instanceID string
 		status     string
-	}{instanceID, status})
-	fake.recordInvocation("WaitForStatus", []interface{}{instanceID, status})
+		timeout    time.Duration
+	}{instanceID, status, timeout})
+	fake.recordInvocation("WaitForStatus", []interface{}{instanceID, status, timeout})
 	fake.waitForStatusMutex.Unlock()
 	if fake.WaitForStatusStub != nil {
-		return fake.WaitForStatusStub(instanceID, status)
+		return fake.WaitForStatusStub(instanceID, status, timeout)
 	}
 	if specificReturn {
 		return ret.result1
@@ -482,10 +731,10 @@ func (fake *FakeAWSClient) WaitForStatusCallCount() int {
 	return len(fake.waitForStatusArgsForCall)
 }
 
-func (fake *FakeAWSClient) WaitForStatusArgsForCall(i int) (string, string) {
+func (fake *FakeAWSClient) WaitForStatusArgsForCall(i int) (string, string, time.Duration) {
 	fake.waitForStatusMutex.RLock()
 	defer fake.waitForStatusMutex.RUnlock()
-	return fake.waitForStatusArgsForCall[i].instanceID, fake.waitForStatusArgsForCall[i].status
+	return fake.waitForStatusArgsForCall[i].instanceID, fake.waitForStatusArgsForCall[i].status, fake.waitForStatusArgsForCall[i].timeout
 }
 
 func (fake *FakeAWSClient) WaitForStatusReturns(result1 error) {
@@ -507,6 +756,243 @@ func (fake *FakeAWSClient) WaitForStatusReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeAWSClient) DetachVolume(volumeID string) error {
+	fake.detachVolumeMutex.Lock()
+	ret, specificReturn := fake.detachVolumeReturnsOnCall[len(fake.detachVolumeArgsForCall)]
+	fake.detachVolumeArgsForCall = append(fake.detachVolumeArgsForCall, struct {
+		volumeID string
+	}{volumeID})
+	fake.recordInvocation("DetachVolume", []interface{}{volumeID})
+	fake.detachVolumeMutex.Unlock()
+	if fake.DetachVolumeStub != nil {
+		return fake.DetachVolumeStub(volumeID)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.detachVolumeReturns.result1
+}
+
+func (fake *FakeAWSClient) DetachVolumeCallCount() int {
+	fake.detachVolumeMutex.RLock()
+	defer fake.detachVolumeMutex.RUnlock()
+	return len(fake.detachVolumeArgsForCall)
+}
+
+func (fake *FakeAWSClient) DetachVolumeArgsForCall(i int) string {
+	fake.detachVolumeMutex.RLock()
+	defer fake.detachVolumeMutex.RUnlock()
+	return fake.detachVolumeArgsForCall[i].volumeID
+}
+
+func (fake *FakeAWSClient) DetachVolumeReturns(result1 error) {
+	fake.DetachVolumeStub = nil
+	fake.detachVolumeReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeAWSClient) DetachVolumeReturnsOnCall(i int, result1 error) {
+	fake.DetachVolumeStub = nil
+	if fake.detachVolumeReturnsOnCall == nil {
+		fake.detachVolumeReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.detachVolumeReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeAWSClient) AttachVolume(instanceID string, volumeID string, deviceName string) error {
+	fake.attachVolumeMutex.Lock()
+	ret, specificReturn := fake.attachVolumeReturnsOnCall[len(fake.attachVolumeArgsForCall)]
+	fake.attachVolumeArgsForCall = append(fake.attachVolumeArgsForCall, struct {
+		instanceID string
+		volumeID   string
+		deviceName string
+	}{instanceID, volumeID, deviceName})
+	fake.recordInvocation("AttachVolume", []interface{}{instanceID, volumeID, deviceName})
+	fake.attachVolumeMutex.Unlock()
+	if fake.AttachVolumeStub != nil {
+		return fake.AttachVolumeStub(instanceID, volumeID, deviceName)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.attachVolumeReturns.result1
+}
+
+func (fake *FakeAWSClient) AttachVolumeCallCount() int {
+	fake.attachVolumeMutex.RLock()
+	defer fake.attachVolumeMutex.RUnlock()
+	return len(fake.attachVolumeArgsForCall)
+}
+
+func (fake *FakeAWSClient) AttachVolumeArgsForCall(i int) (string, string, string) {
+	fake.attachVolumeMutex.RLock()
+	defer fake.attachVolumeMutex.RUnlock()
+	return fake.attachVolumeArgsForCall[i].instanceID, fake.attachVolumeArgsForCall[i].volumeID, fake.attachVolumeArgsForCall[i].deviceName
+}
+
+func (fake *FakeAWSClient) AttachVolumeReturns(result1 error) {
+	fake.AttachVolumeStub = nil
+	fake.attachVolumeReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeAWSClient) AttachVolumeReturnsOnCall(i int, result1 error) {
+	fake.AttachVolumeStub = nil
+	if fake.attachVolumeReturnsOnCall == nil {
+		fake.attachVolumeReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.attachVolumeReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeAWSClient) WaitForVolumeStatus(volumeID string, status string, timeout time.Duration) error {
+	fake.waitForVolumeStatusMutex.Lock()
+	ret, specificReturn := fake.waitForVolumeStatusReturnsOnCall[len(fake.waitForVolumeStatusArgsForCall)]
+	fake.waitForVolumeStatusArgsForCall = append(fake.waitForVolumeStatusArgsForCall, struct {
+		volumeID string
+		status   string
+		timeout  time.Duration
+	}{volumeID, status, timeout})
+	fake.recordInvocation("WaitForVolumeStatus", []interface{}{volumeID, status, timeout})
+	fake.waitForVolumeStatusMutex.Unlock()
+	if fake.WaitForVolumeStatusStub != nil {
+		return fake.WaitForVolumeStatusStub(volumeID, status, timeout)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.waitForVolumeStatusReturns.result1
+}
+
+func (fake *FakeAWSClient) WaitForVolumeStatusCallCount() int {
+	fake.waitForVolumeStatusMutex.RLock()
+	defer fake.waitForVolumeStatusMutex.RUnlock()
+	return len(fake.waitForVolumeStatusArgsForCall)
+}
+
+func (fake *FakeAWSClient) WaitForVolumeStatusArgsForCall(i int) (string, string, time.Duration) {
+	fake.waitForVolumeStatusMutex.RLock()
+	defer fake.waitForVolumeStatusMutex.RUnlock()
+	return fake.waitForVolumeStatusArgsForCall[i].volumeID, fake.waitForVolumeStatusArgsForCall[i].status, fake.waitForVolumeStatusArgsForCall[i].timeout
+}
+
+func (fake *FakeAWSClient) WaitForVolumeStatusReturns(result1 error) {
+	fake.WaitForVolumeStatusStub = nil
+	fake.waitForVolumeStatusReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeAWSClient) WaitForVolumeStatusReturnsOnCall(i int, result1 error) {
+	fake.WaitForVolumeStatusStub = nil
+	if fake.waitForVolumeStatusReturnsOnCall == nil {
+		fake.waitForVolumeStatusReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.waitForVolumeStatusReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeAWSClient) VerifyVPC() error {
+	fake.verifyVPCMutex.Lock()
+	ret, specificReturn := fake.verifyVPCReturnsOnCall[len(fake.verifyVPCArgsForCall)]
+	fake.verifyVPCArgsForCall = append(fake.verifyVPCArgsForCall, struct{}{})
+	fake.recordInvocation("VerifyVPC", []interface{}{})
+	fake.verifyVPCMutex.Unlock()
+	if fake.VerifyVPCStub != nil {
+		return fake.VerifyVPCStub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.verifyVPCReturns.result1
+}
+
+func (fake *FakeAWSClient) VerifyVPCCallCount() int {
+	fake.verifyVPCMutex.RLock()
+	defer fake.verifyVPCMutex.RUnlock()
+	return len(fake.verifyVPCArgsForCall)
+}
+
+func (fake *FakeAWSClient) VerifyVPCReturns(result1 error) {
+	fake.VerifyVPCStub = nil
+	fake.verifyVPCReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeAWSClient) VerifyVPCReturnsOnCall(i int, result1 error) {
+	fake.VerifyVPCStub = nil
+	if fake.verifyVPCReturnsOnCall == nil {
+		fake.verifyVPCReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.verifyVPCReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeAWSClient) CheckPermissions(ami string, vmInfo aws.VMInfo) []iaas.PermissionCheck {
+	fake.checkPermissionsMutex.Lock()
+	ret, specificReturn := fake.checkPermissionsReturnsOnCall[len(fake.checkPermissionsArgsForCall)]
+	fake.checkPermissionsArgsForCall = append(fake.checkPermissionsArgsForCall, struct {
+		ami    string
+		vmInfo aws.VMInfo
+	}{ami, vmInfo})
+	fake.recordInvocation("CheckPermissions", []interface{}{ami, vmInfo})
+	fake.checkPermissionsMutex.Unlock()
+	if fake.CheckPermissionsStub != nil {
+		return fake.CheckPermissionsStub(ami, vmInfo)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.checkPermissionsReturns.result1
+}
+
+func (fake *FakeAWSClient) CheckPermissionsCallCount() int {
+	fake.checkPermissionsMutex.RLock()
+	defer fake.checkPermissionsMutex.RUnlock()
+	return len(fake.checkPermissionsArgsForCall)
+}
+
+func (fake *FakeAWSClient) CheckPermissionsArgsForCall(i int) (string, aws.VMInfo) {
+	fake.checkPermissionsMutex.RLock()
+	defer fake.checkPermissionsMutex.RUnlock()
+	return fake.checkPermissionsArgsForCall[i].ami, fake.checkPermissionsArgsForCall[i].vmInfo
+}
+
+func (fake *FakeAWSClient) CheckPermissionsReturns(result1 []iaas.PermissionCheck) {
+	fake.CheckPermissionsStub = nil
+	fake.checkPermissionsReturns = struct {
+		result1 []iaas.PermissionCheck
+	}{result1}
+}
+
+func (fake *FakeAWSClient) CheckPermissionsReturnsOnCall(i int, result1 []iaas.PermissionCheck) {
+	fake.CheckPermissionsStub = nil
+	if fake.checkPermissionsReturnsOnCall == nil {
+		fake.checkPermissionsReturnsOnCall = make(map[int]struct {
+			result1 []iaas.PermissionCheck
+		})
+	}
+	fake.checkPermissionsReturnsOnCall[i] = struct {
+		result1 []iaas.PermissionCheck
+	}{result1}
+}
+
 func (fake *FakeAWSClient) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -516,16 +1002,32 @@ func (fake *FakeAWSClient) Invocations() map[string][][]interface{} {
 	defer fake.deleteVMMutex.RUnlock()
 	fake.getVMInfoMutex.RLock()
 	defer fake.getVMInfoMutex.RUnlock()
+	fake.getStoppedVMInfoMutex.RLock()
+	defer fake.getStoppedVMInfoMutex.RUnlock()
+	fake.describeVMMutex.RLock()
+	defer fake.describeVMMutex.RUnlock()
 	fake.getDiskMutex.RLock()
 	defer fake.getDiskMutex.RUnlock()
 	fake.startVMMutex.RLock()
 	defer fake.startVMMutex.RUnlock()
 	fake.stopVMMutex.RLock()
 	defer fake.stopVMMutex.RUnlock()
+	fake.shutdownVMMutex.RLock()
+	defer fake.shutdownVMMutex.RUnlock()
 	fake.assignPublicIPMutex.RLock()
 	defer fake.assignPublicIPMutex.RUnlock()
 	fake.waitForStatusMutex.RLock()
 	defer fake.waitForStatusMutex.RUnlock()
+	fake.detachVolumeMutex.RLock()
+	defer fake.detachVolumeMutex.RUnlock()
+	fake.attachVolumeMutex.RLock()
+	defer fake.attachVolumeMutex.RUnlock()
+	fake.waitForVolumeStatusMutex.RLock()
+	defer fake.waitForVolumeStatusMutex.RUnlock()
+	fake.verifyVPCMutex.RLock()
+	defer fake.verifyVPCMutex.RUnlock()
+	fake.checkPermissionsMutex.RLock()
+	defer fake.checkPermissionsMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
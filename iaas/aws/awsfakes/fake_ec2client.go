@@ -126,6 +126,45 @@ type FakeEC2Client struct {
 		result1 *ec2.Reservation
 		result2 error
 	}
+	DescribeVpcsStub        func(*ec2.DescribeVpcsInput) (*ec2.DescribeVpcsOutput, error)
+	describeVpcsMutex       sync.RWMutex
+	describeVpcsArgsForCall []struct {
+		arg1 *ec2.DescribeVpcsInput
+	}
+	describeVpcsReturns struct {
+		result1 *ec2.DescribeVpcsOutput
+		result2 error
+	}
+	describeVpcsReturnsOnCall map[int]struct {
+		result1 *ec2.DescribeVpcsOutput
+		result2 error
+	}
+	DetachVolumeStub        func(*ec2.DetachVolumeInput) (*ec2.VolumeAttachment, error)
+	detachVolumeMutex       sync.RWMutex
+	detachVolumeArgsForCall []struct {
+		arg1 *ec2.DetachVolumeInput
+	}
+	detachVolumeReturns struct {
+		result1 *ec2.VolumeAttachment
+		result2 error
+	}
+	detachVolumeReturnsOnCall map[int]struct {
+		result1 *ec2.VolumeAttachment
+		result2 error
+	}
+	AttachVolumeStub        func(*ec2.AttachVolumeInput) (*ec2.VolumeAttachment, error)
+	attachVolumeMutex       sync.RWMutex
+	attachVolumeArgsForCall []struct {
+		arg1 *ec2.AttachVolumeInput
+	}
+	attachVolumeReturns struct {
+		result1 *ec2.VolumeAttachment
+		result2 error
+	}
+	attachVolumeReturnsOnCall map[int]struct {
+		result1 *ec2.VolumeAttachment
+		result2 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -589,6 +628,159 @@ func (fake *FakeEC2Client) RunInstancesReturnsOnCall(i int, result1 *ec2.Reserva
 	}{result1, result2}
 }
 
+func (fake *FakeEC2Client) DescribeVpcs(arg1 *ec2.DescribeVpcsInput) (*ec2.DescribeVpcsOutput, error) {
+	fake.describeVpcsMutex.Lock()
+	ret, specificReturn := fake.describeVpcsReturnsOnCall[len(fake.describeVpcsArgsForCall)]
+	fake.describeVpcsArgsForCall = append(fake.describeVpcsArgsForCall, struct {
+		arg1 *ec2.DescribeVpcsInput
+	}{arg1})
+	fake.recordInvocation("DescribeVpcs", []interface{}{arg1})
+	fake.describeVpcsMutex.Unlock()
+	if fake.DescribeVpcsStub != nil {
+		return fake.DescribeVpcsStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.describeVpcsReturns.result1, fake.describeVpcsReturns.result2
+}
+
+func (fake *FakeEC2Client) DescribeVpcsCallCount() int {
+	fake.describeVpcsMutex.RLock()
+	defer fake.describeVpcsMutex.RUnlock()
+	return len(fake.describeVpcsArgsForCall)
+}
+
+func (fake *FakeEC2Client) DescribeVpcsArgsForCall(i int) *ec2.DescribeVpcsInput {
+	fake.describeVpcsMutex.RLock()
+	defer fake.describeVpcsMutex.RUnlock()
+	return fake.describeVpcsArgsForCall[i].arg1
+}
+
+func (fake *FakeEC2Client) DescribeVpcsReturns(result1 *ec2.DescribeVpcsOutput, result2 error) {
+	fake.DescribeVpcsStub = nil
+	fake.describeVpcsReturns = struct {
+		result1 *ec2.DescribeVpcsOutput
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEC2Client) DescribeVpcsReturnsOnCall(i int, result1 *ec2.DescribeVpcsOutput, result2 error) {
+	fake.DescribeVpcsStub = nil
+	if fake.describeVpcsReturnsOnCall == nil {
+		fake.describeVpcsReturnsOnCall = make(map[int]struct {
+			result1 *ec2.DescribeVpcsOutput
+			result2 error
+		})
+	}
+	fake.describeVpcsReturnsOnCall[i] = struct {
+		result1 *ec2.DescribeVpcsOutput
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEC2Client) DetachVolume(arg1 *ec2.DetachVolumeInput) (*ec2.VolumeAttachment, error) {
+	fake.detachVolumeMutex.Lock()
+	ret, specificReturn := fake.detachVolumeReturnsOnCall[len(fake.detachVolumeArgsForCall)]
+	fake.detachVolumeArgsForCall = append(fake.detachVolumeArgsForCall, struct {
+		arg1 *ec2.DetachVolumeInput
+	}{arg1})
+	fake.recordInvocation("DetachVolume", []interface{}{arg1})
+	fake.detachVolumeMutex.Unlock()
+	if fake.DetachVolumeStub != nil {
+		return fake.DetachVolumeStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.detachVolumeReturns.result1, fake.detachVolumeReturns.result2
+}
+
+func (fake *FakeEC2Client) DetachVolumeCallCount() int {
+	fake.detachVolumeMutex.RLock()
+	defer fake.detachVolumeMutex.RUnlock()
+	return len(fake.detachVolumeArgsForCall)
+}
+
+func (fake *FakeEC2Client) DetachVolumeArgsForCall(i int) *ec2.DetachVolumeInput {
+	fake.detachVolumeMutex.RLock()
+	defer fake.detachVolumeMutex.RUnlock()
+	return fake.detachVolumeArgsForCall[i].arg1
+}
+
+func (fake *FakeEC2Client) DetachVolumeReturns(result1 *ec2.VolumeAttachment, result2 error) {
+	fake.DetachVolumeStub = nil
+	fake.detachVolumeReturns = struct {
+		result1 *ec2.VolumeAttachment
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEC2Client) DetachVolumeReturnsOnCall(i int, result1 *ec2.VolumeAttachment, result2 error) {
+	fake.DetachVolumeStub = nil
+	if fake.detachVolumeReturnsOnCall == nil {
+		fake.detachVolumeReturnsOnCall = make(map[int]struct {
+			result1 *ec2.VolumeAttachment
+			result2 error
+		})
+	}
+	fake.detachVolumeReturnsOnCall[i] = struct {
+		result1 *ec2.VolumeAttachment
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEC2Client) AttachVolume(arg1 *ec2.AttachVolumeInput) (*ec2.VolumeAttachment, error) {
+	fake.attachVolumeMutex.Lock()
+	ret, specificReturn := fake.attachVolumeReturnsOnCall[len(fake.attachVolumeArgsForCall)]
+	fake.attachVolumeArgsForCall = append(fake.attachVolumeArgsForCall, struct {
+		arg1 *ec2.AttachVolumeInput
+	}{arg1})
+	fake.recordInvocation("AttachVolume", []interface{}{arg1})
+	fake.attachVolumeMutex.Unlock()
+	if fake.AttachVolumeStub != nil {
+		return fake.AttachVolumeStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.attachVolumeReturns.result1, fake.attachVolumeReturns.result2
+}
+
+func (fake *FakeEC2Client) AttachVolumeCallCount() int {
+	fake.attachVolumeMutex.RLock()
+	defer fake.attachVolumeMutex.RUnlock()
+	return len(fake.attachVolumeArgsForCall)
+}
+
+func (fake *FakeEC2Client) AttachVolumeArgsForCall(i int) *ec2.AttachVolumeInput {
+	fake.attachVolumeMutex.RLock()
+	defer fake.attachVolumeMutex.RUnlock()
+	return fake.attachVolumeArgsForCall[i].arg1
+}
+
+func (fake *FakeEC2Client) AttachVolumeReturns(result1 *ec2.VolumeAttachment, result2 error) {
+	fake.AttachVolumeStub = nil
+	fake.attachVolumeReturns = struct {
+		result1 *ec2.VolumeAttachment
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEC2Client) AttachVolumeReturnsOnCall(i int, result1 *ec2.VolumeAttachment, result2 error) {
+	fake.AttachVolumeStub = nil
+	if fake.attachVolumeReturnsOnCall == nil {
+		fake.attachVolumeReturnsOnCall = make(map[int]struct {
+			result1 *ec2.VolumeAttachment
+			result2 error
+		})
+	}
+	fake.attachVolumeReturnsOnCall[i] = struct {
+		result1 *ec2.VolumeAttachment
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeEC2Client) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -610,6 +802,12 @@ func (fake *FakeEC2Client) Invocations() map[string][][]interface{} {
 	defer fake.createTagsMutex.RUnlock()
 	fake.runInstancesMutex.RLock()
 	defer fake.runInstancesMutex.RUnlock()
+	fake.describeVpcsMutex.RLock()
+	defer fake.describeVpcsMutex.RUnlock()
+	fake.detachVolumeMutex.RLock()
+	defer fake.detachVolumeMutex.RUnlock()
+	fake.attachVolumeMutex.RLock()
+	defer fake.attachVolumeMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
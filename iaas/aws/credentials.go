@@ -0,0 +1,120 @@
+package aws
+
+import (
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/ec2rolecreds"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/defaults"
+	"github.com/aws/aws-sdk-go/aws/ec2metadata"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+const (
+	AuthModeStatic       = "static"
+	AuthModeProfile      = "profile"
+	AuthModeDefaultChain = "default-chain"
+)
+
+const defaultRoleSessionName = "cliaas"
+
+var IncompleteStaticCredentialsErr = errors.New("both an access key id and a secret access key are required for static credentials")
+
+// CredentialsConfig - describes where the credentials for the ec2 client come
+// from. Static keys win over a named profile, which wins over the default
+// chain (environment, shared credentials file, then ECS or EC2 metadata). When
+// RoleARN is set the resulting credentials are only used to assume that role.
+// MetadataEndpoint (e.g. http://169.254.169.254/latest) and STSEndpoint
+// override where instance profile and assumed role credentials come from.
+type CredentialsConfig struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Profile         string
+
+	RoleARN         string
+	ExternalID      string
+	RoleSessionName string
+
+	MetadataEndpoint string
+	STSEndpoint      string
+}
+
+func (c CredentialsConfig) AuthMode() string {
+	switch {
+	case c.AccessKeyID != "" || c.SecretAccessKey != "":
+		return AuthModeStatic
+	case c.Profile != "":
+		return AuthModeProfile
+	default:
+		return AuthModeDefaultChain
+	}
+}
+
+func (c CredentialsConfig) Complete() bool {
+	if c.AuthMode() == AuthModeStatic {
+		return c.AccessKeyID != "" && c.SecretAccessKey != ""
+	}
+	return true
+}
+
+// NewCredentials - builds the credentials described by the config. Nothing is
+// fetched until the credentials are first used.
+func NewCredentials(sess *session.Session, c CredentialsConfig) (*credentials.Credentials, error) {
+	if !c.Complete() {
+		return nil, IncompleteStaticCredentialsErr
+	}
+
+	var creds *credentials.Credentials
+	switch c.AuthMode() {
+	case AuthModeStatic:
+		creds = credentials.NewStaticCredentials(c.AccessKeyID, c.SecretAccessKey, c.SessionToken)
+	case AuthModeProfile:
+		creds = credentials.NewSharedCredentials("", c.Profile)
+	default:
+		creds = credentials.NewChainCredentials(c.defaultProviders(sess))
+	}
+
+	if c.RoleARN == "" {
+		return creds, nil
+	}
+
+	return c.assumeRole(sess, creds), nil
+}
+
+func (c CredentialsConfig) defaultProviders(sess *session.Session) []credentials.Provider {
+	providers := []credentials.Provider{
+		&credentials.EnvProvider{},
+		&credentials.SharedCredentialsProvider{},
+	}
+
+	if c.MetadataEndpoint != "" {
+		metadataClient := ec2metadata.New(sess, &aws.Config{
+			Endpoint: aws.String(c.MetadataEndpoint),
+		})
+		return append(providers, &ec2rolecreds.EC2RoleProvider{Client: metadataClient})
+	}
+
+	return append(providers, defaults.RemoteCredProvider(*sess.Config, sess.Handlers))
+}
+
+func (c CredentialsConfig) assumeRole(sess *session.Session, sourceCredentials *credentials.Credentials) *credentials.Credentials {
+	stsConfig := &aws.Config{Credentials: sourceCredentials}
+	if c.STSEndpoint != "" {
+		stsConfig.Endpoint = aws.String(c.STSEndpoint)
+	}
+
+	roleSessionName := c.RoleSessionName
+	if roleSessionName == "" {
+		roleSessionName = defaultRoleSessionName
+	}
+
+	return stscreds.NewCredentials(sess.Copy(stsConfig), c.RoleARN, func(p *stscreds.AssumeRoleProvider) {
+		p.RoleSessionName = roleSessionName
+		if c.ExternalID != "" {
+			p.ExternalID = aws.String(c.ExternalID)
+		}
+	})
+}
@@ -0,0 +1,217 @@
+package aws_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/pivotal-cf/cliaas/iaas/aws"
+)
+
+var _ = Describe("Credentials", func() {
+	var (
+		sess        *session.Session
+		savedEnv    map[string]string
+		credentials CredentialsConfig
+	)
+
+	BeforeEach(func() {
+		savedEnv = map[string]string{}
+		for _, name := range []string{"AWS_ACCESS_KEY_ID", "AWS_ACCESS_KEY", "AWS_SECRET_ACCESS_KEY", "AWS_SECRET_KEY", "AWS_SESSION_TOKEN", "AWS_PROFILE", "AWS_SHARED_CREDENTIALS_FILE"} {
+			savedEnv[name] = os.Getenv(name)
+			os.Unsetenv(name)
+		}
+		os.Setenv("AWS_SHARED_CREDENTIALS_FILE", "does-not-exist")
+
+		var err error
+		sess, err = session.NewSession(&aws.Config{Region: aws.String("us-east-1")})
+		Expect(err).NotTo(HaveOccurred())
+		credentials = CredentialsConfig{}
+	})
+
+	AfterEach(func() {
+		for name, value := range savedEnv {
+			if value == "" {
+				os.Unsetenv(name)
+			} else {
+				os.Setenv(name, value)
+			}
+		}
+	})
+
+	Describe("AuthMode", func() {
+		It("uses static credentials when keys are given", func() {
+			credentials.AccessKeyID = "some-access-key-id"
+			Expect(credentials.AuthMode()).To(Equal(AuthModeStatic))
+		})
+
+		It("uses the named profile when no keys are given", func() {
+			credentials.Profile = "some-profile"
+			Expect(credentials.AuthMode()).To(Equal(AuthModeProfile))
+		})
+
+		It("falls back to the default chain", func() {
+			Expect(credentials.AuthMode()).To(Equal(AuthModeDefaultChain))
+		})
+	})
+
+	Describe("NewCredentials", func() {
+		Context("when only one half of a static key pair is given", func() {
+			It("returns an error", func() {
+				credentials.AccessKeyID = "some-access-key-id"
+				_, err := NewCredentials(sess, credentials)
+				Expect(err).To(Equal(IncompleteStaticCredentialsErr))
+			})
+		})
+
+		Context("when static keys and a session token are given", func() {
+			It("returns them as-is", func() {
+				credentials.AccessKeyID = "some-access-key-id"
+				credentials.SecretAccessKey = "some-secret-access-key"
+				credentials.SessionToken = "some-session-token"
+
+				creds, err := NewCredentials(sess, credentials)
+				Expect(err).NotTo(HaveOccurred())
+
+				value, err := creds.Get()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(value.AccessKeyID).To(Equal("some-access-key-id"))
+				Expect(value.SecretAccessKey).To(Equal("some-secret-access-key"))
+				Expect(value.SessionToken).To(Equal("some-session-token"))
+			})
+		})
+
+		Context("when using the default chain", func() {
+			var metadataServer *httptest.Server
+			var metadataRequests int
+
+			BeforeEach(func() {
+				metadataRequests = 0
+				metadataServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					metadataRequests++
+					switch strings.TrimSuffix(r.URL.Path, "/") {
+					case "/latest/meta-data/iam/security-credentials":
+						fmt.Fprint(w, "some-instance-role")
+					case "/latest/meta-data/iam/security-credentials/some-instance-role":
+						fmt.Fprint(w, `{
+							"Code": "Success",
+							"Type": "AWS-HMAC",
+							"AccessKeyId": "metadata-access-key-id",
+							"SecretAccessKey": "metadata-secret-access-key",
+							"Token": "metadata-token",
+							"Expiration": "2100-01-01T00:00:00Z"
+						}`)
+					default:
+						w.WriteHeader(http.StatusNotFound)
+					}
+				}))
+				credentials.MetadataEndpoint = metadataServer.URL + "/latest"
+			})
+
+			AfterEach(func() {
+				metadataServer.Close()
+			})
+
+			It("prefers credentials from the environment", func() {
+				os.Setenv("AWS_ACCESS_KEY_ID", "env-access-key-id")
+				os.Setenv("AWS_SECRET_ACCESS_KEY", "env-secret-access-key")
+
+				creds, err := NewCredentials(sess, credentials)
+				Expect(err).NotTo(HaveOccurred())
+
+				value, err := creds.Get()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(value.AccessKeyID).To(Equal("env-access-key-id"))
+				Expect(metadataRequests).To(Equal(0))
+			})
+
+			It("falls back to the instance profile from the metadata service", func() {
+				creds, err := NewCredentials(sess, credentials)
+				Expect(err).NotTo(HaveOccurred())
+
+				value, err := creds.Get()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(value.AccessKeyID).To(Equal("metadata-access-key-id"))
+				Expect(value.SecretAccessKey).To(Equal("metadata-secret-access-key"))
+				Expect(value.SessionToken).To(Equal("metadata-token"))
+			})
+
+			Context("when a role arn is given", func() {
+				var stsServer *httptest.Server
+				var assumeRoleForm map[string]string
+				var stsAuthorization string
+
+				BeforeEach(func() {
+					assumeRoleForm = map[string]string{}
+					stsServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+						Expect(r.ParseForm()).To(Succeed())
+						for key := range r.PostForm {
+							assumeRoleForm[key] = r.PostForm.Get(key)
+						}
+						stsAuthorization = r.Header.Get("Authorization")
+
+						w.Header().Set("Content-Type", "text/xml")
+						fmt.Fprint(w, `<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleResult>
+    <Credentials>
+      <AccessKeyId>assumed-access-key-id</AccessKeyId>
+      <SecretAccessKey>assumed-secret-access-key</SecretAccessKey>
+      <SessionToken>assumed-session-token</SessionToken>
+      <Expiration>2100-01-01T00:00:00Z</Expiration>
+    </Credentials>
+    <AssumedRoleUser>
+      <Arn>arn:aws:sts::123456789012:assumed-role/some-role/some-session</Arn>
+      <AssumedRoleId>some-role-id:some-session</AssumedRoleId>
+    </AssumedRoleUser>
+  </AssumeRoleResult>
+  <ResponseMetadata>
+    <RequestId>some-request-id</RequestId>
+  </ResponseMetadata>
+</AssumeRoleResponse>`)
+					}))
+
+					credentials.STSEndpoint = stsServer.URL
+					credentials.RoleARN = "arn:aws:iam::123456789012:role/some-role"
+					credentials.ExternalID = "some-external-id"
+				})
+
+				AfterEach(func() {
+					stsServer.Close()
+				})
+
+				It("assumes the role using the instance profile credentials", func() {
+					credentials.RoleSessionName = "some-session"
+
+					creds, err := NewCredentials(sess, credentials)
+					Expect(err).NotTo(HaveOccurred())
+
+					value, err := creds.Get()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(value.AccessKeyID).To(Equal("assumed-access-key-id"))
+					Expect(value.SessionToken).To(Equal("assumed-session-token"))
+
+					Expect(assumeRoleForm["Action"]).To(Equal("AssumeRole"))
+					Expect(assumeRoleForm["RoleArn"]).To(Equal("arn:aws:iam::123456789012:role/some-role"))
+					Expect(assumeRoleForm["ExternalId"]).To(Equal("some-external-id"))
+					Expect(assumeRoleForm["RoleSessionName"]).To(Equal("some-session"))
+					Expect(stsAuthorization).To(ContainSubstring("metadata-access-key-id"))
+				})
+
+				It("defaults the role session name", func() {
+					creds, err := NewCredentials(sess, credentials)
+					Expect(err).NotTo(HaveOccurred())
+
+					_, err = creds.Get()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(assumeRoleForm["RoleSessionName"]).To(Equal("cliaas"))
+				})
+			})
+		})
+	})
+})
@@ -1,10 +1,14 @@
 package aws
 
 import (
+	"time"
+
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pivotal-cf/cliaas/iaas"
+	errwrap "github.com/pkg/errors"
 )
 
 //go:generate counterfeiter . EC2Client
@@ -19,96 +23,42 @@ type EC2Client interface {
 	StartInstances(*ec2.StartInstancesInput) (*ec2.StartInstancesOutput, error)
 	CreateTags(*ec2.CreateTagsInput) (*ec2.CreateTagsOutput, error)
 	RunInstances(*ec2.RunInstancesInput) (*ec2.Reservation, error)
+	DescribeVpcs(*ec2.DescribeVpcsInput) (*ec2.DescribeVpcsOutput, error)
+	DetachVolume(*ec2.DetachVolumeInput) (*ec2.VolumeAttachment, error)
+	AttachVolume(*ec2.AttachVolumeInput) (*ec2.VolumeAttachment, error)
 }
 
-func NewEC2Client(accessKeyID string, secretAccessKey string, region string) (EC2Client, error) {
-	sess, err := session.NewSession()
+func NewEC2Client(credentialsConfig CredentialsConfig, region string, logger *iaas.Logger) (EC2Client, error) {
+	sess, err := session.NewSession(&aws.Config{
+		Region: aws.String(region),
+	})
+	if err != nil {
+		return nil, errwrap.Wrap(err, "failed to create aws session")
+	}
+
+	creds, err := NewCredentials(sess, credentialsConfig)
 	if err != nil {
-		return nil, err
+		return nil, errwrap.Wrap(err, "failed to build aws credentials")
 	}
 
 	ec2Client := ec2.New(sess, &aws.Config{
-		Credentials: credentials.NewStaticCredentials(accessKeyID, secretAccessKey, ""),
+		Credentials: creds,
 		Region:      aws.String(region),
 	})
-
-	//_, err = ec2Client.Config.Credentials.Get()
-	//if err != nil {
-	//	fmt.Println("Creds not found")
-	//}
-	//
-	//name := "tidy-anteater-OpsMan az1"
-	//
-	//params := &ec2.DescribeInstancesInput{
-	//	Filters: []*ec2.Filter{
-	//		{
-	//			Name: aws.String("tag:Name"),
-	//			Values: []*string{
-	//				aws.String(name),
-	//			},
-	//		},
-	//	},
-	//}
-	//resp, err := ec2Client.DescribeInstances(params)
-	//
-	//var list []*ec2.Instance
-	//
-	//for idx := range resp.Reservations {
-	//	for _, instance := range resp.Reservations[idx].Instances {
-	//		if *instance.State.Name == ec2.InstanceStateNameRunning {
-	//			list = append(list, instance)
-	//		}
-	//	}
-	//}
-	//
-	//if len(list) == 0 {
-	//	println("no matching instances found")
-	//}
-	//
-	//if len(list) > 1 {
-	//	println("more than one matching instance found")
-	//}
-	//
-	//instance := list[0]
-	//
-	//_, err = describeVolumes(instance.BlockDeviceMappings)
-	//if err != nil {
-	//	println(err.Error(), "describeVolumes failure")
-	//}
+	LogRequests(&ec2Client.Handlers, logger)
 
 	return ec2Client, nil
 }
 
-//func describeVolumes(instanceBlockDeviceMappings []*ec2.InstanceBlockDeviceMapping) ([]BlockDeviceMapping, error) {
-//	blockDeviceMappings := []BlockDeviceMapping{}
-//	for _, blockDeviceMapping := range instanceBlockDeviceMappings {
-//		params := &ec2.DescribeVolumesInput{
-//			Filters: []*ec2.Filter{
-//				{
-//					Name: aws.String("volume-id"),
-//					Values: []*string{
-//						blockDeviceMapping.Ebs.VolumeId,
-//					},
-//				},
-//			},
-//		}
-//		resp, err := ec2Client.DescribeVolumes(params)
-//		if err != nil {
-//			println(err.Error(), "Describe Volume call failed")
-//		}
-//
-//		for _, volume := range resp.Volumes {
-//			blockDeviceMappings = append(blockDeviceMappings, BlockDeviceMapping{
-//				DeviceName: aws.StringValue(blockDeviceMapping.DeviceName),
-//				EBS: EBS{
-//					DeleteOnTermination: aws.BoolValue(blockDeviceMapping.Ebs.DeleteOnTermination),
-//					VolumeSize:          aws.Int64Value(volume.Size),
-//					VolumeType:          aws.StringValue(volume.VolumeType),
-//				},
-//			})
-//		}
-//	}
-//	println("********************",blockDeviceMappings[0].EBS.VolumeSize)
-//
-//	return blockDeviceMappings, nil
-//}
+// LogRequests logs every ec2 api call with its request id once its response,
+// or its error, has been unmarshalled
+func LogRequests(handlers *request.Handlers, logger *iaas.Logger) {
+	logRequest := request.NamedHandler{
+		Name: "cliaas.LogRequest",
+		Fn: func(r *request.Request) {
+			logger.APICall(r.Operation.Name, time.Since(r.Time), r.RequestID, ClassifyError(r.Error))
+		},
+	}
+	handlers.Unmarshal.PushBackNamed(logRequest)
+	handlers.UnmarshalError.PushBackNamed(logRequest)
+}
@@ -0,0 +1,53 @@
+package aws
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/pivotal-cf/cliaas/iaas"
+)
+
+// retryableCodes are the ec2 error codes for throttling and for states that
+// pass on their own
+var retryableCodes = map[string]bool{
+	"RequestLimitExceeded":          true,
+	"Throttling":                    true,
+	"ThrottlingException":           true,
+	"RequestThrottled":              true,
+	"InternalError":                 true,
+	"InternalFailure":               true,
+	"ServiceUnavailable":            true,
+	"Unavailable":                   true,
+	"RequestError":                  true,
+	"IncorrectInstanceState":        true,
+	"InsufficientInstanceCapacity":  true,
+	"InsufficientAddressCapacity":   true,
+	"InsufficientReservedInstances": true,
+}
+
+// ClassifyError marks an error from the ec2 api as retryable, fatal or not
+// found. EC2 sends no Retry-After, so throttled calls back off on the poll
+// interval alone.
+func ClassifyError(err error) error {
+	awsErr, ok := err.(awserr.Error)
+	if !ok {
+		return err
+	}
+
+	code := awsErr.Code()
+	switch {
+	case code == dryRunOperationCode:
+		return err
+	case retryableCodes[code]:
+		return iaas.NewRetryableError(err, 0)
+	case strings.HasSuffix(code, ".NotFound"):
+		return iaas.NewNotFoundError(err)
+	}
+
+	if requestFailure, ok := err.(awserr.RequestFailure); ok {
+		if class := iaas.ClassifyStatusCode(requestFailure.StatusCode()); class == iaas.Retryable {
+			return iaas.NewRetryableError(err, 0)
+		}
+	}
+	return iaas.NewFatalError(err)
+}
@@ -0,0 +1,17 @@
+package aws
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// maxNameLength is the longest value ec2 accepts for the Name tag
+const maxNameLength = 255
+
+// ValidateName checks a new instance name against ec2's rules for tag values
+func ValidateName(name string) error {
+	if name == "" || utf8.RuneCountInString(name) > maxNameLength {
+		return fmt.Errorf("%q is not a valid aws name: it must be 1 to %d characters", name, maxNameLength)
+	}
+	return nil
+}
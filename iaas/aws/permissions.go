@@ -0,0 +1,76 @@
+package aws
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pivotal-cf/cliaas/iaas"
+	errwrap "github.com/pkg/errors"
+)
+
+const dryRunOperationCode = "DryRunOperation"
+
+// CheckPermissions - makes a DryRun call for every ec2 action a replace or
+// delete of the given vm makes. EC2 answers a DryRun with DryRunOperation
+// when the call would have been allowed, and UnauthorizedOperation otherwise.
+func (c *client) CheckPermissions(ami string, vmInfo VMInfo) []iaas.PermissionCheck {
+	instanceIDs := []*string{aws.String(vmInfo.InstanceID)}
+	var checks []iaas.PermissionCheck
+
+	runInput := runInstancesInput(ami, vmInfo)
+	runInput.DryRun = aws.Bool(true)
+	_, err := c.ec2Client.RunInstances(runInput)
+	checks = append(checks, dryRunCheck("ec2:RunInstances", err))
+
+	_, err = c.ec2Client.StopInstances(&ec2.StopInstancesInput{
+		InstanceIds: instanceIDs,
+		DryRun:      aws.Bool(true),
+	})
+	checks = append(checks, dryRunCheck("ec2:StopInstances", err))
+
+	if vmInfo.PublicIP == "" {
+		checks = append(checks, iaas.PermissionCheck{
+			Permission: "ec2:AssociateAddress",
+			SkipReason: "the vm has no public ip",
+		})
+	} else {
+		_, err = c.ec2Client.AssociateAddress(&ec2.AssociateAddressInput{
+			InstanceId: aws.String(vmInfo.InstanceID),
+			PublicIp:   aws.String(vmInfo.PublicIP),
+			DryRun:     aws.Bool(true),
+		})
+		checks = append(checks, dryRunCheck("ec2:AssociateAddress", err))
+	}
+
+	_, err = c.ec2Client.CreateTags(&ec2.CreateTagsInput{
+		Resources: instanceIDs,
+		Tags: []*ec2.Tag{
+			{
+				Key:   aws.String("Name"),
+				Value: aws.String(vmInfo.InstanceID),
+			},
+		},
+		DryRun: aws.Bool(true),
+	})
+	checks = append(checks, dryRunCheck("ec2:CreateTags", err))
+
+	_, err = c.ec2Client.TerminateInstances(&ec2.TerminateInstancesInput{
+		InstanceIds: instanceIDs,
+		DryRun:      aws.Bool(true),
+	})
+	checks = append(checks, dryRunCheck("ec2:TerminateInstances", err))
+
+	return checks
+}
+
+func dryRunCheck(permission string, err error) iaas.PermissionCheck {
+	err = errwrap.Cause(err)
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == dryRunOperationCode {
+		err = nil
+	}
+
+	return iaas.PermissionCheck{
+		Permission: permission,
+		Err:        err,
+	}
+}
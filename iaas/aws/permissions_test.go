@@ -0,0 +1,90 @@
+package aws_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/cliaas/iaas"
+	. "github.com/pivotal-cf/cliaas/iaas/aws"
+	"github.com/pivotal-cf/cliaas/iaas/aws/awsfakes"
+)
+
+var _ = Describe("CheckPermissions", func() {
+	var (
+		client    AWSClient
+		ec2Client *awsfakes.FakeEC2Client
+		vmInfo    VMInfo
+		allowed   error
+		denied    error
+	)
+
+	BeforeEach(func() {
+		ec2Client = new(awsfakes.FakeEC2Client)
+		client = NewAWSClient(ec2Client, "some vpc", iaas.NewWaiter(fakeclock.NewFakeClock(time.Now()), iaas.Timeouts{}))
+		vmInfo = VMInfo{
+			InstanceID:   "i-1234",
+			InstanceType: "m3.large",
+			PublicIP:     "1.1.1.1",
+		}
+
+		allowed = awserr.New("DryRunOperation", "Request would have succeeded, but DryRun flag is set.", nil)
+		denied = awserr.New("UnauthorizedOperation", "You are not authorized to perform this operation.", nil)
+
+		ec2Client.RunInstancesReturns(nil, allowed)
+		ec2Client.StopInstancesReturns(nil, allowed)
+		ec2Client.AssociateAddressReturns(nil, allowed)
+		ec2Client.CreateTagsReturns(nil, allowed)
+		ec2Client.TerminateInstancesReturns(nil, allowed)
+	})
+
+	It("dry runs every call a replace makes", func() {
+		checks := client.CheckPermissions("ami-1234", vmInfo)
+		Expect(checks).To(Equal([]iaas.PermissionCheck{
+			{Permission: "ec2:RunInstances"},
+			{Permission: "ec2:StopInstances"},
+			{Permission: "ec2:AssociateAddress"},
+			{Permission: "ec2:CreateTags"},
+			{Permission: "ec2:TerminateInstances"},
+		}))
+
+		runInput := ec2Client.RunInstancesArgsForCall(0)
+		Expect(*runInput.DryRun).To(BeTrue())
+		Expect(*runInput.ImageId).To(Equal("ami-1234"))
+		Expect(*runInput.InstanceType).To(Equal("m3.large"))
+
+		Expect(*ec2Client.StopInstancesArgsForCall(0)).To(Equal(ec2.StopInstancesInput{
+			InstanceIds: []*string{aws.String("i-1234")},
+			DryRun:      aws.Bool(true),
+		}))
+		Expect(*ec2Client.AssociateAddressArgsForCall(0).DryRun).To(BeTrue())
+		Expect(*ec2Client.CreateTagsArgsForCall(0).DryRun).To(BeTrue())
+		Expect(*ec2Client.TerminateInstancesArgsForCall(0).DryRun).To(BeTrue())
+	})
+
+	It("fails the checks that are not authorized", func() {
+		ec2Client.TerminateInstancesReturns(nil, denied)
+
+		checks := client.CheckPermissions("ami-1234", vmInfo)
+		Expect(checks[4].Permission).To(Equal("ec2:TerminateInstances"))
+		Expect(checks[4].Result()).To(Equal("FAIL"))
+		Expect(checks[4].Err).To(Equal(denied))
+		Expect(checks[0].Result()).To(Equal("PASS"))
+	})
+
+	Context("when the vm has no public ip", func() {
+		It("skips the associate address check", func() {
+			vmInfo.PublicIP = ""
+
+			checks := client.CheckPermissions("ami-1234", vmInfo)
+			Expect(checks[2].Permission).To(Equal("ec2:AssociateAddress"))
+			Expect(checks[2].Result()).To(Equal("SKIP"))
+			Expect(ec2Client.AssociateAddressCallCount()).To(Equal(0))
+		})
+	})
+})
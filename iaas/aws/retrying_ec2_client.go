@@ -0,0 +1,115 @@
+package aws
+
+import (
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pivotal-cf/cliaas/iaas"
+)
+
+// retryingEC2Client classifies every ec2 error and retries the retryable ones.
+// RunInstances is only safe to retry because CreateVM sets a client token.
+type retryingEC2Client struct {
+	ec2Client EC2Client
+	waiter    iaas.Waiter
+}
+
+func (c retryingEC2Client) call(description string, operation func() error) error {
+	return c.waiter.Call(description, func() error {
+		return ClassifyError(operation())
+	})
+}
+
+func (c retryingEC2Client) DescribeInstances(input *ec2.DescribeInstancesInput) (output *ec2.DescribeInstancesOutput, err error) {
+	err = c.call("describing instances", func() error {
+		output, err = c.ec2Client.DescribeInstances(input)
+		return err
+	})
+	return output, err
+}
+
+func (c retryingEC2Client) DescribeVolumes(input *ec2.DescribeVolumesInput) (output *ec2.DescribeVolumesOutput, err error) {
+	err = c.call("describing volumes", func() error {
+		output, err = c.ec2Client.DescribeVolumes(input)
+		return err
+	})
+	return output, err
+}
+
+func (c retryingEC2Client) DescribeInstanceStatus(input *ec2.DescribeInstanceStatusInput) (output *ec2.DescribeInstanceStatusOutput, err error) {
+	err = c.call("describing instance status", func() error {
+		output, err = c.ec2Client.DescribeInstanceStatus(input)
+		return err
+	})
+	return output, err
+}
+
+func (c retryingEC2Client) AssociateAddress(input *ec2.AssociateAddressInput) (output *ec2.AssociateAddressOutput, err error) {
+	err = c.call("associating an address", func() error {
+		output, err = c.ec2Client.AssociateAddress(input)
+		return err
+	})
+	return output, err
+}
+
+func (c retryingEC2Client) TerminateInstances(input *ec2.TerminateInstancesInput) (output *ec2.TerminateInstancesOutput, err error) {
+	err = c.call("terminating instances", func() error {
+		output, err = c.ec2Client.TerminateInstances(input)
+		return err
+	})
+	return output, err
+}
+
+func (c retryingEC2Client) StopInstances(input *ec2.StopInstancesInput) (output *ec2.StopInstancesOutput, err error) {
+	err = c.call("stopping instances", func() error {
+		output, err = c.ec2Client.StopInstances(input)
+		return err
+	})
+	return output, err
+}
+
+func (c retryingEC2Client) StartInstances(input *ec2.StartInstancesInput) (output *ec2.StartInstancesOutput, err error) {
+	err = c.call("starting instances", func() error {
+		output, err = c.ec2Client.StartInstances(input)
+		return err
+	})
+	return output, err
+}
+
+func (c retryingEC2Client) CreateTags(input *ec2.CreateTagsInput) (output *ec2.CreateTagsOutput, err error) {
+	err = c.call("creating tags", func() error {
+		output, err = c.ec2Client.CreateTags(input)
+		return err
+	})
+	return output, err
+}
+
+func (c retryingEC2Client) RunInstances(input *ec2.RunInstancesInput) (output *ec2.Reservation, err error) {
+	err = c.call("running instances", func() error {
+		output, err = c.ec2Client.RunInstances(input)
+		return err
+	})
+	return output, err
+}
+
+func (c retryingEC2Client) DescribeVpcs(input *ec2.DescribeVpcsInput) (output *ec2.DescribeVpcsOutput, err error) {
+	err = c.call("describing vpcs", func() error {
+		output, err = c.ec2Client.DescribeVpcs(input)
+		return err
+	})
+	return output, err
+}
+
+func (c retryingEC2Client) DetachVolume(input *ec2.DetachVolumeInput) (output *ec2.VolumeAttachment, err error) {
+	err = c.call("detaching a volume", func() error {
+		output, err = c.ec2Client.DetachVolume(input)
+		return err
+	})
+	return output, err
+}
+
+func (c retryingEC2Client) AttachVolume(input *ec2.AttachVolumeInput) (output *ec2.VolumeAttachment, err error) {
+	err = c.call("attaching a volume", func() error {
+		output, err = c.ec2Client.AttachVolume(input)
+		return err
+	})
+	return output, err
+}
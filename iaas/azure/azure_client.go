@@ -3,16 +3,20 @@ package azure
 import (
 	"errors"
 	"fmt"
+	"net/http"
 	"regexp"
-	"strings"
 	"time"
 
+	"code.cloudfoundry.org/clock"
 	"github.com/google/uuid"
 
 	"github.com/Azure/azure-sdk-for-go/arm/compute"
-	"github.com/Azure/azure-sdk-for-go/arm/examples/helpers"
+	"github.com/Azure/azure-sdk-for-go/arm/network"
+	"github.com/Azure/azure-sdk-for-go/arm/resources/resources"
 	"github.com/Azure/azure-sdk-for-go/storage"
 	"github.com/Azure/go-autorest/autorest"
+	autorestazure "github.com/Azure/go-autorest/autorest/azure"
+	"github.com/Azure/go-autorest/autorest/to"
 	"github.com/pivotal-cf/cliaas/iaas"
 	errwrap "github.com/pkg/errors"
 )
@@ -21,17 +25,34 @@ const defaultResourceManagerEndpoint = "https://management.azure.com/"
 const DefaultBaseURL = "core.windows.net"
 
 type Client struct {
-	BlobServiceClient     BlobCopier
-	VirtualMachinesClient ComputeVirtualMachinesClient
-	resourceGroupName     string
-	storageContainerName  string
-	storageAccountName    string
-	storageBaseURL        string
-	vmAdminPassword       string
+	BlobServiceClient       BlobCopier
+	VirtualMachinesClient   ComputeVirtualMachinesClient
+	NetworkInterfacesClient NetworkInterfacesClient
+	PublicIPAddressesClient PublicIPAddressesClient
+	ResourceGroupsClient    ResourceGroupsClient
+	PermissionsClient       PermissionsClient
+	resourceGroupName       string
+	storageContainerName    string
+	storageAccountName      string
+	storageBaseURL          string
+	vmAdminPassword         string
+	waiter                  iaas.Waiter
+	progress                iaas.ProgressReporter
+	logger                  *iaas.Logger
+	namer                   iaas.Namer
+	hooks                   *iaas.HookRunner
+	migrateDataDisk         bool
 }
 
+// BlobCopier is the part of the blob storage api that copies the vhd into
+// the storage account
 type BlobCopier interface {
-	CopyBlob(container, name, sourceBlob string) error
+	BlobExists(container, name string) (bool, error)
+	StartBlobCopy(container, name, sourceBlob string) (string, error)
+	GetBlobProperties(container, name string) (*storage.BlobProperties, error)
+	AbortBlobCopy(container, name, copyID, currentLeaseID string, timeout int) error
+	DeleteBlobIfExists(container, name string, extraHeaders map[string]string) (bool, error)
+	ContainerExists(name string) (bool, error)
 }
 
 type ComputeVirtualMachinesClient interface {
@@ -40,14 +61,21 @@ type ComputeVirtualMachinesClient interface {
 	CreateOrUpdate(resourceGroupName string, vmName string, parameters compute.VirtualMachine, cancel <-chan struct{}) (result autorest.Response, err error)
 	Delete(resourceGroupName string, vmName string, cancel <-chan struct{}) (result autorest.Response, err error)
 	Deallocate(resourceGroupName string, vmName string, cancel <-chan struct{}) (result autorest.Response, err error)
+	Start(resourceGroupName string, vmName string, cancel <-chan struct{}) (result autorest.Response, err error)
+	BeginDeallocate(resourceGroupName string, vmName string, cancel <-chan struct{}) (result autorest.Response, err error)
+	BeginStart(resourceGroupName string, vmName string, cancel <-chan struct{}) (result autorest.Response, err error)
 	List(resourceGroupName string) (result compute.VirtualMachineListResult, err error)
 }
 
+type ResourceGroupsClient interface {
+	Get(resourceGroupName string) (result resources.Group, err error)
+}
+
 var InvalidAzureClientErr = errors.New("invalid azure sdk client defined")
 var NoMatchesErr = errors.New("no VM names match the provided prefix")
 var MultipleMatchesErr = errors.New("multiple VM names match the provided prefix")
 
-func NewClient(subscriptionID string, clientID string, clientSecret string, tenantID string, resourceGroupName string, resourceManagerEndpoint string) (*Client, error) {
+func NewClient(subscriptionID string, clientID string, clientSecret string, tenantID string, resourceGroupName string, environment Environment) (*Client, error) {
 	c := map[string]string{
 		"AZURE_CLIENT_ID":       clientID,
 		"AZURE_CLIENT_SECRET":   clientSecret,
@@ -57,56 +85,348 @@ func NewClient(subscriptionID string, clientID string, clientSecret string, tena
 	if err := checkEnvVar(c); err != nil {
 		return nil, errwrap.Wrap(err, "failed on check of env vars")
 	}
-	if resourceManagerEndpoint == "" {
-		resourceManagerEndpoint = defaultResourceManagerEndpoint
+	if environment.Name == "" {
+		var err error
+		environment, err = NewEnvironment(AzurePublicCloud, "", "")
+		if err != nil {
+			return nil, errwrap.Wrap(err, "failed resolving default environment")
+		}
 	}
 
-	spt, err := helpers.NewServicePrincipalTokenFromCredentials(c, resourceManagerEndpoint)
+	spt, err := newServicePrincipalToken(environment, tenantID, clientID, clientSecret)
 	if err != nil {
 		return nil, errwrap.Wrap(err, "failed to generate new service principal token")
 	}
-	client := compute.NewVirtualMachinesClient(subscriptionID)
+	recorder := new(retryAfterRecorder)
+	client := compute.NewVirtualMachinesClientWithBaseURI(environment.ResourceManagerEndpoint, subscriptionID)
 	client.Authorizer = spt
-	return &Client{
-		VirtualMachinesClient: &client,
-		resourceGroupName:     resourceGroupName,
-	}, nil
+	client.ResponseInspector = recorder.ByRecording()
+	interfacesRecorder := new(retryAfterRecorder)
+	interfacesClient := network.NewInterfacesClientWithBaseURI(environment.ResourceManagerEndpoint, subscriptionID)
+	interfacesClient.Authorizer = spt
+	interfacesClient.ResponseInspector = interfacesRecorder.ByRecording()
+	publicIPsRecorder := new(retryAfterRecorder)
+	publicIPsClient := network.NewPublicIPAddressesClientWithBaseURI(environment.ResourceManagerEndpoint, subscriptionID)
+	publicIPsClient.Authorizer = spt
+	publicIPsClient.ResponseInspector = publicIPsRecorder.ByRecording()
+	groupsClient := resources.NewGroupsClientWithBaseURI(environment.ResourceManagerEndpoint, subscriptionID)
+	groupsClient.Authorizer = spt
+	azureClient := &Client{
+		ResourceGroupsClient: &groupsClient,
+		PermissionsClient:    NewPermissionsClient(environment.ResourceManagerEndpoint, subscriptionID, spt),
+		resourceGroupName:    resourceGroupName,
+		storageBaseURL:       environment.StorageEndpointSuffix,
+		waiter:               iaas.NewWaiter(clock.NewClock(), iaas.Timeouts{}),
+	}
+	azureClient.VirtualMachinesClient = retryingVirtualMachinesClient{
+		retryingCaller: azureClient.retryingCaller(recorder),
+		client:         virtualMachinesClient{&client},
+	}
+	azureClient.NetworkInterfacesClient = retryingNetworkInterfacesClient{
+		retryingCaller: azureClient.retryingCaller(interfacesRecorder),
+		client:         &interfacesClient,
+	}
+	azureClient.PublicIPAddressesClient = retryingPublicIPAddressesClient{
+		retryingCaller: azureClient.retryingCaller(publicIPsRecorder),
+		client:         &publicIPsClient,
+	}
+	return azureClient, nil
+}
+
+// VerifyResourceGroup checks that the service principal can read the
+// configured resource group
+func (s *Client) VerifyResourceGroup() error {
+	if s.ResourceGroupsClient == nil {
+		return InvalidAzureClientErr
+	}
+
+	_, err := s.ResourceGroupsClient.Get(s.resourceGroupName)
+	if err != nil {
+		return errwrap.Wrapf(err, "could not get resource group %s", s.resourceGroupName)
+	}
+	return nil
 }
 
 /* Cliaas Client Interface */
 func (s *Client) Delete(identifier string) error {
-	_, err := s.executeFunctionOnMatchingVM(identifier, s.VirtualMachinesClient.Delete)
+	_, err := s.executeFunctionOnMatchingVM(identifier, s.getWaiter().Timeouts.Stop, s.VirtualMachinesClient.Delete)
 	return err
 }
 
+// Stop deallocates the vm, so that it is no longer billed. Without wait it
+// returns once azure has accepted the deallocation.
+func (s *Client) Stop(identifier string, wait bool) error {
+	deallocate := s.VirtualMachinesClient.BeginDeallocate
+	if wait {
+		deallocate = s.VirtualMachinesClient.Deallocate
+	}
+
+	iaas.ReportStep(s.progress, iaas.StepStopping, identifier)
+	instance, err := s.executeFunctionOnMatchingVM(identifier, s.getWaiter().Timeouts.Stop, deallocate)
+	if err != nil {
+		return errwrap.Wrap(err, "error shutting down VM")
+	}
+	if wait {
+		iaas.ReportStep(s.progress, iaas.StepStopped, *instance.Name)
+	}
+	return nil
+}
+
+// Start starts a deallocated vm. Its nic keeps the public ip while it is
+// deallocated, so there is nothing to restore. Without wait it returns once
+// azure has accepted the start.
+func (s *Client) Start(identifier string, wait bool) error {
+	start := s.VirtualMachinesClient.BeginStart
+	if wait {
+		start = s.VirtualMachinesClient.Start
+	}
+
+	iaas.ReportStep(s.progress, iaas.StepStarting, identifier)
+	instance, err := s.executeFunctionOnMatchingVM(identifier, s.getWaiter().Timeouts.Start, start)
+	if err != nil {
+		return errwrap.Wrap(err, "error starting VM")
+	}
+	if wait {
+		iaas.ReportStep(s.progress, iaas.StepRunning, *instance.Name)
+	}
+	return nil
+}
+
+// Replace boots a new vm from the vhd in place of the old one. The new vm gets
+// a nic of its own in the old nic's subnet and network security group, and the
+// old vm is only deleted once the new one is running. The old nic's static
+// private ip and public ip then move to the new nic.
 func (s *Client) Replace(identifier string, vhdURL string, diskSizeGB int64) error {
-	instance, err := s.deallocate(identifier)
+	instance, err := s.findMatchingVM(identifier)
 	if err != nil {
 		return errwrap.Wrap(err, "error shutting down VM")
 	}
+	iaas.ReportStep(s.progress, iaas.StepFoundVM, *instance.Name)
 
-	tmpName := generateInstanceName(*instance.Name)
-	localBlobName := tmpName + "-image.vhd"
-	localDiskName := tmpName + "-osdisk.vhd"
-	err = s.BlobServiceClient.CopyBlob(s.storageContainerName, localBlobName, vhdURL)
+	// name everything before anything changes, so a bad template fails early
+	nameData := s.namer.NameData(*instance.Name, vhdURL)
+	tmpName, err := s.newName(nameData, iaas.SuffixVM, ValidateVMName)
 	if err != nil {
-		return errwrap.Wrap(err, "error copying source blob to local blob")
+		return err
+	}
+	localDiskName, err := s.newName(nameData, iaas.SuffixOSDisk, ValidateBlobName)
+	if err != nil {
+		return err
+	}
+	localDiskName += ".vhd"
+	nicName, err := s.newName(nameData, iaas.SuffixNIC, ValidateNICName)
+	if err != nil {
+		return err
+	}
+
+	oldNICID, err := primaryNICID(*instance)
+	if err != nil {
+		return errwrap.Wrapf(err, "could not find the network interface of %s", *instance.Name)
+	}
+	oldNIC, err := s.inspectNIC(oldNICID)
+	if err != nil {
+		return err
+	}
+	if oldNIC.Location == "" && instance.Location != nil {
+		oldNIC.Location = *instance.Location
+	}
+
+	// in migrate mode the new vm is created with the old vm's data disks
+	// attached at the same luns
+	var dataDisks []compute.DataDisk
+	if s.migrateDataDisk {
+		dataDisks = DataDisks(*instance)
+		if len(dataDisks) == 0 {
+			return fmt.Errorf("vm %s has no data disk to migrate", *instance.Name)
+		}
+	}
+
+	hookData := iaas.HookData{
+		Identifier: identifier,
+		OldVM:      vmOf(*instance),
+		Image:      RedactSAS(vhdURL),
+	}
+	if oldNIC.PublicIPID != "" {
+		hookData.PublicIP, err = s.publicIPAddress(oldNIC.PublicIPID)
+		if err != nil {
+			s.getLogger().Warn("could not read the public ip of the old vm for the hooks", iaas.Fields{
+				"public_ip": oldNIC.PublicIPID,
+				"error":     err.Error(),
+			})
+		}
+	}
+	err = s.hooks.Run(iaas.PhasePreStop, hookData)
+	if err != nil {
+		return err
+	}
+
+	// every failure from here on rolls back to the old vm, until it is deleted
+	iaas.ReportStep(s.progress, iaas.StepStopping, *instance.Name)
+	instance, err = s.deallocate(identifier)
+	if err != nil {
+		return s.rollback(hookData, nil, errwrap.Wrap(err, "error shutting down VM"))
+	}
+	iaas.ReportStep(s.progress, iaas.StepStopped, *instance.Name)
+	_ = s.hooks.Run(iaas.PhasePostStop, hookData)
+
+	if len(dataDisks) > 0 {
+		iaas.ReportStep(s.progress, iaas.StepDetachingDisk, *instance.Name)
+		err = s.setDataDisks(*instance.Name, []compute.DataDisk{})
+		if err != nil {
+			return s.rollback(hookData, dataDisks, errwrap.Wrap(err, "failed detaching data disks"))
+		}
+	}
+
+	iaas.ReportStep(s.progress, iaas.StepImportingImage, RedactSAS(vhdURL))
+	localBlobName, err := s.blobCopy().CopyImage(s.storageContainerName, vhdURL)
+	if err != nil {
+		return s.rollback(hookData, dataDisks, errwrap.Wrap(err, "error copying source blob to local blob"))
+	}
+	iaas.ReportStep(s.progress, iaas.StepImportedImage, localBlobName)
+
+	// this is the last point a hook can abort before anything new exists
+	err = s.hooks.Run(iaas.PhasePreCreate, hookData)
+	if err != nil {
+		return s.rollback(hookData, dataDisks, err)
+	}
+
+	// the old nic holds on to its addresses until it is deleted, so the new
+	// nic starts out with a dynamic private ip and no public ip
+	newNICID, err := s.createNIC(nicName, nicConfig{
+		Location:        oldNIC.Location,
+		SubnetID:        oldNIC.SubnetID,
+		SecurityGroupID: oldNIC.SecurityGroupID,
+	})
+	if err != nil {
+		return s.rollback(hookData, dataDisks, err)
 	}
 
 	localImageURL := generateLocalImageURL(s.storageAccountName, s.storageBaseURL, s.storageContainerName, localBlobName)
 	localDiskURL := generateLocalImageURL(s.storageAccountName, s.storageBaseURL, s.storageContainerName, localDiskName)
-	newInstance, err := s.generateInstanceCopy(*instance.Name, tmpName, localImageURL, localDiskURL, int32(diskSizeGB))
+	newInstance, err := s.generateInstanceCopy(*instance.Name, tmpName, newNICID, localImageURL, localDiskURL, int32(diskSizeGB))
 	if err != nil {
-		return errwrap.Wrap(err, "failed to generate a new instance object")
+		_ = s.deleteNIC(newNICID)
+		return s.rollback(hookData, dataDisks, errwrap.Wrap(err, "failed to generate a new instance object"))
+	}
+	if len(dataDisks) > 0 {
+		iaas.ReportStep(s.progress, iaas.StepAttachingDisk, *newInstance.Name)
+		*newInstance = withDataDisks(*newInstance, attachedDataDisks(dataDisks))
 	}
 
-	err = s.Delete(identifier)
+	// CreateOrUpdate only returns once the vm is running
+	iaas.ReportStep(s.progress, iaas.StepCreating, *newInstance.Name)
+	cancel, stop := s.getWaiter().CancelAfter(s.getWaiter().Timeouts.Create)
+	defer stop()
+	_, err = s.VirtualMachinesClient.CreateOrUpdate(s.resourceGroupName, *newInstance.Name, *newInstance, cancel)
 	if err != nil {
-		return errwrap.Wrap(err, "failed removing original VM")
+		s.removeNewVM(*newInstance.Name, newNICID)
+		return s.rollback(hookData, dataDisks, errwrap.Wrapf(err, "failed creating %s", *newInstance.Name))
 	}
+	hookData.NewVM = iaas.VM{Name: *newInstance.Name, Image: localImageURL}
+	_ = s.hooks.Run(iaas.PhasePostCreate, hookData)
 
-	_, err = s.VirtualMachinesClient.CreateOrUpdate(s.resourceGroupName, *newInstance.Name, *newInstance, nil)
-	return err
+	// the old vm goes by its own name, as the identifier may match the new vm
+	// as well by now
+	iaas.ReportStep(s.progress, iaas.StepDeletingOldVM, *instance.Name)
+	err = s.deleteVM(*instance.Name)
+	if err != nil {
+		return errwrap.Wrapf(err, "failed removing original VM, %s is running without its addresses", *newInstance.Name)
+	}
+
+	err = s.moveAddresses(oldNIC, newNICID)
+	if err != nil {
+		return err
+	}
+	iaas.ReportStep(s.progress, iaas.StepIPAssociated, *newInstance.Name)
+	_ = s.hooks.Run(iaas.PhasePostReady, hookData)
+	iaas.ReportStep(s.progress, iaas.StepDone, *newInstance.Name)
+	return nil
+}
+
+// Create copies the vhd into the storage account and boots a new vm from it
+// as the spec describes. The vm is attached to the spec's existing nic, or to
+// a new nic in the spec's subnet, its admin user is ubuntu, and its key name
+// is that user's ssh public key.
+func (s *Client) Create(vhdURL string, spec iaas.VMSpec) (iaas.VM, error) {
+	if spec.Network.Interface == "" && spec.Network.Subnet == "" {
+		return iaas.VM{}, errors.New("an azure spec needs the id of the nic to attach as network.interface, or of the subnet to create one in as network.subnet")
+	}
+	if spec.Zone == "" {
+		return iaas.VM{}, errors.New("an azure spec needs the location of the vm as zone")
+	}
+	err := ValidateVMName(spec.Name)
+	if err != nil {
+		return iaas.VM{}, err
+	}
+
+	nameData := s.namer.NameData(spec.Name, vhdURL)
+	localDiskName, err := s.newName(nameData, iaas.SuffixOSDisk, ValidateBlobName)
+	if err != nil {
+		return iaas.VM{}, err
+	}
+	localDiskURL := generateLocalImageURL(s.storageAccountName, s.storageBaseURL, s.storageContainerName, localDiskName+".vhd")
+	// a nic created for the vm is named after it, as the vm itself is
+	var nicName string
+	if spec.Network.Interface == "" {
+		nicName = spec.Name + iaas.SuffixNIC
+		err = ValidateNICName(nicName)
+		if err != nil {
+			return iaas.VM{}, err
+		}
+	}
+
+	// data disks without a source get a new, empty vhd next to the os disk
+	var dataDisks []compute.DataDisk
+	for i, disk := range spec.Disks {
+		if disk.Boot {
+			continue
+		}
+		lun := int32(i)
+		_, _ = fmt.Sscanf(disk.Name, "lun-%d", &lun)
+		dataDisk := compute.DataDisk{
+			Lun:          &lun,
+			Name:         to.StringPtr(fmt.Sprintf("%s-lun-%d", spec.Name, lun)),
+			CreateOption: compute.Attach,
+			Vhd:          &compute.VirtualHardDisk{URI: to.StringPtr(disk.Source)},
+		}
+		if disk.Source == "" {
+			dataDisk.CreateOption = compute.Empty
+			dataDisk.DiskSizeGB = to.Int32Ptr(int32(disk.SizeGB))
+			dataDisk.Vhd.URI = to.StringPtr(generateLocalImageURL(s.storageAccountName, s.storageBaseURL, s.storageContainerName, fmt.Sprintf("%s-lun-%d.vhd", localDiskName, lun)))
+		}
+		dataDisks = append(dataDisks, dataDisk)
+	}
+
+	iaas.ReportStep(s.progress, iaas.StepImportingImage, RedactSAS(vhdURL))
+	localBlobName, err := s.blobCopy().CopyImage(s.storageContainerName, vhdURL)
+	if err != nil {
+		return iaas.VM{}, errwrap.Wrap(err, "error copying source blob to local blob")
+	}
+	iaas.ReportStep(s.progress, iaas.StepImportedImage, localBlobName)
+	localImageURL := generateLocalImageURL(s.storageAccountName, s.storageBaseURL, s.storageContainerName, localBlobName)
+
+	createdNIC := spec.Network.Interface == ""
+	if createdNIC {
+		spec.Network.Interface, err = s.createSpecNIC(nicName, spec)
+		if err != nil {
+			return iaas.VM{}, err
+		}
+	}
+	newInstance := s.generateInstanceFromSpec(spec, localImageURL, localDiskURL, dataDisks)
+
+	// CreateOrUpdate only returns once the vm is running
+	iaas.ReportStep(s.progress, iaas.StepCreating, spec.Name)
+	cancel, stop := s.getWaiter().CancelAfter(s.getWaiter().Timeouts.Create)
+	defer stop()
+	_, err = s.VirtualMachinesClient.CreateOrUpdate(s.resourceGroupName, spec.Name, newInstance, cancel)
+	if err != nil && createdNIC {
+		s.removeNewVM(spec.Name, spec.Network.Interface)
+	}
+	if err != nil {
+		return iaas.VM{}, err
+	}
+	iaas.ReportStep(s.progress, iaas.StepDone, spec.Name)
+	return iaas.VM{Name: spec.Name, Image: localImageURL}, nil
 }
 
 func (s *Client) GetDisk(identifier string) (iaas.Disk, error) {
@@ -123,12 +443,189 @@ func (s *Client) GetDisk(identifier string) (iaas.Disk, error) {
 	return iaas.Disk{SizeGB: int64(*diskSize)}, nil
 }
 
+func (s *Client) GetVM(identifier string) (iaas.VM, error) {
+	instance, err := s.findMatchingVM(identifier)
+	if err != nil {
+		return iaas.VM{}, errwrap.Wrap(err, "error finding VM")
+	}
+
+	return vmOf(*instance), nil
+}
+
+// vmOf is the id, name and os image of an instance, as far as it has them
+func vmOf(instance compute.VirtualMachine) iaas.VM {
+	var vm iaas.VM
+	if instance.ID != nil {
+		vm.ID = *instance.ID
+	}
+	if instance.Name != nil {
+		vm.Name = *instance.Name
+	}
+	if instance.VirtualMachineProperties != nil &&
+		instance.StorageProfile != nil &&
+		instance.StorageProfile.OsDisk != nil &&
+		instance.StorageProfile.OsDisk.Image != nil &&
+		instance.StorageProfile.OsDisk.Image.URI != nil {
+		vm.Image = *instance.StorageProfile.OsDisk.Image.URI
+	}
+	return vm
+}
+
+// DescribeVM describes the vm as a spec, with the vm as its raw description.
+// The spec names the vm's nic along with the subnet, addresses and network
+// security group the nic holds.
+func (s *Client) DescribeVM(identifier string) (iaas.VMSpec, error) {
+	instance, err := s.findMatchingVM(identifier)
+	if err != nil {
+		return iaas.VMSpec{}, errwrap.Wrap(err, "error finding VM")
+	}
+
+	vm := vmOf(*instance)
+	spec := iaas.VMSpec{
+		IaaS:  "azure",
+		Name:  vm.Name,
+		ID:    vm.ID,
+		Image: vm.Image,
+		Raw:   instance,
+	}
+	if instance.Location != nil {
+		spec.Zone = *instance.Location
+	}
+	if instance.Tags != nil {
+		spec.Tags = map[string]string{}
+		for key, value := range *instance.Tags {
+			if value != nil {
+				spec.Tags[key] = *value
+			}
+		}
+	}
+
+	properties := instance.VirtualMachineProperties
+	if properties == nil {
+		return spec, nil
+	}
+	if properties.HardwareProfile != nil {
+		spec.InstanceType = string(properties.HardwareProfile.VMSize)
+	}
+	if nicID, err := primaryNICID(*instance); err == nil {
+		spec.Network.Interface = nicID
+		err = s.describeNIC(&spec)
+		if err != nil {
+			return iaas.VMSpec{}, err
+		}
+	}
+	if properties.StorageProfile != nil && properties.StorageProfile.OsDisk != nil {
+		osDisk := properties.StorageProfile.OsDisk
+		diskSpec := iaas.DiskSpec{Name: "os", Boot: true}
+		if osDisk.DiskSizeGB != nil {
+			diskSpec.SizeGB = int64(*osDisk.DiskSizeGB)
+		}
+		if osDisk.Vhd != nil && osDisk.Vhd.URI != nil {
+			diskSpec.Source = *osDisk.Vhd.URI
+		}
+		spec.Disks = append(spec.Disks, diskSpec)
+	}
+	for _, dataDisk := range DataDisks(*instance) {
+		diskSpec := iaas.DiskSpec{}
+		if dataDisk.Lun != nil {
+			diskSpec.Name = fmt.Sprintf("lun-%d", *dataDisk.Lun)
+		}
+		if dataDisk.DiskSizeGB != nil {
+			diskSpec.SizeGB = int64(*dataDisk.DiskSizeGB)
+		}
+		if dataDisk.Vhd != nil && dataDisk.Vhd.URI != nil {
+			diskSpec.Source = *dataDisk.Vhd.URI
+		}
+		spec.Disks = append(spec.Disks, diskSpec)
+	}
+	spec.Normalize()
+	return spec, nil
+}
+
 /* End Cliaas Client Interface */
 
 func (s *Client) SetVMAdminPassword(password string) {
 	s.vmAdminPassword = password
 }
 
+// SetWaiter sets the waiter whose timeouts bound the long running vm
+// operations
+func (s *Client) SetWaiter(waiter iaas.Waiter) {
+	s.waiter = waiter
+}
+
+// SetProgressReporter sets where the steps of a replace are reported
+func (s *Client) SetProgressReporter(progress iaas.ProgressReporter) {
+	s.progress = progress
+}
+
+// SetMigrateDataDisk sets whether a replace moves the old vm's data disks to
+// the new vm
+func (s *Client) SetMigrateDataDisk(migrate bool) {
+	s.migrateDataDisk = migrate
+}
+
+// SetNamer sets how the vm and disks a replace creates are named
+func (s *Client) SetNamer(namer iaas.Namer) {
+	s.namer = namer
+}
+
+func (s *Client) newName(data iaas.NameData, suffix string, validate func(string) error) (string, error) {
+	name, err := s.namer.Name(data, suffix)
+	if err != nil {
+		return "", err
+	}
+	return name, validate(name)
+}
+
+// SetHookRunner sets the hooks a replace runs at its phases
+func (s *Client) SetHookRunner(hooks *iaas.HookRunner) {
+	s.hooks = hooks
+}
+
+// SetLogger sets where every api call is logged
+func (s *Client) SetLogger(logger *iaas.Logger) {
+	s.logger = logger
+}
+
+// adminPassword is the admin password of new vms. Without one set, a
+// password is generated and kept out of the logs.
+func (s *Client) adminPassword() string {
+	if s.vmAdminPassword == "" {
+		s.vmAdminPassword = getGUID()
+		s.getLogger().Hide(s.vmAdminPassword)
+	}
+	return s.vmAdminPassword
+}
+
+func (s *Client) getLogger() *iaas.Logger {
+	return s.logger
+}
+
+func (s *Client) retryingCaller(recorder *retryAfterRecorder) retryingCaller {
+	return retryingCaller{
+		waiter:   s.getWaiter,
+		logger:   s.getLogger,
+		recorder: recorder,
+	}
+}
+
+func (s *Client) blobCopy() BlobCopy {
+	return BlobCopy{
+		Storage:  s.BlobServiceClient,
+		Waiter:   s.getWaiter(),
+		Progress: s.progress,
+		Logger:   s.logger,
+	}
+}
+
+func (s *Client) getWaiter() iaas.Waiter {
+	if s.waiter.Clock == nil {
+		s.waiter = iaas.NewWaiter(clock.NewClock(), iaas.Timeouts{})
+	}
+	return s.waiter
+}
+
 func (s *Client) SetStorageContainerName(name string) {
 	s.storageContainerName = name
 }
@@ -142,7 +639,7 @@ func (s *Client) SetStorageBaseURL(baseURL string) {
 }
 
 func (s *Client) SetBlobServiceClient(storageAccountName string, storageAccountKey string, storageURL string) error {
-	blobClient, err := newBlobClient(storageAccountName, storageAccountKey, storageURL)
+	blobClient, err := NewBlobStorageClient(storageAccountName, storageAccountKey, storageURL, nil)
 	if err != nil {
 		return errwrap.Wrap(err, "failed creating a blob client")
 	}
@@ -150,31 +647,236 @@ func (s *Client) SetBlobServiceClient(storageAccountName string, storageAccountK
 	return nil
 }
 
-func (s *Client) generateInstanceCopy(sourceInstanceName string, newInstanceName string, localImageURL string, localOSDiskURL string, diskSizeGB int32) (*compute.VirtualMachine, error) {
-	instance, err := s.VirtualMachinesClient.Get(s.resourceGroupName, sourceInstanceName, compute.InstanceView)
+// generateInstanceCopy is a new vm like the source vm, booting from a copy of
+// the image at localImageURL and attached to the nic with the id. Only the
+// settings a new vm can take are carried over, so the copy holds no
+// reference to the source vm's nic or disks.
+func (s *Client) generateInstanceCopy(sourceInstanceName string, newInstanceName string, nicID string, localImageURL string, localOSDiskURL string, diskSizeGB int32) (*compute.VirtualMachine, error) {
+	source, err := s.VirtualMachinesClient.Get(s.resourceGroupName, sourceInstanceName, compute.InstanceView)
 	if err != nil {
 		return nil, errwrap.Wrap(err, "unable to get virtual machine instance from azure api")
 	}
+	properties := source.VirtualMachineProperties
+	if properties == nil || properties.StorageProfile == nil || properties.StorageProfile.OsDisk == nil {
+		return nil, fmt.Errorf("vm %s has no os disk", sourceInstanceName)
+	}
 
-	instance.Name = &newInstanceName
-	instance.VirtualMachineProperties.StorageProfile.OsDisk.Image.URI = &localImageURL
-	instance.VirtualMachineProperties.StorageProfile.OsDisk.DiskSizeGB = &diskSizeGB
-	instance.VirtualMachineProperties.StorageProfile.OsDisk.Vhd.URI = &localOSDiskURL
-	instance.VirtualMachineProperties.VMID = nil
-	instance.Resources = nil
+	osDisk := *properties.StorageProfile.OsDisk
+	osDisk.Image = &compute.VirtualHardDisk{URI: to.StringPtr(localImageURL)}
+	osDisk.Vhd = &compute.VirtualHardDisk{URI: to.StringPtr(localOSDiskURL)}
+	osDisk.DiskSizeGB = to.Int32Ptr(diskSizeGB)
+	osDisk.CreateOption = compute.FromImage
+
+	var osProfile *compute.OSProfile
+	if properties.OsProfile != nil {
+		profile := *properties.OsProfile
+		profile.AdminPassword = to.StringPtr(s.adminPassword())
+		osProfile = &profile
+	}
 
-	if s.vmAdminPassword == "" {
-		s.vmAdminPassword = getGUID()
+	instance := compute.VirtualMachine{
+		Name:     to.StringPtr(newInstanceName),
+		Location: source.Location,
+		Tags:     source.Tags,
+		Plan:     source.Plan,
+		VirtualMachineProperties: &compute.VirtualMachineProperties{
+			HardwareProfile: properties.HardwareProfile,
+			OsProfile:       osProfile,
+			StorageProfile: &compute.StorageProfile{
+				OsDisk:    &osDisk,
+				DataDisks: &[]compute.DataDisk{},
+			},
+			NetworkProfile: &compute.NetworkProfile{
+				NetworkInterfaces: &[]compute.NetworkInterfaceReference{
+					{
+						ID: to.StringPtr(nicID),
+						NetworkInterfaceReferenceProperties: &compute.NetworkInterfaceReferenceProperties{
+							Primary: to.BoolPtr(true),
+						},
+					},
+				},
+			},
+			AvailabilitySet:    properties.AvailabilitySet,
+			DiagnosticsProfile: properties.DiagnosticsProfile,
+		},
 	}
-	instance.VirtualMachineProperties.OsProfile.AdminPassword = &s.vmAdminPassword
 	return &instance, nil
 }
 
+// generateInstanceFromSpec is the vm a spec describes, booting from a copy of
+// the image at localImageURL
+func (s *Client) generateInstanceFromSpec(spec iaas.VMSpec, localImageURL string, localOSDiskURL string, dataDisks []compute.DataDisk) compute.VirtualMachine {
+	osDisk := &compute.OSDisk{
+		Name:         to.StringPtr(spec.Name + "-osdisk"),
+		OsType:       compute.Linux,
+		Caching:      compute.ReadWrite,
+		CreateOption: compute.FromImage,
+		Image:        &compute.VirtualHardDisk{URI: to.StringPtr(localImageURL)},
+		Vhd:          &compute.VirtualHardDisk{URI: to.StringPtr(localOSDiskURL)},
+	}
+	for _, disk := range spec.Disks {
+		if disk.Boot && disk.SizeGB != 0 {
+			osDisk.DiskSizeGB = to.Int32Ptr(int32(disk.SizeGB))
+		}
+	}
+
+	osProfile := &compute.OSProfile{
+		ComputerName:  to.StringPtr(spec.Name),
+		AdminUsername: to.StringPtr("ubuntu"),
+		AdminPassword: to.StringPtr(s.adminPassword()),
+	}
+	if spec.KeyName != "" {
+		osProfile.LinuxConfiguration = &compute.LinuxConfiguration{
+			SSH: &compute.SSHConfiguration{
+				PublicKeys: &[]compute.SSHPublicKey{
+					{
+						Path:    to.StringPtr("/home/ubuntu/.ssh/authorized_keys"),
+						KeyData: to.StringPtr(spec.KeyName),
+					},
+				},
+			},
+		}
+	}
+
+	instance := compute.VirtualMachine{
+		Name:     to.StringPtr(spec.Name),
+		Location: to.StringPtr(spec.Zone),
+		VirtualMachineProperties: &compute.VirtualMachineProperties{
+			HardwareProfile: &compute.HardwareProfile{
+				VMSize: compute.VirtualMachineSizeTypes(spec.InstanceType),
+			},
+			OsProfile: osProfile,
+			StorageProfile: &compute.StorageProfile{
+				OsDisk:    osDisk,
+				DataDisks: &dataDisks,
+			},
+			NetworkProfile: &compute.NetworkProfile{
+				NetworkInterfaces: &[]compute.NetworkInterfaceReference{
+					{ID: to.StringPtr(spec.Network.Interface)},
+				},
+			},
+		},
+	}
+	if len(spec.Tags) > 0 {
+		tags := map[string]*string{}
+		for key, value := range spec.Tags {
+			tags[key] = to.StringPtr(value)
+		}
+		instance.Tags = &tags
+	}
+	return instance
+}
+
+// setDataDisks updates the data disks attached to a stopped vm
+func (s *Client) setDataDisks(vmName string, dataDisks []compute.DataDisk) error {
+	instance, err := s.VirtualMachinesClient.Get(s.resourceGroupName, vmName, "")
+	if err != nil {
+		return errwrap.Wrap(err, "unable to get virtual machine instance from azure api")
+	}
+	instance.Resources = nil
+
+	cancel, stop := s.getWaiter().CancelAfter(s.getWaiter().Timeouts.DiskAttachment)
+	defer stop()
+	_, err = s.VirtualMachinesClient.CreateOrUpdate(s.resourceGroupName, vmName, withDataDisks(instance, dataDisks), cancel)
+	return err
+}
+
+// rollback puts the old vm back the way it was before Replace deallocated it:
+// it gets back its data disks and is started again. Only then does the
+// on-rollback hook run. It returns cause, the error that failed the replace.
+func (s *Client) rollback(hookData iaas.HookData, dataDisks []compute.DataDisk, cause error) error {
+	s.reattachDataDisks(hookData.OldVM.Name, dataDisks)
+
+	iaas.ReportStep(s.progress, iaas.StepStarting, hookData.OldVM.Name)
+	cancel, stop := s.getWaiter().CancelAfter(s.getWaiter().Timeouts.Start)
+	defer stop()
+	_, err := s.VirtualMachinesClient.Start(s.resourceGroupName, hookData.OldVM.Name, cancel)
+	if err != nil {
+		s.getLogger().Error("could not start the old vm again", iaas.Fields{
+			"vm":    hookData.OldVM.Name,
+			"error": err.Error(),
+		})
+	}
+
+	rollbackData := hookData
+	rollbackData.Error = cause.Error()
+	_ = s.hooks.Run(iaas.PhaseOnRollback, rollbackData)
+	return cause
+}
+
+// reattachDataDisks moves the data disks back to the old vm after a failed
+// replace
+func (s *Client) reattachDataDisks(vmName string, dataDisks []compute.DataDisk) {
+	if len(dataDisks) > 0 {
+		_ = s.setDataDisks(vmName, attachedDataDisks(dataDisks))
+	}
+}
+
+// DataDisks are the disks of the vm other than its os disk
+func DataDisks(instance compute.VirtualMachine) []compute.DataDisk {
+	if instance.VirtualMachineProperties == nil ||
+		instance.VirtualMachineProperties.StorageProfile == nil ||
+		instance.VirtualMachineProperties.StorageProfile.DataDisks == nil {
+		return nil
+	}
+	return *instance.VirtualMachineProperties.StorageProfile.DataDisks
+}
+
+// attachedDataDisks attaches the existing disks, at their luns, instead of
+// creating them again
+func attachedDataDisks(dataDisks []compute.DataDisk) []compute.DataDisk {
+	attached := make([]compute.DataDisk, len(dataDisks))
+	for i, disk := range dataDisks {
+		disk.CreateOption = compute.Attach
+		disk.Image = nil
+		attached[i] = disk
+	}
+	return attached
+}
+
+// withDataDisks returns a copy of instance with dataDisks, leaving the
+// properties instance points at untouched
+func withDataDisks(instance compute.VirtualMachine, dataDisks []compute.DataDisk) compute.VirtualMachine {
+	properties := *instance.VirtualMachineProperties
+	storageProfile := *properties.StorageProfile
+	storageProfile.DataDisks = &dataDisks
+	properties.StorageProfile = &storageProfile
+	instance.VirtualMachineProperties = &properties
+	return instance
+}
+
 func (s *Client) deallocate(identifier string) (*compute.VirtualMachine, error) {
-	return s.executeFunctionOnMatchingVM(identifier, s.VirtualMachinesClient.Deallocate)
+	return s.executeFunctionOnMatchingVM(identifier, s.getWaiter().Timeouts.Stop, s.VirtualMachinesClient.Deallocate)
+}
+
+// deleteVM deletes the vm with exactly the name
+func (s *Client) deleteVM(vmName string) error {
+	cancel, stop := s.getWaiter().CancelAfter(s.getWaiter().Timeouts.Stop)
+	defer stop()
+	_, err := s.VirtualMachinesClient.Delete(s.resourceGroupName, vmName, cancel)
+	return err
+}
+
+// removeNewVM cleans up after a failed create: whatever azure has made of
+// the new vm, and then the nic it was attached to
+func (s *Client) removeNewVM(vmName string, nicID string) {
+	_ = s.deleteVM(vmName)
+	_ = s.deleteNIC(nicID)
+}
+
+func (s *Client) executeFunctionOnMatchingVM(identifier string, timeout time.Duration, f func(resourceGroupName string, vmName string, cancel <-chan struct{}) (result autorest.Response, err error)) (*compute.VirtualMachine, error) {
+	instance, err := s.findMatchingVM(identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	cancel, stop := s.getWaiter().CancelAfter(timeout)
+	defer stop()
+	_, err = f(s.resourceGroupName, *instance.Name, cancel)
+	return instance, err
 }
 
-func (s *Client) executeFunctionOnMatchingVM(identifier string, f func(resourceGroupName string, vmName string, cancel <-chan struct{}) (result autorest.Response, err error)) (*compute.VirtualMachine, error) {
+func (s *Client) findMatchingVM(identifier string) (*compute.VirtualMachine, error) {
 	matchingInstances, err := s.getFilteredList(identifier)
 	if err != nil {
 		return nil, errwrap.Wrap(err, "error when attempting to get filtered vm list")
@@ -182,10 +884,9 @@ func (s *Client) executeFunctionOnMatchingVM(identifier string, f func(resourceG
 
 	switch len(matchingInstances) {
 	case 0:
-		return nil, NoMatchesErr
+		return nil, iaas.NewNotFoundError(NoMatchesErr)
 	case 1:
-		_, err = f(s.resourceGroupName, *matchingInstances[0].Name, nil)
-		return &matchingInstances[0], err
+		return &matchingInstances[0], nil
 	default:
 		return nil, MultipleMatchesErr
 	}
@@ -210,13 +911,71 @@ func (s *Client) getFilteredList(identifier string) ([]compute.VirtualMachine, e
 	return matchingInstances, nil
 }
 
-func newBlobClient(accountName string, accountKey string, baseURL string) (*storage.BlobStorageClient, error) {
+// NewBlobStorageClient builds a blob client for the storage account. The
+// requests go through httpClient when it is given.
+func NewBlobStorageClient(accountName string, accountKey string, baseURL string, httpClient *http.Client) (BlobCopier, error) {
 	client, err := storage.NewClient(accountName, accountKey, baseURL, storage.DefaultAPIVersion, true)
 	if err != nil {
 		return nil, err
 	}
+	if httpClient != nil {
+		client.HTTPClient = httpClient
+	}
 	blobClient := client.GetBlobService()
-	return &blobClient, nil
+	return blobStorageClient{&blobClient}, nil
+}
+
+// virtualMachinesClient is the virtual machines client of the sdk, with
+// calls that send a deallocate or start without polling until it is done
+type virtualMachinesClient struct {
+	*compute.VirtualMachinesClient
+}
+
+func (c virtualMachinesClient) BeginDeallocate(resourceGroupName string, vmName string, cancel <-chan struct{}) (autorest.Response, error) {
+	req, err := c.DeallocatePreparer(resourceGroupName, vmName, cancel)
+	if err != nil {
+		return autorest.Response{}, err
+	}
+	resp, err := autorest.SendWithSender(c, req)
+	if err != nil {
+		return autorest.Response{Response: resp}, err
+	}
+	return c.DeallocateResponder(resp)
+}
+
+func (c virtualMachinesClient) BeginStart(resourceGroupName string, vmName string, cancel <-chan struct{}) (autorest.Response, error) {
+	req, err := c.StartPreparer(resourceGroupName, vmName, cancel)
+	if err != nil {
+		return autorest.Response{}, err
+	}
+	resp, err := autorest.SendWithSender(c, req)
+	if err != nil {
+		return autorest.Response{Response: resp}, err
+	}
+	return c.StartResponder(resp)
+}
+
+// blobStorageClient is the blob client of the storage api, which only tells
+// whether a container exists through a reference to it
+type blobStorageClient struct {
+	*storage.BlobStorageClient
+}
+
+func (c blobStorageClient) ContainerExists(name string) (bool, error) {
+	container := c.GetContainerReference(name)
+	return container.Exists()
+}
+
+func newServicePrincipalToken(environment Environment, tenantID string, clientID string, clientSecret string) (*autorestazure.ServicePrincipalToken, error) {
+	oauthEnvironment := autorestazure.Environment{
+		ActiveDirectoryEndpoint: environment.ActiveDirectoryEndpoint,
+	}
+	oauthConfig, err := oauthEnvironment.OAuthConfigForTenant(tenantID)
+	if err != nil {
+		return nil, errwrap.Wrap(err, "failed to build oauth config for tenant")
+	}
+
+	return autorestazure.NewServicePrincipalToken(*oauthConfig, clientID, clientSecret, environment.TokenAudience)
 }
 
 func generateLocalImageURL(accountName string, baseURL string, containerName string, localBlobName string) string {
@@ -242,18 +1001,6 @@ func checkEnvVar(envVars map[string]string) error {
 	return nil
 }
 
-func generateInstanceName(currentName string) string {
-	tstamp := time.Now().Format("20060112123456")
-	splits := strings.Split(currentName, "_")
-	if len(splits) == 1 {
-		return currentName + "_" + tstamp
-	}
-
-	truncatedSplits := splits[:len(splits)-1]
-	truncatedSplits = append(truncatedSplits, tstamp)
-	return strings.Join(truncatedSplits, "_")
-}
-
 func getMatchingInstances(vmList []compute.VirtualMachine, identifierRegex *regexp.Regexp, matchingInstances []compute.VirtualMachine) []compute.VirtualMachine {
 
 	for _, instance := range vmList {
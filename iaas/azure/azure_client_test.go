@@ -3,12 +3,19 @@ package azure_test
 import (
 	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 
 	"github.com/Azure/azure-sdk-for-go/arm/compute"
+	"github.com/Azure/azure-sdk-for-go/arm/network"
+	"github.com/Azure/azure-sdk-for-go/arm/resources/resources"
+	"github.com/Azure/azure-sdk-for-go/storage"
 	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/to"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	"github.com/pivotal-cf/cliaas/iaas"
 	"github.com/pivotal-cf/cliaas/iaas/azure"
 	"github.com/pivotal-cf/cliaas/iaas/azure/azurefakes"
 	errwrap "github.com/pkg/errors"
@@ -23,7 +30,12 @@ var _ = Describe("Azure", func() {
 			var identifier string
 			var fakeVirtualMachinesClient *azurefakes.FakeComputeVirtualMachinesClient
 			var fakeBlobServiceClient *azurefakes.FakeBlobCopier
-			var controlNewImageURL = "some-control-new-image-url"
+			var fakeNetworkInterfacesClient *azurefakes.FakeNetworkInterfacesClient
+			var controlNewImageURL string
+			var sourceServer *httptest.Server
+			var namer iaas.Namer
+			var migrateDataDisk bool
+			var hooks *iaas.HookRunner
 			var controlRegex = "ops*"
 			var controlValue []compute.VirtualMachine
 			var controlID = "some-id"
@@ -31,7 +43,7 @@ var _ = Describe("Azure", func() {
 			var controlOldName = "ops-manager"
 			var controlContainerName = "mycontainer"
 			var controlStorageAccountName = "myaccount"
-			var controlNewImageLocalContainerURL = fmt.Sprintf("https://%s.blob.core.windows.net/%s/%s", controlStorageAccountName, controlContainerName, controlOldName+"_....*")
+			var controlNewImageLocalContainerURL = fmt.Sprintf("https://%s.blob.core.windows.net/%s/%s", controlStorageAccountName, controlContainerName, "cliaas-[0-9a-f]{24}-image.vhd")
 
 			JustBeforeEach(func() {
 				fakeVirtualMachinesClient.ListReturns(compute.VirtualMachineListResult{Value: &controlValue}, nil)
@@ -39,22 +51,48 @@ var _ = Describe("Azure", func() {
 				azureClient = new(azure.Client)
 				identifier = controlRegex
 				azureClient.VirtualMachinesClient = fakeVirtualMachinesClient
+				azureClient.NetworkInterfacesClient = fakeNetworkInterfacesClient
 				azureClient.BlobServiceClient = fakeBlobServiceClient
 				azureClient.SetStorageAccountName(controlStorageAccountName)
 				azureClient.SetStorageContainerName(controlContainerName)
 				azureClient.SetStorageBaseURL(azure.DefaultBaseURL)
+				azureClient.SetNamer(namer)
+				azureClient.SetMigrateDataDisk(migrateDataDisk)
+				azureClient.SetHookRunner(hooks)
 				err = azureClient.Replace(identifier, controlNewImageURL, int64(controlDiskSize))
 			})
 
 			BeforeEach(func() {
 				controlValue = make([]compute.VirtualMachine, 0)
+				fakeNetworkInterfacesClient = new(azurefakes.FakeNetworkInterfacesClient)
+				fakeNetworkInterfacesClient.GetStub = func(resourceGroupName string, name string, expand string) (network.Interface, error) {
+					return newNetworkInterface(name), nil
+				}
+				namer = iaas.Namer{}
+				migrateDataDisk = false
+				hooks = nil
+				sourceServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Content-Length", "2048")
+					w.Header().Set("Content-MD5", "c29tZS1tZDU=")
+				}))
+				controlNewImageURL = sourceServer.URL + "/opsman.vhd?sv=2016-05-31&sig=some-signature"
+			})
+
+			AfterEach(func() {
+				sourceServer.Close()
 			})
 
 			Context("when there is a single match on a identifier regex", func() {
-				controlNewNameRegex := controlOldName + "_....*"
+				controlNewNameRegex := "^" + controlOldName + `-\d{4}-\d{2}-\d{2}-\d{2}-\d{2}-\d{2}$`
 				BeforeEach(func() {
 					fakeVirtualMachinesClient = new(azurefakes.FakeComputeVirtualMachinesClient)
 					fakeBlobServiceClient = new(azurefakes.FakeBlobCopier)
+					fakeBlobServiceClient.StartBlobCopyReturns("some-copy-id", nil)
+					fakeBlobServiceClient.GetBlobPropertiesReturns(&storage.BlobProperties{
+						CopyStatus:    azure.CopySuccess,
+						ContentLength: 2048,
+						ContentMD5:    "c29tZS1tZDU=",
+					}, nil)
 					vm := newVirtualMachine(controlID, controlOldName, controlOldImageURL, controlDiskSize)
 					fakeVirtualMachinesClient.GetReturns(vm, nil)
 					controlValue = append(controlValue, vm)
@@ -71,13 +109,196 @@ var _ = Describe("Azure", func() {
 				})
 
 				It("should copy the blob from the given public vhd URL into our local account's blob service container", func() {
-					Expect(fakeBlobServiceClient.CopyBlobCallCount()).Should(Equal(1), "we should have called CopyBlob exactly once")
-					container, localImageFilename, sourceBlob := fakeBlobServiceClient.CopyBlobArgsForCall(0)
+					Expect(fakeBlobServiceClient.StartBlobCopyCallCount()).Should(Equal(1), "we should have started the copy exactly once")
+					container, localImageFilename, sourceBlob := fakeBlobServiceClient.StartBlobCopyArgsForCall(0)
 					Expect(container).Should(Equal(controlContainerName))
-					Expect(localImageFilename).Should(MatchRegexp(controlNewNameRegex))
+					Expect(localImageFilename).Should(MatchRegexp("^cliaas-[0-9a-f]{24}-image.vhd$"))
 					Expect(sourceBlob).Should(Equal(controlNewImageURL))
 				})
 
+				It("should name the os disk after the new vm", func() {
+					_, _, parameters, _ := fakeVirtualMachinesClient.CreateOrUpdateArgsForCall(0)
+					diskURL := *parameters.VirtualMachineProperties.StorageProfile.OsDisk.Vhd.URI
+					Expect(diskURL).Should(Equal(fmt.Sprintf("https://%s.blob.core.windows.net/%s/%s-osdisk.vhd", controlStorageAccountName, controlContainerName, *parameters.Name)))
+				})
+
+				Context("when the name template gives an invalid vm name", func() {
+					BeforeEach(func() {
+						namer = iaas.Namer{Template: "{{.Identifier}} {{.Timestamp}}{{.Suffix}}"}
+					})
+
+					It("should fail before deallocating anything", func() {
+						Expect(err).Should(MatchError(ContainSubstring("not a valid azure vm name")))
+						Expect(fakeVirtualMachinesClient.DeallocateCallCount()).Should(Equal(0))
+					})
+				})
+
+				Context("when the pre-create hook fails", func() {
+					BeforeEach(func() {
+						hooks = &iaas.HookRunner{
+							Hooks:  iaas.Hooks{PreCreate: "test \"$CLIAAS_OLD_VM_NAME\" != " + controlOldName},
+							Output: GinkgoWriter,
+						}
+					})
+
+					It("should abort before creating anything", func() {
+						Expect(err).Should(MatchError(ContainSubstring("pre-create hook failed")))
+						Expect(fakeVirtualMachinesClient.DeleteCallCount()).Should(Equal(0))
+						Expect(fakeVirtualMachinesClient.CreateOrUpdateCallCount()).Should(Equal(0))
+						Expect(fakeNetworkInterfacesClient.CreateOrUpdateCallCount()).Should(Equal(0))
+					})
+
+					It("should start the old vm again", func() {
+						Expect(fakeVirtualMachinesClient.StartCallCount()).Should(Equal(1))
+						_, vmName, _ := fakeVirtualMachinesClient.StartArgsForCall(0)
+						Expect(vmName).Should(Equal(controlOldName))
+					})
+				})
+
+				Context("when a hook is configured", func() {
+					BeforeEach(func() {
+						hooks = &iaas.HookRunner{
+							Hooks:  iaas.Hooks{PreStop: `case "$CLIAAS_IMAGE" in *some-signature*) exit 1;; *sig=REDACTED*) exit 0;; *) exit 1;; esac`},
+							Output: GinkgoWriter,
+						}
+					})
+
+					It("should pass it the image url without its sas signature", func() {
+						Expect(err).ShouldNot(HaveOccurred())
+					})
+				})
+
+				Context("when creating the new vm fails", func() {
+					BeforeEach(func() {
+						fakeVirtualMachinesClient.CreateOrUpdateReturns(autorest.Response{}, errors.New("quota exceeded"))
+					})
+
+					It("should remove the new vm and its nic and keep the old vm", func() {
+						Expect(err).Should(MatchError(ContainSubstring("quota exceeded")))
+						_, newName, _, _ := fakeVirtualMachinesClient.CreateOrUpdateArgsForCall(0)
+						Expect(fakeVirtualMachinesClient.DeleteCallCount()).Should(Equal(1))
+						_, vmName, _ := fakeVirtualMachinesClient.DeleteArgsForCall(0)
+						Expect(vmName).Should(Equal(newName))
+
+						Expect(fakeNetworkInterfacesClient.DeleteCallCount()).Should(Equal(1))
+						_, nicName, _ := fakeNetworkInterfacesClient.DeleteArgsForCall(0)
+						Expect(nicName).Should(Equal(newName + "-nic"))
+					})
+				})
+
+				Context("when moving the addresses to the new nic fails", func() {
+					BeforeEach(func() {
+						fakeNetworkInterfacesClient.CreateOrUpdateReturnsOnCall(1, autorest.Response{}, errors.New("conflict"))
+					})
+
+					It("should keep the addresses on a nic by the old nic's name and say which they are", func() {
+						Expect(err).Should(MatchError(ContainSubstring("conflict")))
+						Expect(err).Should(MatchError(ContainSubstring("10.0.0.4")))
+						Expect(err).Should(MatchError(ContainSubstring("some-public-ip")))
+
+						Expect(fakeNetworkInterfacesClient.CreateOrUpdateCallCount()).Should(Equal(3))
+						_, nicName, nic, _ := fakeNetworkInterfacesClient.CreateOrUpdateArgsForCall(2)
+						Expect(nicName).Should(Equal(controlOldName + "-nic"))
+						ipProperties := (*nic.IPConfigurations)[0].InterfaceIPConfigurationPropertiesFormat
+						Expect(ipProperties.PrivateIPAllocationMethod).Should(Equal(network.Static))
+						Expect(*ipProperties.PrivateIPAddress).Should(Equal("10.0.0.4"))
+						Expect(*ipProperties.PublicIPAddress.ID).Should(Equal("some-public-ip"))
+					})
+				})
+
+				Context("when the old nic's private ip is dynamic", func() {
+					BeforeEach(func() {
+						fakeNetworkInterfacesClient.GetStub = func(resourceGroupName string, name string, expand string) (network.Interface, error) {
+							nic := newNetworkInterface(name)
+							(*nic.IPConfigurations)[0].PrivateIPAllocationMethod = network.Dynamic
+							return nic, nil
+						}
+					})
+
+					It("should only move the public ip and keep the new nic's private ip dynamic", func() {
+						Expect(err).ShouldNot(HaveOccurred())
+						_, _, nic, _ := fakeNetworkInterfacesClient.CreateOrUpdateArgsForCall(1)
+						ipProperties := (*nic.IPConfigurations)[0].InterfaceIPConfigurationPropertiesFormat
+						Expect(ipProperties.PrivateIPAllocationMethod).Should(Equal(network.Dynamic))
+						Expect(*ipProperties.PublicIPAddress.ID).Should(Equal("some-public-ip"))
+					})
+				})
+
+				Context("when an earlier replace left a verified copy of the image", func() {
+					BeforeEach(func() {
+						fakeBlobServiceClient.BlobExistsReturns(true, nil)
+					})
+
+					It("should reuse it instead of copying again", func() {
+						Expect(err).ShouldNot(HaveOccurred())
+						Expect(fakeBlobServiceClient.StartBlobCopyCallCount()).Should(Equal(0))
+						Expect(fakeVirtualMachinesClient.CreateOrUpdateCallCount()).Should(Equal(1))
+					})
+				})
+
+				Context("when migrating the data disk", func() {
+					var controlDataDiskURL = "https://myaccount.blob.core.windows.net/mycontainer/opsman-data.vhd"
+
+					BeforeEach(func() {
+						migrateDataDisk = true
+						lun := int32(1)
+						vm := newVirtualMachine(controlID, controlOldName, controlOldImageURL, controlDiskSize)
+						vm.VirtualMachineProperties.StorageProfile.DataDisks = &[]compute.DataDisk{{
+							Lun:          &lun,
+							Vhd:          &compute.VirtualHardDisk{URI: &controlDataDiskURL},
+							CreateOption: compute.Empty,
+						}}
+						fakeVirtualMachinesClient.GetReturns(vm, nil)
+						controlValue = []compute.VirtualMachine{vm}
+					})
+
+					It("should detach it from the old vm and attach it to the new vm at the same lun", func() {
+						Expect(err).ShouldNot(HaveOccurred())
+						Expect(fakeVirtualMachinesClient.CreateOrUpdateCallCount()).Should(Equal(2))
+
+						_, vmName, oldVM, _ := fakeVirtualMachinesClient.CreateOrUpdateArgsForCall(0)
+						Expect(vmName).Should(Equal(controlOldName))
+						Expect(azure.DataDisks(oldVM)).Should(BeEmpty())
+
+						_, vmName, newVM, _ := fakeVirtualMachinesClient.CreateOrUpdateArgsForCall(1)
+						Expect(vmName).Should(MatchRegexp(controlNewNameRegex))
+						dataDisks := azure.DataDisks(newVM)
+						Expect(dataDisks).Should(HaveLen(1))
+						Expect(*dataDisks[0].Lun).Should(Equal(int32(1)))
+						Expect(*dataDisks[0].Vhd.URI).Should(Equal(controlDataDiskURL))
+						Expect(dataDisks[0].CreateOption).Should(Equal(compute.Attach))
+					})
+
+					Context("when the image copy fails", func() {
+						BeforeEach(func() {
+							fakeBlobServiceClient.StartBlobCopyReturns("", errors.New("copy failed"))
+						})
+
+						It("should attach the data disk to the old vm again", func() {
+							Expect(err).Should(HaveOccurred())
+							Expect(fakeVirtualMachinesClient.DeleteCallCount()).Should(Equal(0))
+							Expect(fakeVirtualMachinesClient.CreateOrUpdateCallCount()).Should(Equal(2))
+
+							_, vmName, oldVM, _ := fakeVirtualMachinesClient.CreateOrUpdateArgsForCall(1)
+							Expect(vmName).Should(Equal(controlOldName))
+							Expect(azure.DataDisks(oldVM)).Should(HaveLen(1))
+							Expect(azure.DataDisks(oldVM)[0].CreateOption).Should(Equal(compute.Attach))
+						})
+					})
+
+					Context("when the vm has no data disk", func() {
+						BeforeEach(func() {
+							vm := newVirtualMachine(controlID, controlOldName, controlOldImageURL, controlDiskSize)
+							controlValue = []compute.VirtualMachine{vm}
+						})
+
+						It("should fail before deallocating anything", func() {
+							Expect(err).Should(MatchError(ContainSubstring("no data disk to migrate")))
+							Expect(fakeVirtualMachinesClient.DeallocateCallCount()).Should(Equal(0))
+						})
+					})
+				})
+
 				It("should spin down & delete the matching vm instance", func() {
 					Expect(fakeVirtualMachinesClient.DeallocateCallCount()).Should(Equal(1), "we should call deallocate exactly once")
 					_, vmName, _ := fakeVirtualMachinesClient.DeallocateArgsForCall(0)
@@ -88,13 +309,46 @@ var _ = Describe("Azure", func() {
 
 					Expect(fakeVirtualMachinesClient.DeleteCallCount()).Should(Equal(1), "we should call delete exactly once")
 					_, vmName, _ = fakeVirtualMachinesClient.DeleteArgsForCall(0)
-					Expect(vmName).Should(MatchRegexp(controlRegex))
+					Expect(vmName).Should(Equal(controlOldName))
 				})
 
 				It("should copy the existing vms config into the new vm instance's config ", func() {
 					Expect(fakeVirtualMachinesClient.CreateOrUpdateCallCount()).Should(Equal(1), "we should call createorupdate exactly once")
 					_, _, parameters, _ := fakeVirtualMachinesClient.CreateOrUpdateArgsForCall(0)
-					Expect(*parameters.ID).Should(Equal(controlID))
+					Expect(parameters.ID).Should(BeNil())
+					Expect(*parameters.Location).Should(Equal("westus"))
+					Expect(*parameters.VirtualMachineProperties.AvailabilitySet.ID).Should(Equal("some-availability-set"))
+					Expect(*parameters.VirtualMachineProperties.DiagnosticsProfile.BootDiagnostics.StorageURI).Should(Equal("https://myaccount.blob.core.windows.net/"))
+				})
+
+				It("should create the new vm on a new nic in the old nic's subnet and network security group", func() {
+					Expect(fakeNetworkInterfacesClient.CreateOrUpdateCallCount()).Should(Equal(2))
+					_, nicName, nic, _ := fakeNetworkInterfacesClient.CreateOrUpdateArgsForCall(0)
+					Expect(nicName).Should(MatchRegexp(`^` + controlOldName + `-\d{4}-\d{2}-\d{2}-\d{2}-\d{2}-\d{2}-nic$`))
+					Expect(*nic.NetworkSecurityGroup.ID).Should(Equal("some-nsg"))
+					ipProperties := (*nic.IPConfigurations)[0].InterfaceIPConfigurationPropertiesFormat
+					Expect(*ipProperties.Subnet.ID).Should(Equal("some-subnet"))
+					Expect(ipProperties.PrivateIPAllocationMethod).Should(Equal(network.Dynamic))
+					Expect(ipProperties.PublicIPAddress).Should(BeNil())
+
+					_, _, parameters, _ := fakeVirtualMachinesClient.CreateOrUpdateArgsForCall(0)
+					nics := *parameters.VirtualMachineProperties.NetworkProfile.NetworkInterfaces
+					Expect(nics).Should(HaveLen(1))
+					Expect(*nics[0].ID).Should(HaveSuffix("/networkInterfaces/" + nicName))
+				})
+
+				It("should move the old nic's addresses to the new nic once the old vm and its nic are deleted", func() {
+					Expect(fakeNetworkInterfacesClient.DeleteCallCount()).Should(Equal(1))
+					_, oldNICName, _ := fakeNetworkInterfacesClient.DeleteArgsForCall(0)
+					Expect(oldNICName).Should(Equal(controlOldName + "-nic"))
+
+					_, nicName, nic, _ := fakeNetworkInterfacesClient.CreateOrUpdateArgsForCall(1)
+					Expect(nicName).Should(HaveSuffix("-nic"))
+					Expect(nicName).ShouldNot(Equal(oldNICName))
+					ipProperties := (*nic.IPConfigurations)[0].InterfaceIPConfigurationPropertiesFormat
+					Expect(ipProperties.PrivateIPAllocationMethod).Should(Equal(network.Static))
+					Expect(*ipProperties.PrivateIPAddress).Should(Equal("10.0.0.4"))
+					Expect(*ipProperties.PublicIPAddress.ID).Should(Equal("some-public-ip"))
 				})
 
 				/* TODO: the parameters in this function are not being properly tested.
@@ -148,6 +402,82 @@ var _ = Describe("Azure", func() {
 			})
 		})
 
+		Describe("VerifyResourceGroup()", func() {
+			var azureClient *azure.Client
+			var fakeResourceGroupsClient *azurefakes.FakeResourceGroupsClient
+
+			BeforeEach(func() {
+				fakeResourceGroupsClient = new(azurefakes.FakeResourceGroupsClient)
+				azureClient = new(azure.Client)
+				azureClient.ResourceGroupsClient = fakeResourceGroupsClient
+			})
+
+			Context("when the resource group can be read", func() {
+				It("should succeed", func() {
+					Expect(azureClient.VerifyResourceGroup()).Should(Succeed())
+					Expect(fakeResourceGroupsClient.GetCallCount()).Should(Equal(1))
+				})
+			})
+
+			Context("when the resource group cannot be read", func() {
+				controlErr := errors.New("resource group not found")
+				BeforeEach(func() {
+					fakeResourceGroupsClient.GetReturns(resources.Group{}, controlErr)
+				})
+				It("should return the error", func() {
+					err := azureClient.VerifyResourceGroup()
+					Expect(err).Should(HaveOccurred())
+					Expect(errwrap.Cause(err)).Should(Equal(controlErr))
+				})
+			})
+
+			Context("when no resource groups client is set", func() {
+				It("should return an error", func() {
+					azureClient.ResourceGroupsClient = nil
+					Expect(azureClient.VerifyResourceGroup()).Should(Equal(azure.InvalidAzureClientErr))
+				})
+			})
+		})
+
+		Describe("Stop() and Start()", func() {
+			var azureClient *azure.Client
+			var fakeVirtualMachinesClient *azurefakes.FakeComputeVirtualMachinesClient
+
+			BeforeEach(func() {
+				controlName := "ops-manager"
+				fakeVirtualMachinesClient = new(azurefakes.FakeComputeVirtualMachinesClient)
+				fakeVirtualMachinesClient.ListReturns(compute.VirtualMachineListResult{Value: &[]compute.VirtualMachine{{Name: &controlName}}}, nil)
+				azureClient = new(azure.Client)
+				azureClient.VirtualMachinesClient = fakeVirtualMachinesClient
+			})
+
+			It("should deallocate the matching vm to stop it", func() {
+				Expect(azureClient.Stop("ops*", true)).Should(Succeed())
+				_, vmName, _ := fakeVirtualMachinesClient.DeallocateArgsForCall(0)
+				Expect(vmName).Should(Equal("ops-manager"))
+			})
+
+			It("should only begin the deallocation without wait", func() {
+				Expect(azureClient.Stop("ops*", false)).Should(Succeed())
+				Expect(fakeVirtualMachinesClient.DeallocateCallCount()).Should(Equal(0))
+				_, vmName, _ := fakeVirtualMachinesClient.BeginDeallocateArgsForCall(0)
+				Expect(vmName).Should(Equal("ops-manager"))
+			})
+
+			It("should start the matching vm", func() {
+				Expect(azureClient.Start("ops*", true)).Should(Succeed())
+				_, vmName, _ := fakeVirtualMachinesClient.StartArgsForCall(0)
+				Expect(vmName).Should(Equal("ops-manager"))
+			})
+
+			It("should only begin the start without wait", func() {
+				Expect(azureClient.Start("ops*", false)).Should(Succeed())
+				Expect(fakeVirtualMachinesClient.StartCallCount()).Should(Equal(0))
+				_, vmName, _ := fakeVirtualMachinesClient.BeginStartArgsForCall(0)
+				Expect(vmName).Should(Equal("ops-manager"))
+			})
+		})
+
 		Describe("Delete()", func() {
 			var azureClient *azure.Client
 			var err error
@@ -300,6 +630,238 @@ var _ = Describe("Azure", func() {
 				})
 			})
 		})
+
+		Describe("Create()", func() {
+			var azureClient *azure.Client
+			var fakeVirtualMachinesClient *azurefakes.FakeComputeVirtualMachinesClient
+			var fakeBlobServiceClient *azurefakes.FakeBlobCopier
+			var sourceServer *httptest.Server
+			var vhdURL string
+			var spec iaas.VMSpec
+			var nicID = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/networkInterfaces/opsman-nic"
+
+			BeforeEach(func() {
+				sourceServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Content-Length", "2048")
+					w.Header().Set("Content-MD5", "c29tZS1tZDU=")
+				}))
+				vhdURL = sourceServer.URL + "/opsman.vhd"
+				fakeVirtualMachinesClient = new(azurefakes.FakeComputeVirtualMachinesClient)
+				fakeBlobServiceClient = new(azurefakes.FakeBlobCopier)
+				fakeBlobServiceClient.StartBlobCopyReturns("some-copy-id", nil)
+				fakeBlobServiceClient.GetBlobPropertiesReturns(&storage.BlobProperties{
+					CopyStatus:    azure.CopySuccess,
+					ContentLength: 2048,
+					ContentMD5:    "c29tZS1tZDU=",
+				}, nil)
+				azureClient = new(azure.Client)
+				azureClient.VirtualMachinesClient = fakeVirtualMachinesClient
+				azureClient.BlobServiceClient = fakeBlobServiceClient
+				azureClient.SetStorageAccountName("myaccount")
+				azureClient.SetStorageContainerName("mycontainer")
+				azureClient.SetStorageBaseURL(azure.DefaultBaseURL)
+				spec = iaas.VMSpec{
+					Name:         "opsman",
+					InstanceType: "Standard_DS2_v2",
+					Zone:         "westus",
+					Network:      iaas.NetworkSpec{Interface: nicID},
+					Disks: []iaas.DiskSpec{
+						{Name: "os", Boot: true, SizeGB: 120},
+						{Name: "lun-2", SizeGB: 50},
+					},
+					KeyName: "ssh-rsa AAAA",
+					Tags:    map[string]string{"env": "prod"},
+				}
+			})
+
+			AfterEach(func() {
+				sourceServer.Close()
+			})
+
+			It("should copy the vhd and create the vm on the spec's nic", func() {
+				vm, err := azureClient.Create(vhdURL, spec)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(vm.Name).Should(Equal("opsman"))
+				Expect(fakeBlobServiceClient.StartBlobCopyCallCount()).Should(Equal(1))
+
+				_, name, instance, _ := fakeVirtualMachinesClient.CreateOrUpdateArgsForCall(0)
+				Expect(name).Should(Equal("opsman"))
+				Expect(*instance.Location).Should(Equal("westus"))
+				Expect(*(*instance.Tags)["env"]).Should(Equal("prod"))
+				properties := instance.VirtualMachineProperties
+				Expect(properties.HardwareProfile.VMSize).Should(Equal(compute.StandardDS2V2))
+				Expect(*(*properties.NetworkProfile.NetworkInterfaces)[0].ID).Should(Equal(nicID))
+				Expect(*properties.StorageProfile.OsDisk.DiskSizeGB).Should(Equal(int32(120)))
+				Expect(*properties.StorageProfile.OsDisk.Image.URI).Should(Equal(vm.Image))
+				Expect(*(*properties.OsProfile.LinuxConfiguration.SSH.PublicKeys)[0].KeyData).Should(Equal("ssh-rsa AAAA"))
+				dataDisks := *properties.StorageProfile.DataDisks
+				Expect(dataDisks).Should(HaveLen(1))
+				Expect(*dataDisks[0].Lun).Should(Equal(int32(2)))
+				Expect(dataDisks[0].CreateOption).Should(Equal(compute.Empty))
+				Expect(*dataDisks[0].DiskSizeGB).Should(Equal(int32(50)))
+			})
+
+			It("should keep a generated admin password out of the logs", func() {
+				var hidden []string
+				azureClient.SetLogger(&iaas.Logger{
+					Writer: GinkgoWriter,
+					AddSecrets: func(secrets ...string) {
+						hidden = append(hidden, secrets...)
+					},
+				})
+
+				_, err := azureClient.Create(vhdURL, spec)
+				Expect(err).ShouldNot(HaveOccurred())
+				_, _, instance, _ := fakeVirtualMachinesClient.CreateOrUpdateArgsForCall(0)
+				Expect(hidden).Should(Equal([]string{*instance.VirtualMachineProperties.OsProfile.AdminPassword}))
+			})
+
+			It("should need the nic to attach the vm to", func() {
+				spec.Network.Interface = ""
+				_, err := azureClient.Create(vhdURL, spec)
+				Expect(err).Should(MatchError(ContainSubstring("network.interface")))
+				Expect(fakeBlobServiceClient.StartBlobCopyCallCount()).Should(Equal(0))
+			})
+
+			It("should create a nic in the spec's subnet when it names none", func() {
+				fakeNetworkInterfacesClient := new(azurefakes.FakeNetworkInterfacesClient)
+				fakeNetworkInterfacesClient.GetStub = func(resourceGroupName string, name string, expand string) (network.Interface, error) {
+					return newNetworkInterface(name), nil
+				}
+				publicIPID := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/publicIPAddresses/opsman-ip"
+				fakePublicIPAddressesClient := new(azurefakes.FakePublicIPAddressesClient)
+				fakePublicIPAddressesClient.ListReturns(network.PublicIPAddressListResult{Value: &[]network.PublicIPAddress{{
+					ID:                              &publicIPID,
+					PublicIPAddressPropertiesFormat: &network.PublicIPAddressPropertiesFormat{IPAddress: to.StringPtr("1.2.3.4")},
+				}}}, nil)
+				azureClient.NetworkInterfacesClient = fakeNetworkInterfacesClient
+				azureClient.PublicIPAddressesClient = fakePublicIPAddressesClient
+				spec.Network = iaas.NetworkSpec{
+					Subnet:         "some-subnet",
+					PrivateIP:      "10.0.0.5",
+					PublicIP:       "1.2.3.4",
+					SecurityGroups: []string{"some-nsg"},
+				}
+
+				_, err := azureClient.Create(vhdURL, spec)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				_, nicName, nic, _ := fakeNetworkInterfacesClient.CreateOrUpdateArgsForCall(0)
+				Expect(nicName).Should(Equal("opsman-nic"))
+				Expect(*nic.Location).Should(Equal("westus"))
+				Expect(*nic.NetworkSecurityGroup.ID).Should(Equal("some-nsg"))
+				ipProperties := (*nic.IPConfigurations)[0].InterfaceIPConfigurationPropertiesFormat
+				Expect(*ipProperties.Subnet.ID).Should(Equal("some-subnet"))
+				Expect(ipProperties.PrivateIPAllocationMethod).Should(Equal(network.Static))
+				Expect(*ipProperties.PrivateIPAddress).Should(Equal("10.0.0.5"))
+				Expect(*ipProperties.PublicIPAddress.ID).Should(Equal(publicIPID))
+
+				_, _, instance, _ := fakeVirtualMachinesClient.CreateOrUpdateArgsForCall(0)
+				Expect(*(*instance.VirtualMachineProperties.NetworkProfile.NetworkInterfaces)[0].ID).Should(HaveSuffix("/networkInterfaces/opsman-nic"))
+			})
+		})
+
+		Describe("DescribeVM()", func() {
+			It("should describe the matching vm as a spec", func() {
+				location := "westus"
+				nicID := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/networkInterfaces/opsman-nic"
+				env := "prod"
+				vm := newVirtualMachine("some-id", "ops-manager", "https://myaccount.blob.core.windows.net/images/ops-manager-1.10.3.vhd", controlDiskSize)
+				vm.Location = &location
+				vm.Tags = &map[string]*string{"env": &env}
+				vm.VirtualMachineProperties.HardwareProfile = &compute.HardwareProfile{VMSize: compute.StandardDS2V2}
+				vm.VirtualMachineProperties.NetworkProfile = &compute.NetworkProfile{
+					NetworkInterfaces: &[]compute.NetworkInterfaceReference{{ID: &nicID}},
+				}
+				fakeVirtualMachinesClient := new(azurefakes.FakeComputeVirtualMachinesClient)
+				fakeVirtualMachinesClient.ListReturns(compute.VirtualMachineListResult{Value: &[]compute.VirtualMachine{vm}}, nil)
+				azureClient := new(azure.Client)
+				azureClient.VirtualMachinesClient = fakeVirtualMachinesClient
+
+				spec, err := azureClient.DescribeVM("ops*")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(spec.Raw).ShouldNot(BeNil())
+				spec.Raw = nil
+				Expect(spec).Should(Equal(iaas.VMSpec{
+					IaaS:         "azure",
+					Name:         "ops-manager",
+					ID:           "some-id",
+					Image:        "https://myaccount.blob.core.windows.net/images/ops-manager-1.10.3.vhd",
+					InstanceType: "Standard_DS2_v2",
+					Zone:         "westus",
+					Network:      iaas.NetworkSpec{Interface: nicID},
+					Disks: []iaas.DiskSpec{
+						{Name: "os", Boot: true, SizeGB: int64(controlDiskSize), Source: "https://myaccount.blob.core.windows.net/images/ops-manager-1.10.3.vhd"},
+					},
+					Tags: map[string]string{"env": "prod"},
+				}))
+			})
+		})
+
+		Describe("DescribeVM() with a network interfaces client", func() {
+			It("should describe the subnet, addresses and security group of the vm's nic", func() {
+				vm := newVirtualMachine("some-id", "ops-manager", "some-image-url", controlDiskSize)
+				fakeVirtualMachinesClient := new(azurefakes.FakeComputeVirtualMachinesClient)
+				fakeVirtualMachinesClient.ListReturns(compute.VirtualMachineListResult{Value: &[]compute.VirtualMachine{vm}}, nil)
+				fakeNetworkInterfacesClient := new(azurefakes.FakeNetworkInterfacesClient)
+				fakeNetworkInterfacesClient.GetReturns(newNetworkInterface("ops-manager-nic"), nil)
+				fakePublicIPAddressesClient := new(azurefakes.FakePublicIPAddressesClient)
+				fakePublicIPAddressesClient.GetReturns(network.PublicIPAddress{
+					PublicIPAddressPropertiesFormat: &network.PublicIPAddressPropertiesFormat{IPAddress: to.StringPtr("1.2.3.4")},
+				}, nil)
+				azureClient := new(azure.Client)
+				azureClient.VirtualMachinesClient = fakeVirtualMachinesClient
+				azureClient.NetworkInterfacesClient = fakeNetworkInterfacesClient
+				azureClient.PublicIPAddressesClient = fakePublicIPAddressesClient
+
+				spec, err := azureClient.DescribeVM("ops*")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(spec.Network).Should(Equal(iaas.NetworkSpec{
+					Interface:      *(*vm.VirtualMachineProperties.NetworkProfile.NetworkInterfaces)[0].ID,
+					Subnet:         "some-subnet",
+					PrivateIP:      "10.0.0.4",
+					PublicIP:       "1.2.3.4",
+					SecurityGroups: []string{"some-nsg"},
+				}))
+				_, nicName, _ := fakeNetworkInterfacesClient.GetArgsForCall(0)
+				Expect(nicName).Should(Equal("ops-manager-nic"))
+			})
+
+			It("should describe a public ip it cannot read by its id", func() {
+				vm := newVirtualMachine("some-id", "ops-manager", "some-image-url", controlDiskSize)
+				fakeVirtualMachinesClient := new(azurefakes.FakeComputeVirtualMachinesClient)
+				fakeVirtualMachinesClient.ListReturns(compute.VirtualMachineListResult{Value: &[]compute.VirtualMachine{vm}}, nil)
+				fakeNetworkInterfacesClient := new(azurefakes.FakeNetworkInterfacesClient)
+				fakeNetworkInterfacesClient.GetReturns(newNetworkInterface("ops-manager-nic"), nil)
+				fakePublicIPAddressesClient := new(azurefakes.FakePublicIPAddressesClient)
+				fakePublicIPAddressesClient.GetReturns(network.PublicIPAddress{}, errors.New("forbidden"))
+				azureClient := new(azure.Client)
+				azureClient.VirtualMachinesClient = fakeVirtualMachinesClient
+				azureClient.NetworkInterfacesClient = fakeNetworkInterfacesClient
+				azureClient.PublicIPAddressesClient = fakePublicIPAddressesClient
+
+				spec, err := azureClient.DescribeVM("ops*")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(spec.Network.PublicIP).Should(Equal("some-public-ip"))
+				Expect(spec.Network.Subnet).Should(Equal("some-subnet"))
+			})
+		})
+
+		Describe("GetVM()", func() {
+			It("should return the matching vm and the image its os disk was created from", func() {
+				fakeVirtualMachinesClient := new(azurefakes.FakeComputeVirtualMachinesClient)
+				fakeVirtualMachinesClient.ListReturns(compute.VirtualMachineListResult{Value: &[]compute.VirtualMachine{
+					newVirtualMachine("some-vm-id", "testid", "some-image-url", controlDiskSize),
+				}}, nil)
+				fakeVirtualMachinesClient.ListAllNextResultsReturns(compute.VirtualMachineListResult{}, nil)
+				azureClient := new(azure.Client)
+				azureClient.VirtualMachinesClient = fakeVirtualMachinesClient
+
+				vm, err := azureClient.GetVM("testid")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(vm).To(Equal(iaas.VM{ID: "some-vm-id", Name: "testid", Image: "some-image-url"}))
+			})
+		})
 	})
 
 	Describe("NewClient", func() {
@@ -310,7 +872,7 @@ var _ = Describe("Azure", func() {
 		var clientSecret string
 		var tenantID string
 		var resourceGroupName string
-		var resourceManagerEndpoint string
+		var environment azure.Environment
 
 		JustBeforeEach(func() {
 			azureClient, err = azure.NewClient(
@@ -319,7 +881,7 @@ var _ = Describe("Azure", func() {
 				clientSecret,
 				tenantID,
 				resourceGroupName,
-				resourceManagerEndpoint,
+				environment,
 			)
 		})
 
@@ -330,7 +892,7 @@ var _ = Describe("Azure", func() {
 				clientSecret = "some-client-secret"
 				tenantID = "some-tenant-id"
 				resourceGroupName = "some-resource-group-name"
-				resourceManagerEndpoint = ""
+				environment = azure.Environment{}
 			})
 			It("should return a azure client", func() {
 				Expect(err).ShouldNot(HaveOccurred())
@@ -359,8 +921,9 @@ func newVirtualMachine(id string, name string, vmDiskURL string, diskSize int32)
 	tmpName := name
 	tmpURL := vmDiskURL
 	vm := compute.VirtualMachine{
-		ID:   &tmpID,
-		Name: &tmpName,
+		ID:       &tmpID,
+		Name:     &tmpName,
+		Location: to.StringPtr("westus"),
 		Resources: &[]compute.VirtualMachineExtension{
 			compute.VirtualMachineExtension{},
 			compute.VirtualMachineExtension{},
@@ -368,7 +931,19 @@ func newVirtualMachine(id string, name string, vmDiskURL string, diskSize int32)
 			compute.VirtualMachineExtension{},
 		},
 		VirtualMachineProperties: &compute.VirtualMachineProperties{
-			OsProfile: &compute.OSProfile{},
+			OsProfile:       &compute.OSProfile{},
+			AvailabilitySet: &compute.SubResource{ID: to.StringPtr("some-availability-set")},
+			DiagnosticsProfile: &compute.DiagnosticsProfile{
+				BootDiagnostics: &compute.BootDiagnostics{
+					Enabled:    to.BoolPtr(true),
+					StorageURI: to.StringPtr("https://myaccount.blob.core.windows.net/"),
+				},
+			},
+			NetworkProfile: &compute.NetworkProfile{
+				NetworkInterfaces: &[]compute.NetworkInterfaceReference{
+					{ID: to.StringPtr("/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/networkInterfaces/" + name + "-nic")},
+				},
+			},
 			StorageProfile: &compute.StorageProfile{
 				OsDisk: &compute.OSDisk{
 					DiskSizeGB: &diskSize,
@@ -384,3 +959,23 @@ func newVirtualMachine(id string, name string, vmDiskURL string, diskSize int32)
 	}
 	return vm
 }
+
+func newNetworkInterface(name string) network.Interface {
+	return network.Interface{
+		ID:       to.StringPtr("/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/networkInterfaces/" + name),
+		Location: to.StringPtr("westus"),
+		InterfacePropertiesFormat: &network.InterfacePropertiesFormat{
+			NetworkSecurityGroup: &network.SecurityGroup{ID: to.StringPtr("some-nsg")},
+			IPConfigurations: &[]network.InterfaceIPConfiguration{
+				{
+					InterfaceIPConfigurationPropertiesFormat: &network.InterfaceIPConfigurationPropertiesFormat{
+						PrivateIPAddress:          to.StringPtr("10.0.0.4"),
+						PrivateIPAllocationMethod: network.Static,
+						Subnet:                    &network.Subnet{ID: to.StringPtr("some-subnet")},
+						PublicIPAddress:           &network.PublicIPAddress{ID: to.StringPtr("some-public-ip")},
+					},
+				},
+			},
+		},
+	}
+}
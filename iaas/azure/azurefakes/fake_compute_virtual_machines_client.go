@@ -84,6 +84,51 @@ type FakeComputeVirtualMachinesClient struct {
 		result1 autorest.Response
 		result2 error
 	}
+	StartStub        func(resourceGroupName string, vmName string, cancel <-chan struct{}) (result autorest.Response, err error)
+	startMutex       sync.RWMutex
+	startArgsForCall []struct {
+		resourceGroupName string
+		vmName            string
+		cancel            <-chan struct{}
+	}
+	startReturns struct {
+		result1 autorest.Response
+		result2 error
+	}
+	startReturnsOnCall map[int]struct {
+		result1 autorest.Response
+		result2 error
+	}
+	BeginDeallocateStub        func(resourceGroupName string, vmName string, cancel <-chan struct{}) (result autorest.Response, err error)
+	beginDeallocateMutex       sync.RWMutex
+	beginDeallocateArgsForCall []struct {
+		resourceGroupName string
+		vmName            string
+		cancel            <-chan struct{}
+	}
+	beginDeallocateReturns struct {
+		result1 autorest.Response
+		result2 error
+	}
+	beginDeallocateReturnsOnCall map[int]struct {
+		result1 autorest.Response
+		result2 error
+	}
+	BeginStartStub        func(resourceGroupName string, vmName string, cancel <-chan struct{}) (result autorest.Response, err error)
+	beginStartMutex       sync.RWMutex
+	beginStartArgsForCall []struct {
+		resourceGroupName string
+		vmName            string
+		cancel            <-chan struct{}
+	}
+	beginStartReturns struct {
+		result1 autorest.Response
+		result2 error
+	}
+	beginStartReturnsOnCall map[int]struct {
+		result1 autorest.Response
+		result2 error
+	}
 	ListStub        func(resourceGroupName string) (result compute.VirtualMachineListResult, err error)
 	listMutex       sync.RWMutex
 	listArgsForCall []struct {
@@ -365,6 +410,165 @@ func (fake *FakeComputeVirtualMachinesClient) DeallocateReturnsOnCall(i int, res
 	}{result1, result2}
 }
 
+func (fake *FakeComputeVirtualMachinesClient) Start(resourceGroupName string, vmName string, cancel <-chan struct{}) (result autorest.Response, err error) {
+	fake.startMutex.Lock()
+	ret, specificReturn := fake.startReturnsOnCall[len(fake.startArgsForCall)]
+	fake.startArgsForCall = append(fake.startArgsForCall, struct {
+		resourceGroupName string
+		vmName            string
+		cancel            <-chan struct{}
+	}{resourceGroupName, vmName, cancel})
+	fake.recordInvocation("Start", []interface{}{resourceGroupName, vmName, cancel})
+	fake.startMutex.Unlock()
+	if fake.StartStub != nil {
+		return fake.StartStub(resourceGroupName, vmName, cancel)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.startReturns.result1, fake.startReturns.result2
+}
+
+func (fake *FakeComputeVirtualMachinesClient) StartCallCount() int {
+	fake.startMutex.RLock()
+	defer fake.startMutex.RUnlock()
+	return len(fake.startArgsForCall)
+}
+
+func (fake *FakeComputeVirtualMachinesClient) StartArgsForCall(i int) (string, string, <-chan struct{}) {
+	fake.startMutex.RLock()
+	defer fake.startMutex.RUnlock()
+	return fake.startArgsForCall[i].resourceGroupName, fake.startArgsForCall[i].vmName, fake.startArgsForCall[i].cancel
+}
+
+func (fake *FakeComputeVirtualMachinesClient) StartReturns(result1 autorest.Response, result2 error) {
+	fake.StartStub = nil
+	fake.startReturns = struct {
+		result1 autorest.Response
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeComputeVirtualMachinesClient) StartReturnsOnCall(i int, result1 autorest.Response, result2 error) {
+	fake.StartStub = nil
+	if fake.startReturnsOnCall == nil {
+		fake.startReturnsOnCall = make(map[int]struct {
+			result1 autorest.Response
+			result2 error
+		})
+	}
+	fake.startReturnsOnCall[i] = struct {
+		result1 autorest.Response
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeComputeVirtualMachinesClient) BeginDeallocate(resourceGroupName string, vmName string, cancel <-chan struct{}) (result autorest.Response, err error) {
+	fake.beginDeallocateMutex.Lock()
+	ret, specificReturn := fake.beginDeallocateReturnsOnCall[len(fake.beginDeallocateArgsForCall)]
+	fake.beginDeallocateArgsForCall = append(fake.beginDeallocateArgsForCall, struct {
+		resourceGroupName string
+		vmName            string
+		cancel            <-chan struct{}
+	}{resourceGroupName, vmName, cancel})
+	fake.recordInvocation("BeginDeallocate", []interface{}{resourceGroupName, vmName, cancel})
+	fake.beginDeallocateMutex.Unlock()
+	if fake.BeginDeallocateStub != nil {
+		return fake.BeginDeallocateStub(resourceGroupName, vmName, cancel)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.beginDeallocateReturns.result1, fake.beginDeallocateReturns.result2
+}
+
+func (fake *FakeComputeVirtualMachinesClient) BeginDeallocateCallCount() int {
+	fake.beginDeallocateMutex.RLock()
+	defer fake.beginDeallocateMutex.RUnlock()
+	return len(fake.beginDeallocateArgsForCall)
+}
+
+func (fake *FakeComputeVirtualMachinesClient) BeginDeallocateArgsForCall(i int) (string, string, <-chan struct{}) {
+	fake.beginDeallocateMutex.RLock()
+	defer fake.beginDeallocateMutex.RUnlock()
+	return fake.beginDeallocateArgsForCall[i].resourceGroupName, fake.beginDeallocateArgsForCall[i].vmName, fake.beginDeallocateArgsForCall[i].cancel
+}
+
+func (fake *FakeComputeVirtualMachinesClient) BeginDeallocateReturns(result1 autorest.Response, result2 error) {
+	fake.BeginDeallocateStub = nil
+	fake.beginDeallocateReturns = struct {
+		result1 autorest.Response
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeComputeVirtualMachinesClient) BeginDeallocateReturnsOnCall(i int, result1 autorest.Response, result2 error) {
+	fake.BeginDeallocateStub = nil
+	if fake.beginDeallocateReturnsOnCall == nil {
+		fake.beginDeallocateReturnsOnCall = make(map[int]struct {
+			result1 autorest.Response
+			result2 error
+		})
+	}
+	fake.beginDeallocateReturnsOnCall[i] = struct {
+		result1 autorest.Response
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeComputeVirtualMachinesClient) BeginStart(resourceGroupName string, vmName string, cancel <-chan struct{}) (result autorest.Response, err error) {
+	fake.beginStartMutex.Lock()
+	ret, specificReturn := fake.beginStartReturnsOnCall[len(fake.beginStartArgsForCall)]
+	fake.beginStartArgsForCall = append(fake.beginStartArgsForCall, struct {
+		resourceGroupName string
+		vmName            string
+		cancel            <-chan struct{}
+	}{resourceGroupName, vmName, cancel})
+	fake.recordInvocation("BeginStart", []interface{}{resourceGroupName, vmName, cancel})
+	fake.beginStartMutex.Unlock()
+	if fake.BeginStartStub != nil {
+		return fake.BeginStartStub(resourceGroupName, vmName, cancel)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.beginStartReturns.result1, fake.beginStartReturns.result2
+}
+
+func (fake *FakeComputeVirtualMachinesClient) BeginStartCallCount() int {
+	fake.beginStartMutex.RLock()
+	defer fake.beginStartMutex.RUnlock()
+	return len(fake.beginStartArgsForCall)
+}
+
+func (fake *FakeComputeVirtualMachinesClient) BeginStartArgsForCall(i int) (string, string, <-chan struct{}) {
+	fake.beginStartMutex.RLock()
+	defer fake.beginStartMutex.RUnlock()
+	return fake.beginStartArgsForCall[i].resourceGroupName, fake.beginStartArgsForCall[i].vmName, fake.beginStartArgsForCall[i].cancel
+}
+
+func (fake *FakeComputeVirtualMachinesClient) BeginStartReturns(result1 autorest.Response, result2 error) {
+	fake.BeginStartStub = nil
+	fake.beginStartReturns = struct {
+		result1 autorest.Response
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeComputeVirtualMachinesClient) BeginStartReturnsOnCall(i int, result1 autorest.Response, result2 error) {
+	fake.BeginStartStub = nil
+	if fake.beginStartReturnsOnCall == nil {
+		fake.beginStartReturnsOnCall = make(map[int]struct {
+			result1 autorest.Response
+			result2 error
+		})
+	}
+	fake.beginStartReturnsOnCall[i] = struct {
+		result1 autorest.Response
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeComputeVirtualMachinesClient) List(resourceGroupName string) (result compute.VirtualMachineListResult, err error) {
 	fake.listMutex.Lock()
 	ret, specificReturn := fake.listReturnsOnCall[len(fake.listArgsForCall)]
@@ -429,6 +633,12 @@ func (fake *FakeComputeVirtualMachinesClient) Invocations() map[string][][]inter
 	defer fake.deleteMutex.RUnlock()
 	fake.deallocateMutex.RLock()
 	defer fake.deallocateMutex.RUnlock()
+	fake.startMutex.RLock()
+	defer fake.startMutex.RUnlock()
+	fake.beginDeallocateMutex.RLock()
+	defer fake.beginDeallocateMutex.RUnlock()
+	fake.beginStartMutex.RLock()
+	defer fake.beginStartMutex.RUnlock()
 	fake.listMutex.RLock()
 	defer fake.listMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
@@ -0,0 +1,310 @@
+package azure
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/storage"
+	"github.com/pivotal-cf/cliaas/iaas"
+	errwrap "github.com/pkg/errors"
+)
+
+// Copy statuses of a blob that is the destination of a server side copy
+const (
+	CopyPending = "pending"
+	CopySuccess = "success"
+	CopyAborted = "aborted"
+	CopyFailed  = "failed"
+)
+
+// DefaultCopyAttempts is how many times a failed or corrupt copy is started
+// over before giving up
+const DefaultCopyAttempts = 3
+
+// BlobCopy copies a blob server side into the storage account, reports the
+// bytes copied as it goes and checks the copy against the source once it is
+// done. Azure cannot resume a copy, so a failed, aborted or corrupt copy is
+// deleted and started over.
+type BlobCopy struct {
+	Storage  BlobCopier
+	Waiter   iaas.Waiter
+	Progress iaas.ProgressReporter
+	Logger   *iaas.Logger
+
+	// HTTPClient reads the properties of the source blob, which may be in
+	// another account behind a SAS token; http.DefaultClient when nil
+	HTTPClient *http.Client
+	Attempts   int
+}
+
+// BlobSource is what the source of a copy says about itself
+type BlobSource struct {
+	ContentLength int64
+	ContentMD5    string
+	ETag          string
+}
+
+// CopyFailedError is a copy the storage service gave up on
+type CopyFailedError struct {
+	Status      string
+	Description string
+}
+
+func (e *CopyFailedError) Error() string {
+	return fmt.Sprintf("blob copy %s: %s", e.Status, e.Description)
+}
+
+// VerificationError is a copy that finished, but whose length or md5 differ
+// from the source
+type VerificationError struct {
+	Field    string
+	Expected string
+	Actual   string
+}
+
+func (e *VerificationError) Error() string {
+	return fmt.Sprintf("copied blob %s is %q, expected %q", e.Field, e.Actual, e.Expected)
+}
+
+// CopyImage copies sourceURL into container under the name ImageBlobName
+// gives it, and returns that name. A verified copy of the same source left by
+// an earlier replace is reused instead of being copied again.
+func (c BlobCopy) CopyImage(container, sourceURL string) (string, error) {
+	source, err := c.SourceProperties(sourceURL)
+	if err != nil {
+		return "", err
+	}
+
+	name := ImageBlobName(sourceURL, source)
+	reused, err := c.reuseCopy(container, name, source)
+	if err != nil || reused {
+		return name, err
+	}
+
+	return name, c.copy(container, name, sourceURL, source)
+}
+
+// Copy copies sourceURL to name in container, reporting progress under the
+// destination name
+func (c BlobCopy) Copy(container, name, sourceURL string) error {
+	source, err := c.SourceProperties(sourceURL)
+	if err != nil {
+		return err
+	}
+
+	return c.copy(container, name, sourceURL, source)
+}
+
+// reuseCopy reports whether name already holds a verified copy of source.
+// Anything else under that name, e.g. a copy interrupted by an earlier
+// failure, is deleted so that it can be copied afresh.
+func (c BlobCopy) reuseCopy(container, name string, source BlobSource) (bool, error) {
+	exists, err := c.Storage.BlobExists(container, name)
+	if err != nil {
+		return false, errwrap.Wrapf(err, "failed checking for an earlier copy %s", name)
+	}
+	if !exists {
+		return false, nil
+	}
+
+	properties, err := c.Storage.GetBlobProperties(container, name)
+	if err != nil {
+		return false, errwrap.Wrapf(err, "failed getting properties of earlier copy %s", name)
+	}
+
+	if properties.CopyStatus == CopySuccess && verifyCopy(source, properties) == nil {
+		c.Logger.Info("reusing verified copy of the image", iaas.Fields{"blob": name})
+		iaas.ReportProgress(c.Progress, iaas.ProgressEvent{
+			Step:    iaas.StepImportingImage,
+			Subject: name,
+			Current: properties.ContentLength,
+			Total:   properties.ContentLength,
+		})
+		return true, nil
+	}
+
+	c.Logger.Info("deleting unfinished copy of the image", iaas.Fields{"blob": name, "copy_status": properties.CopyStatus})
+	_, err = c.Storage.DeleteBlobIfExists(container, name, nil)
+	if err != nil {
+		return false, errwrap.Wrapf(err, "failed deleting unfinished copy %s", name)
+	}
+	return false, nil
+}
+
+func (c BlobCopy) copy(container, name, sourceURL string, source BlobSource) error {
+	var err error
+	attempts := c.Attempts
+	if attempts <= 0 {
+		attempts = DefaultCopyAttempts
+	}
+
+	for attempt := 1; ; attempt++ {
+		err = c.copyOnce(container, name, sourceURL, source)
+		if err == nil {
+			return nil
+		}
+
+		switch errwrap.Cause(err).(type) {
+		case *CopyFailedError, *VerificationError:
+		default:
+			return err
+		}
+		if attempt >= attempts {
+			return errwrap.Wrapf(err, "giving up on copying %s after %d attempts", name, attempt)
+		}
+
+		_, deleteErr := c.Storage.DeleteBlobIfExists(container, name, nil)
+		if deleteErr != nil {
+			return errwrap.Wrapf(deleteErr, "failed deleting %s to start the copy over after: %s", name, err)
+		}
+	}
+}
+
+func (c BlobCopy) copyOnce(container, name, sourceURL string, source BlobSource) error {
+	copyID, err := c.Storage.StartBlobCopy(container, name, sourceURL)
+	if err != nil {
+		return errwrap.Wrapf(err, "failed starting copy of %s", RedactSAS(sourceURL))
+	}
+
+	var properties *storage.BlobProperties
+	err = c.Waiter.Wait("copying "+name, c.Waiter.Timeouts.ImageImport, func() (bool, error) {
+		properties, err = c.Storage.GetBlobProperties(container, name)
+		if err != nil {
+			return false, errwrap.Wrapf(err, "failed getting copy status of %s", name)
+		}
+		return c.checkCopyStatus(name, properties)
+	})
+	if _, ok := err.(*iaas.TimeoutError); ok {
+		// leave nothing copying in the background once we have given up
+		c.Storage.AbortBlobCopy(container, name, copyID, "", 0)
+	}
+	if err != nil {
+		return err
+	}
+
+	return verifyCopy(source, properties)
+}
+
+func (c BlobCopy) checkCopyStatus(name string, properties *storage.BlobProperties) (bool, error) {
+	switch properties.CopyStatus {
+	case CopySuccess:
+		iaas.ReportProgress(c.Progress, iaas.ProgressEvent{
+			Step:    iaas.StepImportingImage,
+			Subject: name,
+			Current: properties.ContentLength,
+			Total:   properties.ContentLength,
+		})
+		return true, nil
+	case CopyPending:
+		copied, total := ParseCopyProgress(properties.CopyProgress)
+		iaas.ReportProgress(c.Progress, iaas.ProgressEvent{
+			Step:    iaas.StepImportingImage,
+			Subject: name,
+			Current: copied,
+			Total:   total,
+		})
+		return false, nil
+	default:
+		return false, &CopyFailedError{Status: properties.CopyStatus, Description: properties.CopyStatusDescription}
+	}
+}
+
+func verifyCopy(source BlobSource, properties *storage.BlobProperties) error {
+	if source.ContentLength > 0 && properties.ContentLength != source.ContentLength {
+		return &VerificationError{
+			Field:    "length",
+			Expected: strconv.FormatInt(source.ContentLength, 10),
+			Actual:   strconv.FormatInt(properties.ContentLength, 10),
+		}
+	}
+
+	if source.ContentMD5 != "" && properties.ContentMD5 != source.ContentMD5 {
+		return &VerificationError{
+			Field:    "md5",
+			Expected: source.ContentMD5,
+			Actual:   properties.ContentMD5,
+		}
+	}
+	return nil
+}
+
+// SourceProperties reads the length, md5 and ETag of the source blob, with
+// any SAS token in its url
+func (c BlobCopy) SourceProperties(sourceURL string) (BlobSource, error) {
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	resp, err := httpClient.Head(sourceURL)
+	if err != nil {
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return BlobSource{}, errwrap.Wrapf(err, "failed reading source blob %s", RedactSAS(sourceURL))
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return BlobSource{}, fmt.Errorf("failed reading source blob %s: %s", RedactSAS(sourceURL), resp.Status)
+	}
+
+	return BlobSource{
+		ContentLength: resp.ContentLength,
+		ContentMD5:    resp.Header.Get("Content-MD5"),
+		ETag:          resp.Header.Get("ETag"),
+	}, nil
+}
+
+// ImageBlobName names the local copy of a source blob after its url, without
+// any SAS token, and its ETag. Copying the same source again, even with a new
+// SAS token, gives the same name, while a changed source gives a new one.
+func ImageBlobName(sourceURL string, source BlobSource) string {
+	if parsed, err := url.Parse(sourceURL); err == nil {
+		parsed.RawQuery = ""
+		sourceURL = parsed.String()
+	}
+
+	sum := sha256.Sum256([]byte(sourceURL + "\n" + source.ETag))
+	return fmt.Sprintf("cliaas-%x-image.vhd", sum[:12])
+}
+
+// ParseCopyProgress reads the "copied/total" bytes of a pending copy, or
+// zeros when the storage service has not said yet
+func ParseCopyProgress(progress string) (copied int64, total int64) {
+	parts := strings.SplitN(progress, "/", 2)
+	if len(parts) != 2 {
+		return 0, 0
+	}
+
+	copied, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0
+	}
+	total, err = strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0
+	}
+	return copied, total
+}
+
+// RedactSAS hides the signature of a SAS token in a blob url, so that it
+// can be shown in errors
+func RedactSAS(blobURL string) string {
+	parsed, err := url.Parse(blobURL)
+	if err != nil || parsed.RawQuery == "" {
+		return blobURL
+	}
+
+	query := parsed.Query()
+	if query.Get("sig") == "" {
+		return blobURL
+	}
+	query.Set("sig", "REDACTED")
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
@@ -0,0 +1,287 @@
+package azure_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/cliaas/iaas"
+	"github.com/pivotal-cf/cliaas/iaas/azure"
+	"github.com/pivotal-cf/cliaas/iaas/iaasfakes"
+	errwrap "github.com/pkg/errors"
+)
+
+const (
+	sourceLength = "4096"
+	sourceMD5    = "c29tZS1tZDU="
+)
+
+// copyState is what the fake blob endpoint reports for the destination blob
+// on one poll
+type copyState struct {
+	status      string
+	progress    string
+	description string
+	length      string
+	md5         string
+}
+
+// fakeBlobEndpoint plays the part of the blob service for a single copy. The
+// destination is existing until a copy is started, and each started copy
+// walks through the next script of states, one per poll, and stays on the
+// last.
+type fakeBlobEndpoint struct {
+	mutex       sync.Mutex
+	existing    *copyState
+	scripts     [][]copyState
+	copySources []string
+	polls       int
+	deletes     int
+	aborts      int
+}
+
+func (f *fakeBlobEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	switch {
+	case r.URL.Path == "/source/opsman.vhd" && r.Method == "HEAD":
+		w.Header().Set("Content-Length", sourceLength)
+		w.Header().Set("Content-MD5", sourceMD5)
+		w.Header().Set("ETag", `"0x8D4BCC2E4835CD0"`)
+	case r.URL.Path == "/source/missing.vhd":
+		w.WriteHeader(http.StatusForbidden)
+	case r.Method == "PUT" && r.URL.Query().Get("comp") == "copy":
+		f.aborts++
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == "PUT":
+		f.copySources = append(f.copySources, r.Header.Get("x-ms-copy-source"))
+		f.polls = 0
+		w.Header().Set("x-ms-copy-id", "some-copy-id")
+		w.Header().Set("x-ms-copy-status", azure.CopyPending)
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == "HEAD" && len(f.copySources) == 0 && f.existing == nil:
+		w.WriteHeader(http.StatusNotFound)
+	case r.Method == "HEAD":
+		var state copyState
+		if len(f.copySources) == 0 {
+			state = *f.existing
+		} else {
+			script := f.scripts[len(f.copySources)-1]
+			state = script[len(script)-1]
+			if f.polls < len(script) {
+				state = script[f.polls]
+			}
+			f.polls++
+		}
+
+		w.Header().Set("Content-Length", state.length)
+		w.Header().Set("Content-MD5", state.md5)
+		w.Header().Set("x-ms-blob-type", "PageBlob")
+		w.Header().Set("x-ms-copy-id", "some-copy-id")
+		w.Header().Set("x-ms-copy-status", state.status)
+		w.Header().Set("x-ms-copy-progress", state.progress)
+		w.Header().Set("x-ms-copy-status-description", state.description)
+	case r.Method == "DELETE":
+		f.existing = nil
+		f.deletes++
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+// redirectTransport sends every storage account request to the fake endpoint
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (t redirectTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r.URL.Scheme = t.target.Scheme
+	r.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+var _ = Describe("BlobCopy", func() {
+	var (
+		endpoint     *fakeBlobEndpoint
+		server       *httptest.Server
+		fakeProgress *iaasfakes.FakeProgressReporter
+		blobCopy     azure.BlobCopy
+		sourceURL    string
+		timeouts     iaas.Timeouts
+	)
+
+	BeforeEach(func() {
+		endpoint = new(fakeBlobEndpoint)
+		server = httptest.NewServer(endpoint)
+		serverURL, err := url.Parse(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+
+		storageClient, err := azure.NewBlobStorageClient("myaccount", "c29tZS1hY2NvdW50LWtleQ==", azure.DefaultBaseURL, &http.Client{
+			Transport: redirectTransport{target: serverURL},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		fakeProgress = new(iaasfakes.FakeProgressReporter)
+		timeouts = iaas.Timeouts{
+			ImageImport:     time.Second,
+			PollInterval:    time.Millisecond,
+			MaxPollInterval: time.Millisecond,
+		}
+		blobCopy = azure.BlobCopy{
+			Storage:  storageClient,
+			Waiter:   iaas.NewWaiter(clock.NewClock(), timeouts),
+			Progress: fakeProgress,
+		}
+		sourceURL = server.URL + "/source/opsman.vhd?sv=2016-05-31&sr=b&sig=some-signature"
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	success := copyState{status: azure.CopySuccess, progress: "4096/4096", length: sourceLength, md5: sourceMD5}
+
+	Context("when the copy succeeds", func() {
+		BeforeEach(func() {
+			endpoint.scripts = [][]copyState{{
+				{status: azure.CopyPending, progress: "1024/4096", length: "0"},
+				{status: azure.CopyPending, progress: "3072/4096", length: "0"},
+				success,
+			}}
+		})
+
+		It("passes the source url with its SAS token and reports the bytes copied", func() {
+			Expect(blobCopy.Copy("mycontainer", "opsman-copy.vhd", sourceURL)).To(Succeed())
+
+			Expect(endpoint.copySources).To(Equal([]string{sourceURL}))
+			Expect(fakeProgress.ReportCallCount()).To(Equal(3))
+			Expect(fakeProgress.ReportArgsForCall(0)).To(Equal(iaas.ProgressEvent{Step: iaas.StepImportingImage, Subject: "opsman-copy.vhd", Current: 1024, Total: 4096}))
+			Expect(fakeProgress.ReportArgsForCall(1)).To(Equal(iaas.ProgressEvent{Step: iaas.StepImportingImage, Subject: "opsman-copy.vhd", Current: 3072, Total: 4096}))
+			Expect(fakeProgress.ReportArgsForCall(2)).To(Equal(iaas.ProgressEvent{Step: iaas.StepImportingImage, Subject: "opsman-copy.vhd", Current: 4096, Total: 4096}))
+		})
+	})
+
+	Context("when the storage service fails the copy", func() {
+		BeforeEach(func() {
+			endpoint.scripts = [][]copyState{
+				{{status: azure.CopyFailed, description: "500 InternalError", length: "0"}},
+				{success},
+			}
+		})
+
+		It("deletes the partial blob and starts over", func() {
+			Expect(blobCopy.Copy("mycontainer", "opsman-copy.vhd", sourceURL)).To(Succeed())
+
+			Expect(endpoint.copySources).To(HaveLen(2))
+			Expect(endpoint.deletes).To(Equal(1))
+		})
+	})
+
+	Context("when the copy never matches the source", func() {
+		BeforeEach(func() {
+			corrupt := copyState{status: azure.CopySuccess, length: sourceLength, md5: "b3RoZXItbWQ1"}
+			endpoint.scripts = [][]copyState{{corrupt}, {corrupt}, {corrupt}}
+		})
+
+		It("gives up after the attempts with the mismatch", func() {
+			err := blobCopy.Copy("mycontainer", "opsman-copy.vhd", sourceURL)
+			Expect(err).To(MatchError(ContainSubstring("after 3 attempts")))
+
+			verificationErr, ok := errwrap.Cause(err).(*azure.VerificationError)
+			Expect(ok).To(BeTrue())
+			Expect(verificationErr.Field).To(Equal("md5"))
+			Expect(endpoint.copySources).To(HaveLen(3))
+			Expect(endpoint.deletes).To(Equal(2))
+		})
+	})
+
+	Context("when the copy does not finish in time", func() {
+		BeforeEach(func() {
+			endpoint.scripts = [][]copyState{{{status: azure.CopyPending, progress: "0/4096", length: "0"}}}
+			timeouts.ImageImport = 20 * time.Millisecond
+			blobCopy.Waiter = iaas.NewWaiter(clock.NewClock(), timeouts)
+		})
+
+		It("aborts the copy and times out", func() {
+			err := blobCopy.Copy("mycontainer", "opsman-copy.vhd", sourceURL)
+			_, ok := err.(*iaas.TimeoutError)
+			Expect(ok).To(BeTrue())
+			Expect(endpoint.aborts).To(Equal(1))
+		})
+	})
+
+	Context("when the source cannot be read", func() {
+		It("errors without starting a copy or showing the SAS signature", func() {
+			err := blobCopy.Copy("mycontainer", "opsman-copy.vhd", server.URL+"/source/missing.vhd?sig=some-signature")
+			Expect(err).To(MatchError(ContainSubstring("403")))
+			Expect(err.Error()).NotTo(ContainSubstring("some-signature"))
+			Expect(endpoint.copySources).To(BeEmpty())
+		})
+	})
+
+	Describe("CopyImage", func() {
+		BeforeEach(func() {
+			endpoint.scripts = [][]copyState{{success}}
+		})
+
+		It("copies the source to a blob named after it", func() {
+			name, err := blobCopy.CopyImage("mycontainer", sourceURL)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(name).To(MatchRegexp("^cliaas-[0-9a-f]{24}-image.vhd$"))
+			Expect(endpoint.copySources).To(Equal([]string{sourceURL}))
+		})
+
+		Context("when an earlier replace left a verified copy", func() {
+			BeforeEach(func() {
+				endpoint.existing = &success
+			})
+
+			It("reuses it without copying", func() {
+				_, err := blobCopy.CopyImage("mycontainer", sourceURL)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(endpoint.copySources).To(BeEmpty())
+				Expect(fakeProgress.ReportCallCount()).To(Equal(1))
+				Expect(fakeProgress.ReportArgsForCall(0).Current).To(Equal(int64(4096)))
+			})
+		})
+
+		Context("when an earlier replace left an unfinished copy", func() {
+			BeforeEach(func() {
+				endpoint.existing = &copyState{status: azure.CopyPending, progress: "1024/4096", length: "0"}
+			})
+
+			It("deletes it and copies again", func() {
+				_, err := blobCopy.CopyImage("mycontainer", sourceURL)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(endpoint.deletes).To(Equal(1))
+				Expect(endpoint.copySources).To(HaveLen(1))
+			})
+		})
+	})
+
+	It("names image blobs by the source url without its SAS token and by its ETag", func() {
+		source := azure.BlobSource{ETag: `"0x8D4BCC2E4835CD0"`}
+		name := azure.ImageBlobName("https://opsman.blob.core.windows.net/images/opsman.vhd?sig=one", source)
+
+		Expect(azure.ImageBlobName("https://opsman.blob.core.windows.net/images/opsman.vhd?sig=two", source)).To(Equal(name))
+		Expect(azure.ImageBlobName("https://opsman.blob.core.windows.net/images/opsman.vhd", azure.BlobSource{ETag: `"0x8D4BCC2E4835CD1"`})).NotTo(Equal(name))
+	})
+
+	It("reads the bytes copied from the copy progress", func() {
+		copied, total := azure.ParseCopyProgress("512/2048")
+		Expect(copied).To(Equal(int64(512)))
+		Expect(total).To(Equal(int64(2048)))
+
+		copied, total = azure.ParseCopyProgress("")
+		Expect(copied).To(BeZero())
+		Expect(total).To(BeZero())
+	})
+})
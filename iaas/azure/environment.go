@@ -0,0 +1,161 @@
+package azure
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	errwrap "github.com/pkg/errors"
+)
+
+const (
+	AzurePublicCloud       = "AzurePublicCloud"
+	AzureUSGovernmentCloud = "AzureUSGovernmentCloud"
+	AzureChinaCloud        = "AzureChinaCloud"
+	AzureStack             = "AzureStack"
+)
+
+const azureStackMetadataPath = "metadata/endpoints?api-version=2015-01-01"
+
+// Environment - the set of endpoints a client needs to talk to one azure cloud
+type Environment struct {
+	Name                    string
+	ActiveDirectoryEndpoint string
+	ResourceManagerEndpoint string
+	TokenAudience           string
+	StorageEndpointSuffix   string
+}
+
+var knownEnvironments = map[string]Environment{
+	strings.ToLower(AzurePublicCloud): Environment{
+		Name:                    AzurePublicCloud,
+		ActiveDirectoryEndpoint: "https://login.microsoftonline.com/",
+		ResourceManagerEndpoint: defaultResourceManagerEndpoint,
+		TokenAudience:           defaultResourceManagerEndpoint,
+		StorageEndpointSuffix:   DefaultBaseURL,
+	},
+	strings.ToLower(AzureUSGovernmentCloud): Environment{
+		Name:                    AzureUSGovernmentCloud,
+		ActiveDirectoryEndpoint: "https://login.microsoftonline.us/",
+		ResourceManagerEndpoint: "https://management.usgovcloudapi.net/",
+		TokenAudience:           "https://management.usgovcloudapi.net/",
+		StorageEndpointSuffix:   "core.usgovcloudapi.net",
+	},
+	strings.ToLower(AzureChinaCloud): Environment{
+		Name:                    AzureChinaCloud,
+		ActiveDirectoryEndpoint: "https://login.chinacloudapi.cn/",
+		ResourceManagerEndpoint: "https://management.chinacloudapi.cn/",
+		TokenAudience:           "https://management.chinacloudapi.cn/",
+		StorageEndpointSuffix:   "core.chinacloudapi.cn",
+	},
+}
+
+var UnknownEnvironmentErr = fmt.Errorf("unknown azure environment, expected one of %s, %s, %s or %s", AzurePublicCloud, AzureUSGovernmentCloud, AzureChinaCloud, AzureStack)
+var MissingResourceManagerEndpointErr = fmt.Errorf("a resource manager endpoint is required for %s", AzureStack)
+
+// NewEnvironment - resolves the named environment. An empty name means
+// AzurePublicCloud. AzureStack environments are discovered from the stack's
+// metadata endpoint, which defaults to the one published under the resource
+// manager endpoint. For the other clouds an overridden resource manager
+// endpoint is also the audience tokens are requested for.
+func NewEnvironment(name string, resourceManagerEndpoint string, metadataEndpoint string) (Environment, error) {
+	if name == "" {
+		name = AzurePublicCloud
+	}
+
+	if strings.EqualFold(name, AzureStack) {
+		return newAzureStackEnvironment(resourceManagerEndpoint, metadataEndpoint)
+	}
+
+	environment, ok := knownEnvironments[strings.ToLower(name)]
+	if !ok {
+		return Environment{}, UnknownEnvironmentErr
+	}
+
+	if resourceManagerEndpoint != "" {
+		if !strings.HasSuffix(resourceManagerEndpoint, "/") {
+			resourceManagerEndpoint += "/"
+		}
+		environment.ResourceManagerEndpoint = resourceManagerEndpoint
+		environment.TokenAudience = resourceManagerEndpoint
+	}
+	return environment, nil
+}
+
+type azureStackMetadata struct {
+	Authentication struct {
+		LoginEndpoint string   `json:"loginEndpoint"`
+		Audiences     []string `json:"audiences"`
+	} `json:"authentication"`
+}
+
+func newAzureStackEnvironment(resourceManagerEndpoint string, metadataEndpoint string) (Environment, error) {
+	if resourceManagerEndpoint == "" {
+		return Environment{}, MissingResourceManagerEndpointErr
+	}
+
+	if !strings.HasSuffix(resourceManagerEndpoint, "/") {
+		resourceManagerEndpoint += "/"
+	}
+
+	if metadataEndpoint == "" {
+		metadataEndpoint = resourceManagerEndpoint + azureStackMetadataPath
+	}
+
+	storageEndpointSuffix, err := storageSuffixFromEndpoint(resourceManagerEndpoint)
+	if err != nil {
+		return Environment{}, errwrap.Wrap(err, "could not derive a storage endpoint suffix")
+	}
+
+	httpClient := &http.Client{Timeout: 30 * time.Second}
+	resp, err := httpClient.Get(metadataEndpoint)
+	if err != nil {
+		return Environment{}, errwrap.Wrap(err, "failed fetching azure stack metadata")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Environment{}, fmt.Errorf("azure stack metadata endpoint returned %s", resp.Status)
+	}
+
+	var metadata azureStackMetadata
+	err = json.NewDecoder(resp.Body).Decode(&metadata)
+	if err != nil {
+		return Environment{}, errwrap.Wrap(err, "failed decoding azure stack metadata")
+	}
+
+	if metadata.Authentication.LoginEndpoint == "" || len(metadata.Authentication.Audiences) == 0 {
+		return Environment{}, fmt.Errorf("azure stack metadata is missing authentication endpoints")
+	}
+
+	activeDirectoryEndpoint := metadata.Authentication.LoginEndpoint
+	if !strings.HasSuffix(activeDirectoryEndpoint, "/") {
+		activeDirectoryEndpoint += "/"
+	}
+
+	return Environment{
+		Name:                    AzureStack,
+		ActiveDirectoryEndpoint: activeDirectoryEndpoint,
+		ResourceManagerEndpoint: resourceManagerEndpoint,
+		TokenAudience:           metadata.Authentication.Audiences[0],
+		StorageEndpointSuffix:   storageEndpointSuffix,
+	}, nil
+}
+
+// storageSuffixFromEndpoint turns https://management.region.stack.example/
+// into region.stack.example
+func storageSuffixFromEndpoint(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	labels := strings.SplitN(u.Hostname(), ".", 2)
+	if len(labels) != 2 || labels[1] == "" {
+		return "", fmt.Errorf("endpoint host %q has no domain", u.Hostname())
+	}
+	return labels[1], nil
+}
@@ -0,0 +1,134 @@
+package azure_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/cliaas/iaas/azure"
+)
+
+var _ = Describe("Environment", func() {
+	Describe("NewEnvironment()", func() {
+		Context("when no environment name is given", func() {
+			It("should default to the public cloud", func() {
+				environment, err := azure.NewEnvironment("", "", "")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(environment.Name).Should(Equal(azure.AzurePublicCloud))
+				Expect(environment.ActiveDirectoryEndpoint).Should(Equal("https://login.microsoftonline.com/"))
+				Expect(environment.ResourceManagerEndpoint).Should(Equal("https://management.azure.com/"))
+				Expect(environment.StorageEndpointSuffix).Should(Equal(azure.DefaultBaseURL))
+			})
+		})
+
+		Context("when given the us government cloud", func() {
+			It("should derive all endpoints from the government cloud", func() {
+				environment, err := azure.NewEnvironment(azure.AzureUSGovernmentCloud, "", "")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(environment.ActiveDirectoryEndpoint).Should(Equal("https://login.microsoftonline.us/"))
+				Expect(environment.ResourceManagerEndpoint).Should(Equal("https://management.usgovcloudapi.net/"))
+				Expect(environment.TokenAudience).Should(Equal("https://management.usgovcloudapi.net/"))
+				Expect(environment.StorageEndpointSuffix).Should(Equal("core.usgovcloudapi.net"))
+			})
+		})
+
+		Context("when given the china cloud in any case", func() {
+			It("should derive all endpoints from the china cloud", func() {
+				environment, err := azure.NewEnvironment("azurechinacloud", "", "")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(environment.Name).Should(Equal(azure.AzureChinaCloud))
+				Expect(environment.ActiveDirectoryEndpoint).Should(Equal("https://login.chinacloudapi.cn/"))
+				Expect(environment.StorageEndpointSuffix).Should(Equal("core.chinacloudapi.cn"))
+			})
+		})
+
+		Context("when given a resource manager endpoint override", func() {
+			It("should use it in place of the environment default", func() {
+				environment, err := azure.NewEnvironment(azure.AzurePublicCloud, "https://some.endpoint/", "")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(environment.ResourceManagerEndpoint).Should(Equal("https://some.endpoint/"))
+			})
+
+			It("should request tokens for the overridden endpoint", func() {
+				environment, err := azure.NewEnvironment(azure.AzureUSGovernmentCloud, "https://some.endpoint", "")
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(environment.ResourceManagerEndpoint).Should(Equal("https://some.endpoint/"))
+				Expect(environment.TokenAudience).Should(Equal("https://some.endpoint/"))
+			})
+		})
+
+		Context("when given an unknown environment", func() {
+			It("should return an error", func() {
+				_, err := azure.NewEnvironment("AzureMoonCloud", "", "")
+				Expect(err).Should(Equal(azure.UnknownEnvironmentErr))
+			})
+		})
+
+		Describe("Azure Stack", func() {
+			var server *httptest.Server
+			var metadataResponse string
+			var requestedPaths []string
+
+			BeforeEach(func() {
+				requestedPaths = nil
+				metadataResponse = `{
+					"galleryEndpoint": "https://portal.local.azurestack.external:30015/",
+					"graphEndpoint": "https://graph.windows.net/",
+					"authentication": {
+						"loginEndpoint": "https://login.windows.net",
+						"audiences": ["https://management.stack.onmicrosoft.com/abc"]
+					}
+				}`
+				server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					requestedPaths = append(requestedPaths, r.URL.RequestURI())
+					w.Write([]byte(metadataResponse))
+				}))
+			})
+
+			AfterEach(func() {
+				server.Close()
+			})
+
+			Context("when given a custom metadata endpoint", func() {
+				It("should derive the endpoints from the stack metadata", func() {
+					environment, err := azure.NewEnvironment(azure.AzureStack, "https://management.local.azurestack.external", server.URL+"/custom")
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(requestedPaths).Should(Equal([]string{"/custom"}))
+					Expect(environment.Name).Should(Equal(azure.AzureStack))
+					Expect(environment.ActiveDirectoryEndpoint).Should(Equal("https://login.windows.net/"))
+					Expect(environment.ResourceManagerEndpoint).Should(Equal("https://management.local.azurestack.external/"))
+					Expect(environment.TokenAudience).Should(Equal("https://management.stack.onmicrosoft.com/abc"))
+					Expect(environment.StorageEndpointSuffix).Should(Equal("local.azurestack.external"))
+				})
+			})
+
+			Context("when no metadata endpoint is given", func() {
+				It("should use the metadata published under the resource manager endpoint", func() {
+					_, err := azure.NewEnvironment(azure.AzureStack, server.URL, "")
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(requestedPaths).Should(Equal([]string{"/metadata/endpoints?api-version=2015-01-01"}))
+				})
+			})
+
+			Context("when the metadata has no authentication section", func() {
+				BeforeEach(func() {
+					metadataResponse = `{}`
+				})
+
+				It("should return an error", func() {
+					_, err := azure.NewEnvironment(azure.AzureStack, "https://management.local.azurestack.external", server.URL)
+					Expect(err).Should(HaveOccurred())
+				})
+			})
+
+			Context("when no resource manager endpoint is given", func() {
+				It("should return an error", func() {
+					_, err := azure.NewEnvironment(azure.AzureStack, "", server.URL)
+					Expect(err).Should(Equal(azure.MissingResourceManagerEndpointErr))
+				})
+			})
+		})
+	})
+})
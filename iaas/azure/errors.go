@@ -0,0 +1,85 @@
+package azure
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest"
+	autorestazure "github.com/Azure/go-autorest/autorest/azure"
+	"github.com/pivotal-cf/cliaas/iaas"
+)
+
+// ClassifyError marks an error from the azure resource manager as retryable,
+// fatal or not found by its status code. retryAfter is what the throttled
+// response asked for, if anything.
+func ClassifyError(err error, retryAfter time.Duration) error {
+	if err == nil {
+		return nil
+	}
+
+	statusCode, ok := statusCodeOf(err)
+	if !ok {
+		return err
+	}
+
+	switch iaas.ClassifyStatusCode(statusCode) {
+	case iaas.Retryable:
+		return iaas.NewRetryableError(err, retryAfter)
+	case iaas.NotFound:
+		return iaas.NewNotFoundError(err)
+	default:
+		return iaas.NewFatalError(err)
+	}
+}
+
+func statusCodeOf(err error) (int, bool) {
+	switch e := err.(type) {
+	case autorest.DetailedError:
+		statusCode, ok := e.StatusCode.(int)
+		return statusCode, ok
+	case *autorest.DetailedError:
+		statusCode, ok := e.StatusCode.(int)
+		return statusCode, ok
+	case autorestazure.RequestError:
+		statusCode, ok := e.StatusCode.(int)
+		return statusCode, ok
+	case *autorestazure.RequestError:
+		statusCode, ok := e.StatusCode.(int)
+		return statusCode, ok
+	}
+	return 0, false
+}
+
+// retryAfterRecorder remembers the Retry-After of the last response, since the
+// errors the sdk returns do not carry the response headers
+type retryAfterRecorder struct {
+	mutex      sync.Mutex
+	retryAfter time.Duration
+}
+
+func (r *retryAfterRecorder) ByRecording() autorest.RespondDecorator {
+	return func(responder autorest.Responder) autorest.Responder {
+		return autorest.ResponderFunc(func(resp *http.Response) error {
+			if resp != nil {
+				r.mutex.Lock()
+				r.retryAfter = 0
+				if iaas.ClassifyStatusCode(resp.StatusCode) == iaas.Retryable {
+					r.retryAfter = iaas.ParseRetryAfter(resp.Header, time.Now())
+				}
+				r.mutex.Unlock()
+			}
+			return responder.Respond(resp)
+		})
+	}
+}
+
+func (r *retryAfterRecorder) RetryAfter() time.Duration {
+	if r == nil {
+		return 0
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.retryAfter
+}
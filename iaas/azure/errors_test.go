@@ -0,0 +1,33 @@
+package azure_test
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/cliaas/iaas"
+	"github.com/pivotal-cf/cliaas/iaas/azure"
+)
+
+var _ = Describe("ClassifyError", func() {
+	It("marks throttled calls as retryable after the given delay", func() {
+		err := azure.ClassifyError(autorest.DetailedError{StatusCode: http.StatusTooManyRequests}, 17*time.Second)
+		Expect(iaas.ClassOf(err)).To(Equal(iaas.Retryable))
+		Expect(iaas.RetryAfterOf(err)).To(Equal(17 * time.Second))
+	})
+
+	It("classifies missing resources and bad requests", func() {
+		Expect(iaas.ClassOf(azure.ClassifyError(autorest.DetailedError{StatusCode: http.StatusNotFound}, 0))).To(Equal(iaas.NotFound))
+		Expect(iaas.ClassOf(azure.ClassifyError(autorest.DetailedError{StatusCode: http.StatusConflict}, 0))).To(Equal(iaas.Fatal))
+	})
+
+	It("leaves errors without a status code alone", func() {
+		controlErr := errors.New("some error")
+		Expect(azure.ClassifyError(controlErr, 0)).To(Equal(controlErr))
+		Expect(azure.ClassifyError(nil, 0)).To(BeNil())
+	})
+})
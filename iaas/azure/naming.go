@@ -0,0 +1,43 @@
+package azure
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// vmNamePattern is what azure allows in linux vm names
+var vmNamePattern = regexp.MustCompile(`^[a-zA-Z0-9]([-a-zA-Z0-9._]{0,62}[-a-zA-Z0-9_])?$`)
+
+// nicNamePattern is what azure allows in network interface names
+var nicNamePattern = regexp.MustCompile(`^[a-zA-Z0-9]([-a-zA-Z0-9._]{0,78}[a-zA-Z0-9_])?$`)
+
+// maxBlobNameLength leaves room for the .vhd extension
+const maxBlobNameLength = 1020
+
+// ValidateVMName checks a new vm name against azure's rules
+func ValidateVMName(name string) error {
+	if !vmNamePattern.MatchString(name) {
+		return fmt.Errorf("%q is not a valid azure vm name: it must be 1 to 64 letters, digits, dashes, underscores or periods, start with a letter or digit and not end with a period", name)
+	}
+	return nil
+}
+
+// ValidateNICName checks a new network interface name against azure's rules
+func ValidateNICName(name string) error {
+	if !nicNamePattern.MatchString(name) {
+		return fmt.Errorf("%q is not a valid azure network interface name: it must be 1 to 80 letters, digits, dashes, underscores or periods, start with a letter or digit and end with a letter, digit or underscore", name)
+	}
+	return nil
+}
+
+// ValidateBlobName checks a new disk blob name against azure's rules
+func ValidateBlobName(name string) error {
+	if name == "" || len(name) > maxBlobNameLength {
+		return fmt.Errorf("%q is not a valid azure blob name: it must be 1 to %d characters", name, maxBlobNameLength)
+	}
+	if strings.HasSuffix(name, ".") || strings.HasSuffix(name, "/") {
+		return fmt.Errorf("%q is not a valid azure blob name: it must not end with a period or slash", name)
+	}
+	return nil
+}
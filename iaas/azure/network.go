@@ -0,0 +1,371 @@
+package azure
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/arm/compute"
+	"github.com/Azure/azure-sdk-for-go/arm/network"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/pivotal-cf/cliaas/iaas"
+	errwrap "github.com/pkg/errors"
+)
+
+// NetworkInterfacesClient is the part of the network api that manages the
+// nics vms are attached to
+type NetworkInterfacesClient interface {
+	Get(resourceGroupName string, networkInterfaceName string, expand string) (result network.Interface, err error)
+	CreateOrUpdate(resourceGroupName string, networkInterfaceName string, parameters network.Interface, cancel <-chan struct{}) (result autorest.Response, err error)
+	Delete(resourceGroupName string, networkInterfaceName string, cancel <-chan struct{}) (result autorest.Response, err error)
+}
+
+// PublicIPAddressesClient is the part of the network api that reads the
+// public ip resources nics point at
+type PublicIPAddressesClient interface {
+	Get(resourceGroupName string, publicIPAddressName string, expand string) (result network.PublicIPAddress, err error)
+	List(resourceGroupName string) (result network.PublicIPAddressListResult, err error)
+}
+
+// nicConfig is what a vm's nic holds: the subnet and network security group
+// a nic for the vm is created in, and the addresses that move to it
+type nicConfig struct {
+	ID                  string
+	Location            string
+	SubnetID            string
+	SecurityGroupID     string
+	PrivateIP           string
+	PrivateIPAllocation network.IPAllocationMethod
+	PublicIPID          string
+}
+
+// privateIPAllocation is how a nic for the config gets its private ip: as the
+// nic the config was read from did, and otherwise statically when the config
+// has a private ip
+func (c nicConfig) privateIPAllocation() network.IPAllocationMethod {
+	if c.PrivateIPAllocation != "" {
+		return c.PrivateIPAllocation
+	}
+	if c.PrivateIP != "" {
+		return network.Static
+	}
+	return network.Dynamic
+}
+
+// primaryNICID is the id of the nic the vm is reached on
+func primaryNICID(instance compute.VirtualMachine) (string, error) {
+	if instance.VirtualMachineProperties == nil ||
+		instance.VirtualMachineProperties.NetworkProfile == nil ||
+		instance.VirtualMachineProperties.NetworkProfile.NetworkInterfaces == nil ||
+		len(*instance.VirtualMachineProperties.NetworkProfile.NetworkInterfaces) == 0 {
+		return "", errors.New("vm has no network interface")
+	}
+
+	nics := *instance.VirtualMachineProperties.NetworkProfile.NetworkInterfaces
+	for _, nic := range nics {
+		properties := nic.NetworkInterfaceReferenceProperties
+		if nic.ID != nil && properties != nil && properties.Primary != nil && *properties.Primary {
+			return *nic.ID, nil
+		}
+	}
+	if nics[0].ID == nil {
+		return "", errors.New("vm has no network interface")
+	}
+	return *nics[0].ID, nil
+}
+
+// inspectNIC reads the nic with the id and what its primary ip configuration
+// holds
+func (s *Client) inspectNIC(nicID string) (nicConfig, error) {
+	if s.NetworkInterfacesClient == nil {
+		return nicConfig{}, InvalidAzureClientErr
+	}
+
+	resourceGroupName, name := resourceGroupAndName(nicID)
+	nic, err := s.NetworkInterfacesClient.Get(resourceGroupName, name, "")
+	if err != nil {
+		return nicConfig{}, errwrap.Wrapf(err, "could not get network interface %s", name)
+	}
+
+	config := nicConfig{ID: nicID}
+	if nic.Location != nil {
+		config.Location = *nic.Location
+	}
+	properties := nic.InterfacePropertiesFormat
+	if properties == nil {
+		return config, nil
+	}
+	if properties.NetworkSecurityGroup != nil && properties.NetworkSecurityGroup.ID != nil {
+		config.SecurityGroupID = *properties.NetworkSecurityGroup.ID
+	}
+
+	ipConfiguration := primaryIPConfiguration(nic)
+	if ipConfiguration == nil || ipConfiguration.InterfaceIPConfigurationPropertiesFormat == nil {
+		return config, nil
+	}
+	ipProperties := ipConfiguration.InterfaceIPConfigurationPropertiesFormat
+	if ipProperties.Subnet != nil && ipProperties.Subnet.ID != nil {
+		config.SubnetID = *ipProperties.Subnet.ID
+	}
+	if ipProperties.PrivateIPAddress != nil {
+		config.PrivateIP = *ipProperties.PrivateIPAddress
+	}
+	config.PrivateIPAllocation = ipProperties.PrivateIPAllocationMethod
+	if ipProperties.PublicIPAddress != nil && ipProperties.PublicIPAddress.ID != nil {
+		config.PublicIPID = *ipProperties.PublicIPAddress.ID
+	}
+	return config, nil
+}
+
+// describeNIC fills in the network of the spec from its nic. A client
+// without a network interfaces client leaves the spec with just the nic.
+func (s *Client) describeNIC(spec *iaas.VMSpec) error {
+	if s.NetworkInterfacesClient == nil {
+		return nil
+	}
+	nic, err := s.inspectNIC(spec.Network.Interface)
+	if err != nil {
+		return err
+	}
+
+	spec.Network.Subnet = nic.SubnetID
+	spec.Network.PrivateIP = nic.PrivateIP
+	if nic.SecurityGroupID != "" {
+		spec.Network.SecurityGroups = []string{nic.SecurityGroupID}
+	}
+	if nic.PublicIPID != "" {
+		spec.Network.PublicIP = nic.PublicIPID
+		address, err := s.publicIPAddress(nic.PublicIPID)
+		if err != nil {
+			s.getLogger().Warn("could not read the public ip, describing it by its id", iaas.Fields{
+				"public_ip": nic.PublicIPID,
+				"error":     err.Error(),
+			})
+		} else if address != "" {
+			spec.Network.PublicIP = address
+		}
+	}
+	return nil
+}
+
+// primaryIPConfiguration is the ip configuration the nic's addresses are on
+func primaryIPConfiguration(nic network.Interface) *network.InterfaceIPConfiguration {
+	if nic.InterfacePropertiesFormat == nil ||
+		nic.InterfacePropertiesFormat.IPConfigurations == nil ||
+		len(*nic.InterfacePropertiesFormat.IPConfigurations) == 0 {
+		return nil
+	}
+
+	ipConfigurations := *nic.InterfacePropertiesFormat.IPConfigurations
+	for i, ipConfiguration := range ipConfigurations {
+		properties := ipConfiguration.InterfaceIPConfigurationPropertiesFormat
+		if properties != nil && properties.Primary != nil && *properties.Primary {
+			return &ipConfigurations[i]
+		}
+	}
+	return &ipConfigurations[0]
+}
+
+// createNIC creates a nic in the subnet and network security group of the
+// config, and returns its id. The nic takes the config's private ip when it
+// is allocated statically, or a dynamic one otherwise, and its public ip.
+func (s *Client) createNIC(name string, config nicConfig) (string, error) {
+	if s.NetworkInterfacesClient == nil {
+		return "", InvalidAzureClientErr
+	}
+
+	ipProperties := &network.InterfaceIPConfigurationPropertiesFormat{
+		PrivateIPAllocationMethod: config.privateIPAllocation(),
+		Subnet:                    &network.Subnet{ID: to.StringPtr(config.SubnetID)},
+		Primary:                   to.BoolPtr(true),
+	}
+	if ipProperties.PrivateIPAllocationMethod == network.Static {
+		ipProperties.PrivateIPAddress = to.StringPtr(config.PrivateIP)
+	}
+	if config.PublicIPID != "" {
+		ipProperties.PublicIPAddress = &network.PublicIPAddress{ID: to.StringPtr(config.PublicIPID)}
+	}
+
+	nic := network.Interface{
+		Location: to.StringPtr(config.Location),
+		InterfacePropertiesFormat: &network.InterfacePropertiesFormat{
+			IPConfigurations: &[]network.InterfaceIPConfiguration{
+				{
+					Name:                                     to.StringPtr("ipconfig1"),
+					InterfaceIPConfigurationPropertiesFormat: ipProperties,
+				},
+			},
+		},
+	}
+	if config.SecurityGroupID != "" {
+		nic.InterfacePropertiesFormat.NetworkSecurityGroup = &network.SecurityGroup{ID: to.StringPtr(config.SecurityGroupID)}
+	}
+
+	cancel, stop := s.getWaiter().CancelAfter(s.getWaiter().Timeouts.Create)
+	defer stop()
+	_, err := s.NetworkInterfacesClient.CreateOrUpdate(s.resourceGroupName, name, nic, cancel)
+	if err != nil {
+		return "", errwrap.Wrapf(err, "could not create network interface %s", name)
+	}
+
+	created, err := s.NetworkInterfacesClient.Get(s.resourceGroupName, name, "")
+	if err != nil {
+		return "", errwrap.Wrapf(err, "could not get network interface %s", name)
+	}
+	if created.ID == nil {
+		return "", fmt.Errorf("network interface %s has no id", name)
+	}
+	return *created.ID, nil
+}
+
+// createSpecNIC creates a nic in the spec's subnet, with its private ip, its
+// first security group and the public ip resource with its public address
+func (s *Client) createSpecNIC(name string, spec iaas.VMSpec) (string, error) {
+	config := nicConfig{
+		Location:  spec.Zone,
+		SubnetID:  spec.Network.Subnet,
+		PrivateIP: spec.Network.PrivateIP,
+	}
+	if len(spec.Network.SecurityGroups) > 0 {
+		config.SecurityGroupID = spec.Network.SecurityGroups[0]
+	}
+	if spec.Network.PublicIP != "" {
+		publicIPID, err := s.publicIPID(spec.Network.PublicIP)
+		if err != nil {
+			return "", err
+		}
+		config.PublicIPID = publicIPID
+	}
+	return s.createNIC(name, config)
+}
+
+// deleteNIC deletes the nic with the id, which releases its addresses
+func (s *Client) deleteNIC(nicID string) error {
+	resourceGroupName, name := resourceGroupAndName(nicID)
+	cancel, stop := s.getWaiter().CancelAfter(s.getWaiter().Timeouts.Stop)
+	defer stop()
+	_, err := s.NetworkInterfacesClient.Delete(resourceGroupName, name, cancel)
+	if err != nil {
+		return errwrap.Wrapf(err, "could not delete network interface %s", name)
+	}
+	return nil
+}
+
+// moveAddresses gives the new nic the static private ip and the public ip of
+// the old nic. The old nic holds on to its addresses until it is deleted, so
+// it is deleted first; its vm must already be gone.
+func (s *Client) moveAddresses(oldNIC nicConfig, newNICID string) error {
+	err := s.deleteNIC(oldNIC.ID)
+	if err != nil {
+		return err
+	}
+
+	err = s.assignAddresses(oldNIC, newNICID)
+	if err != nil {
+		return s.keepAddresses(oldNIC, err)
+	}
+	return nil
+}
+
+// keepAddresses recreates the deleted nic with the addresses that could not
+// move, so that they are not given up
+func (s *Client) keepAddresses(oldNIC nicConfig, moveErr error) error {
+	addresses := fmt.Sprintf("private ip %q and public ip %q", oldNIC.PrivateIP, oldNIC.PublicIPID)
+	_, name := resourceGroupAndName(oldNIC.ID)
+	_, err := s.createNIC(name, oldNIC)
+	if err != nil {
+		s.getLogger().Error("could not recreate the network interface of the old vm", iaas.Fields{
+			"network_interface": name,
+			"error":             err.Error(),
+		})
+		return errwrap.Wrapf(moveErr, "%s are lost, they could not move to the new vm", addresses)
+	}
+	return errwrap.Wrapf(moveErr, "%s are kept on network interface %s, they could not move to the new vm", addresses, name)
+}
+
+// assignAddresses gives the nic with the id the addresses of the old nic. A
+// dynamic private ip is not kept, the nic keeps the one it has.
+func (s *Client) assignAddresses(oldNIC nicConfig, newNICID string) error {
+	resourceGroupName, name := resourceGroupAndName(newNICID)
+	nic, err := s.NetworkInterfacesClient.Get(resourceGroupName, name, "")
+	if err != nil {
+		return errwrap.Wrapf(err, "could not get network interface %s", name)
+	}
+	ipConfiguration := primaryIPConfiguration(nic)
+	if ipConfiguration == nil || ipConfiguration.InterfaceIPConfigurationPropertiesFormat == nil {
+		return fmt.Errorf("network interface %s has no ip configuration", name)
+	}
+
+	ipProperties := ipConfiguration.InterfaceIPConfigurationPropertiesFormat
+	if oldNIC.privateIPAllocation() == network.Static {
+		ipProperties.PrivateIPAllocationMethod = network.Static
+		ipProperties.PrivateIPAddress = to.StringPtr(oldNIC.PrivateIP)
+	}
+	if oldNIC.PublicIPID != "" {
+		ipProperties.PublicIPAddress = &network.PublicIPAddress{ID: to.StringPtr(oldNIC.PublicIPID)}
+	}
+
+	cancel, stop := s.getWaiter().CancelAfter(s.getWaiter().Timeouts.IPAssociation)
+	defer stop()
+	_, err = s.NetworkInterfacesClient.CreateOrUpdate(resourceGroupName, name, nic, cancel)
+	if err != nil {
+		return errwrap.Wrapf(err, "could not move the addresses of the old vm to network interface %s", name)
+	}
+	return nil
+}
+
+// publicIPAddress is the address of the public ip resource with the id
+func (s *Client) publicIPAddress(publicIPID string) (string, error) {
+	if s.PublicIPAddressesClient == nil {
+		return "", InvalidAzureClientErr
+	}
+
+	resourceGroupName, name := resourceGroupAndName(publicIPID)
+	publicIP, err := s.PublicIPAddressesClient.Get(resourceGroupName, name, "")
+	if err != nil {
+		return "", errwrap.Wrapf(err, "could not get public ip %s", name)
+	}
+	if publicIP.PublicIPAddressPropertiesFormat == nil || publicIP.PublicIPAddressPropertiesFormat.IPAddress == nil {
+		return "", nil
+	}
+	return *publicIP.PublicIPAddressPropertiesFormat.IPAddress, nil
+}
+
+// publicIPID is the id of the public ip resource in the resource group with
+// the address, or the address itself when it already is an id
+func (s *Client) publicIPID(address string) (string, error) {
+	if strings.HasPrefix(address, "/") {
+		return address, nil
+	}
+	if s.PublicIPAddressesClient == nil {
+		return "", InvalidAzureClientErr
+	}
+
+	list, err := s.PublicIPAddressesClient.List(s.resourceGroupName)
+	if err != nil {
+		return "", errwrap.Wrap(err, "could not list public ips")
+	}
+	if list.Value != nil {
+		for _, publicIP := range *list.Value {
+			properties := publicIP.PublicIPAddressPropertiesFormat
+			if publicIP.ID != nil && properties != nil && properties.IPAddress != nil && *properties.IPAddress == address {
+				return *publicIP.ID, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no public ip resource in resource group %s has the address %s", s.resourceGroupName, address)
+}
+
+// resourceGroupAndName splits the resource group and name out of an azure
+// resource id
+func resourceGroupAndName(id string) (string, string) {
+	segments := strings.Split(id, "/")
+	var resourceGroupName string
+	for i := 0; i+1 < len(segments); i++ {
+		if strings.EqualFold(segments[i], "resourceGroups") {
+			resourceGroupName = segments[i+1]
+		}
+	}
+	return resourceGroupName, segments[len(segments)-1]
+}
@@ -0,0 +1,161 @@
+package azure
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest"
+	autorestazure "github.com/Azure/go-autorest/autorest/azure"
+	"github.com/pivotal-cf/cliaas/iaas"
+	errwrap "github.com/pkg/errors"
+)
+
+const permissionsAPIVersion = "2015-07-01"
+
+// RequiredActions are the resource group actions a replace or delete of a vm
+// performs
+var RequiredActions = []string{
+	"Microsoft.Compute/virtualMachines/read",
+	"Microsoft.Compute/virtualMachines/write",
+	"Microsoft.Compute/virtualMachines/delete",
+	"Microsoft.Compute/virtualMachines/deallocate/action",
+	"Microsoft.Network/networkInterfaces/read",
+	"Microsoft.Network/networkInterfaces/write",
+	"Microsoft.Network/networkInterfaces/delete",
+	"Microsoft.Network/networkInterfaces/join/action",
+	"Microsoft.Network/publicIPAddresses/read",
+	"Microsoft.Network/publicIPAddresses/join/action",
+	"Microsoft.Network/virtualNetworks/subnets/join/action",
+	"Microsoft.Network/networkSecurityGroups/join/action",
+}
+
+// Permission is one entry of the effective permissions the caller holds on a
+// scope, as the sum of its role assignments
+type Permission struct {
+	Actions    []string `json:"actions"`
+	NotActions []string `json:"notActions"`
+}
+
+type PermissionsClient interface {
+	ListForResourceGroup(resourceGroupName string) ([]Permission, error)
+}
+
+// CheckPermissions matches the caller's effective permissions on the resource
+// group against the required actions, and checks that the storage container
+// the vhd is copied into can be read
+func (s *Client) CheckPermissions(identifier string, vhdURL string) []iaas.PermissionCheck {
+	var checks []iaas.PermissionCheck
+
+	var permissions []Permission
+	var err error
+	if s.PermissionsClient == nil {
+		err = InvalidAzureClientErr
+	} else {
+		permissions, err = s.PermissionsClient.ListForResourceGroup(s.resourceGroupName)
+		if err != nil {
+			err = errwrap.Wrapf(err, "could not list permissions on resource group %s", s.resourceGroupName)
+		}
+	}
+
+	for _, action := range RequiredActions {
+		check := iaas.PermissionCheck{Permission: action, Err: err}
+		if err == nil && !allowsAction(permissions, action) {
+			check.Err = fmt.Errorf("not granted on resource group %s", s.resourceGroupName)
+		}
+		checks = append(checks, check)
+	}
+
+	return append(checks, s.checkStorageContainer())
+}
+
+func (s *Client) checkStorageContainer() iaas.PermissionCheck {
+	check := iaas.PermissionCheck{Permission: fmt.Sprintf("storage container %s/%s", s.storageAccountName, s.storageContainerName)}
+	if s.BlobServiceClient == nil {
+		check.Err = InvalidAzureClientErr
+		return check
+	}
+
+	exists, err := s.BlobServiceClient.ContainerExists(s.storageContainerName)
+	if err != nil {
+		check.Err = errwrap.Wrap(err, "could not read storage container")
+	} else if !exists {
+		check.Err = fmt.Errorf("storage container %s does not exist", s.storageContainerName)
+	}
+	return check
+}
+
+func allowsAction(permissions []Permission, action string) bool {
+	for _, permission := range permissions {
+		if matchesAnyAction(permission.Actions, action) && !matchesAnyAction(permission.NotActions, action) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAnyAction(patterns []string, action string) bool {
+	for _, pattern := range patterns {
+		expression := "(?i)^" + strings.Replace(regexp.QuoteMeta(pattern), `\*`, ".*", -1) + "$"
+		if regexp.MustCompile(expression).MatchString(action) {
+			return true
+		}
+	}
+	return false
+}
+
+type permissionsClient struct {
+	autorest.Client
+	baseURI        string
+	subscriptionID string
+}
+
+// NewPermissionsClient returns a client for the Microsoft.Authorization
+// permissions api, which the vendored sdk does not include
+func NewPermissionsClient(baseURI string, subscriptionID string, authorizer autorest.Authorizer) PermissionsClient {
+	client := permissionsClient{
+		Client:         autorest.NewClientWithUserAgent(""),
+		baseURI:        baseURI,
+		subscriptionID: subscriptionID,
+	}
+	client.Authorizer = authorizer
+	return client
+}
+
+func (c permissionsClient) ListForResourceGroup(resourceGroupName string) ([]Permission, error) {
+	pathParameters := map[string]interface{}{
+		"resourceGroupName": autorest.Encode("path", resourceGroupName),
+		"subscriptionId":    autorest.Encode("path", c.subscriptionID),
+	}
+	queryParameters := map[string]interface{}{
+		"api-version": permissionsAPIVersion,
+	}
+
+	req, err := autorest.Prepare(&http.Request{},
+		autorest.AsGet(),
+		autorest.WithBaseURL(c.baseURI),
+		autorest.WithPathParameters("/subscriptions/{subscriptionId}/resourceGroups/{resourceGroupName}/providers/Microsoft.Authorization/permissions", pathParameters),
+		autorest.WithQueryParameters(queryParameters))
+	if err != nil {
+		return nil, errwrap.Wrap(err, "failed preparing permissions request")
+	}
+
+	resp, err := autorest.SendWithSender(c, req)
+	if err != nil {
+		return nil, errwrap.Wrap(err, "permissions request failed")
+	}
+
+	var result struct {
+		Value []Permission `json:"value"`
+	}
+	err = autorest.Respond(resp,
+		c.ByInspecting(),
+		autorestazure.WithErrorUnlessStatusCode(http.StatusOK),
+		autorest.ByUnmarshallingJSON(&result),
+		autorest.ByClosing())
+	if err != nil {
+		return nil, errwrap.Wrap(err, "failed reading permissions response")
+	}
+	return result.Value, nil
+}
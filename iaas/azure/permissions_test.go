@@ -0,0 +1,100 @@
+package azure_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/cliaas/iaas/azure"
+	"github.com/pivotal-cf/cliaas/iaas/azure/azurefakes"
+)
+
+var _ = Describe("CheckPermissions()", func() {
+	var azureClient *azure.Client
+	var fakePermissionsClient *azurefakes.FakePermissionsClient
+	var fakeBlobServiceClient *azurefakes.FakeBlobCopier
+
+	BeforeEach(func() {
+		fakePermissionsClient = new(azurefakes.FakePermissionsClient)
+		fakeBlobServiceClient = new(azurefakes.FakeBlobCopier)
+		fakeBlobServiceClient.ContainerExistsReturns(true, nil)
+
+		azureClient = new(azure.Client)
+		azureClient.PermissionsClient = fakePermissionsClient
+		azureClient.BlobServiceClient = fakeBlobServiceClient
+		azureClient.SetStorageAccountName("myaccount")
+		azureClient.SetStorageContainerName("mycontainer")
+	})
+
+	results := func() map[string]string {
+		results := map[string]string{}
+		for _, check := range azureClient.CheckPermissions("ops-manager", "https://some/image.vhd") {
+			results[check.Permission] = check.Result()
+		}
+		return results
+	}
+
+	Context("when the role grants everything on the resource group", func() {
+		BeforeEach(func() {
+			fakePermissionsClient.ListForResourceGroupReturns([]azure.Permission{{Actions: []string{"*"}}}, nil)
+		})
+
+		It("should pass every check", func() {
+			Expect(results()).Should(Equal(map[string]string{
+				"Microsoft.Compute/virtualMachines/read":                "PASS",
+				"Microsoft.Compute/virtualMachines/write":               "PASS",
+				"Microsoft.Compute/virtualMachines/delete":              "PASS",
+				"Microsoft.Compute/virtualMachines/deallocate/action":   "PASS",
+				"Microsoft.Network/networkInterfaces/read":              "PASS",
+				"Microsoft.Network/networkInterfaces/write":             "PASS",
+				"Microsoft.Network/networkInterfaces/delete":            "PASS",
+				"Microsoft.Network/networkInterfaces/join/action":       "PASS",
+				"Microsoft.Network/publicIPAddresses/read":              "PASS",
+				"Microsoft.Network/publicIPAddresses/join/action":       "PASS",
+				"Microsoft.Network/virtualNetworks/subnets/join/action": "PASS",
+				"Microsoft.Network/networkSecurityGroups/join/action":   "PASS",
+				"storage container myaccount/mycontainer":               "PASS",
+			}))
+			Expect(fakeBlobServiceClient.ContainerExistsArgsForCall(0)).Should(Equal("mycontainer"))
+		})
+	})
+
+	Context("when the role excludes an action", func() {
+		BeforeEach(func() {
+			fakePermissionsClient.ListForResourceGroupReturns([]azure.Permission{{
+				Actions:    []string{"microsoft.compute/*", "Microsoft.Network/*/join/action"},
+				NotActions: []string{"Microsoft.Compute/virtualMachines/delete"},
+			}}, nil)
+		})
+
+		It("should fail only that action", func() {
+			checks := results()
+			Expect(checks["Microsoft.Compute/virtualMachines/delete"]).Should(Equal("FAIL"))
+			Expect(checks["Microsoft.Compute/virtualMachines/write"]).Should(Equal("PASS"))
+			Expect(checks["Microsoft.Network/networkInterfaces/join/action"]).Should(Equal("PASS"))
+		})
+	})
+
+	Context("when the storage container does not exist", func() {
+		BeforeEach(func() {
+			fakeBlobServiceClient.ContainerExistsReturns(false, nil)
+		})
+
+		It("should fail the storage check", func() {
+			Expect(results()["storage container myaccount/mycontainer"]).Should(Equal("FAIL"))
+		})
+	})
+
+	Context("when the permissions cannot be listed", func() {
+		BeforeEach(func() {
+			fakePermissionsClient.ListForResourceGroupReturns(nil, errors.New("AuthorizationFailed"))
+		})
+
+		It("should fail every role check", func() {
+			checks := results()
+			Expect(checks["Microsoft.Compute/virtualMachines/read"]).Should(Equal("FAIL"))
+			Expect(checks["storage container myaccount/mycontainer"]).Should(Equal("PASS"))
+		})
+	})
+})
@@ -0,0 +1,60 @@
+package azure
+
+import (
+	"github.com/Azure/azure-sdk-for-go/arm/network"
+	"github.com/Azure/go-autorest/autorest"
+)
+
+// retryingNetworkInterfacesClient makes every network interfaces api call
+// through a retryingCaller
+type retryingNetworkInterfacesClient struct {
+	retryingCaller
+	client NetworkInterfacesClient
+}
+
+func (c retryingNetworkInterfacesClient) Get(resourceGroupName string, networkInterfaceName string, expand string) (result network.Interface, err error) {
+	err = c.call("getting network interface "+networkInterfaceName, func() (autorest.Response, error) {
+		result, err = c.client.Get(resourceGroupName, networkInterfaceName, expand)
+		return result.Response, err
+	})
+	return result, err
+}
+
+func (c retryingNetworkInterfacesClient) CreateOrUpdate(resourceGroupName string, networkInterfaceName string, parameters network.Interface, cancel <-chan struct{}) (result autorest.Response, err error) {
+	err = c.call("updating network interface "+networkInterfaceName, func() (autorest.Response, error) {
+		result, err = c.client.CreateOrUpdate(resourceGroupName, networkInterfaceName, parameters, cancel)
+		return result, err
+	})
+	return result, err
+}
+
+func (c retryingNetworkInterfacesClient) Delete(resourceGroupName string, networkInterfaceName string, cancel <-chan struct{}) (result autorest.Response, err error) {
+	err = c.call("deleting network interface "+networkInterfaceName, func() (autorest.Response, error) {
+		result, err = c.client.Delete(resourceGroupName, networkInterfaceName, cancel)
+		return result, err
+	})
+	return result, err
+}
+
+// retryingPublicIPAddressesClient makes every public ip addresses api call
+// through a retryingCaller
+type retryingPublicIPAddressesClient struct {
+	retryingCaller
+	client PublicIPAddressesClient
+}
+
+func (c retryingPublicIPAddressesClient) Get(resourceGroupName string, publicIPAddressName string, expand string) (result network.PublicIPAddress, err error) {
+	err = c.call("getting public ip "+publicIPAddressName, func() (autorest.Response, error) {
+		result, err = c.client.Get(resourceGroupName, publicIPAddressName, expand)
+		return result.Response, err
+	})
+	return result, err
+}
+
+func (c retryingPublicIPAddressesClient) List(resourceGroupName string) (result network.PublicIPAddressListResult, err error) {
+	err = c.call("listing public ips", func() (autorest.Response, error) {
+		result, err = c.client.List(resourceGroupName)
+		return result.Response, err
+	})
+	return result, err
+}
@@ -0,0 +1,117 @@
+package azure
+
+import (
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/arm/compute"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/pivotal-cf/cliaas/iaas"
+)
+
+// correlationIDHeader identifies a request to azure support
+const correlationIDHeader = "x-ms-correlation-request-id"
+
+// retryingCaller classifies every api error, logs every call and retries the
+// retryable ones. The client is asked for its waiter and logger on every call,
+// so SetWaiter and SetLogger apply to calls made afterwards.
+type retryingCaller struct {
+	waiter   func() iaas.Waiter
+	logger   func() *iaas.Logger
+	recorder *retryAfterRecorder
+}
+
+// retryingVirtualMachinesClient makes every virtual machines api call through
+// a retryingCaller
+type retryingVirtualMachinesClient struct {
+	retryingCaller
+	client ComputeVirtualMachinesClient
+}
+
+func (c retryingCaller) call(description string, operation func() (autorest.Response, error)) error {
+	return c.waiter().Call(description, func() error {
+		start := time.Now()
+		response, err := operation()
+		err = ClassifyError(err, c.recorder.RetryAfter())
+		c.logger().APICall(description, time.Since(start), correlationID(response), err)
+		return err
+	})
+}
+
+func correlationID(response autorest.Response) string {
+	if response.Response == nil {
+		return ""
+	}
+	return response.Header.Get(correlationIDHeader)
+}
+
+func (c retryingVirtualMachinesClient) Get(resourceGroupName string, vmName string, expand compute.InstanceViewTypes) (result compute.VirtualMachine, err error) {
+	err = c.call("getting vm "+vmName, func() (autorest.Response, error) {
+		result, err = c.client.Get(resourceGroupName, vmName, expand)
+		return result.Response, err
+	})
+	return result, err
+}
+
+func (c retryingVirtualMachinesClient) ListAllNextResults(lastResults compute.VirtualMachineListResult) (result compute.VirtualMachineListResult, err error) {
+	err = c.call("listing vms", func() (autorest.Response, error) {
+		result, err = c.client.ListAllNextResults(lastResults)
+		return result.Response, err
+	})
+	return result, err
+}
+
+func (c retryingVirtualMachinesClient) CreateOrUpdate(resourceGroupName string, vmName string, parameters compute.VirtualMachine, cancel <-chan struct{}) (result autorest.Response, err error) {
+	err = c.call("creating vm "+vmName, func() (autorest.Response, error) {
+		result, err = c.client.CreateOrUpdate(resourceGroupName, vmName, parameters, cancel)
+		return result, err
+	})
+	return result, err
+}
+
+func (c retryingVirtualMachinesClient) Delete(resourceGroupName string, vmName string, cancel <-chan struct{}) (result autorest.Response, err error) {
+	err = c.call("deleting vm "+vmName, func() (autorest.Response, error) {
+		result, err = c.client.Delete(resourceGroupName, vmName, cancel)
+		return result, err
+	})
+	return result, err
+}
+
+func (c retryingVirtualMachinesClient) Deallocate(resourceGroupName string, vmName string, cancel <-chan struct{}) (result autorest.Response, err error) {
+	err = c.call("deallocating vm "+vmName, func() (autorest.Response, error) {
+		result, err = c.client.Deallocate(resourceGroupName, vmName, cancel)
+		return result, err
+	})
+	return result, err
+}
+
+func (c retryingVirtualMachinesClient) Start(resourceGroupName string, vmName string, cancel <-chan struct{}) (result autorest.Response, err error) {
+	err = c.call("starting vm "+vmName, func() (autorest.Response, error) {
+		result, err = c.client.Start(resourceGroupName, vmName, cancel)
+		return result, err
+	})
+	return result, err
+}
+
+func (c retryingVirtualMachinesClient) BeginDeallocate(resourceGroupName string, vmName string, cancel <-chan struct{}) (result autorest.Response, err error) {
+	err = c.call("beginning to deallocate vm "+vmName, func() (autorest.Response, error) {
+		result, err = c.client.BeginDeallocate(resourceGroupName, vmName, cancel)
+		return result, err
+	})
+	return result, err
+}
+
+func (c retryingVirtualMachinesClient) BeginStart(resourceGroupName string, vmName string, cancel <-chan struct{}) (result autorest.Response, err error) {
+	err = c.call("beginning to start vm "+vmName, func() (autorest.Response, error) {
+		result, err = c.client.BeginStart(resourceGroupName, vmName, cancel)
+		return result, err
+	})
+	return result, err
+}
+
+func (c retryingVirtualMachinesClient) List(resourceGroupName string) (result compute.VirtualMachineListResult, err error) {
+	err = c.call("listing vms", func() (autorest.Response, error) {
+		result, err = c.client.List(resourceGroupName)
+		return result.Response, err
+	})
+	return result, err
+}
@@ -0,0 +1,126 @@
+package iaas
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Drift is one field of a vm that is not as its spec says
+type Drift struct {
+	Field   string
+	Desired string
+	Live    string
+}
+
+func (d Drift) String() string {
+	return fmt.Sprintf("%s: want %q, have %q", d.Field, d.Desired, d.Live)
+}
+
+// DriftError lists every way a vm has drifted from its spec
+type DriftError struct {
+	Drifts []Drift
+}
+
+func (e *DriftError) Error() string {
+	var lines []string
+	for _, drift := range e.Drifts {
+		lines = append(lines, drift.String())
+	}
+	return "vm has drifted from its spec:\n  " + strings.Join(lines, "\n  ")
+}
+
+// Drift compares the live vm with the spec. Fields left empty in the spec are
+// not checked, nor are the ones every replace changes: the id, name, image,
+// disk sources, the Name tag and, when the spec has its subnet and addresses,
+// the nic.
+func (s VMSpec) Drift(live VMSpec) []Drift {
+	var drifts []Drift
+	check := func(field, desired, actual string) {
+		if desired != "" && desired != actual {
+			drifts = append(drifts, Drift{Field: field, Desired: desired, Live: actual})
+		}
+	}
+
+	check("iaas", s.IaaS, live.IaaS)
+	check("instance_type", s.InstanceType, live.InstanceType)
+	check("zone", s.Zone, live.Zone)
+	check("key_name", s.KeyName, live.KeyName)
+	check("identity", s.Identity, live.Identity)
+	check("network.network", s.Network.Network, live.Network.Network)
+	// an azure replace gives the new vm a new nic, so the nic only counts when
+	// the spec leaves out the subnet and addresses it holds
+	if s.Network.Subnet == "" || (s.Network.PrivateIP == "" && s.Network.PublicIP == "") {
+		check("network.interface", s.Network.Interface, live.Network.Interface)
+	}
+	check("network.subnet", s.Network.Subnet, live.Network.Subnet)
+	check("network.private_ip", s.Network.PrivateIP, live.Network.PrivateIP)
+	check("network.public_ip", s.Network.PublicIP, live.Network.PublicIP)
+	if s.Network.SecurityGroups != nil {
+		check("network.security_groups", sortedList(s.Network.SecurityGroups), sortedList(live.Network.SecurityGroups))
+	}
+
+	if s.Disks != nil {
+		liveDisks := map[string]DiskSpec{}
+		for _, disk := range live.Disks {
+			liveDisks[disk.Name] = disk
+		}
+		for _, disk := range s.Disks {
+			liveDisk, ok := liveDisks[disk.Name]
+			delete(liveDisks, disk.Name)
+			if !ok {
+				check("disks."+disk.Name, "present", "missing")
+				continue
+			}
+			if disk.SizeGB != 0 {
+				check("disks."+disk.Name+".size_gb", strconv.FormatInt(disk.SizeGB, 10), strconv.FormatInt(liveDisk.SizeGB, 10))
+			}
+			check("disks."+disk.Name+".type", disk.Type, liveDisk.Type)
+		}
+		var extra []string
+		for name := range liveDisks {
+			extra = append(extra, name)
+		}
+		sort.Strings(extra)
+		for _, name := range extra {
+			check("disks."+name, "missing", "present")
+		}
+	}
+
+	if s.Tags != nil {
+		var keys []string
+		for key := range s.Tags {
+			keys = append(keys, key)
+		}
+		for key := range live.Tags {
+			if _, ok := s.Tags[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if key == "Name" {
+				continue
+			}
+			desired, ok := s.Tags[key]
+			actual, liveOK := live.Tags[key]
+			switch {
+			case !ok:
+				check("tags."+key, "(unset)", actual)
+			case !liveOK:
+				check("tags."+key, desired, "(unset)")
+			default:
+				check("tags."+key, desired, actual)
+			}
+		}
+	}
+
+	return drifts
+}
+
+func sortedList(values []string) string {
+	sorted := append([]string{}, values...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
@@ -0,0 +1,82 @@
+package iaas_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/pivotal-cf/cliaas/iaas"
+)
+
+var _ = Describe("VMSpec", func() {
+	var desired VMSpec
+	var live VMSpec
+
+	BeforeEach(func() {
+		desired = VMSpec{
+			IaaS:         "aws",
+			Name:         "ops-manager",
+			ID:           "i-1",
+			InstanceType: "m4.large",
+			Network: NetworkSpec{
+				Subnet:         "subnet-1",
+				PrivateIP:      "10.0.0.5",
+				SecurityGroups: []string{"sg-2", "sg-1"},
+			},
+			Disks: []DiskSpec{{Name: "/dev/xvda", Boot: true, SizeGB: 100, Source: "vol-1"}},
+			Tags:  map[string]string{"Name": "ops-manager", "env": "prod"},
+		}
+		live = VMSpec{
+			IaaS:         "aws",
+			Name:         "ops-manager-2017-03-01",
+			ID:           "i-2",
+			InstanceType: "m4.large",
+			Zone:         "us-east-1a",
+			Network: NetworkSpec{
+				Subnet:         "subnet-1",
+				PrivateIP:      "10.0.0.5",
+				PublicIP:       "54.0.0.5",
+				SecurityGroups: []string{"sg-1", "sg-2"},
+			},
+			Disks: []DiskSpec{{Name: "/dev/xvda", Boot: true, SizeGB: 100, Type: "gp2", Source: "vol-2"}},
+			Tags:  map[string]string{"Name": "ops-manager-2017-03-01", "env": "prod"},
+		}
+	})
+
+	It("finds no drift in what a replace changes or the spec leaves out", func() {
+		Expect(desired.Drift(live)).To(BeEmpty())
+	})
+
+	It("checks the subnet and addresses instead of the nic a replace changes", func() {
+		desired.Network.Interface = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/networkInterfaces/ops-manager-nic"
+		live.Network.Interface = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/networkInterfaces/ops-manager-2017-03-01-nic"
+		Expect(desired.Drift(live)).To(BeEmpty())
+
+		desired.Network.Subnet = ""
+		Expect(desired.Drift(live)).To(Equal([]Drift{
+			{Field: "network.interface", Desired: desired.Network.Interface, Live: live.Network.Interface},
+		}))
+	})
+
+	It("reports every field that drifted", func() {
+		live.InstanceType = "m4.xlarge"
+		live.Network.PrivateIP = "10.0.0.6"
+		live.Network.SecurityGroups = []string{"sg-1"}
+		live.Disks[0].SizeGB = 200
+		live.Disks = append(live.Disks, DiskSpec{Name: "/dev/sdb", SizeGB: 10})
+		live.Tags = map[string]string{"team": "platform"}
+
+		Expect(desired.Drift(live)).To(Equal([]Drift{
+			{Field: "instance_type", Desired: "m4.large", Live: "m4.xlarge"},
+			{Field: "network.private_ip", Desired: "10.0.0.5", Live: "10.0.0.6"},
+			{Field: "network.security_groups", Desired: "sg-1,sg-2", Live: "sg-1"},
+			{Field: "disks./dev/xvda.size_gb", Desired: "100", Live: "200"},
+			{Field: "disks./dev/sdb", Desired: "missing", Live: "present"},
+			{Field: "tags.env", Desired: "prod", Live: "(unset)"},
+			{Field: "tags.team", Desired: "(unset)", Live: "platform"},
+		}))
+	})
+
+	It("lists the drift in its error", func() {
+		err := &DriftError{Drifts: []Drift{{Field: "instance_type", Desired: "m4.large", Live: "m4.xlarge"}}}
+		Expect(err).To(MatchError("vm has drifted from its spec:\n  instance_type: want \"m4.large\", have \"m4.xlarge\""))
+	})
+})
@@ -0,0 +1,139 @@
+package iaas
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrorClass says what cliaas can do about a failed IaaS call
+type ErrorClass int
+
+const (
+	// Unclassified errors did not come from a provider api, or came back in a
+	// shape no classifier recognised
+	Unclassified ErrorClass = iota
+	// Retryable errors are throttling and transient server failures; the same
+	// call may succeed later
+	Retryable
+	// Fatal errors will fail the same way however often they are retried,
+	// e.g. bad credentials or an invalid request
+	Fatal
+	// NotFound errors mean the resource does not exist, or does not exist yet
+	NotFound
+)
+
+func (c ErrorClass) String() string {
+	switch c {
+	case Retryable:
+		return "retryable"
+	case Fatal:
+		return "fatal"
+	case NotFound:
+		return "not found"
+	default:
+		return "unclassified"
+	}
+}
+
+// ClassifiedError is a provider error together with its class, and for
+// throttled calls how long the provider asked us to back off
+type ClassifiedError struct {
+	Class      ErrorClass
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *ClassifiedError) Error() string {
+	return e.Err.Error()
+}
+
+// Cause lets errwrap.Cause reach the provider error underneath
+func (e *ClassifiedError) Cause() error {
+	return e.Err
+}
+
+// NewRetryableError marks err as retryable after at least retryAfter, which
+// may be zero when the provider gave no hint
+func NewRetryableError(err error, retryAfter time.Duration) error {
+	return &ClassifiedError{Class: Retryable, RetryAfter: retryAfter, Err: err}
+}
+
+func NewFatalError(err error) error {
+	return &ClassifiedError{Class: Fatal, Err: err}
+}
+
+func NewNotFoundError(err error) error {
+	return &ClassifiedError{Class: NotFound, Err: err}
+}
+
+// ClassOf returns the class of the outermost classified error in err's chain
+// of causes
+func ClassOf(err error) ErrorClass {
+	if classified := findClassified(err); classified != nil {
+		return classified.Class
+	}
+	return Unclassified
+}
+
+// RetryAfterOf returns how long the provider asked to back off, or zero
+func RetryAfterOf(err error) time.Duration {
+	if classified := findClassified(err); classified != nil {
+		return classified.RetryAfter
+	}
+	return 0
+}
+
+// ClassifyStatusCode classifies an http status code the way every provider's
+// rest api uses them
+func ClassifyStatusCode(statusCode int) ErrorClass {
+	switch {
+	case statusCode == http.StatusNotFound:
+		return NotFound
+	case statusCode == http.StatusTooManyRequests,
+		statusCode == http.StatusRequestTimeout,
+		statusCode >= http.StatusInternalServerError:
+		return Retryable
+	case statusCode >= http.StatusBadRequest:
+		return Fatal
+	default:
+		return Unclassified
+	}
+}
+
+// ParseRetryAfter reads a Retry-After header given either in seconds or as an
+// http date, and returns zero when it is missing or malformed
+func ParseRetryAfter(header http.Header, now time.Time) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+type causer interface {
+	Cause() error
+}
+
+func findClassified(err error) *ClassifiedError {
+	for err != nil {
+		if classified, ok := err.(*ClassifiedError); ok {
+			return classified
+		}
+
+		cause, ok := err.(causer)
+		if !ok {
+			return nil
+		}
+		err = cause.Cause()
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package iaas_test
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/pivotal-cf/cliaas/iaas"
+	errwrap "github.com/pkg/errors"
+)
+
+var _ = Describe("Errors", func() {
+	Describe("ClassOf", func() {
+		It("finds the class through wrapped errors", func() {
+			err := errwrap.Wrap(NewRetryableError(errors.New("throttled"), time.Second), "stop instances failed")
+			Expect(ClassOf(err)).To(Equal(Retryable))
+			Expect(RetryAfterOf(err)).To(Equal(time.Second))
+			Expect(errwrap.Cause(err)).To(MatchError("throttled"))
+		})
+
+		It("leaves errors from outside a provider unclassified", func() {
+			Expect(ClassOf(errors.New("no matching instances found"))).To(Equal(Unclassified))
+			Expect(ClassOf(nil)).To(Equal(Unclassified))
+		})
+	})
+
+	Describe("ClassifyStatusCode", func() {
+		It("classifies throttling and server errors as retryable", func() {
+			Expect(ClassifyStatusCode(http.StatusTooManyRequests)).To(Equal(Retryable))
+			Expect(ClassifyStatusCode(http.StatusServiceUnavailable)).To(Equal(Retryable))
+			Expect(ClassifyStatusCode(http.StatusNotFound)).To(Equal(NotFound))
+			Expect(ClassifyStatusCode(http.StatusForbidden)).To(Equal(Fatal))
+			Expect(ClassifyStatusCode(http.StatusOK)).To(Equal(Unclassified))
+		})
+	})
+
+	Describe("ParseRetryAfter", func() {
+		now := time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC)
+
+		It("reads seconds", func() {
+			header := http.Header{"Retry-After": []string{"30"}}
+			Expect(ParseRetryAfter(header, now)).To(Equal(30 * time.Second))
+		})
+
+		It("reads an http date", func() {
+			header := http.Header{"Retry-After": []string{"Wed, 01 Mar 2017 12:01:00 GMT"}}
+			Expect(ParseRetryAfter(header, now)).To(Equal(time.Minute))
+		})
+
+		It("ignores a missing or malformed header", func() {
+			Expect(ParseRetryAfter(http.Header{}, now)).To(BeZero())
+			Expect(ParseRetryAfter(http.Header{"Retry-After": []string{"soon"}}, now)).To(BeZero())
+		})
+	})
+})
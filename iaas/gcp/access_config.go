@@ -0,0 +1,71 @@
+package gcp
+
+import (
+	"encoding/json"
+
+	"google.golang.org/api/compute/v1"
+)
+
+// savedAccessConfigKey is the metadata item Stop keeps the deleted access
+// config of an instance in, so that Start can add it back
+const savedAccessConfigKey = "cliaas-access-config"
+
+// savedAccessConfig is the external address of a stopped instance
+type savedAccessConfig struct {
+	NetworkInterface string `json:"network_interface"`
+	Name             string `json:"name"`
+	NatIP            string `json:"nat_ip"`
+}
+
+// withSavedAccessConfig returns the metadata of the instance with its first
+// access config saved in it, or nil when it has no access config
+func withSavedAccessConfig(instance *compute.Instance) *compute.Metadata {
+	if len(instance.NetworkInterfaces) == 0 || len(instance.NetworkInterfaces[0].AccessConfigs) == 0 {
+		return nil
+	}
+	accessConfig := instance.NetworkInterfaces[0].AccessConfigs[0]
+	value, err := json.Marshal(savedAccessConfig{
+		NetworkInterface: instance.NetworkInterfaces[0].Name,
+		Name:             accessConfig.Name,
+		NatIP:            accessConfig.NatIP,
+	})
+	if err != nil {
+		return nil
+	}
+	saved := string(value)
+
+	metadata := &compute.Metadata{}
+	if instance.Metadata != nil {
+		metadata.Fingerprint = instance.Metadata.Fingerprint
+		for _, item := range instance.Metadata.Items {
+			if item.Key != savedAccessConfigKey {
+				metadata.Items = append(metadata.Items, item)
+			}
+		}
+	}
+	metadata.Items = append(metadata.Items, &compute.MetadataItems{Key: savedAccessConfigKey, Value: &saved})
+	return metadata
+}
+
+// savedAccessConfigOf is the access config Stop saved on the instance, when
+// the instance has not got it back yet
+func savedAccessConfigOf(instance *compute.Instance) (savedAccessConfig, bool) {
+	var saved savedAccessConfig
+	if instance.Metadata == nil {
+		return saved, false
+	}
+	for _, networkInterface := range instance.NetworkInterfaces {
+		if len(networkInterface.AccessConfigs) > 0 {
+			return saved, false
+		}
+	}
+
+	for _, item := range instance.Metadata.Items {
+		if item.Key != savedAccessConfigKey || item.Value == nil {
+			continue
+		}
+		err := json.Unmarshal([]byte(*item.Value), &saved)
+		return saved, err == nil && saved.NetworkInterface != ""
+	}
+	return saved, false
+}
@@ -0,0 +1,222 @@
+package gcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	errwrap "github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	CredentialsSourceJSON               = "json"
+	CredentialsSourceFile               = "file"
+	CredentialsSourceApplicationDefault = "application-default"
+)
+
+const defaultIAMCredentialsEndpoint = "https://iamcredentials.googleapis.com/v1/"
+
+var MultipleCredentialsErr = errors.New("only one of a credentials file or inline credentials json may be given")
+
+// CredentialsConfig - describes where the oauth2 token source for the compute
+// client comes from. Inline json wins over a credentials file, and with
+// neither given the application default credentials are used. When
+// ImpersonateServiceAccount is set the resulting token is only used to mint
+// short lived tokens for that service account.
+type CredentialsConfig struct {
+	CredfilePath    string
+	CredentialsJSON string
+
+	ImpersonateServiceAccount string
+	Delegates                 []string
+	IAMCredentialsEndpoint    string
+}
+
+func (c CredentialsConfig) Source() string {
+	switch {
+	case c.CredentialsJSON != "":
+		return CredentialsSourceJSON
+	case c.CredfilePath != "":
+		return CredentialsSourceFile
+	default:
+		return CredentialsSourceApplicationDefault
+	}
+}
+
+// NewTokenSource - builds a token source from the config without reading or
+// writing any process environment besides what application default
+// credentials themselves look at
+func NewTokenSource(ctx context.Context, c CredentialsConfig, scopes ...string) (oauth2.TokenSource, error) {
+	if c.CredentialsJSON != "" && c.CredfilePath != "" {
+		return nil, MultipleCredentialsErr
+	}
+
+	var tokenSource oauth2.TokenSource
+	var err error
+	switch c.Source() {
+	case CredentialsSourceJSON:
+		tokenSource, err = tokenSourceFromJSON(ctx, []byte(c.CredentialsJSON), scopes)
+	case CredentialsSourceFile:
+		var credentials []byte
+		credentials, err = ioutil.ReadFile(c.CredfilePath)
+		if err != nil {
+			return nil, errwrap.Wrap(err, "failed reading credentials file")
+		}
+		tokenSource, err = tokenSourceFromJSON(ctx, credentials, scopes)
+	default:
+		tokenSource, err = google.DefaultTokenSource(ctx, scopes...)
+	}
+	if err != nil {
+		return nil, errwrap.Wrap(err, "failed building token source")
+	}
+
+	if c.ImpersonateServiceAccount == "" {
+		return tokenSource, nil
+	}
+
+	endpoint := c.IAMCredentialsEndpoint
+	if endpoint == "" {
+		endpoint = defaultIAMCredentialsEndpoint
+	}
+	return NewImpersonatedTokenSource(ctx, tokenSource, endpoint, c.ImpersonateServiceAccount, c.Delegates, scopes...), nil
+}
+
+type credentialsFile struct {
+	Type         string `json:"type"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+func tokenSourceFromJSON(ctx context.Context, credentials []byte, scopes []string) (oauth2.TokenSource, error) {
+	var f credentialsFile
+	err := json.Unmarshal(credentials, &f)
+	if err != nil {
+		return nil, errwrap.Wrap(err, "credentials are not valid json")
+	}
+
+	switch f.Type {
+	case "service_account":
+		jwtConfig, err := google.JWTConfigFromJSON(credentials, scopes...)
+		if err != nil {
+			return nil, errwrap.Wrap(err, "invalid service account credentials")
+		}
+		return jwtConfig.TokenSource(ctx), nil
+	case "authorized_user":
+		config := &oauth2.Config{
+			ClientID:     f.ClientID,
+			ClientSecret: f.ClientSecret,
+			Endpoint:     google.Endpoint,
+			Scopes:       scopes,
+		}
+		return config.TokenSource(ctx, &oauth2.Token{RefreshToken: f.RefreshToken}), nil
+	default:
+		return nil, fmt.Errorf("unsupported credentials type %q", f.Type)
+	}
+}
+
+type impersonatedTokenSource struct {
+	client    *http.Client
+	url       string
+	delegates []string
+	scopes    []string
+}
+
+// NewImpersonatedTokenSource - exchanges tokens from base for access tokens of
+// the target service account through the IAM credentials api
+func NewImpersonatedTokenSource(ctx context.Context, base oauth2.TokenSource, endpoint string, serviceAccount string, delegates []string, scopes ...string) oauth2.TokenSource {
+	var delegateNames []string
+	for _, delegate := range delegates {
+		delegateNames = append(delegateNames, serviceAccountResource(delegate))
+	}
+
+	return oauth2.ReuseTokenSource(nil, &impersonatedTokenSource{
+		client:    oauth2.NewClient(ctx, base),
+		url:       fmt.Sprintf("%s%s:generateAccessToken", endpoint, serviceAccountResource(serviceAccount)),
+		delegates: delegateNames,
+		scopes:    scopes,
+	})
+}
+
+type generateAccessTokenRequest struct {
+	Delegates []string `json:"delegates,omitempty"`
+	Scope     []string `json:"scope"`
+	Lifetime  string   `json:"lifetime"`
+}
+
+type generateAccessTokenResponse struct {
+	AccessToken string `json:"accessToken"`
+	ExpireTime  string `json:"expireTime"`
+}
+
+func (s *impersonatedTokenSource) Token() (*oauth2.Token, error) {
+	body, err := json.Marshal(generateAccessTokenRequest{
+		Delegates: s.delegates,
+		Scope:     s.scopes,
+		Lifetime:  "3600s",
+	})
+	if err != nil {
+		return nil, errwrap.Wrap(err, "failed encoding generateAccessToken request")
+	}
+
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, errwrap.Wrap(err, "generateAccessToken call failed")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("generateAccessToken returned %s", resp.Status)
+	}
+
+	var token generateAccessTokenResponse
+	err = json.NewDecoder(resp.Body).Decode(&token)
+	if err != nil {
+		return nil, errwrap.Wrap(err, "failed decoding generateAccessToken response")
+	}
+
+	expiry, err := time.Parse(time.RFC3339, token.ExpireTime)
+	if err != nil {
+		return nil, errwrap.Wrap(err, "invalid expireTime in generateAccessToken response")
+	}
+
+	return &oauth2.Token{
+		AccessToken: token.AccessToken,
+		TokenType:   "Bearer",
+		Expiry:      expiry,
+	}, nil
+}
+
+func serviceAccountResource(serviceAccount string) string {
+	return "projects/-/serviceAccounts/" + serviceAccount
+}
+
+// CredentialsSecrets returns the private keys and client secrets inside
+// credentials json, so they can be redacted wherever they show up on their
+// own
+func CredentialsSecrets(credentialsJSON string) []string {
+	var credentials struct {
+		PrivateKey   string `json:"private_key"`
+		PrivateKeyID string `json:"private_key_id"`
+		ClientSecret string `json:"client_secret"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	if json.Unmarshal([]byte(credentialsJSON), &credentials) != nil {
+		return nil
+	}
+
+	var secrets []string
+	for _, secret := range []string{credentials.PrivateKey, credentials.PrivateKeyID, credentials.ClientSecret, credentials.RefreshToken} {
+		if secret != "" {
+			secrets = append(secrets, secret)
+		}
+	}
+	return secrets
+}
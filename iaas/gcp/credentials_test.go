@@ -0,0 +1,140 @@
+package gcp_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/pivotal-cf/cliaas/iaas/gcp"
+	"golang.org/x/net/context"
+	"golang.org/x/oauth2"
+)
+
+var _ = Describe("Credentials", func() {
+	Describe("NewTokenSource", func() {
+		var credentials CredentialsConfig
+
+		BeforeEach(func() {
+			credentials = CredentialsConfig{}
+		})
+
+		It("uses application default credentials when nothing is given", func() {
+			Expect(credentials.Source()).To(Equal(CredentialsSourceApplicationDefault))
+		})
+
+		Context("when given both a credentials file and inline json", func() {
+			It("returns an error", func() {
+				credentials.CredfilePath = "../../testdata/fake_gcp_creds.json"
+				credentials.CredentialsJSON = `{"type": "service_account"}`
+				_, err := NewTokenSource(context.Background(), credentials)
+				Expect(err).To(Equal(MultipleCredentialsErr))
+			})
+		})
+
+		Context("when given a credentials file", func() {
+			It("builds a token source from the file", func() {
+				credentials.CredfilePath = "../../testdata/fake_gcp_creds.json"
+				Expect(credentials.Source()).To(Equal(CredentialsSourceFile))
+
+				tokenSource, err := NewTokenSource(context.Background(), credentials)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(tokenSource).NotTo(BeNil())
+			})
+
+			It("returns an error when the file does not exist", func() {
+				credentials.CredfilePath = "does-not-exist.json"
+				_, err := NewTokenSource(context.Background(), credentials)
+				Expect(err).To(MatchError(ContainSubstring("does-not-exist.json")))
+			})
+		})
+
+		Context("when given inline credentials json", func() {
+			It("builds a token source for an authorized user", func() {
+				credentials.CredentialsJSON = `{"type": "authorized_user", "client_id": "id", "client_secret": "secret", "refresh_token": "token"}`
+				Expect(credentials.Source()).To(Equal(CredentialsSourceJSON))
+
+				tokenSource, err := NewTokenSource(context.Background(), credentials)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(tokenSource).NotTo(BeNil())
+			})
+
+			It("returns an error for malformed json", func() {
+				credentials.CredentialsJSON = `{"type": `
+				_, err := NewTokenSource(context.Background(), credentials)
+				Expect(err).To(HaveOccurred())
+			})
+
+			It("returns an error for unsupported credential types", func() {
+				credentials.CredentialsJSON = `{"type": "external_account"}`
+				_, err := NewTokenSource(context.Background(), credentials)
+				Expect(err).To(MatchError(ContainSubstring("external_account")))
+			})
+		})
+	})
+
+	Describe("NewImpersonatedTokenSource", func() {
+		var server *httptest.Server
+		var requestPath string
+		var requestAuthorization string
+		var requestBody map[string]interface{}
+		var responseStatus int
+
+		BeforeEach(func() {
+			responseStatus = http.StatusOK
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requestPath = r.URL.Path
+				requestAuthorization = r.Header.Get("Authorization")
+				Expect(json.NewDecoder(r.Body).Decode(&requestBody)).To(Succeed())
+
+				w.WriteHeader(responseStatus)
+				w.Write([]byte(`{"accessToken": "impersonated-token", "expireTime": "2100-01-01T00:00:00Z"}`))
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("exchanges the base token for one of the target service account", func() {
+			base := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "base-token"})
+			tokenSource := NewImpersonatedTokenSource(context.Background(), base, server.URL+"/v1/", "target@prj.iam.gserviceaccount.com", []string{"delegate@prj.iam.gserviceaccount.com"}, "some-scope")
+
+			token, err := tokenSource.Token()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(token.AccessToken).To(Equal("impersonated-token"))
+			Expect(token.Expiry.Year()).To(Equal(2100))
+
+			Expect(requestPath).To(Equal("/v1/projects/-/serviceAccounts/target@prj.iam.gserviceaccount.com:generateAccessToken"))
+			Expect(requestAuthorization).To(Equal("Bearer base-token"))
+			Expect(requestBody["scope"]).To(Equal([]interface{}{"some-scope"}))
+			Expect(requestBody["delegates"]).To(Equal([]interface{}{"projects/-/serviceAccounts/delegate@prj.iam.gserviceaccount.com"}))
+		})
+
+		Context("when the iam credentials api refuses", func() {
+			BeforeEach(func() {
+				responseStatus = http.StatusForbidden
+			})
+
+			It("returns an error", func() {
+				base := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "base-token"})
+				tokenSource := NewImpersonatedTokenSource(context.Background(), base, server.URL+"/v1/", "target@prj.iam.gserviceaccount.com", nil)
+
+				_, err := tokenSource.Token()
+				Expect(err).To(MatchError(ContainSubstring("403")))
+			})
+		})
+	})
+
+	Describe("CredentialsSecrets", func() {
+		It("returns the keys and secrets inside the credentials", func() {
+			Expect(CredentialsSecrets(`{"type":"service_account","private_key_id":"some-key-id","private_key":"some-private-key","client_email":"sa@prj.iam.gserviceaccount.com"}`)).To(Equal([]string{"some-private-key", "some-key-id"}))
+			Expect(CredentialsSecrets(`{"type":"authorized_user","client_secret":"some-client-secret","refresh_token":"some-refresh-token"}`)).To(Equal([]string{"some-client-secret", "some-refresh-token"}))
+		})
+
+		It("returns nothing for malformed json", func() {
+			Expect(CredentialsSecrets("not json")).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,26 @@
+package gcp
+
+import (
+	"time"
+
+	"github.com/pivotal-cf/cliaas/iaas"
+	"google.golang.org/api/googleapi"
+)
+
+// ClassifyError marks an error from the google apis as retryable, fatal or not
+// found, using the status code and any Retry-After the api sent
+func ClassifyError(err error) error {
+	apiErr, ok := err.(*googleapi.Error)
+	if !ok {
+		return err
+	}
+
+	switch iaas.ClassifyStatusCode(apiErr.Code) {
+	case iaas.Retryable:
+		return iaas.NewRetryableError(err, iaas.ParseRetryAfter(apiErr.Header, time.Now()))
+	case iaas.NotFound:
+		return iaas.NewNotFoundError(err)
+	default:
+		return iaas.NewFatalError(err)
+	}
+}
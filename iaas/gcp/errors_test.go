@@ -0,0 +1,74 @@
+package gcp_test
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/cliaas/iaas"
+	. "github.com/pivotal-cf/cliaas/iaas/gcp"
+	"github.com/pivotal-cf/cliaas/iaas/gcp/gcpfakes"
+	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+)
+
+var _ = Describe("ClassifyError", func() {
+	It("honours the Retry-After of a throttled call", func() {
+		err := ClassifyError(&googleapi.Error{
+			Code:   http.StatusTooManyRequests,
+			Header: http.Header{"Retry-After": []string{"20"}},
+		})
+		Expect(iaas.ClassOf(err)).To(Equal(iaas.Retryable))
+		Expect(iaas.RetryAfterOf(err)).To(Equal(20 * time.Second))
+	})
+
+	It("classifies missing resources and bad requests", func() {
+		Expect(iaas.ClassOf(ClassifyError(&googleapi.Error{Code: http.StatusNotFound}))).To(Equal(iaas.NotFound))
+		Expect(iaas.ClassOf(ClassifyError(&googleapi.Error{Code: http.StatusForbidden}))).To(Equal(iaas.Fatal))
+	})
+
+	It("leaves other errors alone", func() {
+		controlErr := errors.New("some error")
+		Expect(ClassifyError(controlErr)).To(Equal(controlErr))
+	})
+})
+
+var _ = Describe("retrying google api calls", func() {
+	var (
+		client           *Client
+		fakeGoogleClient *gcpfakes.FakeGoogleComputeClient
+	)
+
+	BeforeEach(func() {
+		fakeGoogleClient = new(gcpfakes.FakeGoogleComputeClient)
+		fakeGoogleClient.ListReturns(&compute.InstanceList{}, nil)
+
+		var err error
+		client, err = NewClient(
+			ConfigGoogleClient(fakeGoogleClient),
+			ConfigZoneName("zone"),
+			ConfigProjectName("prj"),
+			ConfigWaiter(iaas.NewWaiter(clock.NewClock(), iaas.Timeouts{PollInterval: time.Millisecond})),
+		)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("retries a call that is unavailable", func() {
+		fakeGoogleClient.StopReturnsOnCall(0, nil, &googleapi.Error{Code: http.StatusServiceUnavailable})
+		fakeGoogleClient.StopReturnsOnCall(1, &compute.Operation{Status: "DONE"}, nil)
+
+		Expect(client.StopVM("blah")).To(Succeed())
+		Expect(fakeGoogleClient.StopCallCount()).To(Equal(2))
+	})
+
+	It("does not retry a forbidden call", func() {
+		fakeGoogleClient.StopReturns(nil, &googleapi.Error{Code: http.StatusForbidden})
+
+		err := client.StopVM("blah")
+		Expect(iaas.ClassOf(err)).To(Equal(iaas.Fatal))
+		Expect(fakeGoogleClient.StopCallCount()).To(Equal(1))
+	})
+})
@@ -3,15 +3,16 @@ package gcp
 import (
 	"errors"
 	"fmt"
-	"os"
+	"net/http"
 	"regexp"
 	"strings"
 	"time"
 
+	"code.cloudfoundry.org/clock"
 	"github.com/pivotal-cf/cliaas/iaas"
 	errwrap "github.com/pkg/errors"
 	"golang.org/x/net/context"
-	"golang.org/x/oauth2/google"
+	"golang.org/x/oauth2"
 	"google.golang.org/api/compute/v1"
 )
 
@@ -20,8 +21,19 @@ type GoogleComputeClient interface {
 	DiskList(project string, zone string) (*compute.DiskList, error)
 	Delete(project string, zone string, instanceName string) (*compute.Operation, error)
 	Insert(project string, zone string, instance *compute.Instance) (*compute.Operation, error)
-	ImageInsert(project string, image *compute.Image, timeout time.Duration) (*compute.Operation, error)
+	ImageInsert(project string, image *compute.Image) (*compute.Operation, error)
+	ImageDelete(project string, image string) (*compute.Operation, error)
+	GlobalOperationGet(project string, operationName string) (*compute.Operation, error)
 	Stop(project string, zone string, instanceName string) (*compute.Operation, error)
+	Start(project string, zone string, instanceName string) (*compute.Operation, error)
+	AddAccessConfig(project string, zone string, instanceName string, networkInterface string, accessConfig *compute.AccessConfig) (*compute.Operation, error)
+	DeleteAccessConfig(project string, zone string, instanceName string, accessConfig string, networkInterface string) (*compute.Operation, error)
+	SetMetadata(project string, zone string, instanceName string, metadata *compute.Metadata) (*compute.Operation, error)
+	ProjectGet(project string) (*compute.Project, error)
+	TestIamPermissions(project string, permissions []string) ([]string, error)
+	DetachDisk(project string, zone string, instanceName string, deviceName string) (*compute.Operation, error)
+	AttachDisk(project string, zone string, instanceName string, disk *compute.AttachedDisk) (*compute.Operation, error)
+	ZoneOperationGet(project string, zone string, operationName string) (*compute.Operation, error)
 }
 
 type ClientAPI interface {
@@ -30,31 +42,40 @@ type ClientAPI interface {
 	GetVMInfo(filter Filter) (*compute.Instance, error)
 	Disk(filter Filter) (*compute.Disk, error)
 	StopVM(instanceName string) error
-	CreateImage(tarball string, diskSizeGB int64) (string, error)
-	WaitForStatus(vmName string, desiredStatus string) error
+	CreateImage(imageName string, tarball string, diskSizeGB int64) (string, error)
+	WaitForStatus(vmName string, desiredStatus string, timeout time.Duration) error
+	DetachDisk(instanceName string, deviceName string) error
+	AttachDisk(instanceName string, disk compute.AttachedDisk) error
 }
 
 type Client struct {
 	projectName  string
 	zoneName     string
 	googleClient GoogleComputeClient
-	timeout      time.Duration
+	waiter       iaas.Waiter
+	progress     iaas.ProgressReporter
+	logger       *iaas.Logger
+	namer        iaas.Namer
+	hooks        *iaas.HookRunner
+
+	migrateDataDisk bool
 }
 
-//NewDefaultGoogleComputeClient -- builds a gcp client which connects to your gcp using `GOOGLE_APPLICATION_CREDENTIALS`
+//NewDefaultGoogleComputeClient -- builds a gcp client which connects to your gcp using the given credentials file
 func NewDefaultGoogleComputeClient(credpath string) (GoogleComputeClient, error) {
-	err := os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", credpath)
-	if err != nil {
-		return nil, errwrap.Wrap(err, "couldnt set credentials ENV Var")
-	}
+	return NewGoogleComputeClient(CredentialsConfig{CredfilePath: credpath})
+}
 
+//NewGoogleComputeClient -- builds a gcp client which connects to your gcp using the given credentials
+func NewGoogleComputeClient(credentials CredentialsConfig) (GoogleComputeClient, error) {
 	ctx := context.Background()
-	hc, err := google.DefaultClient(ctx, compute.CloudPlatformScope)
+	tokenSource, err := NewTokenSource(ctx, credentials, compute.CloudPlatformScope)
 	if err != nil {
-		return nil, errwrap.Wrap(err, "we have a DefaultClient error")
+		return nil, errwrap.Wrap(err, "we have a token source error")
 	}
 
-	c, err := compute.New(hc)
+	httpClient := oauth2.NewClient(ctx, tokenSource)
+	c, err := compute.New(httpClient)
 	if err != nil {
 		return nil, errwrap.Wrap(err, "we have a compute.New error")
 	}
@@ -62,13 +83,17 @@ func NewDefaultGoogleComputeClient(credpath string) (GoogleComputeClient, error)
 		instanceService: c.Instances,
 		disksService:    c.Disks,
 		imageService:    c.Images,
+		operations:      c.GlobalOperations,
+		zoneOperations:  c.ZoneOperations,
+		projectService:  c.Projects,
+		httpClient:      httpClient,
 		ctx:             ctx,
 	}, nil
 }
 
 func NewClient(configs ...func(*Client) error) (*Client, error) {
 	gcpClient := new(Client)
-	gcpClient.timeout = 5 * time.Minute
+	gcpClient.waiter = iaas.NewWaiter(clock.NewClock(), iaas.Timeouts{})
 
 	for _, cfg := range configs {
 		err := cfg(gcpClient)
@@ -88,14 +113,127 @@ func NewClient(configs ...func(*Client) error) (*Client, error) {
 	if gcpClient.projectName == "" {
 		return nil, fmt.Errorf("You have an incomplete GCPClientAPI.projectName")
 	}
+
+	gcpClient.googleClient = retryingGoogleComputeClient{
+		googleClient: gcpClient.googleClient,
+		waiter:       gcpClient.waiter,
+		logger:       gcpClient.logger,
+	}
 	return gcpClient, nil
 }
 
+// VerifyProject - checks that the credentials can see the configured project
+func (c *Client) VerifyProject() error {
+	_, err := c.googleClient.ProjectGet(c.projectName)
+	if err != nil {
+		return errwrap.Wrapf(err, "could not get project %s", c.projectName)
+	}
+	return nil
+}
+
 /* Cliaas Client Interface */
 func (c *Client) Delete(identifier string) error {
 	return c.DeleteVM(identifier)
 }
 
+// Stop stops the running instance. Its external address is released while it
+// is stopped, and given back by Start.
+func (c *Client) Stop(identifier string, wait bool) error {
+	instance, err := c.GetVMInfo(Filter{NameRegexString: identifier + "*"})
+	if err != nil {
+		return errwrap.Wrap(err, "getvminfo failed")
+	}
+
+	iaas.ReportStep(c.progress, iaas.StepStopping, instance.Name)
+	err = c.StopVM(instance.Name)
+	if err != nil {
+		return errwrap.Wrap(err, "stopvm failed")
+	}
+	if !wait {
+		return nil
+	}
+
+	err = c.WaitForStatus(instance.Name, InstanceTerminated, c.waiter.Timeouts.Stop)
+	if err != nil {
+		return errwrap.Wrap(err, "waitforstatus after stopvm failed")
+	}
+	iaas.ReportStep(c.progress, iaas.StepStopped, instance.Name)
+	return nil
+}
+
+// Start starts the stopped instance, after adding back the access config Stop
+// deleted
+func (c *Client) Start(identifier string, wait bool) error {
+	instance, err := c.getVMInfo(Filter{NameRegexString: identifier + "*"}, InstanceTerminated)
+	if err != nil {
+		return errwrap.Wrap(err, "getvminfo failed")
+	}
+
+	err = c.restoreAccessConfig(instance)
+	if err != nil {
+		return err
+	}
+
+	iaas.ReportStep(c.progress, iaas.StepStarting, instance.Name)
+	operation, err := c.googleClient.Start(c.projectName, c.zoneName, instance.Name)
+	if err != nil {
+		return errwrap.Wrap(err, "call to googleclient.Start yielded error")
+	}
+	if !wait {
+		return nil
+	}
+
+	err = c.waitForZoneOperation(operation, "waiting for "+instance.Name+" to start", c.waiter.Timeouts.Start)
+	if err != nil {
+		return err
+	}
+	err = c.WaitForStatus(instance.Name, InstanceRunning, c.waiter.Timeouts.Start)
+	if err != nil {
+		return errwrap.Wrap(err, "waitforstatus after start failed")
+	}
+	iaas.ReportStep(c.progress, iaas.StepRunning, instance.Name)
+	return nil
+}
+
+// restoreAccessConfig adds back the saved access config of a stopped
+// instance. An ephemeral address is gone once the instance stopped, so when
+// the saved address cannot be had the instance gets a new ephemeral one.
+func (c *Client) restoreAccessConfig(instance *compute.Instance) error {
+	saved, ok := savedAccessConfigOf(instance)
+	if !ok {
+		return nil
+	}
+
+	err := c.addAccessConfig(instance.Name, saved.NetworkInterface, &compute.AccessConfig{
+		Name:  saved.Name,
+		NatIP: saved.NatIP,
+		Type:  "ONE_TO_ONE_NAT",
+	})
+	if err != nil && saved.NatIP != "" {
+		c.logger.Warn("could not restore the external address, using an ephemeral one", iaas.Fields{
+			"instance": instance.Name,
+			"nat_ip":   saved.NatIP,
+			"error":    err.Error(),
+		})
+		err = c.addAccessConfig(instance.Name, saved.NetworkInterface, &compute.AccessConfig{
+			Name: saved.Name,
+			Type: "ONE_TO_ONE_NAT",
+		})
+	}
+	if err != nil {
+		return errwrap.Wrap(err, "could not restore access config")
+	}
+	return nil
+}
+
+func (c *Client) addAccessConfig(instanceName string, networkInterface string, accessConfig *compute.AccessConfig) error {
+	operation, err := c.googleClient.AddAccessConfig(c.projectName, c.zoneName, instanceName, networkInterface, accessConfig)
+	if err != nil {
+		return err
+	}
+	return c.waitForZoneOperation(operation, "waiting for the access config of "+instanceName, c.waiter.Timeouts.IPAssociation)
+}
+
 func (c *Client) Replace(identifier string, sourceImageTarballURL string, diskSizeGB int64) error {
 	vmInstance, err := c.GetVMInfo(Filter{
 		NameRegexString: identifier + "*",
@@ -103,34 +241,314 @@ func (c *Client) Replace(identifier string, sourceImageTarballURL string, diskSi
 	if err != nil {
 		return errwrap.Wrap(err, "getvminfo failed")
 	}
+	iaas.ReportStep(c.progress, iaas.StepFoundVM, vmInstance.Name)
+
+	// name everything before anything changes, so a bad template fails early
+	nameData := c.namer.NameData(vmInstance.Name, sourceImageTarballURL)
+	vmName, err := c.newName(nameData, iaas.SuffixVM)
+	if err != nil {
+		return err
+	}
+	imageName, err := c.newName(nameData, iaas.SuffixImage)
+	if err != nil {
+		return err
+	}
+
+	// in migrate mode the new vm boots with the old vm's data disks instead of
+	// without them
+	var dataDisks []*compute.AttachedDisk
+	if c.migrateDataDisk {
+		dataDisks = DataDisks(vmInstance)
+		if len(dataDisks) == 0 {
+			return fmt.Errorf("instance %s has no data disk to migrate", vmInstance.Name)
+		}
+	}
+
+	hookData := iaas.HookData{
+		Identifier: identifier,
+		OldVM:      iaas.VM{ID: fmt.Sprintf("%d", vmInstance.Id), Name: vmInstance.Name},
+		Image:      sourceImageTarballURL,
+		PublicIP:   publicIP(vmInstance),
+	}
+	err = c.hooks.Run(iaas.PhasePreStop, hookData)
+	if err != nil {
+		return err
+	}
 
+	iaas.ReportStep(c.progress, iaas.StepStopping, vmInstance.Name)
 	err = c.StopVM(vmInstance.Name)
 	if err != nil {
-		return errwrap.Wrap(err, "stopvm failed")
+		return c.rollback(hookData, "", nil, errwrap.Wrap(err, "stopvm failed"))
 	}
 
-	err = c.WaitForStatus(vmInstance.Name, InstanceTerminated)
+	err = c.WaitForStatus(vmInstance.Name, InstanceTerminated, c.waiter.Timeouts.Stop)
 	if err != nil {
-		return errwrap.Wrap(err, "waitforstatus after stopvm failed")
+		return c.rollback(hookData, "", nil, errwrap.Wrap(err, "waitforstatus after stopvm failed"))
+	}
+	iaas.ReportStep(c.progress, iaas.StepStopped, vmInstance.Name)
+	_ = c.hooks.Run(iaas.PhasePostStop, hookData)
+
+	// detached only holds the disks that have left the old vm, so that a
+	// rollback moves back just those
+	var detached []*compute.AttachedDisk
+	for _, disk := range dataDisks {
+		iaas.ReportStep(c.progress, iaas.StepDetachingDisk, disk.DeviceName)
+		err = c.DetachDisk(vmInstance.Name, disk.DeviceName)
+		if err != nil {
+			return c.rollback(hookData, "", detached, errwrap.Wrap(err, "could not detach data disk"))
+		}
+		detached = append(detached, disk)
+	}
+
+	sourceImage, err := c.CreateImage(imageName, sourceImageTarballURL, diskSizeGB)
+	if err != nil {
+		return c.rollback(hookData, "", detached, errwrap.Wrap(err, "could not create new disk image"))
+	}
+
+	newInstance := createGCPInstanceFromExisting(vmInstance, sourceImage, diskSizeGB, vmName)
+	for _, disk := range dataDisks {
+		iaas.ReportStep(c.progress, iaas.StepAttachingDisk, disk.DeviceName)
+		newInstance.Disks = append(newInstance.Disks, migratedDisk(disk))
+	}
+	err = c.hooks.Run(iaas.PhasePreCreate, hookData)
+	if err != nil {
+		return c.rollback(hookData, imageName, detached, err)
+	}
+
+	iaas.ReportStep(c.progress, iaas.StepCreating, newInstance.Name)
+	err = c.CreateVM(*newInstance)
+	if err != nil {
+		return c.rollback(hookData, imageName, detached, errwrap.Wrap(err, "CreateVM call failed"))
+	}
+	hookData.NewVM = iaas.VM{Name: newInstance.Name, Image: sourceImage}
+	_ = c.hooks.Run(iaas.PhasePostCreate, hookData)
+
+	iaas.ReportStep(c.progress, iaas.StepWaitingForRunning, newInstance.Name)
+	err = c.WaitForStatus(newInstance.Name, InstanceRunning, c.waiter.Timeouts.Create)
+	if err != nil {
+		return c.rollback(hookData, imageName, detached, err)
+	}
+	_ = c.hooks.Run(iaas.PhasePostReady, hookData)
+	iaas.ReportStep(c.progress, iaas.StepDone, newInstance.Name)
+	return nil
+}
+
+// Create imports the tarball as an image and boots a new instance from it as
+// the spec describes. Data disks with a source are attached as they are, and
+// any other is created empty.
+func (c *Client) Create(sourceImageTarballURL string, spec iaas.VMSpec) (iaas.VM, error) {
+	if spec.Zone != "" && spec.Zone != c.zoneName {
+		return iaas.VM{}, fmt.Errorf("spec is for zone %s, but the client is for zone %s", spec.Zone, c.zoneName)
+	}
+	err := ValidateName(spec.Name)
+	if err != nil {
+		return iaas.VM{}, err
+	}
+	imageName, err := c.newName(c.namer.NameData(spec.Name, sourceImageTarballURL), iaas.SuffixImage)
+	if err != nil {
+		return iaas.VM{}, err
 	}
 
-	sourceImage, err := c.CreateImage(sourceImageTarballURL, diskSizeGB)
+	var diskSizeGB int64
+	for _, disk := range spec.Disks {
+		if disk.Boot {
+			diskSizeGB = disk.SizeGB
+		}
+	}
+	sourceImage, err := c.CreateImage(imageName, sourceImageTarballURL, diskSizeGB)
 	if err != nil {
-		return errwrap.Wrap(err, "could not create new disk image")
+		return iaas.VM{}, errwrap.Wrap(err, "could not create new disk image")
 	}
 
-	newInstance := createGCPInstanceFromExisting(vmInstance, sourceImage, diskSizeGB, fmt.Sprintf("%s-%s", identifier, time.Now().Format("2006-01-02-15-04-05")))
+	newInstance := createGCPInstanceFromSpec(spec, sourceImage, c.zoneName)
+	iaas.ReportStep(c.progress, iaas.StepCreating, newInstance.Name)
 	err = c.CreateVM(*newInstance)
 	if err != nil {
-		return errwrap.Wrap(err, "CreateVM call failed")
+		return iaas.VM{}, errwrap.Wrap(err, "CreateVM call failed")
+	}
+
+	iaas.ReportStep(c.progress, iaas.StepWaitingForRunning, newInstance.Name)
+	err = c.WaitForStatus(newInstance.Name, InstanceRunning, c.waiter.Timeouts.Create)
+	if err != nil {
+		_ = c.DeleteVM(newInstance.Name)
+		return iaas.VM{}, err
+	}
+	iaas.ReportStep(c.progress, iaas.StepDone, newInstance.Name)
+	return iaas.VM{Name: newInstance.Name, Image: sourceImage}, nil
+}
+
+// reattachDataDisks moves disks back to the old vm after a failed replace,
+// detaching them from the new vm first when it got that far
+// rollback puts the old vm back the way it was before Replace stopped it. It
+// gets back the data disks that left it, the new vm and its image are
+// deleted, and it is started again with its access config. Only then does the
+// on-rollback hook run. It returns cause, the error that failed the replace.
+func (c *Client) rollback(hookData iaas.HookData, imageName string, detached []*compute.AttachedDisk, cause error) error {
+	c.reattachDataDisks(hookData.OldVM.Name, hookData.NewVM.Name, detached)
+
+	// the new vm may hold the old vm's address, so it has to be gone before
+	// the old vm gets its access config back
+	if hookData.NewVM.Name != "" {
+		err := c.deleteNewVM(hookData.NewVM.Name)
+		if err != nil {
+			c.logger.Warn("could not delete the new vm", iaas.Fields{
+				"instance": hookData.NewVM.Name,
+				"error":    err.Error(),
+			})
+		}
+	}
+
+	if imageName != "" {
+		err := c.deleteImage(imageName)
+		if err != nil {
+			c.logger.Warn("could not delete the image of the new vm", iaas.Fields{
+				"image": imageName,
+				"error": err.Error(),
+			})
+		}
+	}
+
+	err := c.restartVM(hookData.OldVM.Name)
+	if err != nil {
+		c.logger.Error("could not start the old vm again", iaas.Fields{
+			"instance": hookData.OldVM.Name,
+			"error":    err.Error(),
+		})
+	}
+
+	rollbackData := hookData
+	rollbackData.Error = cause.Error()
+	_ = c.hooks.Run(iaas.PhaseOnRollback, rollbackData)
+	return cause
+}
+
+// deleteNewVM deletes the new vm after a failed replace, and waits until it
+// is gone
+func (c *Client) deleteNewVM(instanceName string) error {
+	operation, err := c.googleClient.Delete(c.projectName, c.zoneName, instanceName)
+	if err != nil {
+		return errwrap.Wrap(err, "call to googleclient.Delete yielded error")
+	}
+	return c.waitForZoneOperation(operation, "waiting for "+instanceName+" to be deleted", c.waiter.Timeouts.Stop)
+}
+
+// restartVM starts the old vm again after a failed replace, with the access
+// config StopVM took from it. It is left alone when it never stopped.
+func (c *Client) restartVM(instanceName string) error {
+	instance, err := c.getVMInfo(Filter{NameRegexString: "^" + regexp.QuoteMeta(instanceName) + "$"}, InstanceAll)
+	if err != nil {
+		return errwrap.Wrap(err, "getvminfo failed")
+	}
+
+	err = c.restoreAccessConfig(instance)
+	if err != nil {
+		return err
 	}
+	if instance.Status == InstanceRunning {
+		return nil
+	}
+
+	iaas.ReportStep(c.progress, iaas.StepStarting, instance.Name)
+	operation, err := c.googleClient.Start(c.projectName, c.zoneName, instance.Name)
+	if err != nil {
+		return errwrap.Wrap(err, "call to googleclient.Start yielded error")
+	}
+	return c.waitForZoneOperation(operation, "waiting for "+instance.Name+" to start", c.waiter.Timeouts.Start)
+}
+
+func (c *Client) reattachDataDisks(instanceName string, newInstanceName string, disks []*compute.AttachedDisk) {
+	for _, disk := range disks {
+		if newInstanceName != "" {
+			_ = c.DetachDisk(newInstanceName, disk.DeviceName)
+		}
+		_ = c.AttachDisk(instanceName, *migratedDisk(disk))
+	}
+}
+
+// publicIP is the external address of the instance, which the new vm takes
+// over with its network interfaces
+func publicIP(instance *compute.Instance) string {
+	for _, networkInterface := range instance.NetworkInterfaces {
+		for _, accessConfig := range networkInterface.AccessConfigs {
+			if accessConfig.NatIP != "" {
+				return accessConfig.NatIP
+			}
+		}
+	}
+	return ""
+}
+
+// DataDisks are the disks of the instance other than its boot disk
+func DataDisks(instance *compute.Instance) []*compute.AttachedDisk {
+	var dataDisks []*compute.AttachedDisk
+	for _, disk := range instance.Disks {
+		if !disk.Boot {
+			dataDisks = append(dataDisks, disk)
+		}
+	}
+	return dataDisks
+}
+
+// migratedDisk attaches an existing disk under the same device name, so that
+// it shows up at the same /dev/disk/by-id path, and keeps it when the vm it
+// is attached to is deleted
+func migratedDisk(disk *compute.AttachedDisk) *compute.AttachedDisk {
+	return &compute.AttachedDisk{
+		Source:     disk.Source,
+		DeviceName: disk.DeviceName,
+		Mode:       disk.Mode,
+		Interface:  disk.Interface,
+		Type:       disk.Type,
+		AutoDelete: false,
+	}
+}
 
-	return c.WaitForStatus(newInstance.Name, InstanceRunning)
+func (c *Client) newName(data iaas.NameData, suffix string) (string, error) {
+	name, err := c.namer.Name(data, suffix)
+	if err != nil {
+		return "", err
+	}
+	return name, ValidateName(name)
+}
+
+func ConfigWaiter(value iaas.Waiter) func(*Client) error {
+	return func(gcpClient *Client) error {
+		gcpClient.waiter = value
+		return nil
+	}
+}
+
+func ConfigProgressReporter(value iaas.ProgressReporter) func(*Client) error {
+	return func(gcpClient *Client) error {
+		gcpClient.progress = value
+		return nil
+	}
 }
 
-func ConfigTimeout(value time.Duration) func(*Client) error {
+func ConfigMigrateDataDisk(value bool) func(*Client) error {
 	return func(gcpClient *Client) error {
-		gcpClient.timeout = value * time.Second
+		gcpClient.migrateDataDisk = value
+		return nil
+	}
+}
+
+func ConfigNamer(value iaas.Namer) func(*Client) error {
+	return func(gcpClient *Client) error {
+		gcpClient.namer = value
+		return nil
+	}
+}
+
+func ConfigHookRunner(value *iaas.HookRunner) func(*Client) error {
+	return func(gcpClient *Client) error {
+		gcpClient.hooks = value
+		return nil
+	}
+}
+
+func ConfigLogger(value *iaas.Logger) func(*Client) error {
+	return func(gcpClient *Client) error {
+		gcpClient.logger = value
 		return nil
 	}
 }
@@ -147,6 +565,101 @@ func (s *Client) GetDisk(identifier string) (iaas.Disk, error) {
 	}, nil
 }
 
+func (s *Client) GetVM(identifier string) (iaas.VM, error) {
+	instance, err := s.GetVMInfo(Filter{
+		NameRegexString: identifier + "*",
+	})
+	if err != nil {
+		return iaas.VM{}, errwrap.Wrap(err, "getvminfo failed")
+	}
+
+	vm := iaas.VM{
+		ID:   fmt.Sprintf("%d", instance.Id),
+		Name: instance.Name,
+	}
+	for _, attachedDisk := range instance.Disks {
+		if !attachedDisk.Boot {
+			continue
+		}
+
+		// instances only know their disks, and the disks their images
+		diskName := attachedDisk.Source[strings.LastIndex(attachedDisk.Source, "/")+1:]
+		disk, err := s.Disk(Filter{NameRegexString: "^" + regexp.QuoteMeta(diskName) + "$"})
+		if err != nil {
+			return iaas.VM{}, errwrap.Wrap(err, "failed finding the boot disk")
+		}
+		vm.Image = disk.SourceImage
+	}
+	return vm, nil
+}
+
+// DescribeVM describes the running instance as a spec, with the instance as
+// its raw description. The network tags, which firewall rules apply by, are
+// its security groups.
+func (s *Client) DescribeVM(identifier string) (iaas.VMSpec, error) {
+	instance, err := s.GetVMInfo(Filter{
+		NameRegexString: identifier + "*",
+	})
+	if err != nil {
+		return iaas.VMSpec{}, errwrap.Wrap(err, "getvminfo failed")
+	}
+
+	diskList, err := s.googleClient.DiskList(s.projectName, s.zoneName)
+	if err != nil {
+		return iaas.VMSpec{}, errwrap.Wrap(err, "call DiskList on google client failed")
+	}
+	disks := map[string]*compute.Disk{}
+	for _, disk := range diskList.Items {
+		disks[disk.Name] = disk
+	}
+
+	spec := iaas.VMSpec{
+		IaaS:         "gcp",
+		Name:         instance.Name,
+		ID:           fmt.Sprintf("%d", instance.Id),
+		InstanceType: lastSegment(instance.MachineType),
+		Zone:         lastSegment(instance.Zone),
+		Network: iaas.NetworkSpec{
+			PublicIP: publicIP(instance),
+		},
+		Tags: instance.Labels,
+		Raw:  instance,
+	}
+	if instance.Tags != nil {
+		spec.Network.SecurityGroups = append([]string{}, instance.Tags.Items...)
+	}
+	if len(instance.NetworkInterfaces) > 0 {
+		spec.Network.Network = lastSegment(instance.NetworkInterfaces[0].Network)
+		spec.Network.Subnet = lastSegment(instance.NetworkInterfaces[0].Subnetwork)
+		spec.Network.PrivateIP = instance.NetworkInterfaces[0].NetworkIP
+	}
+	if len(instance.ServiceAccounts) > 0 {
+		spec.Identity = instance.ServiceAccounts[0].Email
+	}
+	for _, attachedDisk := range instance.Disks {
+		diskSpec := iaas.DiskSpec{
+			Name:   attachedDisk.DeviceName,
+			Boot:   attachedDisk.Boot,
+			Source: lastSegment(attachedDisk.Source),
+		}
+		if disk, ok := disks[diskSpec.Source]; ok {
+			diskSpec.SizeGB = disk.SizeGb
+			diskSpec.Type = lastSegment(disk.Type)
+			if attachedDisk.Boot {
+				spec.Image = disk.SourceImage
+			}
+		}
+		spec.Disks = append(spec.Disks, diskSpec)
+	}
+	spec.Normalize()
+	return spec, nil
+}
+
+// lastSegment is the name at the end of a resource url
+func lastSegment(url string) string {
+	return url[strings.LastIndex(url, "/")+1:]
+}
+
 /* End Cliaas Client Interface */
 
 func (s *Client) Disk(filter Filter) (*compute.Disk, error) {
@@ -186,23 +699,70 @@ func ConfigProjectName(value string) func(*Client) error {
 	}
 }
 
-func (s *Client) CreateImage(tarball string, diskSizeGB int64) (string, error) {
-	imageName := fmt.Sprintf("opsman-disk-%v", time.Now().Format("2006-01-02-15-04-05"))
-	_, err := s.googleClient.ImageInsert(s.projectName, &compute.Image{
+func (s *Client) CreateImage(imageName string, tarball string, diskSizeGB int64) (string, error) {
+	operation, err := s.googleClient.ImageInsert(s.projectName, &compute.Image{
 		Name:       imageName,
 		DiskSizeGb: diskSizeGB,
 		RawDisk: &compute.ImageRawDisk{
 			Source: fmt.Sprintf("http://storage.googleapis.com/%v", tarball),
 		},
-	}, s.timeout)
+	})
+	if err != nil {
+		return "", errwrap.Wrap(err, "disk image insert failed")
+	}
+
+	// the insert operation reports how far along the import is, the image
+	// itself only whether it is done
+	err = s.waiter.Wait("waiting for image "+imageName+" to be ready", s.waiter.Timeouts.ImageImport, func() (bool, error) {
+		if operation.Status != OperationDone {
+			current, err := s.googleClient.GlobalOperationGet(s.projectName, operation.Name)
+			if err != nil {
+				return false, errwrap.Wrap(err, "global operation get failed")
+			}
+			operation = current
+		}
+
+		progress := operation.Progress
+		if operation.Status == OperationDone {
+			progress = 100
+		}
+		iaas.ReportProgress(s.progress, iaas.ProgressEvent{
+			Step:    iaas.StepImportingImage,
+			Subject: imageName,
+			Current: progress,
+			Total:   100,
+		})
+
+		if operation.Status != OperationDone {
+			return false, nil
+		}
+		if operation.Error != nil {
+			return false, errors.New("image creation failed")
+		}
+		return true, nil
+	})
 	if err != nil {
 		return "", err
 	}
+	iaas.ReportStep(s.progress, iaas.StepImportedImage, imageName)
 
 	sourceImage := fmt.Sprintf("projects/%s/global/images/%s", s.projectName, imageName)
 	return sourceImage, nil
 }
 
+func (s *Client) deleteImage(imageName string) error {
+	operation, err := s.googleClient.ImageDelete(s.projectName, imageName)
+	if err != nil {
+		return errwrap.Wrap(err, "call to googleclient.ImageDelete yielded error")
+	}
+
+	if operation.Error != nil {
+		return errors.New("unexpected errors from operation response from google client")
+	}
+
+	return nil
+}
+
 func (s *Client) CreateVM(instance compute.Instance) error {
 	operation, err := s.googleClient.Insert(s.projectName, s.zoneName, &instance)
 	if err != nil {
@@ -216,6 +776,87 @@ func (s *Client) CreateVM(instance compute.Instance) error {
 	return nil
 }
 
+// DetachDisk detaches the disk with deviceName from the instance, and waits
+// until it is free to attach elsewhere
+func (s *Client) DetachDisk(instanceName string, deviceName string) error {
+	operation, err := s.googleClient.DetachDisk(s.projectName, s.zoneName, instanceName, deviceName)
+	if err != nil {
+		return errwrap.Wrap(err, "call to googleclient.DetachDisk yielded error")
+	}
+
+	return s.waitForZoneOperation(operation, "waiting for disk "+deviceName+" to detach from "+instanceName, s.waiter.Timeouts.DiskAttachment)
+}
+
+// AttachDisk attaches an existing disk to the instance, and waits until it
+// is attached
+func (s *Client) AttachDisk(instanceName string, disk compute.AttachedDisk) error {
+	operation, err := s.googleClient.AttachDisk(s.projectName, s.zoneName, instanceName, &disk)
+	if err != nil {
+		return errwrap.Wrap(err, "call to googleclient.AttachDisk yielded error")
+	}
+
+	return s.waitForZoneOperation(operation, "waiting for disk "+disk.DeviceName+" to attach to "+instanceName, s.waiter.Timeouts.DiskAttachment)
+}
+
+func (s *Client) waitForZoneOperation(operation *compute.Operation, description string, timeout time.Duration) error {
+	return s.waiter.Wait(description, timeout, func() (bool, error) {
+		if operation.Status != OperationDone {
+			current, err := s.googleClient.ZoneOperationGet(s.projectName, s.zoneName, operation.Name)
+			if err != nil {
+				return false, errwrap.Wrap(err, "zone operation get failed")
+			}
+			operation = current
+		}
+
+		if operation.Status != OperationDone {
+			return false, nil
+		}
+		if operation.Error != nil && len(operation.Error.Errors) > 0 {
+			return false, fmt.Errorf("operation %s failed: %s", operation.Name, operation.Error.Errors[0].Message)
+		}
+		return true, nil
+	})
+}
+
+// releaseAccessConfig deletes the external access config of the instance, so
+// that its address is free for the new vm. The access config is saved on the
+// instance first, and only deleted once the save is done, so that Start can
+// give the instance its address back.
+func (s *Client) releaseAccessConfig(instanceName string) error {
+	instance, err := s.getVMInfo(Filter{NameRegexString: "^" + regexp.QuoteMeta(instanceName) + "$"}, InstanceAll)
+	if iaas.ClassOf(err) == iaas.NotFound {
+		return nil
+	}
+	if err != nil {
+		return errwrap.Wrap(err, "failed getting vm instance")
+	}
+	metadata := withSavedAccessConfig(instance)
+	if metadata == nil {
+		return nil
+	}
+
+	operation, err := s.googleClient.SetMetadata(s.projectName, s.zoneName, instanceName, metadata)
+	if err != nil {
+		return errwrap.Wrap(err, "could not save access config")
+	}
+	err = s.waitForZoneOperation(operation, "waiting for the access config of "+instanceName+" to be saved", s.waiter.Timeouts.IPAssociation)
+	if err != nil {
+		return errwrap.Wrap(err, "could not save access config")
+	}
+
+	accessConfigName := instance.NetworkInterfaces[0].AccessConfigs[0].Name
+	nicName := instance.NetworkInterfaces[0].Name
+	operation, err = s.googleClient.DeleteAccessConfig(s.projectName, s.zoneName, instanceName, accessConfigName, nicName)
+	if err != nil {
+		return errwrap.Wrap(err, "could not delete access config")
+	}
+	err = s.waitForZoneOperation(operation, "waiting for the access config of "+instanceName+" to be deleted", s.waiter.Timeouts.IPAssociation)
+	if err != nil {
+		return errwrap.Wrap(err, "could not delete access config")
+	}
+	return nil
+}
+
 func (s *Client) DeleteVM(instanceName string) error {
 	operation, err := s.googleClient.Delete(s.projectName, s.zoneName, instanceName)
 	if err != nil {
@@ -231,6 +872,11 @@ func (s *Client) DeleteVM(instanceName string) error {
 
 //StopVM - will try to stop the VM with the given name
 func (s *Client) StopVM(instanceName string) error {
+	err := s.releaseAccessConfig(instanceName)
+	if err != nil {
+		return err
+	}
+
 	operation, err := s.googleClient.Stop(s.projectName, s.zoneName, instanceName)
 	if err != nil {
 		return errwrap.Wrap(err, "call to googleclient.Stop yielded error")
@@ -259,47 +905,44 @@ func (s *Client) getVMInfo(filter Filter, status string) (*compute.Instance, err
 	for _, item := range list.Items {
 		var validID = regexp.MustCompile(filter.TagRegexString)
 		var validName = regexp.MustCompile(filter.NameRegexString)
-		taglist := strings.Join(item.Tags.Items, " ")
+		var taglist string
+		if item.Tags != nil {
+			taglist = strings.Join(item.Tags.Items, " ")
+		}
 		tagMatch := validID.MatchString(taglist)
 		nameMatch := validName.MatchString(item.Name)
 
 		if tagMatch &&
 			nameMatch &&
-			(status == InstanceAll || item.Status == InstanceRunning) {
+			(status == InstanceAll || item.Status == status) {
 			return item, nil
 		}
 	}
-	return nil, fmt.Errorf("No instance matches found")
+	return nil, iaas.NewNotFoundError(fmt.Errorf("No instance matches found"))
 }
 
-func (s *Client) WaitForStatus(vmName string, desiredStatus string) error {
-	errChannel := make(chan error)
-	go func() {
-		for {
-			vmInfo, err := s.getVMInfo(Filter{NameRegexString: vmName}, InstanceAll)
-			if err != nil {
-				errChannel <- errwrap.Wrap(err, "GetVMInfo call failed")
-				return
-			}
-
-			if vmInfo.Status == desiredStatus {
-				errChannel <- nil
-				return
-			}
+func (s *Client) WaitForStatus(vmName string, desiredStatus string, timeout time.Duration) error {
+	return s.waiter.Wait(fmt.Sprintf("waiting for %s to become %s", vmName, desiredStatus), timeout, func() (bool, error) {
+		vmInfo, err := s.getVMInfo(Filter{NameRegexString: vmName}, InstanceAll)
+		if iaas.ClassOf(err) == iaas.NotFound {
+			return false, nil
 		}
-	}()
-	select {
-	case res := <-errChannel:
-		return res
-	case <-time.After(s.timeout):
-		return errors.New("polling for status timed out")
-	}
+		if err != nil {
+			return false, errwrap.Wrap(err, "GetVMInfo call failed")
+		}
+
+		return vmInfo.Status == desiredStatus, nil
+	})
 }
 
 type googleComputeClientWrapper struct {
 	imageService    *compute.ImagesService
+	operations      *compute.GlobalOperationsService
+	zoneOperations  *compute.ZoneOperationsService
 	instanceService *compute.InstancesService
 	disksService    *compute.DisksService
+	projectService  *compute.ProjectsService
+	httpClient      *http.Client
 	ctx             context.Context
 }
 
@@ -312,22 +955,23 @@ func (s *googleComputeClientWrapper) Delete(project string, zone string, instanc
 }
 
 func (s *googleComputeClientWrapper) Stop(project string, zone string, instance string) (*compute.Operation, error) {
-	vmInstance, err := s.instanceService.Get(project, zone, instance).Context(s.ctx).Do()
-	if err != nil {
-		return nil, errwrap.Wrap(err, "failed getting vm instance")
-	}
+	return s.instanceService.Stop(project, zone, instance).Context(s.ctx).Do()
+}
 
-	if len(vmInstance.NetworkInterfaces) > 0 && len(vmInstance.NetworkInterfaces[0].AccessConfigs) > 0 {
-		accessConfigName := vmInstance.NetworkInterfaces[0].AccessConfigs[0].Name
-		nicName := vmInstance.NetworkInterfaces[0].Name
-		operation, err := s.instanceService.DeleteAccessConfig(project, zone, instance, accessConfigName, nicName).Context(s.ctx).Do()
-		if err != nil {
-			return operation, errwrap.Wrap(err, "could not delete access config")
-		}
+func (s *googleComputeClientWrapper) Start(project string, zone string, instance string) (*compute.Operation, error) {
+	return s.instanceService.Start(project, zone, instance).Context(s.ctx).Do()
+}
 
-	}
+func (s *googleComputeClientWrapper) AddAccessConfig(project string, zone string, instance string, networkInterface string, accessConfig *compute.AccessConfig) (*compute.Operation, error) {
+	return s.instanceService.AddAccessConfig(project, zone, instance, networkInterface, accessConfig).Context(s.ctx).Do()
+}
 
-	return s.instanceService.Stop(project, zone, instance).Context(s.ctx).Do()
+func (s *googleComputeClientWrapper) DeleteAccessConfig(project string, zone string, instance string, accessConfig string, networkInterface string) (*compute.Operation, error) {
+	return s.instanceService.DeleteAccessConfig(project, zone, instance, accessConfig, networkInterface).Context(s.ctx).Do()
+}
+
+func (s *googleComputeClientWrapper) SetMetadata(project string, zone string, instance string, metadata *compute.Metadata) (*compute.Operation, error) {
+	return s.instanceService.SetMetadata(project, zone, instance, metadata).Context(s.ctx).Do()
 }
 
 func (s *googleComputeClientWrapper) Insert(project string, zone string, instance *compute.Instance) (*compute.Operation, error) {
@@ -338,36 +982,32 @@ func (s *googleComputeClientWrapper) DiskList(project string, zone string) (*com
 	return s.disksService.List(project, zone).Context(s.ctx).Do()
 }
 
-func (s *googleComputeClientWrapper) ImageInsert(project string, image *compute.Image, timeout time.Duration) (*compute.Operation, error) {
-	operation, err := s.imageService.Insert(project, image).Context(s.ctx).Do()
-	if err != nil {
-		return operation, errwrap.Wrap(err, "disk image insert failed")
-	}
+func (s *googleComputeClientWrapper) ProjectGet(project string) (*compute.Project, error) {
+	return s.projectService.Get(project).Context(s.ctx).Do()
+}
 
-	errChannel := make(chan error)
-	go func() {
-		for {
-			image, err := s.imageService.Get(project, image.Name).Context(s.ctx).Do()
-			if err != nil {
-				errChannel <- errwrap.Wrap(err, "image get failed")
-			}
+func (s *googleComputeClientWrapper) ImageInsert(project string, image *compute.Image) (*compute.Operation, error) {
+	return s.imageService.Insert(project, image).Context(s.ctx).Do()
+}
 
-			if image != nil && image.Status == ImageReady {
-				errChannel <- nil
-			}
+func (s *googleComputeClientWrapper) ImageDelete(project string, image string) (*compute.Operation, error) {
+	return s.imageService.Delete(project, image).Context(s.ctx).Do()
+}
 
-			if image != nil && image.Status == ImageFailed {
-				errChannel <- errors.New("image creation failed")
-			}
-		}
-	}()
-	select {
-	case res := <-errChannel:
-		return operation, res
-	case <-time.After(timeout):
-		return nil, errors.New("polling for status timed out")
-	}
-	return nil, nil
+func (s *googleComputeClientWrapper) GlobalOperationGet(project string, operationName string) (*compute.Operation, error) {
+	return s.operations.Get(project, operationName).Context(s.ctx).Do()
+}
+
+func (s *googleComputeClientWrapper) DetachDisk(project string, zone string, instance string, deviceName string) (*compute.Operation, error) {
+	return s.instanceService.DetachDisk(project, zone, instance, deviceName).Context(s.ctx).Do()
+}
+
+func (s *googleComputeClientWrapper) AttachDisk(project string, zone string, instance string, disk *compute.AttachedDisk) (*compute.Operation, error) {
+	return s.instanceService.AttachDisk(project, zone, instance, disk).Context(s.ctx).Do()
+}
+
+func (s *googleComputeClientWrapper) ZoneOperationGet(project string, zone string, operationName string) (*compute.Operation, error) {
+	return s.zoneOperations.Get(project, zone, operationName).Context(s.ctx).Do()
 }
 
 func createGCPInstanceFromExisting(vmInstance *compute.Instance, sourceImage string, diskSizeGB int64, name string) *compute.Instance {
@@ -375,9 +1015,7 @@ func createGCPInstanceFromExisting(vmInstance *compute.Instance, sourceImage str
 		NetworkInterfaces: vmInstance.NetworkInterfaces,
 		MachineType:       vmInstance.MachineType,
 		Name:              name,
-		Tags: &compute.Tags{
-			Items: vmInstance.Tags.Items,
-		},
+		Tags:              &compute.Tags{},
 		Disks: []*compute.AttachedDisk{
 			&compute.AttachedDisk{
 				Boot: true,
@@ -388,6 +1026,90 @@ func createGCPInstanceFromExisting(vmInstance *compute.Instance, sourceImage str
 			},
 		},
 	}
+	if vmInstance.Tags != nil {
+		newInstance.Tags.Items = vmInstance.Tags.Items
+	}
 	newInstance.NetworkInterfaces[0].NetworkIP = ""
 	return newInstance
 }
+
+// createGCPInstanceFromSpec is the instance a spec describes. Names in the
+// spec are turned into the partial urls the api takes, and the network tags
+// are its security groups.
+func createGCPInstanceFromSpec(spec iaas.VMSpec, sourceImage string, zone string) *compute.Instance {
+	region := zone
+	if i := strings.LastIndex(zone, "-"); i > 0 {
+		region = zone[:i]
+	}
+
+	networkInterface := &compute.NetworkInterface{
+		Network:    resourceURL(spec.Network.Network, "global/networks/"),
+		Subnetwork: resourceURL(spec.Network.Subnet, "regions/"+region+"/subnetworks/"),
+		NetworkIP:  spec.Network.PrivateIP,
+	}
+	if spec.Network.PublicIP != "" {
+		networkInterface.AccessConfigs = []*compute.AccessConfig{
+			{
+				Name:  "External NAT",
+				Type:  "ONE_TO_ONE_NAT",
+				NatIP: spec.Network.PublicIP,
+			},
+		}
+	}
+
+	newInstance := &compute.Instance{
+		Name:              spec.Name,
+		MachineType:       resourceURL(spec.InstanceType, "zones/"+zone+"/machineTypes/"),
+		NetworkInterfaces: []*compute.NetworkInterface{networkInterface},
+		Tags: &compute.Tags{
+			Items: spec.Network.SecurityGroups,
+		},
+		Labels: spec.Tags,
+	}
+	if spec.Identity != "" {
+		newInstance.ServiceAccounts = []*compute.ServiceAccount{
+			{
+				Email:  spec.Identity,
+				Scopes: []string{"https://www.googleapis.com/auth/cloud-platform"},
+			},
+		}
+	}
+
+	for _, disk := range spec.Disks {
+		switch {
+		case disk.Boot:
+			newInstance.Disks = append(newInstance.Disks, &compute.AttachedDisk{
+				Boot:       true,
+				AutoDelete: true,
+				InitializeParams: &compute.AttachedDiskInitializeParams{
+					SourceImage: sourceImage,
+					DiskSizeGb:  disk.SizeGB,
+					DiskType:    resourceURL(disk.Type, "zones/"+zone+"/diskTypes/"),
+				},
+			})
+		case disk.Source != "":
+			newInstance.Disks = append(newInstance.Disks, &compute.AttachedDisk{
+				DeviceName: disk.Name,
+				Source:     resourceURL(disk.Source, "zones/"+zone+"/disks/"),
+			})
+		default:
+			newInstance.Disks = append(newInstance.Disks, &compute.AttachedDisk{
+				DeviceName: disk.Name,
+				InitializeParams: &compute.AttachedDiskInitializeParams{
+					DiskSizeGb: disk.SizeGB,
+					DiskType:   resourceURL(disk.Type, "zones/"+zone+"/diskTypes/"),
+				},
+			})
+		}
+	}
+	return newInstance
+}
+
+// resourceURL is the partial url of a resource given by name, or the name
+// itself when it already is a url
+func resourceURL(name string, prefix string) string {
+	if name == "" || strings.Contains(name, "/") {
+		return name
+	}
+	return prefix + name
+}
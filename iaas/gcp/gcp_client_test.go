@@ -2,12 +2,17 @@ package gcp_test
 
 import (
 	"fmt"
+	"time"
 
 	"errors"
+
+	"code.cloudfoundry.org/clock"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/cliaas/iaas"
 	. "github.com/pivotal-cf/cliaas/iaas/gcp"
 	"github.com/pivotal-cf/cliaas/iaas/gcp/gcpfakes"
+	"github.com/pivotal-cf/cliaas/iaas/iaasfakes"
 	errwrap "github.com/pkg/errors"
 	"google.golang.org/api/compute/v1"
 )
@@ -124,17 +129,55 @@ var _ = Describe("GCPClientAPI", func() {
 				})
 
 				It("then the image should be created", func() {
-					_, err := client.CreateImage(controlTarballPath, controlDiskSizeGB)
+					_, err := client.CreateImage("opsman-2017-03-01-12-00-00-image", controlTarballPath, controlDiskSizeGB)
 					Expect(err).ShouldNot(HaveOccurred())
 					Expect(fakeGoogleClient.ImageInsertCallCount()).Should(Equal(1))
-					project, image, _ := fakeGoogleClient.ImageInsertArgsForCall(0)
+					project, image := fakeGoogleClient.ImageInsertArgsForCall(0)
 					Expect(project).Should(Equal(controlProject))
 					Expect(image.DiskSizeGb).Should(Equal(controlDiskSizeGB))
-					Expect(len(image.Name)).Should(BeNumerically(">", 0))
+					Expect(image.Name).Should(Equal("opsman-2017-03-01-12-00-00-image"))
 					Expect(image.RawDisk.Source).Should(ContainSubstring(controlTarballPath))
 				})
 			})
 
+			Context("when the import takes a while", func() {
+				var fakeGoogleClient *gcpfakes.FakeGoogleComputeClient
+				var fakeProgress *iaasfakes.FakeProgressReporter
+
+				BeforeEach(func() {
+					fakeGoogleClient = new(gcpfakes.FakeGoogleComputeClient)
+					fakeProgress = new(iaasfakes.FakeProgressReporter)
+					fakeGoogleClient.ImageInsertReturns(&compute.Operation{Name: "operation-1", Status: "PENDING"}, nil)
+					fakeGoogleClient.GlobalOperationGetReturnsOnCall(0, &compute.Operation{Name: "operation-1", Status: "RUNNING", Progress: 40}, nil)
+					fakeGoogleClient.GlobalOperationGetReturnsOnCall(1, &compute.Operation{Name: "operation-1", Status: "DONE", Progress: 100}, nil)
+
+					client, _ = NewClient(
+						ConfigGoogleClient(fakeGoogleClient),
+						ConfigZoneName(controlZone),
+						ConfigProjectName(controlProject),
+						ConfigWaiter(iaas.NewWaiter(clock.NewClock(), iaas.Timeouts{PollInterval: time.Millisecond})),
+						ConfigProgressReporter(fakeProgress),
+					)
+				})
+
+				It("then it should report the progress of the insert operation", func() {
+					_, err := client.CreateImage("opsman-2017-03-01-12-00-00-image", controlTarballPath, controlDiskSizeGB)
+					Expect(err).ShouldNot(HaveOccurred())
+
+					project, operationName := fakeGoogleClient.GlobalOperationGetArgsForCall(0)
+					Expect(project).Should(Equal(controlProject))
+					Expect(operationName).Should(Equal("operation-1"))
+
+					var percents []int
+					for i := 0; i < fakeProgress.ReportCallCount(); i++ {
+						if event := fakeProgress.ReportArgsForCall(i); event.Step == iaas.StepImportingImage {
+							percents = append(percents, event.Percent())
+						}
+					}
+					Expect(percents).Should(Equal([]int{40, 100}))
+				})
+			})
+
 			Context("when gcp returns an error", func() {
 				BeforeEach(func() {
 					fakeGoogleClient := new(gcpfakes.FakeGoogleComputeClient)
@@ -147,7 +190,7 @@ var _ = Describe("GCPClientAPI", func() {
 					)
 				})
 				It("then we should exit in error", func() {
-					_, err := client.CreateImage(controlTarballPath, controlDiskSizeGB)
+					_, err := client.CreateImage("opsman-2017-03-01-12-00-00-image", controlTarballPath, controlDiskSizeGB)
 					Expect(err).Should(HaveOccurred())
 				})
 			})
@@ -236,6 +279,7 @@ var _ = Describe("GCPClientAPI", func() {
 				}
 				BeforeEach(func() {
 					fakeGoogleClient = new(gcpfakes.FakeGoogleComputeClient)
+					fakeGoogleClient.ListReturns(&compute.InstanceList{}, nil)
 					fakeGoogleClient.StopReturns(fakeOperation, nil)
 
 					client, _ = NewClient(
@@ -255,6 +299,74 @@ var _ = Describe("GCPClientAPI", func() {
 				})
 			})
 
+			Context("when the instance has an external address", func() {
+				var fakeGoogleClient *gcpfakes.FakeGoogleComputeClient
+
+				BeforeEach(func() {
+					fakeGoogleClient = new(gcpfakes.FakeGoogleComputeClient)
+					fakeGoogleClient.ListReturns(&compute.InstanceList{Items: []*compute.Instance{
+						{Name: controlInstanceName + "-2017-03-01-12-00-00", Status: InstanceRunning, Tags: &compute.Tags{}},
+						{
+							Name:   controlInstanceName,
+							Status: InstanceRunning,
+							Tags:   &compute.Tags{},
+							NetworkInterfaces: []*compute.NetworkInterface{{
+								Name:          "nic0",
+								AccessConfigs: []*compute.AccessConfig{{Name: "External NAT", NatIP: "1.2.3.4"}},
+							}},
+						},
+					}}, nil)
+					fakeGoogleClient.SetMetadataReturns(&compute.Operation{Name: "operation-1", Status: "RUNNING"}, nil)
+					fakeGoogleClient.ZoneOperationGetReturns(&compute.Operation{Name: "operation-1", Status: "DONE"}, nil)
+					fakeGoogleClient.DeleteAccessConfigReturns(&compute.Operation{Status: "DONE"}, nil)
+					fakeGoogleClient.StopReturns(&compute.Operation{Status: "DONE"}, nil)
+
+					client, _ = NewClient(
+						ConfigGoogleClient(fakeGoogleClient),
+						ConfigZoneName(controlZone),
+						ConfigProjectName(controlProject),
+						ConfigWaiter(iaas.NewWaiter(clock.NewClock(), iaas.Timeouts{PollInterval: time.Millisecond})),
+					)
+				})
+
+				It("then it should save the access config and wait for the save before deleting it", func() {
+					err := client.StopVM(controlInstanceName)
+					Expect(err).ShouldNot(HaveOccurred())
+
+					Expect(fakeGoogleClient.SetMetadataCallCount()).Should(Equal(1))
+					_, _, instanceName, metadata := fakeGoogleClient.SetMetadataArgsForCall(0)
+					Expect(instanceName).Should(Equal(controlInstanceName))
+					Expect(metadata.Items[0].Key).Should(Equal("cliaas-access-config"))
+					Expect(fakeGoogleClient.ZoneOperationGetCallCount()).Should(Equal(1))
+
+					Expect(fakeGoogleClient.DeleteAccessConfigCallCount()).Should(Equal(1))
+					_, _, instanceName, accessConfig, networkInterface := fakeGoogleClient.DeleteAccessConfigArgsForCall(0)
+					Expect(instanceName).Should(Equal(controlInstanceName))
+					Expect(accessConfig).Should(Equal("External NAT"))
+					Expect(networkInterface).Should(Equal("nic0"))
+					Expect(fakeGoogleClient.StopCallCount()).Should(Equal(1))
+				})
+
+				Context("when saving the access config fails", func() {
+					BeforeEach(func() {
+						fakeGoogleClient.ZoneOperationGetReturns(&compute.Operation{
+							Name:   "operation-1",
+							Status: "DONE",
+							Error: &compute.OperationError{
+								Errors: []*compute.OperationErrorErrors{{Message: "fingerprint mismatch"}},
+							},
+						}, nil)
+					})
+
+					It("then it should keep the access config and not stop the instance", func() {
+						err := client.StopVM(controlInstanceName)
+						Expect(err).Should(MatchError(ContainSubstring("could not save access config")))
+						Expect(fakeGoogleClient.DeleteAccessConfigCallCount()).Should(Equal(0))
+						Expect(fakeGoogleClient.StopCallCount()).Should(Equal(0))
+					})
+				})
+			})
+
 			Context("when called with a invalid (not-running) instance name", func() {
 				BeforeEach(func() {
 					var fakeGoogleClient = new(gcpfakes.FakeGoogleComputeClient)
@@ -268,6 +380,7 @@ var _ = Describe("GCPClientAPI", func() {
 						},
 						Status: "DONE",
 					}
+					fakeGoogleClient.ListReturns(&compute.InstanceList{}, nil)
 					fakeGoogleClient.StopReturns(fakeOperation, nil)
 
 					client, _ = NewClient(
@@ -286,6 +399,7 @@ var _ = Describe("GCPClientAPI", func() {
 				var controlErr = fmt.Errorf("Some GCP API Error")
 				BeforeEach(func() {
 					var fakeGoogleClient = new(gcpfakes.FakeGoogleComputeClient)
+					fakeGoogleClient.ListReturns(&compute.InstanceList{}, nil)
 					fakeGoogleClient.StopReturns(nil, controlErr)
 
 					client, _ = NewClient(
@@ -302,6 +416,168 @@ var _ = Describe("GCPClientAPI", func() {
 			})
 		})
 
+		Describe("given a Start method and a stopped instance", func() {
+			var fakeGoogleClient *gcpfakes.FakeGoogleComputeClient
+			var savedAccessConfig = `{"network_interface":"nic0","name":"External NAT","nat_ip":"1.2.3.4"}`
+
+			BeforeEach(func() {
+				fakeGoogleClient = new(gcpfakes.FakeGoogleComputeClient)
+				fakeGoogleClient.ListReturns(&compute.InstanceList{Items: []*compute.Instance{
+					{Name: controlInstanceName, Status: InstanceRunning, Tags: &compute.Tags{}},
+					{
+						Name:              controlInstanceName,
+						Status:            InstanceTerminated,
+						Tags:              &compute.Tags{},
+						NetworkInterfaces: []*compute.NetworkInterface{{Name: "nic0"}},
+						Metadata: &compute.Metadata{Items: []*compute.MetadataItems{
+							{Key: "cliaas-access-config", Value: &savedAccessConfig},
+						}},
+					},
+				}}, nil)
+				fakeGoogleClient.AddAccessConfigReturns(&compute.Operation{Status: "DONE"}, nil)
+				fakeGoogleClient.StartReturns(&compute.Operation{Status: "DONE"}, nil)
+
+				client, _ = NewClient(
+					ConfigGoogleClient(fakeGoogleClient),
+					ConfigZoneName(controlZone),
+					ConfigProjectName(controlProject),
+					ConfigWaiter(iaas.NewWaiter(clock.NewClock(), iaas.Timeouts{PollInterval: time.Millisecond})),
+				)
+			})
+
+			It("then it should give the instance its address back before starting it", func() {
+				err := client.Start(controlInstanceName, false)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				Expect(fakeGoogleClient.AddAccessConfigCallCount()).Should(Equal(1))
+				_, _, instanceName, networkInterface, accessConfig := fakeGoogleClient.AddAccessConfigArgsForCall(0)
+				Expect(instanceName).Should(Equal(controlInstanceName))
+				Expect(networkInterface).Should(Equal("nic0"))
+				Expect(accessConfig).Should(Equal(&compute.AccessConfig{Name: "External NAT", NatIP: "1.2.3.4", Type: "ONE_TO_ONE_NAT"}))
+
+				_, _, instanceName = fakeGoogleClient.StartArgsForCall(0)
+				Expect(instanceName).Should(Equal(controlInstanceName))
+			})
+
+			Context("when the saved address is gone", func() {
+				BeforeEach(func() {
+					fakeGoogleClient.AddAccessConfigReturnsOnCall(0, &compute.Operation{
+						Status: "DONE",
+						Error: &compute.OperationError{
+							Errors: []*compute.OperationErrorErrors{{Message: "address is not reserved"}},
+						},
+					}, nil)
+				})
+
+				It("then it should fall back to an ephemeral address", func() {
+					err := client.Start(controlInstanceName, false)
+					Expect(err).ShouldNot(HaveOccurred())
+
+					Expect(fakeGoogleClient.AddAccessConfigCallCount()).Should(Equal(2))
+					_, _, _, _, accessConfig := fakeGoogleClient.AddAccessConfigArgsForCall(1)
+					Expect(accessConfig.NatIP).Should(BeEmpty())
+					Expect(fakeGoogleClient.StartCallCount()).Should(Equal(1))
+				})
+			})
+		})
+
+		Describe("given a Replace method and a running instance", func() {
+			var fakeGoogleClient *gcpfakes.FakeGoogleComputeClient
+			var savedAccessConfig = `{"network_interface":"nic0","name":"External NAT","nat_ip":"1.2.3.4"}`
+
+			BeforeEach(func() {
+				running := &compute.InstanceList{Items: []*compute.Instance{{
+					Name:   controlInstanceName,
+					Status: InstanceRunning,
+					Tags:   &compute.Tags{},
+					NetworkInterfaces: []*compute.NetworkInterface{{
+						Name:          "nic0",
+						AccessConfigs: []*compute.AccessConfig{{Name: "External NAT", NatIP: "1.2.3.4"}},
+					}},
+				}}}
+				fakeGoogleClient = new(gcpfakes.FakeGoogleComputeClient)
+				fakeGoogleClient.ListReturnsOnCall(0, running, nil)
+				fakeGoogleClient.ListReturnsOnCall(1, running, nil)
+				fakeGoogleClient.ListReturns(&compute.InstanceList{Items: []*compute.Instance{{
+					Name:              controlInstanceName,
+					Status:            InstanceTerminated,
+					Tags:              &compute.Tags{},
+					NetworkInterfaces: []*compute.NetworkInterface{{Name: "nic0"}},
+					Metadata: &compute.Metadata{Items: []*compute.MetadataItems{
+						{Key: "cliaas-access-config", Value: &savedAccessConfig},
+					}},
+				}}}, nil)
+				done := &compute.Operation{Status: "DONE"}
+				fakeGoogleClient.SetMetadataReturns(done, nil)
+				fakeGoogleClient.DeleteAccessConfigReturns(done, nil)
+				fakeGoogleClient.StopReturns(done, nil)
+				fakeGoogleClient.ImageInsertReturns(done, nil)
+				fakeGoogleClient.ImageDeleteReturns(done, nil)
+				fakeGoogleClient.AddAccessConfigReturns(done, nil)
+				fakeGoogleClient.StartReturns(done, nil)
+
+				client, _ = NewClient(
+					ConfigGoogleClient(fakeGoogleClient),
+					ConfigZoneName(controlZone),
+					ConfigProjectName(controlProject),
+					ConfigWaiter(iaas.NewWaiter(clock.NewClock(), iaas.Timeouts{PollInterval: time.Millisecond})),
+					ConfigHookRunner(&iaas.HookRunner{
+						Hooks:  iaas.Hooks{PreCreate: "false"},
+						Output: GinkgoWriter,
+					}),
+				)
+			})
+
+			Context("when the pre-create hook fails", func() {
+				It("then it should delete the new image and start the old instance with its address", func() {
+					err := client.Replace(controlInstanceName, "bucket/image.tar.gz", controlDiskSizeGB)
+					Expect(err).Should(MatchError(ContainSubstring("pre-create hook failed")))
+					Expect(fakeGoogleClient.InsertCallCount()).Should(Equal(0))
+
+					Expect(fakeGoogleClient.ImageDeleteCallCount()).Should(Equal(1))
+					_, imageName := fakeGoogleClient.ImageDeleteArgsForCall(0)
+					_, image := fakeGoogleClient.ImageInsertArgsForCall(0)
+					Expect(imageName).Should(Equal(image.Name))
+
+					Expect(fakeGoogleClient.AddAccessConfigCallCount()).Should(Equal(1))
+					_, _, instanceName, _, accessConfig := fakeGoogleClient.AddAccessConfigArgsForCall(0)
+					Expect(instanceName).Should(Equal(controlInstanceName))
+					Expect(accessConfig.NatIP).Should(Equal("1.2.3.4"))
+
+					Expect(fakeGoogleClient.StartCallCount()).Should(Equal(1))
+					_, _, instanceName = fakeGoogleClient.StartArgsForCall(0)
+					Expect(instanceName).Should(Equal(controlInstanceName))
+				})
+			})
+
+			Context("when the new instance does not come up", func() {
+				BeforeEach(func() {
+					fakeGoogleClient.InsertReturns(&compute.Operation{Status: "DONE"}, nil)
+					fakeGoogleClient.DeleteReturns(&compute.Operation{Status: "DONE"}, nil)
+					client, _ = NewClient(
+						ConfigGoogleClient(fakeGoogleClient),
+						ConfigZoneName(controlZone),
+						ConfigProjectName(controlProject),
+						ConfigWaiter(iaas.NewWaiter(clock.NewClock(), iaas.Timeouts{PollInterval: time.Millisecond, Create: time.Millisecond})),
+					)
+				})
+
+				It("then it should delete the new instance before starting the old one", func() {
+					err := client.Replace(controlInstanceName, "bucket/image.tar.gz", controlDiskSizeGB)
+					Expect(err).Should(HaveOccurred())
+
+					Expect(fakeGoogleClient.DeleteCallCount()).Should(Equal(1))
+					_, _, deleted := fakeGoogleClient.DeleteArgsForCall(0)
+					_, _, created := fakeGoogleClient.InsertArgsForCall(0)
+					Expect(deleted).Should(Equal(created.Name))
+
+					Expect(fakeGoogleClient.StartCallCount()).Should(Equal(1))
+					_, _, instanceName := fakeGoogleClient.StartArgsForCall(0)
+					Expect(instanceName).Should(Equal(controlInstanceName))
+				})
+			})
+		})
+
 		Describe("given a GetVMInfo method and a filter object argument", func() {
 			Context("when there is a matching instance", func() {
 				controlInstanceList := createInstanceList(controlInstanceName, controlInstanceTag)
@@ -325,6 +601,26 @@ var _ = Describe("GCPClientAPI", func() {
 				})
 			})
 
+			Context("when an instance has no tags", func() {
+				var untagged = &compute.Instance{Name: controlInstanceName, Status: InstanceRunning}
+				BeforeEach(func() {
+					var fakeGoogleClient = new(gcpfakes.FakeGoogleComputeClient)
+					fakeGoogleClient.ListReturns(&compute.InstanceList{Items: []*compute.Instance{untagged}}, nil)
+
+					client, _ = NewClient(
+						ConfigGoogleClient(fakeGoogleClient),
+						ConfigZoneName(controlZone),
+						ConfigProjectName(controlProject),
+					)
+				})
+
+				It("then it should match it by its name", func() {
+					inst, err := client.GetVMInfo(Filter{NameRegexString: controlInstanceName})
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(inst).Should(Equal(untagged))
+				})
+			})
+
 			Context("when there is no matching instance", func() {
 				BeforeEach(func() {
 					var fakeGoogleClient = new(gcpfakes.FakeGoogleComputeClient)
@@ -411,6 +707,261 @@ var _ = Describe("GCPClientAPI", func() {
 				})
 			})
 		})
+
+		Describe("given a GetVM method and an identifier", func() {
+			It("then it should yield the running instance and the image of its boot disk", func() {
+				fakeGoogleClient := new(gcpfakes.FakeGoogleComputeClient)
+				fakeGoogleClient.ListReturns(&compute.InstanceList{
+					Items: []*compute.Instance{{
+						Id:     1234,
+						Name:   controlInstanceName,
+						Status: InstanceRunning,
+						Tags:   &compute.Tags{},
+						Disks: []*compute.AttachedDisk{
+							{Boot: true, Source: "https://www.googleapis.com/compute/v1/projects/prj/zones/zone/disks/blah-boot"},
+							{Source: "https://www.googleapis.com/compute/v1/projects/prj/zones/zone/disks/blah-data"},
+						},
+					}},
+				}, nil)
+				fakeGoogleClient.DiskListReturns(&compute.DiskList{
+					Items: []*compute.Disk{
+						{Name: "blah-boot-old", SourceImage: "some-older-image"},
+						{Name: "blah-boot", SourceImage: "https://www.googleapis.com/compute/v1/projects/prj/global/images/opsman-1-10-3"},
+					},
+				}, nil)
+				client, _ = NewClient(
+					ConfigGoogleClient(fakeGoogleClient),
+					ConfigZoneName(controlZone),
+					ConfigProjectName(controlProject),
+				)
+
+				vm, err := client.GetVM(controlInstanceName)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(vm).Should(Equal(iaas.VM{
+					ID:    "1234",
+					Name:  controlInstanceName,
+					Image: "https://www.googleapis.com/compute/v1/projects/prj/global/images/opsman-1-10-3",
+				}))
+			})
+		})
+
+		Describe("given a DescribeVM method and an identifier", func() {
+			It("then it should describe the running instance as a spec", func() {
+				instance := &compute.Instance{
+					Id:          1234,
+					Name:        controlInstanceName,
+					Status:      InstanceRunning,
+					MachineType: "https://www.googleapis.com/compute/v1/projects/prj/zones/zone/machineTypes/n1-standard-2",
+					Zone:        "https://www.googleapis.com/compute/v1/projects/prj/zones/zone",
+					Tags:        &compute.Tags{Items: []string{"opsman", "allow-https"}},
+					Labels:      map[string]string{"env": "prod"},
+					NetworkInterfaces: []*compute.NetworkInterface{{
+						Network:       "https://www.googleapis.com/compute/v1/projects/prj/global/networks/pcf",
+						Subnetwork:    "https://www.googleapis.com/compute/v1/projects/prj/regions/region/subnetworks/infra",
+						NetworkIP:     "10.0.0.5",
+						AccessConfigs: []*compute.AccessConfig{{NatIP: "1.2.3.4"}},
+					}},
+					ServiceAccounts: []*compute.ServiceAccount{{Email: "opsman@prj.iam.gserviceaccount.com"}},
+					Disks: []*compute.AttachedDisk{
+						{DeviceName: "persistent-disk-0", Boot: true, Source: "https://www.googleapis.com/compute/v1/projects/prj/zones/zone/disks/blah-boot"},
+					},
+				}
+				fakeGoogleClient := new(gcpfakes.FakeGoogleComputeClient)
+				fakeGoogleClient.ListReturns(&compute.InstanceList{Items: []*compute.Instance{instance}}, nil)
+				fakeGoogleClient.DiskListReturns(&compute.DiskList{
+					Items: []*compute.Disk{{
+						Name:        "blah-boot",
+						SizeGb:      100,
+						Type:        "https://www.googleapis.com/compute/v1/projects/prj/zones/zone/diskTypes/pd-ssd",
+						SourceImage: "https://www.googleapis.com/compute/v1/projects/prj/global/images/opsman-1-10-3",
+					}},
+				}, nil)
+				client, _ = NewClient(
+					ConfigGoogleClient(fakeGoogleClient),
+					ConfigZoneName(controlZone),
+					ConfigProjectName(controlProject),
+				)
+
+				spec, err := client.DescribeVM(controlInstanceName)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(spec).Should(Equal(iaas.VMSpec{
+					IaaS:         "gcp",
+					Name:         controlInstanceName,
+					ID:           "1234",
+					Image:        "https://www.googleapis.com/compute/v1/projects/prj/global/images/opsman-1-10-3",
+					InstanceType: "n1-standard-2",
+					Zone:         "zone",
+					Network: iaas.NetworkSpec{
+						Network:        "pcf",
+						Subnet:         "infra",
+						PrivateIP:      "10.0.0.5",
+						PublicIP:       "1.2.3.4",
+						SecurityGroups: []string{"allow-https", "opsman"},
+					},
+					Disks: []iaas.DiskSpec{
+						{Name: "persistent-disk-0", Boot: true, SizeGB: 100, Type: "pd-ssd", Source: "blah-boot"},
+					},
+					Identity: "opsman@prj.iam.gserviceaccount.com",
+					Tags:     map[string]string{"env": "prod"},
+					Raw:      instance,
+				}))
+			})
+		})
+
+		Describe("given a Create method, an image tarball and a spec", func() {
+			var fakeGoogleClient *gcpfakes.FakeGoogleComputeClient
+			var spec iaas.VMSpec
+
+			BeforeEach(func() {
+				fakeGoogleClient = new(gcpfakes.FakeGoogleComputeClient)
+				fakeGoogleClient.ImageInsertReturns(&compute.Operation{Status: OperationDone}, nil)
+				fakeGoogleClient.InsertReturns(&compute.Operation{}, nil)
+				fakeGoogleClient.ListReturns(&compute.InstanceList{
+					Items: []*compute.Instance{{Name: "opsman", Status: InstanceRunning, Tags: &compute.Tags{}}},
+				}, nil)
+				client, _ = NewClient(
+					ConfigGoogleClient(fakeGoogleClient),
+					ConfigZoneName("us-central1-a"),
+					ConfigProjectName(controlProject),
+				)
+				spec = iaas.VMSpec{
+					Name:         "opsman",
+					InstanceType: "n1-standard-2",
+					Network: iaas.NetworkSpec{
+						Network:        "pcf",
+						Subnet:         "infra",
+						PrivateIP:      "10.0.0.5",
+						PublicIP:       "1.2.3.4",
+						SecurityGroups: []string{"opsman"},
+					},
+					Disks: []iaas.DiskSpec{
+						{Name: "persistent-disk-0", Boot: true, SizeGB: 100, Type: "pd-ssd"},
+						{Name: "data", Source: "opsman-data"},
+					},
+					Identity: "opsman@prj.iam.gserviceaccount.com",
+					Tags:     map[string]string{"env": "prod"},
+				}
+			})
+
+			It("then it should boot the imported image as the spec describes", func() {
+				vm, err := client.Create("ops-manager-1.10.3.tar.gz", spec)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(vm.Name).Should(Equal("opsman"))
+				Expect(vm.Image).Should(HavePrefix("projects/prj/global/images/opsman-"))
+
+				_, image := fakeGoogleClient.ImageInsertArgsForCall(0)
+				Expect(image.DiskSizeGb).Should(Equal(int64(100)))
+
+				_, zone, instance := fakeGoogleClient.InsertArgsForCall(0)
+				Expect(zone).Should(Equal("us-central1-a"))
+				Expect(instance.MachineType).Should(Equal("zones/us-central1-a/machineTypes/n1-standard-2"))
+				Expect(instance.NetworkInterfaces[0].Network).Should(Equal("global/networks/pcf"))
+				Expect(instance.NetworkInterfaces[0].Subnetwork).Should(Equal("regions/us-central1/subnetworks/infra"))
+				Expect(instance.NetworkInterfaces[0].NetworkIP).Should(Equal("10.0.0.5"))
+				Expect(instance.NetworkInterfaces[0].AccessConfigs[0].NatIP).Should(Equal("1.2.3.4"))
+				Expect(instance.Tags.Items).Should(Equal([]string{"opsman"}))
+				Expect(instance.Labels).Should(Equal(map[string]string{"env": "prod"}))
+				Expect(instance.ServiceAccounts[0].Email).Should(Equal("opsman@prj.iam.gserviceaccount.com"))
+				Expect(instance.Disks[0].InitializeParams.SourceImage).Should(Equal(vm.Image))
+				Expect(instance.Disks[0].InitializeParams.DiskType).Should(Equal("zones/us-central1-a/diskTypes/pd-ssd"))
+				Expect(instance.Disks[1].Source).Should(Equal("zones/us-central1-a/disks/opsman-data"))
+			})
+
+			It("then it should refuse a spec for another zone", func() {
+				spec.Zone = "europe-west1-b"
+				_, err := client.Create("ops-manager-1.10.3.tar.gz", spec)
+				Expect(err).Should(MatchError(ContainSubstring("spec is for zone europe-west1-b")))
+				Expect(fakeGoogleClient.ImageInsertCallCount()).Should(Equal(0))
+			})
+		})
+
+		Describe("given a DetachDisk method and the device name of a data disk", func() {
+			var fakeGoogleClient *gcpfakes.FakeGoogleComputeClient
+
+			BeforeEach(func() {
+				fakeGoogleClient = new(gcpfakes.FakeGoogleComputeClient)
+				fakeGoogleClient.DetachDiskReturns(&compute.Operation{Name: "operation-1", Status: "PENDING"}, nil)
+
+				client, _ = NewClient(
+					ConfigGoogleClient(fakeGoogleClient),
+					ConfigZoneName(controlZone),
+					ConfigProjectName(controlProject),
+					ConfigWaiter(iaas.NewWaiter(clock.NewClock(), iaas.Timeouts{PollInterval: time.Millisecond})),
+				)
+			})
+
+			Context("when the detach finishes", func() {
+				BeforeEach(func() {
+					fakeGoogleClient.ZoneOperationGetReturnsOnCall(0, &compute.Operation{Name: "operation-1", Status: "RUNNING"}, nil)
+					fakeGoogleClient.ZoneOperationGetReturnsOnCall(1, &compute.Operation{Name: "operation-1", Status: "DONE"}, nil)
+				})
+
+				It("then it should wait for the zone operation", func() {
+					err := client.DetachDisk(controlInstanceName, "opsman-data")
+					Expect(err).ShouldNot(HaveOccurred())
+
+					project, zone, instanceName, deviceName := fakeGoogleClient.DetachDiskArgsForCall(0)
+					Expect([]string{project, zone, instanceName, deviceName}).Should(Equal([]string{controlProject, controlZone, controlInstanceName, "opsman-data"}))
+					Expect(fakeGoogleClient.ZoneOperationGetCallCount()).Should(Equal(2))
+				})
+			})
+
+			Context("when the detach operation fails", func() {
+				BeforeEach(func() {
+					fakeGoogleClient.ZoneOperationGetReturns(&compute.Operation{
+						Name:   "operation-1",
+						Status: "DONE",
+						Error: &compute.OperationError{
+							Errors: []*compute.OperationErrorErrors{{Message: "disk is in use"}},
+						},
+					}, nil)
+				})
+
+				It("then it should give the operation's error", func() {
+					err := client.DetachDisk(controlInstanceName, "opsman-data")
+					Expect(err).Should(MatchError(ContainSubstring("disk is in use")))
+				})
+			})
+		})
+
+		It("finds the data disks of an instance", func() {
+			dataDisk := &compute.AttachedDisk{DeviceName: "opsman-data", Source: "zones/zone/disks/opsman-data"}
+			instance := &compute.Instance{
+				Disks: []*compute.AttachedDisk{{Boot: true, DeviceName: "persistent-disk-0"}, dataDisk},
+			}
+			Expect(DataDisks(instance)).Should(Equal([]*compute.AttachedDisk{dataDisk}))
+		})
+
+		Describe("given a VerifyProject method", func() {
+			var fakeGoogleClient *gcpfakes.FakeGoogleComputeClient
+
+			BeforeEach(func() {
+				fakeGoogleClient = new(gcpfakes.FakeGoogleComputeClient)
+				client, _ = NewClient(
+					ConfigGoogleClient(fakeGoogleClient),
+					ConfigZoneName(controlZone),
+					ConfigProjectName(controlProject),
+				)
+			})
+
+			Context("when the project can be read", func() {
+				It("then it should look up the configured project", func() {
+					fakeGoogleClient.ProjectGetReturns(&compute.Project{Name: controlProject}, nil)
+					err := client.VerifyProject()
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(fakeGoogleClient.ProjectGetCallCount()).Should(Equal(1))
+					Expect(fakeGoogleClient.ProjectGetArgsForCall(0)).Should(Equal(controlProject))
+				})
+			})
+
+			Context("when gcp api call fails", func() {
+				It("then it should give an error naming the project", func() {
+					fakeGoogleClient.ProjectGetReturns(nil, errors.New("forbidden"))
+					err := client.VerifyProject()
+					Expect(err).Should(MatchError("could not get project prj: forbidden"))
+				})
+			})
+		})
 	})
 
 	Describe("given a NewGCPClientAPI()", func() {
@@ -3,9 +3,10 @@ package gcpfakes
 
 import (
 	"sync"
+	"time"
 
 	"github.com/pivotal-cf/cliaas/iaas/gcp"
-	"google.golang.org/api/compute/v1"
+	compute "google.golang.org/api/compute/v1"
 )
 
 type FakeClientAPI struct {
@@ -68,9 +69,10 @@ type FakeClientAPI struct {
 	stopVMReturnsOnCall map[int]struct {
 		result1 error
 	}
-	CreateImageStub        func(tarball string, diskSizeGB int64) (string, error)
+	CreateImageStub        func(imageName string, tarball string, diskSizeGB int64) (string, error)
 	createImageMutex       sync.RWMutex
 	createImageArgsForCall []struct {
+		imageName  string
 		tarball    string
 		diskSizeGB int64
 	}
@@ -82,11 +84,12 @@ type FakeClientAPI struct {
 		result1 string
 		result2 error
 	}
-	WaitForStatusStub        func(vmName string, desiredStatus string) error
+	WaitForStatusStub        func(vmName string, desiredStatus string, timeout time.Duration) error
 	waitForStatusMutex       sync.RWMutex
 	waitForStatusArgsForCall []struct {
 		vmName        string
 		desiredStatus string
+		timeout       time.Duration
 	}
 	waitForStatusReturns struct {
 		result1 error
@@ -94,6 +97,30 @@ type FakeClientAPI struct {
 	waitForStatusReturnsOnCall map[int]struct {
 		result1 error
 	}
+	DetachDiskStub        func(instanceName string, deviceName string) error
+	detachDiskMutex       sync.RWMutex
+	detachDiskArgsForCall []struct {
+		instanceName string
+		deviceName   string
+	}
+	detachDiskReturns struct {
+		result1 error
+	}
+	detachDiskReturnsOnCall map[int]struct {
+		result1 error
+	}
+	AttachDiskStub        func(instanceName string, disk compute.AttachedDisk) error
+	attachDiskMutex       sync.RWMutex
+	attachDiskArgsForCall []struct {
+		instanceName string
+		disk         compute.AttachedDisk
+	}
+	attachDiskReturns struct {
+		result1 error
+	}
+	attachDiskReturnsOnCall map[int]struct {
+		result1 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -344,17 +371,18 @@ func (fake *FakeClientAPI) StopVMReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *FakeClientAPI) CreateImage(tarball string, diskSizeGB int64) (string, error) {
+func (fake *FakeClientAPI) CreateImage(imageName string, tarball string, diskSizeGB int64) (string, error) {
 	fake.createImageMutex.Lock()
 	ret, specificReturn := fake.createImageReturnsOnCall[len(fake.createImageArgsForCall)]
 	fake.createImageArgsForCall = append(fake.createImageArgsForCall, struct {
+		imageName  string
 		tarball    string
 		diskSizeGB int64
-	}{tarball, diskSizeGB})
-	fake.recordInvocation("CreateImage", []interface{}{tarball, diskSizeGB})
+	}{imageName, tarball, diskSizeGB})
+	fake.recordInvocation("CreateImage", []interface{}{imageName, tarball, diskSizeGB})
 	fake.createImageMutex.Unlock()
 	if fake.CreateImageStub != nil {
-		return fake.CreateImageStub(tarball, diskSizeGB)
+		return fake.CreateImageStub(imageName, tarball, diskSizeGB)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -368,10 +396,10 @@ func (fake *FakeClientAPI) CreateImageCallCount() int {
 	return len(fake.createImageArgsForCall)
 }
 
-func (fake *FakeClientAPI) CreateImageArgsForCall(i int) (string, int64) {
+func (fake *FakeClientAPI) CreateImageArgsForCall(i int) (string, string, int64) {
 	fake.createImageMutex.RLock()
 	defer fake.createImageMutex.RUnlock()
-	return fake.createImageArgsForCall[i].tarball, fake.createImageArgsForCall[i].diskSizeGB
+	return fake.createImageArgsForCall[i].imageName, fake.createImageArgsForCall[i].tarball, fake.createImageArgsForCall[i].diskSizeGB
 }
 
 func (fake *FakeClientAPI) CreateImageReturns(result1 string, result2 error) {
@@ -396,17 +424,18 @@ func (fake *FakeClientAPI) CreateImageReturnsOnCall(i int, result1 string, resul
 	}{result1, result2}
 }
 
-func (fake *FakeClientAPI) WaitForStatus(vmName string, desiredStatus string) error {
+func (fake *FakeClientAPI) WaitForStatus(vmName string, desiredStatus string, timeout time.Duration) error {
 	fake.waitForStatusMutex.Lock()
 	ret, specificReturn := fake.waitForStatusReturnsOnCall[len(fake.waitForStatusArgsForCall)]
 	fake.waitForStatusArgsForCall = append(fake.waitForStatusArgsForCall, struct {
 		vmName        string
 		desiredStatus string
-	}{vmName, desiredStatus})
-	fake.recordInvocation("WaitForStatus", []interface{}{vmName, desiredStatus})
+		timeout       time.Duration
+	}{vmName, desiredStatus, timeout})
+	fake.recordInvocation("WaitForStatus", []interface{}{vmName, desiredStatus, timeout})
 	fake.waitForStatusMutex.Unlock()
 	if fake.WaitForStatusStub != nil {
-		return fake.WaitForStatusStub(vmName, desiredStatus)
+		return fake.WaitForStatusStub(vmName, desiredStatus, timeout)
 	}
 	if specificReturn {
 		return ret.result1
@@ -420,10 +449,10 @@ func (fake *FakeClientAPI) WaitForStatusCallCount() int {
 	return len(fake.waitForStatusArgsForCall)
 }
 
-func (fake *FakeClientAPI) WaitForStatusArgsForCall(i int) (string, string) {
+func (fake *FakeClientAPI) WaitForStatusArgsForCall(i int) (string, string, time.Duration) {
 	fake.waitForStatusMutex.RLock()
 	defer fake.waitForStatusMutex.RUnlock()
-	return fake.waitForStatusArgsForCall[i].vmName, fake.waitForStatusArgsForCall[i].desiredStatus
+	return fake.waitForStatusArgsForCall[i].vmName, fake.waitForStatusArgsForCall[i].desiredStatus, fake.waitForStatusArgsForCall[i].timeout
 }
 
 func (fake *FakeClientAPI) WaitForStatusReturns(result1 error) {
@@ -445,6 +474,104 @@ func (fake *FakeClientAPI) WaitForStatusReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeClientAPI) DetachDisk(instanceName string, deviceName string) error {
+	fake.detachDiskMutex.Lock()
+	ret, specificReturn := fake.detachDiskReturnsOnCall[len(fake.detachDiskArgsForCall)]
+	fake.detachDiskArgsForCall = append(fake.detachDiskArgsForCall, struct {
+		instanceName string
+		deviceName   string
+	}{instanceName, deviceName})
+	fake.recordInvocation("DetachDisk", []interface{}{instanceName, deviceName})
+	fake.detachDiskMutex.Unlock()
+	if fake.DetachDiskStub != nil {
+		return fake.DetachDiskStub(instanceName, deviceName)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.detachDiskReturns.result1
+}
+
+func (fake *FakeClientAPI) DetachDiskCallCount() int {
+	fake.detachDiskMutex.RLock()
+	defer fake.detachDiskMutex.RUnlock()
+	return len(fake.detachDiskArgsForCall)
+}
+
+func (fake *FakeClientAPI) DetachDiskArgsForCall(i int) (string, string) {
+	fake.detachDiskMutex.RLock()
+	defer fake.detachDiskMutex.RUnlock()
+	return fake.detachDiskArgsForCall[i].instanceName, fake.detachDiskArgsForCall[i].deviceName
+}
+
+func (fake *FakeClientAPI) DetachDiskReturns(result1 error) {
+	fake.DetachDiskStub = nil
+	fake.detachDiskReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeClientAPI) DetachDiskReturnsOnCall(i int, result1 error) {
+	fake.DetachDiskStub = nil
+	if fake.detachDiskReturnsOnCall == nil {
+		fake.detachDiskReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.detachDiskReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeClientAPI) AttachDisk(instanceName string, disk compute.AttachedDisk) error {
+	fake.attachDiskMutex.Lock()
+	ret, specificReturn := fake.attachDiskReturnsOnCall[len(fake.attachDiskArgsForCall)]
+	fake.attachDiskArgsForCall = append(fake.attachDiskArgsForCall, struct {
+		instanceName string
+		disk         compute.AttachedDisk
+	}{instanceName, disk})
+	fake.recordInvocation("AttachDisk", []interface{}{instanceName, disk})
+	fake.attachDiskMutex.Unlock()
+	if fake.AttachDiskStub != nil {
+		return fake.AttachDiskStub(instanceName, disk)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.attachDiskReturns.result1
+}
+
+func (fake *FakeClientAPI) AttachDiskCallCount() int {
+	fake.attachDiskMutex.RLock()
+	defer fake.attachDiskMutex.RUnlock()
+	return len(fake.attachDiskArgsForCall)
+}
+
+func (fake *FakeClientAPI) AttachDiskArgsForCall(i int) (string, compute.AttachedDisk) {
+	fake.attachDiskMutex.RLock()
+	defer fake.attachDiskMutex.RUnlock()
+	return fake.attachDiskArgsForCall[i].instanceName, fake.attachDiskArgsForCall[i].disk
+}
+
+func (fake *FakeClientAPI) AttachDiskReturns(result1 error) {
+	fake.AttachDiskStub = nil
+	fake.attachDiskReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeClientAPI) AttachDiskReturnsOnCall(i int, result1 error) {
+	fake.AttachDiskStub = nil
+	if fake.attachDiskReturnsOnCall == nil {
+		fake.attachDiskReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.attachDiskReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeClientAPI) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -462,6 +589,10 @@ func (fake *FakeClientAPI) Invocations() map[string][][]interface{} {
 	defer fake.createImageMutex.RUnlock()
 	fake.waitForStatusMutex.RLock()
 	defer fake.waitForStatusMutex.RUnlock()
+	fake.detachDiskMutex.RLock()
+	defer fake.detachDiskMutex.RUnlock()
+	fake.attachDiskMutex.RLock()
+	defer fake.attachDiskMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
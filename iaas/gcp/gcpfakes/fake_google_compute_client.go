@@ -3,7 +3,6 @@ package gcpfakes
 
 import (
 	"sync"
-	"time"
 
 	"github.com/pivotal-cf/cliaas/iaas/gcp"
 	compute "google.golang.org/api/compute/v1"
@@ -68,12 +67,11 @@ type FakeGoogleComputeClient struct {
 		result1 *compute.Operation
 		result2 error
 	}
-	ImageInsertStub        func(project string, image *compute.Image, timeout time.Duration) (*compute.Operation, error)
+	ImageInsertStub        func(project string, image *compute.Image) (*compute.Operation, error)
 	imageInsertMutex       sync.RWMutex
 	imageInsertArgsForCall []struct {
 		project string
 		image   *compute.Image
-		timeout time.Duration
 	}
 	imageInsertReturns struct {
 		result1 *compute.Operation
@@ -83,6 +81,34 @@ type FakeGoogleComputeClient struct {
 		result1 *compute.Operation
 		result2 error
 	}
+	ImageDeleteStub        func(project string, image string) (*compute.Operation, error)
+	imageDeleteMutex       sync.RWMutex
+	imageDeleteArgsForCall []struct {
+		project string
+		image   string
+	}
+	imageDeleteReturns struct {
+		result1 *compute.Operation
+		result2 error
+	}
+	imageDeleteReturnsOnCall map[int]struct {
+		result1 *compute.Operation
+		result2 error
+	}
+	GlobalOperationGetStub        func(project string, operationName string) (*compute.Operation, error)
+	globalOperationGetMutex       sync.RWMutex
+	globalOperationGetArgsForCall []struct {
+		project       string
+		operationName string
+	}
+	globalOperationGetReturns struct {
+		result1 *compute.Operation
+		result2 error
+	}
+	globalOperationGetReturnsOnCall map[int]struct {
+		result1 *compute.Operation
+		result2 error
+	}
 	StopStub        func(project string, zone string, instanceName string) (*compute.Operation, error)
 	stopMutex       sync.RWMutex
 	stopArgsForCall []struct {
@@ -98,6 +124,145 @@ type FakeGoogleComputeClient struct {
 		result1 *compute.Operation
 		result2 error
 	}
+	StartStub        func(project string, zone string, instanceName string) (*compute.Operation, error)
+	startMutex       sync.RWMutex
+	startArgsForCall []struct {
+		project      string
+		zone         string
+		instanceName string
+	}
+	startReturns struct {
+		result1 *compute.Operation
+		result2 error
+	}
+	startReturnsOnCall map[int]struct {
+		result1 *compute.Operation
+		result2 error
+	}
+	AddAccessConfigStub        func(project string, zone string, instanceName string, networkInterface string, accessConfig *compute.AccessConfig) (*compute.Operation, error)
+	addAccessConfigMutex       sync.RWMutex
+	addAccessConfigArgsForCall []struct {
+		project          string
+		zone             string
+		instanceName     string
+		networkInterface string
+		accessConfig     *compute.AccessConfig
+	}
+	addAccessConfigReturns struct {
+		result1 *compute.Operation
+		result2 error
+	}
+	addAccessConfigReturnsOnCall map[int]struct {
+		result1 *compute.Operation
+		result2 error
+	}
+	DeleteAccessConfigStub        func(project string, zone string, instanceName string, accessConfig string, networkInterface string) (*compute.Operation, error)
+	deleteAccessConfigMutex       sync.RWMutex
+	deleteAccessConfigArgsForCall []struct {
+		project          string
+		zone             string
+		instanceName     string
+		accessConfig     string
+		networkInterface string
+	}
+	deleteAccessConfigReturns struct {
+		result1 *compute.Operation
+		result2 error
+	}
+	deleteAccessConfigReturnsOnCall map[int]struct {
+		result1 *compute.Operation
+		result2 error
+	}
+	SetMetadataStub        func(project string, zone string, instanceName string, metadata *compute.Metadata) (*compute.Operation, error)
+	setMetadataMutex       sync.RWMutex
+	setMetadataArgsForCall []struct {
+		project      string
+		zone         string
+		instanceName string
+		metadata     *compute.Metadata
+	}
+	setMetadataReturns struct {
+		result1 *compute.Operation
+		result2 error
+	}
+	setMetadataReturnsOnCall map[int]struct {
+		result1 *compute.Operation
+		result2 error
+	}
+	ProjectGetStub        func(project string) (*compute.Project, error)
+	projectGetMutex       sync.RWMutex
+	projectGetArgsForCall []struct {
+		project string
+	}
+	projectGetReturns struct {
+		result1 *compute.Project
+		result2 error
+	}
+	projectGetReturnsOnCall map[int]struct {
+		result1 *compute.Project
+		result2 error
+	}
+	TestIamPermissionsStub        func(project string, permissions []string) ([]string, error)
+	testIamPermissionsMutex       sync.RWMutex
+	testIamPermissionsArgsForCall []struct {
+		project     string
+		permissions []string
+	}
+	testIamPermissionsReturns struct {
+		result1 []string
+		result2 error
+	}
+	testIamPermissionsReturnsOnCall map[int]struct {
+		result1 []string
+		result2 error
+	}
+	DetachDiskStub        func(project string, zone string, instanceName string, deviceName string) (*compute.Operation, error)
+	detachDiskMutex       sync.RWMutex
+	detachDiskArgsForCall []struct {
+		project      string
+		zone         string
+		instanceName string
+		deviceName   string
+	}
+	detachDiskReturns struct {
+		result1 *compute.Operation
+		result2 error
+	}
+	detachDiskReturnsOnCall map[int]struct {
+		result1 *compute.Operation
+		result2 error
+	}
+	AttachDiskStub        func(project string, zone string, instanceName string, disk *compute.AttachedDisk) (*compute.Operation, error)
+	attachDiskMutex       sync.RWMutex
+	attachDiskArgsForCall []struct {
+		project      string
+		zone         string
+		instanceName string
+		disk         *compute.AttachedDisk
+	}
+	attachDiskReturns struct {
+		result1 *compute.Operation
+		result2 error
+	}
+	attachDiskReturnsOnCall map[int]struct {
+		result1 *compute.Operation
+		result2 error
+	}
+	ZoneOperationGetStub        func(project string, zone string, operationName string) (*compute.Operation, error)
+	zoneOperationGetMutex       sync.RWMutex
+	zoneOperationGetArgsForCall []struct {
+		project       string
+		zone          string
+		operationName string
+	}
+	zoneOperationGetReturns struct {
+		result1 *compute.Operation
+		result2 error
+	}
+	zoneOperationGetReturnsOnCall map[int]struct {
+		result1 *compute.Operation
+		result2 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -161,7 +326,7 @@ func (fake *FakeGoogleComputeClient) DiskList(project string, zone string) (*com
 		project string
 		zone    string
 	}{project, zone})
-	fake.recordInvocation("GetDiskInfo", []interface{}{project, zone})
+	fake.recordInvocation("DiskList", []interface{}{project, zone})
 	fake.diskListMutex.Unlock()
 	if fake.DiskListStub != nil {
 		return fake.DiskListStub(project, zone)
@@ -312,18 +477,17 @@ func (fake *FakeGoogleComputeClient) InsertReturnsOnCall(i int, result1 *compute
 	}{result1, result2}
 }
 
-func (fake *FakeGoogleComputeClient) ImageInsert(project string, image *compute.Image, timeout time.Duration) (*compute.Operation, error) {
+func (fake *FakeGoogleComputeClient) ImageInsert(project string, image *compute.Image) (*compute.Operation, error) {
 	fake.imageInsertMutex.Lock()
 	ret, specificReturn := fake.imageInsertReturnsOnCall[len(fake.imageInsertArgsForCall)]
 	fake.imageInsertArgsForCall = append(fake.imageInsertArgsForCall, struct {
 		project string
 		image   *compute.Image
-		timeout time.Duration
-	}{project, image, timeout})
-	fake.recordInvocation("ImageInsert", []interface{}{project, image, timeout})
+	}{project, image})
+	fake.recordInvocation("ImageInsert", []interface{}{project, image})
 	fake.imageInsertMutex.Unlock()
 	if fake.ImageInsertStub != nil {
-		return fake.ImageInsertStub(project, image, timeout)
+		return fake.ImageInsertStub(project, image)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -337,10 +501,10 @@ func (fake *FakeGoogleComputeClient) ImageInsertCallCount() int {
 	return len(fake.imageInsertArgsForCall)
 }
 
-func (fake *FakeGoogleComputeClient) ImageInsertArgsForCall(i int) (string, *compute.Image, time.Duration) {
+func (fake *FakeGoogleComputeClient) ImageInsertArgsForCall(i int) (string, *compute.Image) {
 	fake.imageInsertMutex.RLock()
 	defer fake.imageInsertMutex.RUnlock()
-	return fake.imageInsertArgsForCall[i].project, fake.imageInsertArgsForCall[i].image, fake.imageInsertArgsForCall[i].timeout
+	return fake.imageInsertArgsForCall[i].project, fake.imageInsertArgsForCall[i].image
 }
 
 func (fake *FakeGoogleComputeClient) ImageInsertReturns(result1 *compute.Operation, result2 error) {
@@ -365,6 +529,110 @@ func (fake *FakeGoogleComputeClient) ImageInsertReturnsOnCall(i int, result1 *co
 	}{result1, result2}
 }
 
+func (fake *FakeGoogleComputeClient) ImageDelete(project string, image string) (*compute.Operation, error) {
+	fake.imageDeleteMutex.Lock()
+	ret, specificReturn := fake.imageDeleteReturnsOnCall[len(fake.imageDeleteArgsForCall)]
+	fake.imageDeleteArgsForCall = append(fake.imageDeleteArgsForCall, struct {
+		project string
+		image   string
+	}{project, image})
+	fake.recordInvocation("ImageDelete", []interface{}{project, image})
+	fake.imageDeleteMutex.Unlock()
+	if fake.ImageDeleteStub != nil {
+		return fake.ImageDeleteStub(project, image)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.imageDeleteReturns.result1, fake.imageDeleteReturns.result2
+}
+
+func (fake *FakeGoogleComputeClient) ImageDeleteCallCount() int {
+	fake.imageDeleteMutex.RLock()
+	defer fake.imageDeleteMutex.RUnlock()
+	return len(fake.imageDeleteArgsForCall)
+}
+
+func (fake *FakeGoogleComputeClient) ImageDeleteArgsForCall(i int) (string, string) {
+	fake.imageDeleteMutex.RLock()
+	defer fake.imageDeleteMutex.RUnlock()
+	return fake.imageDeleteArgsForCall[i].project, fake.imageDeleteArgsForCall[i].image
+}
+
+func (fake *FakeGoogleComputeClient) ImageDeleteReturns(result1 *compute.Operation, result2 error) {
+	fake.ImageDeleteStub = nil
+	fake.imageDeleteReturns = struct {
+		result1 *compute.Operation
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGoogleComputeClient) ImageDeleteReturnsOnCall(i int, result1 *compute.Operation, result2 error) {
+	fake.ImageDeleteStub = nil
+	if fake.imageDeleteReturnsOnCall == nil {
+		fake.imageDeleteReturnsOnCall = make(map[int]struct {
+			result1 *compute.Operation
+			result2 error
+		})
+	}
+	fake.imageDeleteReturnsOnCall[i] = struct {
+		result1 *compute.Operation
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGoogleComputeClient) GlobalOperationGet(project string, operationName string) (*compute.Operation, error) {
+	fake.globalOperationGetMutex.Lock()
+	ret, specificReturn := fake.globalOperationGetReturnsOnCall[len(fake.globalOperationGetArgsForCall)]
+	fake.globalOperationGetArgsForCall = append(fake.globalOperationGetArgsForCall, struct {
+		project       string
+		operationName string
+	}{project, operationName})
+	fake.recordInvocation("GlobalOperationGet", []interface{}{project, operationName})
+	fake.globalOperationGetMutex.Unlock()
+	if fake.GlobalOperationGetStub != nil {
+		return fake.GlobalOperationGetStub(project, operationName)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.globalOperationGetReturns.result1, fake.globalOperationGetReturns.result2
+}
+
+func (fake *FakeGoogleComputeClient) GlobalOperationGetCallCount() int {
+	fake.globalOperationGetMutex.RLock()
+	defer fake.globalOperationGetMutex.RUnlock()
+	return len(fake.globalOperationGetArgsForCall)
+}
+
+func (fake *FakeGoogleComputeClient) GlobalOperationGetArgsForCall(i int) (string, string) {
+	fake.globalOperationGetMutex.RLock()
+	defer fake.globalOperationGetMutex.RUnlock()
+	return fake.globalOperationGetArgsForCall[i].project, fake.globalOperationGetArgsForCall[i].operationName
+}
+
+func (fake *FakeGoogleComputeClient) GlobalOperationGetReturns(result1 *compute.Operation, result2 error) {
+	fake.GlobalOperationGetStub = nil
+	fake.globalOperationGetReturns = struct {
+		result1 *compute.Operation
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGoogleComputeClient) GlobalOperationGetReturnsOnCall(i int, result1 *compute.Operation, result2 error) {
+	fake.GlobalOperationGetStub = nil
+	if fake.globalOperationGetReturnsOnCall == nil {
+		fake.globalOperationGetReturnsOnCall = make(map[int]struct {
+			result1 *compute.Operation
+			result2 error
+		})
+	}
+	fake.globalOperationGetReturnsOnCall[i] = struct {
+		result1 *compute.Operation
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeGoogleComputeClient) Stop(project string, zone string, instanceName string) (*compute.Operation, error) {
 	fake.stopMutex.Lock()
 	ret, specificReturn := fake.stopReturnsOnCall[len(fake.stopArgsForCall)]
@@ -418,6 +686,492 @@ func (fake *FakeGoogleComputeClient) StopReturnsOnCall(i int, result1 *compute.O
 	}{result1, result2}
 }
 
+func (fake *FakeGoogleComputeClient) Start(project string, zone string, instanceName string) (*compute.Operation, error) {
+	fake.startMutex.Lock()
+	ret, specificReturn := fake.startReturnsOnCall[len(fake.startArgsForCall)]
+	fake.startArgsForCall = append(fake.startArgsForCall, struct {
+		project      string
+		zone         string
+		instanceName string
+	}{project, zone, instanceName})
+	fake.recordInvocation("Start", []interface{}{project, zone, instanceName})
+	fake.startMutex.Unlock()
+	if fake.StartStub != nil {
+		return fake.StartStub(project, zone, instanceName)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.startReturns.result1, fake.startReturns.result2
+}
+
+func (fake *FakeGoogleComputeClient) StartCallCount() int {
+	fake.startMutex.RLock()
+	defer fake.startMutex.RUnlock()
+	return len(fake.startArgsForCall)
+}
+
+func (fake *FakeGoogleComputeClient) StartArgsForCall(i int) (string, string, string) {
+	fake.startMutex.RLock()
+	defer fake.startMutex.RUnlock()
+	return fake.startArgsForCall[i].project, fake.startArgsForCall[i].zone, fake.startArgsForCall[i].instanceName
+}
+
+func (fake *FakeGoogleComputeClient) StartReturns(result1 *compute.Operation, result2 error) {
+	fake.StartStub = nil
+	fake.startReturns = struct {
+		result1 *compute.Operation
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGoogleComputeClient) StartReturnsOnCall(i int, result1 *compute.Operation, result2 error) {
+	fake.StartStub = nil
+	if fake.startReturnsOnCall == nil {
+		fake.startReturnsOnCall = make(map[int]struct {
+			result1 *compute.Operation
+			result2 error
+		})
+	}
+	fake.startReturnsOnCall[i] = struct {
+		result1 *compute.Operation
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGoogleComputeClient) AddAccessConfig(project string, zone string, instanceName string, networkInterface string, accessConfig *compute.AccessConfig) (*compute.Operation, error) {
+	fake.addAccessConfigMutex.Lock()
+	ret, specificReturn := fake.addAccessConfigReturnsOnCall[len(fake.addAccessConfigArgsForCall)]
+	fake.addAccessConfigArgsForCall = append(fake.addAccessConfigArgsForCall, struct {
+		project          string
+		zone             string
+		instanceName     string
+		networkInterface string
+		accessConfig     *compute.AccessConfig
+	}{project, zone, instanceName, networkInterface, accessConfig})
+	fake.recordInvocation("AddAccessConfig", []interface{}{project, zone, instanceName, networkInterface, accessConfig})
+	fake.addAccessConfigMutex.Unlock()
+	if fake.AddAccessConfigStub != nil {
+		return fake.AddAccessConfigStub(project, zone, instanceName, networkInterface, accessConfig)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.addAccessConfigReturns.result1, fake.addAccessConfigReturns.result2
+}
+
+func (fake *FakeGoogleComputeClient) AddAccessConfigCallCount() int {
+	fake.addAccessConfigMutex.RLock()
+	defer fake.addAccessConfigMutex.RUnlock()
+	return len(fake.addAccessConfigArgsForCall)
+}
+
+func (fake *FakeGoogleComputeClient) AddAccessConfigArgsForCall(i int) (string, string, string, string, *compute.AccessConfig) {
+	fake.addAccessConfigMutex.RLock()
+	defer fake.addAccessConfigMutex.RUnlock()
+	return fake.addAccessConfigArgsForCall[i].project, fake.addAccessConfigArgsForCall[i].zone, fake.addAccessConfigArgsForCall[i].instanceName, fake.addAccessConfigArgsForCall[i].networkInterface, fake.addAccessConfigArgsForCall[i].accessConfig
+}
+
+func (fake *FakeGoogleComputeClient) AddAccessConfigReturns(result1 *compute.Operation, result2 error) {
+	fake.AddAccessConfigStub = nil
+	fake.addAccessConfigReturns = struct {
+		result1 *compute.Operation
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGoogleComputeClient) AddAccessConfigReturnsOnCall(i int, result1 *compute.Operation, result2 error) {
+	fake.AddAccessConfigStub = nil
+	if fake.addAccessConfigReturnsOnCall == nil {
+		fake.addAccessConfigReturnsOnCall = make(map[int]struct {
+			result1 *compute.Operation
+			result2 error
+		})
+	}
+	fake.addAccessConfigReturnsOnCall[i] = struct {
+		result1 *compute.Operation
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGoogleComputeClient) DeleteAccessConfig(project string, zone string, instanceName string, accessConfig string, networkInterface string) (*compute.Operation, error) {
+	fake.deleteAccessConfigMutex.Lock()
+	ret, specificReturn := fake.deleteAccessConfigReturnsOnCall[len(fake.deleteAccessConfigArgsForCall)]
+	fake.deleteAccessConfigArgsForCall = append(fake.deleteAccessConfigArgsForCall, struct {
+		project          string
+		zone             string
+		instanceName     string
+		accessConfig     string
+		networkInterface string
+	}{project, zone, instanceName, accessConfig, networkInterface})
+	fake.recordInvocation("DeleteAccessConfig", []interface{}{project, zone, instanceName, accessConfig, networkInterface})
+	fake.deleteAccessConfigMutex.Unlock()
+	if fake.DeleteAccessConfigStub != nil {
+		return fake.DeleteAccessConfigStub(project, zone, instanceName, accessConfig, networkInterface)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.deleteAccessConfigReturns.result1, fake.deleteAccessConfigReturns.result2
+}
+
+func (fake *FakeGoogleComputeClient) DeleteAccessConfigCallCount() int {
+	fake.deleteAccessConfigMutex.RLock()
+	defer fake.deleteAccessConfigMutex.RUnlock()
+	return len(fake.deleteAccessConfigArgsForCall)
+}
+
+func (fake *FakeGoogleComputeClient) DeleteAccessConfigArgsForCall(i int) (string, string, string, string, string) {
+	fake.deleteAccessConfigMutex.RLock()
+	defer fake.deleteAccessConfigMutex.RUnlock()
+	return fake.deleteAccessConfigArgsForCall[i].project, fake.deleteAccessConfigArgsForCall[i].zone, fake.deleteAccessConfigArgsForCall[i].instanceName, fake.deleteAccessConfigArgsForCall[i].accessConfig, fake.deleteAccessConfigArgsForCall[i].networkInterface
+}
+
+func (fake *FakeGoogleComputeClient) DeleteAccessConfigReturns(result1 *compute.Operation, result2 error) {
+	fake.DeleteAccessConfigStub = nil
+	fake.deleteAccessConfigReturns = struct {
+		result1 *compute.Operation
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGoogleComputeClient) DeleteAccessConfigReturnsOnCall(i int, result1 *compute.Operation, result2 error) {
+	fake.DeleteAccessConfigStub = nil
+	if fake.deleteAccessConfigReturnsOnCall == nil {
+		fake.deleteAccessConfigReturnsOnCall = make(map[int]struct {
+			result1 *compute.Operation
+			result2 error
+		})
+	}
+	fake.deleteAccessConfigReturnsOnCall[i] = struct {
+		result1 *compute.Operation
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGoogleComputeClient) SetMetadata(project string, zone string, instanceName string, metadata *compute.Metadata) (*compute.Operation, error) {
+	fake.setMetadataMutex.Lock()
+	ret, specificReturn := fake.setMetadataReturnsOnCall[len(fake.setMetadataArgsForCall)]
+	fake.setMetadataArgsForCall = append(fake.setMetadataArgsForCall, struct {
+		project      string
+		zone         string
+		instanceName string
+		metadata     *compute.Metadata
+	}{project, zone, instanceName, metadata})
+	fake.recordInvocation("SetMetadata", []interface{}{project, zone, instanceName, metadata})
+	fake.setMetadataMutex.Unlock()
+	if fake.SetMetadataStub != nil {
+		return fake.SetMetadataStub(project, zone, instanceName, metadata)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.setMetadataReturns.result1, fake.setMetadataReturns.result2
+}
+
+func (fake *FakeGoogleComputeClient) SetMetadataCallCount() int {
+	fake.setMetadataMutex.RLock()
+	defer fake.setMetadataMutex.RUnlock()
+	return len(fake.setMetadataArgsForCall)
+}
+
+func (fake *FakeGoogleComputeClient) SetMetadataArgsForCall(i int) (string, string, string, *compute.Metadata) {
+	fake.setMetadataMutex.RLock()
+	defer fake.setMetadataMutex.RUnlock()
+	return fake.setMetadataArgsForCall[i].project, fake.setMetadataArgsForCall[i].zone, fake.setMetadataArgsForCall[i].instanceName, fake.setMetadataArgsForCall[i].metadata
+}
+
+func (fake *FakeGoogleComputeClient) SetMetadataReturns(result1 *compute.Operation, result2 error) {
+	fake.SetMetadataStub = nil
+	fake.setMetadataReturns = struct {
+		result1 *compute.Operation
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGoogleComputeClient) SetMetadataReturnsOnCall(i int, result1 *compute.Operation, result2 error) {
+	fake.SetMetadataStub = nil
+	if fake.setMetadataReturnsOnCall == nil {
+		fake.setMetadataReturnsOnCall = make(map[int]struct {
+			result1 *compute.Operation
+			result2 error
+		})
+	}
+	fake.setMetadataReturnsOnCall[i] = struct {
+		result1 *compute.Operation
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGoogleComputeClient) ProjectGet(project string) (*compute.Project, error) {
+	fake.projectGetMutex.Lock()
+	ret, specificReturn := fake.projectGetReturnsOnCall[len(fake.projectGetArgsForCall)]
+	fake.projectGetArgsForCall = append(fake.projectGetArgsForCall, struct {
+		project string
+	}{project})
+	fake.recordInvocation("ProjectGet", []interface{}{project})
+	fake.projectGetMutex.Unlock()
+	if fake.ProjectGetStub != nil {
+		return fake.ProjectGetStub(project)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.projectGetReturns.result1, fake.projectGetReturns.result2
+}
+
+func (fake *FakeGoogleComputeClient) ProjectGetCallCount() int {
+	fake.projectGetMutex.RLock()
+	defer fake.projectGetMutex.RUnlock()
+	return len(fake.projectGetArgsForCall)
+}
+
+func (fake *FakeGoogleComputeClient) ProjectGetArgsForCall(i int) string {
+	fake.projectGetMutex.RLock()
+	defer fake.projectGetMutex.RUnlock()
+	return fake.projectGetArgsForCall[i].project
+}
+
+func (fake *FakeGoogleComputeClient) ProjectGetReturns(result1 *compute.Project, result2 error) {
+	fake.ProjectGetStub = nil
+	fake.projectGetReturns = struct {
+		result1 *compute.Project
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGoogleComputeClient) ProjectGetReturnsOnCall(i int, result1 *compute.Project, result2 error) {
+	fake.ProjectGetStub = nil
+	if fake.projectGetReturnsOnCall == nil {
+		fake.projectGetReturnsOnCall = make(map[int]struct {
+			result1 *compute.Project
+			result2 error
+		})
+	}
+	fake.projectGetReturnsOnCall[i] = struct {
+		result1 *compute.Project
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGoogleComputeClient) TestIamPermissions(project string, permissions []string) ([]string, error) {
+	var permissionsCopy []string
+	if permissions != nil {
+		permissionsCopy = make([]string, len(permissions))
+		copy(permissionsCopy, permissions)
+	}
+	fake.testIamPermissionsMutex.Lock()
+	ret, specificReturn := fake.testIamPermissionsReturnsOnCall[len(fake.testIamPermissionsArgsForCall)]
+	fake.testIamPermissionsArgsForCall = append(fake.testIamPermissionsArgsForCall, struct {
+		project     string
+		permissions []string
+	}{project, permissionsCopy})
+	fake.recordInvocation("TestIamPermissions", []interface{}{project, permissionsCopy})
+	fake.testIamPermissionsMutex.Unlock()
+	if fake.TestIamPermissionsStub != nil {
+		return fake.TestIamPermissionsStub(project, permissions)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.testIamPermissionsReturns.result1, fake.testIamPermissionsReturns.result2
+}
+
+func (fake *FakeGoogleComputeClient) TestIamPermissionsCallCount() int {
+	fake.testIamPermissionsMutex.RLock()
+	defer fake.testIamPermissionsMutex.RUnlock()
+	return len(fake.testIamPermissionsArgsForCall)
+}
+
+func (fake *FakeGoogleComputeClient) TestIamPermissionsArgsForCall(i int) (string, []string) {
+	fake.testIamPermissionsMutex.RLock()
+	defer fake.testIamPermissionsMutex.RUnlock()
+	return fake.testIamPermissionsArgsForCall[i].project, fake.testIamPermissionsArgsForCall[i].permissions
+}
+
+func (fake *FakeGoogleComputeClient) TestIamPermissionsReturns(result1 []string, result2 error) {
+	fake.TestIamPermissionsStub = nil
+	fake.testIamPermissionsReturns = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGoogleComputeClient) TestIamPermissionsReturnsOnCall(i int, result1 []string, result2 error) {
+	fake.TestIamPermissionsStub = nil
+	if fake.testIamPermissionsReturnsOnCall == nil {
+		fake.testIamPermissionsReturnsOnCall = make(map[int]struct {
+			result1 []string
+			result2 error
+		})
+	}
+	fake.testIamPermissionsReturnsOnCall[i] = struct {
+		result1 []string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGoogleComputeClient) DetachDisk(project string, zone string, instanceName string, deviceName string) (*compute.Operation, error) {
+	fake.detachDiskMutex.Lock()
+	ret, specificReturn := fake.detachDiskReturnsOnCall[len(fake.detachDiskArgsForCall)]
+	fake.detachDiskArgsForCall = append(fake.detachDiskArgsForCall, struct {
+		project      string
+		zone         string
+		instanceName string
+		deviceName   string
+	}{project, zone, instanceName, deviceName})
+	fake.recordInvocation("DetachDisk", []interface{}{project, zone, instanceName, deviceName})
+	fake.detachDiskMutex.Unlock()
+	if fake.DetachDiskStub != nil {
+		return fake.DetachDiskStub(project, zone, instanceName, deviceName)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.detachDiskReturns.result1, fake.detachDiskReturns.result2
+}
+
+func (fake *FakeGoogleComputeClient) DetachDiskCallCount() int {
+	fake.detachDiskMutex.RLock()
+	defer fake.detachDiskMutex.RUnlock()
+	return len(fake.detachDiskArgsForCall)
+}
+
+func (fake *FakeGoogleComputeClient) DetachDiskArgsForCall(i int) (string, string, string, string) {
+	fake.detachDiskMutex.RLock()
+	defer fake.detachDiskMutex.RUnlock()
+	return fake.detachDiskArgsForCall[i].project, fake.detachDiskArgsForCall[i].zone, fake.detachDiskArgsForCall[i].instanceName, fake.detachDiskArgsForCall[i].deviceName
+}
+
+func (fake *FakeGoogleComputeClient) DetachDiskReturns(result1 *compute.Operation, result2 error) {
+	fake.DetachDiskStub = nil
+	fake.detachDiskReturns = struct {
+		result1 *compute.Operation
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGoogleComputeClient) DetachDiskReturnsOnCall(i int, result1 *compute.Operation, result2 error) {
+	fake.DetachDiskStub = nil
+	if fake.detachDiskReturnsOnCall == nil {
+		fake.detachDiskReturnsOnCall = make(map[int]struct {
+			result1 *compute.Operation
+			result2 error
+		})
+	}
+	fake.detachDiskReturnsOnCall[i] = struct {
+		result1 *compute.Operation
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGoogleComputeClient) AttachDisk(project string, zone string, instanceName string, disk *compute.AttachedDisk) (*compute.Operation, error) {
+	fake.attachDiskMutex.Lock()
+	ret, specificReturn := fake.attachDiskReturnsOnCall[len(fake.attachDiskArgsForCall)]
+	fake.attachDiskArgsForCall = append(fake.attachDiskArgsForCall, struct {
+		project      string
+		zone         string
+		instanceName string
+		disk         *compute.AttachedDisk
+	}{project, zone, instanceName, disk})
+	fake.recordInvocation("AttachDisk", []interface{}{project, zone, instanceName, disk})
+	fake.attachDiskMutex.Unlock()
+	if fake.AttachDiskStub != nil {
+		return fake.AttachDiskStub(project, zone, instanceName, disk)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.attachDiskReturns.result1, fake.attachDiskReturns.result2
+}
+
+func (fake *FakeGoogleComputeClient) AttachDiskCallCount() int {
+	fake.attachDiskMutex.RLock()
+	defer fake.attachDiskMutex.RUnlock()
+	return len(fake.attachDiskArgsForCall)
+}
+
+func (fake *FakeGoogleComputeClient) AttachDiskArgsForCall(i int) (string, string, string, *compute.AttachedDisk) {
+	fake.attachDiskMutex.RLock()
+	defer fake.attachDiskMutex.RUnlock()
+	return fake.attachDiskArgsForCall[i].project, fake.attachDiskArgsForCall[i].zone, fake.attachDiskArgsForCall[i].instanceName, fake.attachDiskArgsForCall[i].disk
+}
+
+func (fake *FakeGoogleComputeClient) AttachDiskReturns(result1 *compute.Operation, result2 error) {
+	fake.AttachDiskStub = nil
+	fake.attachDiskReturns = struct {
+		result1 *compute.Operation
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGoogleComputeClient) AttachDiskReturnsOnCall(i int, result1 *compute.Operation, result2 error) {
+	fake.AttachDiskStub = nil
+	if fake.attachDiskReturnsOnCall == nil {
+		fake.attachDiskReturnsOnCall = make(map[int]struct {
+			result1 *compute.Operation
+			result2 error
+		})
+	}
+	fake.attachDiskReturnsOnCall[i] = struct {
+		result1 *compute.Operation
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGoogleComputeClient) ZoneOperationGet(project string, zone string, operationName string) (*compute.Operation, error) {
+	fake.zoneOperationGetMutex.Lock()
+	ret, specificReturn := fake.zoneOperationGetReturnsOnCall[len(fake.zoneOperationGetArgsForCall)]
+	fake.zoneOperationGetArgsForCall = append(fake.zoneOperationGetArgsForCall, struct {
+		project       string
+		zone          string
+		operationName string
+	}{project, zone, operationName})
+	fake.recordInvocation("ZoneOperationGet", []interface{}{project, zone, operationName})
+	fake.zoneOperationGetMutex.Unlock()
+	if fake.ZoneOperationGetStub != nil {
+		return fake.ZoneOperationGetStub(project, zone, operationName)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.zoneOperationGetReturns.result1, fake.zoneOperationGetReturns.result2
+}
+
+func (fake *FakeGoogleComputeClient) ZoneOperationGetCallCount() int {
+	fake.zoneOperationGetMutex.RLock()
+	defer fake.zoneOperationGetMutex.RUnlock()
+	return len(fake.zoneOperationGetArgsForCall)
+}
+
+func (fake *FakeGoogleComputeClient) ZoneOperationGetArgsForCall(i int) (string, string, string) {
+	fake.zoneOperationGetMutex.RLock()
+	defer fake.zoneOperationGetMutex.RUnlock()
+	return fake.zoneOperationGetArgsForCall[i].project, fake.zoneOperationGetArgsForCall[i].zone, fake.zoneOperationGetArgsForCall[i].operationName
+}
+
+func (fake *FakeGoogleComputeClient) ZoneOperationGetReturns(result1 *compute.Operation, result2 error) {
+	fake.ZoneOperationGetStub = nil
+	fake.zoneOperationGetReturns = struct {
+		result1 *compute.Operation
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGoogleComputeClient) ZoneOperationGetReturnsOnCall(i int, result1 *compute.Operation, result2 error) {
+	fake.ZoneOperationGetStub = nil
+	if fake.zoneOperationGetReturnsOnCall == nil {
+		fake.zoneOperationGetReturnsOnCall = make(map[int]struct {
+			result1 *compute.Operation
+			result2 error
+		})
+	}
+	fake.zoneOperationGetReturnsOnCall[i] = struct {
+		result1 *compute.Operation
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeGoogleComputeClient) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -431,8 +1185,30 @@ func (fake *FakeGoogleComputeClient) Invocations() map[string][][]interface{} {
 	defer fake.insertMutex.RUnlock()
 	fake.imageInsertMutex.RLock()
 	defer fake.imageInsertMutex.RUnlock()
+	fake.imageDeleteMutex.RLock()
+	defer fake.imageDeleteMutex.RUnlock()
+	fake.globalOperationGetMutex.RLock()
+	defer fake.globalOperationGetMutex.RUnlock()
 	fake.stopMutex.RLock()
 	defer fake.stopMutex.RUnlock()
+	fake.startMutex.RLock()
+	defer fake.startMutex.RUnlock()
+	fake.addAccessConfigMutex.RLock()
+	defer fake.addAccessConfigMutex.RUnlock()
+	fake.deleteAccessConfigMutex.RLock()
+	defer fake.deleteAccessConfigMutex.RUnlock()
+	fake.setMetadataMutex.RLock()
+	defer fake.setMetadataMutex.RUnlock()
+	fake.projectGetMutex.RLock()
+	defer fake.projectGetMutex.RUnlock()
+	fake.testIamPermissionsMutex.RLock()
+	defer fake.testIamPermissionsMutex.RUnlock()
+	fake.detachDiskMutex.RLock()
+	defer fake.detachDiskMutex.RUnlock()
+	fake.attachDiskMutex.RLock()
+	defer fake.attachDiskMutex.RUnlock()
+	fake.zoneOperationGetMutex.RLock()
+	defer fake.zoneOperationGetMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
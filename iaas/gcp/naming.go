@@ -0,0 +1,18 @@
+package gcp
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// namePattern is what compute engine allows in instance and image names
+var namePattern = regexp.MustCompile(`^[a-z]([-a-z0-9]{0,61}[a-z0-9])?$`)
+
+// ValidateName checks a new instance or image name against compute engine's
+// rules
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("%q is not a valid gcp name: it must be 1 to 63 lowercase letters, digits or dashes, start with a letter and not end with a dash", name)
+	}
+	return nil
+}
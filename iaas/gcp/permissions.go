@@ -0,0 +1,86 @@
+package gcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pivotal-cf/cliaas/iaas"
+	errwrap "github.com/pkg/errors"
+	"google.golang.org/api/googleapi"
+)
+
+const resourceManagerEndpoint = "https://cloudresourcemanager.googleapis.com/v1/"
+
+// RequiredPermissions - every iam permission a replace or delete of a vm uses
+var RequiredPermissions = []string{
+	"compute.instances.list",
+	"compute.instances.get",
+	"compute.instances.stop",
+	"compute.instances.setMetadata",
+	"compute.instances.delete",
+	"compute.instances.create",
+	"compute.instances.setTags",
+	"compute.instances.deleteAccessConfig",
+	"compute.disks.list",
+	"compute.disks.create",
+	"compute.images.create",
+	"compute.globalOperations.get",
+	"compute.images.useReadOnly",
+	"compute.subnetworks.use",
+	"compute.subnetworks.useExternalIp",
+}
+
+// CheckPermissions - asks the project which of the required permissions the
+// credentials hold
+func (c *Client) CheckPermissions(identifier string, sourceImageTarballURL string) []iaas.PermissionCheck {
+	granted, err := c.googleClient.TestIamPermissions(c.projectName, RequiredPermissions)
+	if err != nil {
+		err = errwrap.Wrap(err, "testIamPermissions failed")
+	}
+
+	grantedSet := map[string]bool{}
+	for _, permission := range granted {
+		grantedSet[permission] = true
+	}
+
+	var checks []iaas.PermissionCheck
+	for _, permission := range RequiredPermissions {
+		check := iaas.PermissionCheck{Permission: permission, Err: err}
+		if err == nil && !grantedSet[permission] {
+			check.Err = fmt.Errorf("not granted on project %s", c.projectName)
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+type testIamPermissionsBody struct {
+	Permissions []string `json:"permissions"`
+}
+
+func (s *googleComputeClientWrapper) TestIamPermissions(project string, permissions []string) ([]string, error) {
+	body, err := json.Marshal(testIamPermissionsBody{Permissions: permissions})
+	if err != nil {
+		return nil, errwrap.Wrap(err, "failed encoding testIamPermissions request")
+	}
+
+	url := fmt.Sprintf("%sprojects/%s:testIamPermissions", resourceManagerEndpoint, project)
+	resp, err := s.httpClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	err = googleapi.CheckResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	var granted testIamPermissionsBody
+	err = json.NewDecoder(resp.Body).Decode(&granted)
+	if err != nil {
+		return nil, errwrap.Wrap(err, "failed decoding testIamPermissions response")
+	}
+	return granted.Permissions, nil
+}
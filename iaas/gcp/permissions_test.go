@@ -0,0 +1,61 @@
+package gcp_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/pivotal-cf/cliaas/iaas/gcp"
+	"github.com/pivotal-cf/cliaas/iaas/gcp/gcpfakes"
+)
+
+var _ = Describe("CheckPermissions", func() {
+	var client *Client
+	var fakeGoogleClient *gcpfakes.FakeGoogleComputeClient
+
+	BeforeEach(func() {
+		fakeGoogleClient = new(gcpfakes.FakeGoogleComputeClient)
+		client, _ = NewClient(
+			ConfigGoogleClient(fakeGoogleClient),
+			ConfigZoneName("zone"),
+			ConfigProjectName("prj"),
+		)
+	})
+
+	Context("when every permission is granted", func() {
+		It("should pass every check", func() {
+			fakeGoogleClient.TestIamPermissionsReturns(RequiredPermissions, nil)
+
+			checks := client.CheckPermissions("ops-manager", "bucket/image.tar.gz")
+			Expect(checks).Should(HaveLen(len(RequiredPermissions)))
+			for _, check := range checks {
+				Expect(check.Result()).Should(Equal("PASS"))
+			}
+
+			project, permissions := fakeGoogleClient.TestIamPermissionsArgsForCall(0)
+			Expect(project).Should(Equal("prj"))
+			Expect(permissions).Should(Equal(RequiredPermissions))
+		})
+	})
+
+	Context("when a permission is missing", func() {
+		It("should fail only that check", func() {
+			fakeGoogleClient.TestIamPermissionsReturns(RequiredPermissions[1:], nil)
+
+			checks := client.CheckPermissions("ops-manager", "bucket/image.tar.gz")
+			Expect(checks[0].Permission).Should(Equal(RequiredPermissions[0]))
+			Expect(checks[0].Err).Should(MatchError("not granted on project prj"))
+			Expect(checks[1].Result()).Should(Equal("PASS"))
+		})
+	})
+
+	Context("when the gcp api call fails", func() {
+		It("should fail every check", func() {
+			fakeGoogleClient.TestIamPermissionsReturns(nil, errors.New("forbidden"))
+
+			for _, check := range client.CheckPermissions("ops-manager", "bucket/image.tar.gz") {
+				Expect(check.Err).Should(MatchError("testIamPermissions failed: forbidden"))
+			}
+		})
+	})
+})
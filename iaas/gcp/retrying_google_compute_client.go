@@ -0,0 +1,175 @@
+package gcp
+
+import (
+	"time"
+
+	"github.com/pivotal-cf/cliaas/iaas"
+	"google.golang.org/api/compute/v1"
+)
+
+// retryingGoogleComputeClient classifies every google api error, logs every
+// call and retries the retryable ones
+type retryingGoogleComputeClient struct {
+	googleClient GoogleComputeClient
+	waiter       iaas.Waiter
+	logger       *iaas.Logger
+}
+
+func (c retryingGoogleComputeClient) call(description string, operation func() error) error {
+	return c.callOperation(description, func() (*compute.Operation, error) {
+		return nil, operation()
+	})
+}
+
+// callOperation logs the name of the operation a call started, which is what
+// google support asks for
+func (c retryingGoogleComputeClient) callOperation(description string, operation func() (*compute.Operation, error)) error {
+	return c.waiter.Call(description, func() error {
+		start := time.Now()
+		result, err := operation()
+		err = ClassifyError(err)
+
+		var operationName string
+		if result != nil {
+			operationName = result.Name
+		}
+		c.logger.APICall(description, time.Since(start), operationName, err)
+		return err
+	})
+}
+
+func (c retryingGoogleComputeClient) List(project string, zone string) (result *compute.InstanceList, err error) {
+	err = c.call("listing instances", func() error {
+		result, err = c.googleClient.List(project, zone)
+		return err
+	})
+	return result, err
+}
+
+func (c retryingGoogleComputeClient) DiskList(project string, zone string) (result *compute.DiskList, err error) {
+	err = c.call("listing disks", func() error {
+		result, err = c.googleClient.DiskList(project, zone)
+		return err
+	})
+	return result, err
+}
+
+func (c retryingGoogleComputeClient) Delete(project string, zone string, instanceName string) (result *compute.Operation, err error) {
+	err = c.callOperation("deleting instance "+instanceName, func() (*compute.Operation, error) {
+		result, err = c.googleClient.Delete(project, zone, instanceName)
+		return result, err
+	})
+	return result, err
+}
+
+func (c retryingGoogleComputeClient) Insert(project string, zone string, instance *compute.Instance) (result *compute.Operation, err error) {
+	err = c.callOperation("inserting instance "+instance.Name, func() (*compute.Operation, error) {
+		result, err = c.googleClient.Insert(project, zone, instance)
+		return result, err
+	})
+	return result, err
+}
+
+func (c retryingGoogleComputeClient) ImageInsert(project string, image *compute.Image) (result *compute.Operation, err error) {
+	err = c.callOperation("inserting image "+image.Name, func() (*compute.Operation, error) {
+		result, err = c.googleClient.ImageInsert(project, image)
+		return result, err
+	})
+	return result, err
+}
+
+func (c retryingGoogleComputeClient) ImageDelete(project string, image string) (result *compute.Operation, err error) {
+	err = c.callOperation("deleting image "+image, func() (*compute.Operation, error) {
+		result, err = c.googleClient.ImageDelete(project, image)
+		return result, err
+	})
+	return result, err
+}
+
+func (c retryingGoogleComputeClient) GlobalOperationGet(project string, operationName string) (result *compute.Operation, err error) {
+	err = c.callOperation("getting operation "+operationName, func() (*compute.Operation, error) {
+		result, err = c.googleClient.GlobalOperationGet(project, operationName)
+		return result, err
+	})
+	return result, err
+}
+
+func (c retryingGoogleComputeClient) Stop(project string, zone string, instanceName string) (result *compute.Operation, err error) {
+	err = c.callOperation("stopping instance "+instanceName, func() (*compute.Operation, error) {
+		result, err = c.googleClient.Stop(project, zone, instanceName)
+		return result, err
+	})
+	return result, err
+}
+
+func (c retryingGoogleComputeClient) Start(project string, zone string, instanceName string) (result *compute.Operation, err error) {
+	err = c.callOperation("starting instance "+instanceName, func() (*compute.Operation, error) {
+		result, err = c.googleClient.Start(project, zone, instanceName)
+		return result, err
+	})
+	return result, err
+}
+
+func (c retryingGoogleComputeClient) AddAccessConfig(project string, zone string, instanceName string, networkInterface string, accessConfig *compute.AccessConfig) (result *compute.Operation, err error) {
+	err = c.callOperation("adding access config to instance "+instanceName, func() (*compute.Operation, error) {
+		result, err = c.googleClient.AddAccessConfig(project, zone, instanceName, networkInterface, accessConfig)
+		return result, err
+	})
+	return result, err
+}
+
+func (c retryingGoogleComputeClient) DeleteAccessConfig(project string, zone string, instanceName string, accessConfig string, networkInterface string) (result *compute.Operation, err error) {
+	err = c.callOperation("deleting access config of instance "+instanceName, func() (*compute.Operation, error) {
+		result, err = c.googleClient.DeleteAccessConfig(project, zone, instanceName, accessConfig, networkInterface)
+		return result, err
+	})
+	return result, err
+}
+
+func (c retryingGoogleComputeClient) SetMetadata(project string, zone string, instanceName string, metadata *compute.Metadata) (result *compute.Operation, err error) {
+	err = c.callOperation("setting metadata of instance "+instanceName, func() (*compute.Operation, error) {
+		result, err = c.googleClient.SetMetadata(project, zone, instanceName, metadata)
+		return result, err
+	})
+	return result, err
+}
+
+func (c retryingGoogleComputeClient) ProjectGet(project string) (result *compute.Project, err error) {
+	err = c.call("getting project "+project, func() error {
+		result, err = c.googleClient.ProjectGet(project)
+		return err
+	})
+	return result, err
+}
+
+func (c retryingGoogleComputeClient) TestIamPermissions(project string, permissions []string) (result []string, err error) {
+	err = c.call("testing iam permissions", func() error {
+		result, err = c.googleClient.TestIamPermissions(project, permissions)
+		return err
+	})
+	return result, err
+}
+
+func (c retryingGoogleComputeClient) DetachDisk(project string, zone string, instanceName string, deviceName string) (result *compute.Operation, err error) {
+	err = c.callOperation("detaching disk "+deviceName+" from instance "+instanceName, func() (*compute.Operation, error) {
+		result, err = c.googleClient.DetachDisk(project, zone, instanceName, deviceName)
+		return result, err
+	})
+	return result, err
+}
+
+func (c retryingGoogleComputeClient) AttachDisk(project string, zone string, instanceName string, disk *compute.AttachedDisk) (result *compute.Operation, err error) {
+	err = c.callOperation("attaching disk "+disk.DeviceName+" to instance "+instanceName, func() (*compute.Operation, error) {
+		result, err = c.googleClient.AttachDisk(project, zone, instanceName, disk)
+		return result, err
+	})
+	return result, err
+}
+
+func (c retryingGoogleComputeClient) ZoneOperationGet(project string, zone string, operationName string) (result *compute.Operation, err error) {
+	err = c.callOperation("getting operation "+operationName, func() (*compute.Operation, error) {
+		result, err = c.googleClient.ZoneOperationGet(project, zone, operationName)
+		return result, err
+	})
+	return result, err
+}
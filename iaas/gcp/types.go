@@ -12,4 +12,5 @@ const (
 	InstanceTerminated = "TERMINATED"
 	ImageReady         = "READY"
 	ImageFailed        = "FAILED"
+	OperationDone      = "DONE"
 )
@@ -0,0 +1,166 @@
+package iaas
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+)
+
+// Phases of a replace that can run a hook
+const (
+	PhasePreStop    = "pre-stop"
+	PhasePostStop   = "post-stop"
+	PhasePreCreate  = "pre-create"
+	PhasePostCreate = "post-create"
+	PhasePostReady  = "post-ready"
+	PhaseOnRollback = "on-rollback"
+)
+
+// Hooks are shell commands a replace runs at its phases. A pre-stop or
+// pre-create hook that fails aborts the replace; any other hook that fails
+// is only logged, as the replace has gone too far to stop.
+type Hooks struct {
+	PreStop    string `yaml:"pre-stop"`
+	PostStop   string `yaml:"post-stop"`
+	PreCreate  string `yaml:"pre-create"`
+	PostCreate string `yaml:"post-create"`
+	PostReady  string `yaml:"post-ready"`
+	OnRollback string `yaml:"on-rollback"`
+}
+
+// Command is the hook of phase, or empty when there is none
+func (h Hooks) Command(phase string) string {
+	switch phase {
+	case PhasePreStop:
+		return h.PreStop
+	case PhasePostStop:
+		return h.PostStop
+	case PhasePreCreate:
+		return h.PreCreate
+	case PhasePostCreate:
+		return h.PostCreate
+	case PhasePostReady:
+		return h.PostReady
+	case PhaseOnRollback:
+		return h.OnRollback
+	}
+	return ""
+}
+
+// HookData is what a hook is told about the replace, as json on its stdin.
+// NewVM is only set once the new vm has been created.
+type HookData struct {
+	Phase      string `json:"phase"`
+	IaaS       string `json:"iaas"`
+	Identifier string `json:"identifier"`
+	OldVM      VM     `json:"old_vm"`
+	NewVM      VM     `json:"new_vm"`
+	Image      string `json:"image"`
+	PublicIP   string `json:"public_ip,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Env is the same data as environment variables, for hooks that would
+// rather not parse json
+func (d HookData) Env() []string {
+	return []string{
+		"CLIAAS_PHASE=" + d.Phase,
+		"CLIAAS_IAAS=" + d.IaaS,
+		"CLIAAS_IDENTIFIER=" + d.Identifier,
+		"CLIAAS_OLD_VM_ID=" + d.OldVM.ID,
+		"CLIAAS_OLD_VM_NAME=" + d.OldVM.Name,
+		"CLIAAS_NEW_VM_ID=" + d.NewVM.ID,
+		"CLIAAS_NEW_VM_NAME=" + d.NewVM.Name,
+		"CLIAAS_IMAGE=" + d.Image,
+		"CLIAAS_PUBLIC_IP=" + d.PublicIP,
+		"CLIAAS_ERROR=" + d.Error,
+	}
+}
+
+// HookRunner runs the hooks of a replace. A nil HookRunner runs nothing, so
+// providers can call it unconditionally.
+type HookRunner struct {
+	Hooks    Hooks
+	IaaS     string
+	Timeout  time.Duration
+	Output   io.Writer
+	Logger   *Logger
+	Progress ProgressReporter
+	Clock    clock.Clock
+}
+
+// Run runs the hook of data's phase, if there is one. Only a failed pre-stop
+// or pre-create hook returns an error.
+func (r *HookRunner) Run(phase string, data HookData) error {
+	if r == nil || r.Hooks.Command(phase) == "" {
+		return nil
+	}
+	data.Phase = phase
+	data.IaaS = r.IaaS
+
+	ReportStep(r.Progress, StepRunningHook, phase)
+	err := r.run(r.Hooks.Command(phase), data)
+	if err == nil {
+		return nil
+	}
+
+	if phase == PhasePreStop || phase == PhasePreCreate {
+		return NewFatalError(fmt.Errorf("%s hook failed, aborting replace: %s", phase, err))
+	}
+	r.Logger.Warn("hook failed", Fields{"phase": phase, "error": err.Error()})
+	return nil
+}
+
+func (r *HookRunner) run(command string, data HookData) error {
+	stdin, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	output := r.Output
+	if output == nil {
+		output = os.Stderr
+	}
+
+	cmd := exec.Command("/bin/sh", "-c", command)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = output
+	cmd.Stderr = output
+	cmd.Env = append(os.Environ(), data.Env()...)
+	err = cmd.Start()
+	if err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	c := r.Clock
+	if c == nil {
+		c = clock.NewClock()
+	}
+	timeout := r.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeouts().Hook
+	}
+	timer := c.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case err = <-done:
+		return err
+	case <-timer.C():
+		// children of the shell may still hold its output open, so the wait
+		// is left to finish in the background
+		_ = cmd.Process.Kill()
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+}
@@ -0,0 +1,96 @@
+package iaas_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/pivotal-cf/cliaas/iaas"
+	"github.com/pivotal-cf/cliaas/iaas/iaasfakes"
+)
+
+var _ = Describe("HookRunner", func() {
+	var runner *HookRunner
+	var output *bytes.Buffer
+	var logs *bytes.Buffer
+	var data HookData
+
+	BeforeEach(func() {
+		output = new(bytes.Buffer)
+		logs = new(bytes.Buffer)
+		runner = &HookRunner{
+			IaaS:   "gcp",
+			Output: output,
+			Logger: &Logger{Writer: logs, Level: LogWarn, Format: LogFormatJSON},
+		}
+		data = HookData{
+			Identifier: "ops-manager",
+			OldVM:      VM{ID: "123", Name: "ops-manager"},
+			NewVM:      VM{ID: "456", Name: "ops-manager-2017-03-01-12-00-00"},
+			Image:      "https://storage.googleapis.com/ops-manager.tar.gz",
+			PublicIP:   "1.2.3.4",
+		}
+	})
+
+	It("passes the vm details as environment variables", func() {
+		runner.Hooks.PostReady = `echo "$CLIAAS_PHASE $CLIAAS_IAAS $CLIAAS_OLD_VM_NAME $CLIAAS_NEW_VM_ID $CLIAAS_PUBLIC_IP"`
+
+		Expect(runner.Run(PhasePostReady, data)).To(Succeed())
+		Expect(output.String()).To(Equal("post-ready gcp ops-manager 456 1.2.3.4\n"))
+	})
+
+	It("passes the vm details as json on stdin", func() {
+		runner.Hooks.PreStop = "cat"
+
+		Expect(runner.Run(PhasePreStop, data)).To(Succeed())
+		var received HookData
+		Expect(json.Unmarshal(output.Bytes(), &received)).To(Succeed())
+		data.Phase = PhasePreStop
+		data.IaaS = "gcp"
+		Expect(received).To(Equal(data))
+	})
+
+	It("reports running the hook", func() {
+		progress := new(iaasfakes.FakeProgressReporter)
+		runner.Progress = progress
+		runner.Hooks.PostStop = "true"
+
+		Expect(runner.Run(PhasePostStop, data)).To(Succeed())
+		Expect(progress.ReportArgsForCall(0)).To(Equal(ProgressEvent{Step: StepRunningHook, Subject: PhasePostStop}))
+	})
+
+	It("fails on a pre hook that exits non-zero", func() {
+		runner.Hooks.PreCreate = "exit 3"
+
+		err := runner.Run(PhasePreCreate, data)
+		Expect(err).To(MatchError(ContainSubstring("pre-create hook failed, aborting replace")))
+		Expect(ClassOf(err)).To(Equal(Fatal))
+	})
+
+	It("only logs a post hook that exits non-zero", func() {
+		runner.Hooks.OnRollback = "exit 3"
+
+		Expect(runner.Run(PhaseOnRollback, data)).To(Succeed())
+		Expect(logs.String()).To(ContainSubstring(`"phase":"on-rollback"`))
+	})
+
+	It("kills a hook that runs past its timeout", func() {
+		runner.Hooks.PreStop = "sleep 10"
+		runner.Timeout = 100 * time.Millisecond
+
+		start := time.Now()
+		err := runner.Run(PhasePreStop, data)
+		Expect(err).To(MatchError(ContainSubstring("timed out after 100ms")))
+		Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+	})
+
+	It("runs nothing for a phase without a hook, or without a runner", func() {
+		Expect(runner.Run(PhasePreStop, data)).To(Succeed())
+		Expect(output.String()).To(BeEmpty())
+
+		var none *HookRunner
+		Expect(none.Run(PhasePreStop, data)).To(Succeed())
+	})
+})
@@ -0,0 +1,13 @@
+package iaas_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestIaas(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Iaas Suite")
+}
@@ -0,0 +1,202 @@
+package iaas
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+)
+
+type LogLevel int
+
+const (
+	LogDebug LogLevel = iota
+	LogInfo
+	LogWarn
+	LogError
+)
+
+var logLevelNames = []string{"debug", "info", "warn", "error"}
+
+func (l LogLevel) String() string {
+	if l < LogDebug || l > LogError {
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+	return logLevelNames[l]
+}
+
+func ParseLogLevel(name string) (LogLevel, error) {
+	for i, levelName := range logLevelNames {
+		if strings.EqualFold(name, levelName) {
+			return LogLevel(i), nil
+		}
+	}
+	return LogDebug, fmt.Errorf("unknown log level %q, expected one of %s", name, strings.Join(logLevelNames, ", "))
+}
+
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// sensitiveFieldNames are the parts of a field name whose value is always
+// redacted, whatever it is
+var sensitiveFieldNames = []string{"secret", "password", "key", "token", "credential", "sas"}
+
+// Fields are the structured values of a log line
+type Fields map[string]interface{}
+
+// Logger writes leveled log lines as text or json. A nil Logger discards
+// everything, so providers can log unconditionally.
+type Logger struct {
+	Writer io.Writer
+	Level  LogLevel
+	Format string
+	Clock  clock.Clock
+
+	// Redact hides known secret values in messages and fields
+	Redact func(string) string
+	// AddSecrets makes Redact hide secrets that are only made while running,
+	// such as generated passwords
+	AddSecrets func(secrets ...string)
+
+	mutex sync.Mutex
+}
+
+func (l *Logger) Debug(message string, fields Fields) {
+	l.Log(LogDebug, message, fields)
+}
+
+func (l *Logger) Info(message string, fields Fields) {
+	l.Log(LogInfo, message, fields)
+}
+
+func (l *Logger) Warn(message string, fields Fields) {
+	l.Log(LogWarn, message, fields)
+}
+
+func (l *Logger) Error(message string, fields Fields) {
+	l.Log(LogError, message, fields)
+}
+
+// APICall logs one call to an IaaS api: at debug level when it worked, and
+// at warn level when it failed
+func (l *Logger) APICall(operation string, duration time.Duration, requestID string, err error) {
+	fields := Fields{
+		"operation":   operation,
+		"duration_ms": int64(duration / time.Millisecond),
+	}
+	if requestID != "" {
+		fields["request_id"] = requestID
+	}
+
+	if err != nil {
+		fields["error"] = err.Error()
+		fields["error_class"] = ClassOf(err).String()
+		l.Warn("iaas api call failed", fields)
+		return
+	}
+	l.Debug("iaas api call", fields)
+}
+
+func (l *Logger) Log(level LogLevel, message string, fields Fields) {
+	if l == nil || level < l.Level {
+		return
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	if l.Clock != nil {
+		now = l.Clock.Now()
+	}
+
+	message = l.redact(message)
+	redacted := Fields{}
+	for name, value := range fields {
+		redacted[name] = l.redactField(name, value)
+	}
+
+	if l.Format == LogFormatJSON {
+		l.writeJSON(now, level, message, redacted)
+		return
+	}
+	l.writeText(now, level, message, redacted)
+}
+
+func (l *Logger) writeText(now time.Time, level LogLevel, message string, fields Fields) {
+	line := fmt.Sprintf("%s %s %s", now.UTC().Format(time.RFC3339), strings.ToUpper(level.String()), message)
+	for _, name := range sortedFieldNames(fields) {
+		line += fmt.Sprintf(" %s=%v", name, quoteIfNeeded(fmt.Sprint(fields[name])))
+	}
+	fmt.Fprintln(l.Writer, line)
+}
+
+func (l *Logger) writeJSON(now time.Time, level LogLevel, message string, fields Fields) {
+	line := map[string]interface{}{}
+	for name, value := range fields {
+		line[name] = value
+	}
+	line["time"] = now.UTC().Format(time.RFC3339)
+	line["level"] = level.String()
+	line["message"] = message
+
+	contents, err := json.Marshal(line)
+	if err != nil {
+		fmt.Fprintf(l.Writer, `{"level":"error","message":"failed to encode log line: %s"}`+"\n", err)
+		return
+	}
+	fmt.Fprintln(l.Writer, string(contents))
+}
+
+// Hide redacts the secret from every later line, and from whatever else the
+// logger's redactor is used on
+func (l *Logger) Hide(secret string) {
+	if l == nil || l.AddSecrets == nil {
+		return
+	}
+	l.AddSecrets(secret)
+}
+
+func (l *Logger) redact(s string) string {
+	if l.Redact == nil {
+		return s
+	}
+	return l.Redact(s)
+}
+
+func (l *Logger) redactField(name string, value interface{}) interface{} {
+	lowerName := strings.ToLower(name)
+	for _, sensitive := range sensitiveFieldNames {
+		if strings.Contains(lowerName, sensitive) {
+			return "[REDACTED]"
+		}
+	}
+
+	if s, ok := value.(string); ok {
+		return l.redact(s)
+	}
+	return value
+}
+
+func sortedFieldNames(fields Fields) []string {
+	var names []string
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func quoteIfNeeded(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return fmt.Sprintf("%q", s)
+	}
+	return s
+}
@@ -0,0 +1,96 @@
+package iaas_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/pivotal-cf/cliaas/iaas"
+)
+
+var _ = Describe("Logger", func() {
+	var (
+		output *bytes.Buffer
+		logger *Logger
+	)
+
+	BeforeEach(func() {
+		output = new(bytes.Buffer)
+		logger = &Logger{
+			Writer: output,
+			Level:  LogInfo,
+			Clock:  fakeclock.NewFakeClock(time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC)),
+			Redact: func(s string) string {
+				return strings.Replace(s, "hunter2", "[REDACTED]", -1)
+			},
+		}
+	})
+
+	It("writes text lines with sorted fields and skips lines below its level", func() {
+		logger.Debug("not written", nil)
+		logger.Info("stopped vm", Fields{"vm": "ops-manager", "attempt": 2, "reason": "user asked"})
+
+		Expect(output.String()).To(Equal(`2017-03-01T12:00:00Z INFO stopped vm attempt=2 reason="user asked" vm=ops-manager` + "\n"))
+	})
+
+	It("writes json lines", func() {
+		logger.Format = LogFormatJSON
+		logger.Error("failed", Fields{"vm": "ops-manager"})
+
+		Expect(output.String()).To(Equal(`{"level":"error","message":"failed","time":"2017-03-01T12:00:00Z","vm":"ops-manager"}` + "\n"))
+	})
+
+	It("redacts known secrets and every field named like a credential", func() {
+		logger.Warn("login with hunter2 failed", Fields{
+			"client_secret":       "anything",
+			"storage_account_key": "anything",
+			"admin_password":      "anything",
+			"detail":              "password was hunter2",
+		})
+
+		Expect(output.String()).To(Equal(`2017-03-01T12:00:00Z WARN login with [REDACTED] failed admin_password=[REDACTED] client_secret=[REDACTED] detail="password was [REDACTED]" storage_account_key=[REDACTED]` + "\n"))
+	})
+
+	It("hides secrets made while running", func() {
+		var hidden []string
+		logger.AddSecrets = func(secrets ...string) {
+			hidden = append(hidden, secrets...)
+		}
+		logger.Hide("generated-password")
+
+		Expect(hidden).To(Equal([]string{"generated-password"}))
+	})
+
+	Describe("APICall", func() {
+		It("logs successful calls at debug level", func() {
+			logger.Level = LogDebug
+			logger.APICall("RunInstances", 1500*time.Millisecond, "req-1", nil)
+
+			Expect(output.String()).To(Equal("2017-03-01T12:00:00Z DEBUG iaas api call duration_ms=1500 operation=RunInstances request_id=req-1\n"))
+		})
+
+		It("logs failed calls at warn level with their class", func() {
+			logger.APICall("RunInstances", time.Second, "req-2", NewRetryableError(errors.New("throttled"), 0))
+
+			Expect(output.String()).To(Equal("2017-03-01T12:00:00Z WARN iaas api call failed duration_ms=1000 error=throttled error_class=retryable operation=RunInstances request_id=req-2\n"))
+		})
+	})
+
+	It("does nothing when nil", func() {
+		var nilLogger *Logger
+		nilLogger.Error("failed", nil)
+		nilLogger.Hide("generated-password")
+	})
+
+	It("parses level names", func() {
+		Expect(ParseLogLevel("WARN")).To(Equal(LogWarn))
+
+		_, err := ParseLogLevel("verbose")
+		Expect(err).To(MatchError(ContainSubstring("unknown log level")))
+	})
+})
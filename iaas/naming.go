@@ -0,0 +1,127 @@
+package iaas
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"code.cloudfoundry.org/clock"
+)
+
+// DefaultNameTemplate names new resources after the VM they replace and the
+// time of the replace, e.g. ops-manager-2017-03-01-12-00-00-image
+const DefaultNameTemplate = "{{.Identifier}}-{{.Timestamp}}{{.Suffix}}"
+
+// TimestampLayout is the layout of {{.Timestamp}}, which only uses characters
+// every IaaS allows in names
+const TimestampLayout = "2006-01-02-15-04-05"
+
+// Suffixes telling apart the resources one replace creates
+const (
+	SuffixVM     = ""
+	SuffixImage  = "-image"
+	SuffixOSDisk = "-osdisk"
+	SuffixNIC    = "-nic"
+)
+
+// timestampPattern matches a timestamp appended by an earlier replace, in
+// TimestampLayout or the layout azure names used to have
+var timestampPattern = regexp.MustCompile(`[-_](\d{4}-\d{2}-\d{2}-\d{2}-\d{2}-\d{2}|\d{14})$`)
+
+// versionPattern matches an ops manager version in an image url or name
+var versionPattern = regexp.MustCompile(`\d+\.\d+(\.\d+)?`)
+
+// NameData are the values a name template can use
+type NameData struct {
+	// Identifier is the name of the VM being replaced, less a timestamp an
+	// earlier replace appended to it
+	Identifier string
+	// Timestamp is the time of the replace in TimestampLayout
+	Timestamp string
+	// Version is the ops manager version found in the image, with dashes for
+	// dots, or empty when there is none
+	Version string
+	// Suffix tells apart the VM, image and disks of one replace
+	Suffix string
+}
+
+// Namer names the VMs, images, blobs and disks a replace creates from a
+// text/template. The zero value uses DefaultNameTemplate.
+type Namer struct {
+	Template string
+	Clock    clock.Clock
+}
+
+// ValidateNameTemplate checks that text parses, only uses the NameData fields
+// and names a vm after the one it replaces
+func ValidateNameTemplate(text string) error {
+	_, err := Namer{Template: text}.Name(NameData{
+		Identifier: "ops-manager",
+		Timestamp:  TimestampLayout,
+		Version:    "1-10-3",
+	}, SuffixVM)
+	return err
+}
+
+// NameData returns the values for naming what replacing vmName with image
+// creates. Every name of one replace should come from the same NameData, so
+// that they share a timestamp.
+func (n Namer) NameData(vmName string, image string) NameData {
+	c := n.Clock
+	if c == nil {
+		c = clock.NewClock()
+	}
+
+	return NameData{
+		Identifier: BaseName(vmName),
+		Timestamp:  c.Now().UTC().Format(TimestampLayout),
+		Version:    ImageVersion(image),
+	}
+}
+
+// Name executes the template for the resource with suffix
+func (n Namer) Name(data NameData, suffix string) (string, error) {
+	text := n.Template
+	if text == "" {
+		text = DefaultNameTemplate
+	}
+
+	tmpl, err := template.New("name").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid name template %q: %s", text, err)
+	}
+
+	data.Suffix = suffix
+	var name bytes.Buffer
+	err = tmpl.Execute(&name, data)
+	if err != nil {
+		return "", fmt.Errorf("invalid name template %q: %s", text, err)
+	}
+
+	// the next replace finds the new vm by the identifier, so the vm name has
+	// to start with it
+	if suffix == SuffixVM && !strings.HasPrefix(name.String(), data.Identifier) {
+		return "", fmt.Errorf("invalid name template %q: vm name %q does not start with %q", text, name.String(), data.Identifier)
+	}
+	return name.String(), nil
+}
+
+// BaseName strips the timestamp an earlier replace appended to a VM name, so
+// that names don't grow with every replace
+func BaseName(vmName string) string {
+	return timestampPattern.ReplaceAllString(vmName, "")
+}
+
+// ImageVersion finds the ops manager version in an image url or name, e.g.
+// 1-10-3 in ops-manager-1.10.3.vhd
+func ImageVersion(image string) string {
+	return strings.Replace(OpsManagerVersion(image), ".", "-", -1)
+}
+
+// OpsManagerVersion finds the ops manager version in an image url or name as
+// it is written there, e.g. 1.10.3 in ops-manager-1.10.3.vhd, or returns empty
+func OpsManagerVersion(image string) string {
+	return versionPattern.FindString(image)
+}
@@ -0,0 +1,52 @@
+package iaas_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/pivotal-cf/cliaas/iaas"
+)
+
+var _ = Describe("Namer", func() {
+	var namer Namer
+
+	BeforeEach(func() {
+		namer = Namer{Clock: fakeclock.NewFakeClock(time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC))}
+	})
+
+	It("names resources after the old vm and the time by default", func() {
+		data := namer.NameData("ops-manager", "ami-019e4617")
+
+		Expect(namer.Name(data, SuffixVM)).To(Equal("ops-manager-2017-03-01-12-00-00"))
+		Expect(namer.Name(data, SuffixImage)).To(Equal("ops-manager-2017-03-01-12-00-00-image"))
+	})
+
+	It("does not grow names with every replace", func() {
+		Expect(namer.NameData("ops-manager-2017-02-01-10-00-00", "").Identifier).To(Equal("ops-manager"))
+		Expect(namer.NameData("ops-manager_20170201100000", "").Identifier).To(Equal("ops-manager"))
+	})
+
+	It("finds the ops manager version in the image", func() {
+		namer.Template = "opsman-{{.Version}}{{.Suffix}}"
+		data := namer.NameData("ops-manager", "https://opsman.blob.core.windows.net/images/ops-manager-1.10.3.vhd")
+
+		Expect(namer.Name(data, SuffixOSDisk)).To(Equal("opsman-1-10-3-osdisk"))
+	})
+
+	It("rejects templates that do not parse or use unknown fields", func() {
+		Expect(ValidateNameTemplate("{{.Identifier")).To(MatchError(ContainSubstring("invalid name template")))
+		Expect(ValidateNameTemplate("{{.Name}}")).To(MatchError(ContainSubstring("invalid name template")))
+		Expect(ValidateNameTemplate("{{.Identifier}}-{{.Version}}")).To(Succeed())
+	})
+
+	It("rejects templates that do not start the vm name with the identifier", func() {
+		Expect(ValidateNameTemplate("opsman-{{.Timestamp}}{{.Suffix}}")).To(MatchError(ContainSubstring("does not start with")))
+
+		namer.Template = "{{.Version}}-{{.Identifier}}{{.Suffix}}"
+		_, err := namer.Name(namer.NameData("ops-manager", "ops-manager-1.10.3.vhd"), SuffixVM)
+		Expect(err).To(MatchError(ContainSubstring(`vm name "1-10-3-ops-manager" does not start with "ops-manager"`)))
+	})
+})
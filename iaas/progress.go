@@ -0,0 +1,184 @@
+package iaas
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+)
+
+// Steps a replace, stop or start reports as it goes
+const (
+	StepFoundVM           = "found-vm"
+	StepStopping          = "stopping"
+	StepStopped           = "stopped"
+	StepStarting          = "starting"
+	StepDetachingDisk     = "detaching-disk"
+	StepImportingImage    = "importing-image"
+	StepImportedImage     = "imported-image"
+	StepCreating          = "creating"
+	StepWaitingForRunning = "waiting-for-running"
+	StepRunning           = "running"
+	StepAttachingDisk     = "attaching-disk"
+	StepIPAssociated      = "ip-associated"
+	StepRunningHook       = "running-hook"
+	StepDeletingOldVM     = "deleting-old-vm"
+	StepDone              = "done"
+)
+
+// ProgressEvent is one step of a long running operation. Current and Total
+// are set for steps that can tell how far along they are, e.g. the percent of
+// an image import or the bytes of a blob copy.
+type ProgressEvent struct {
+	Step    string
+	Subject string
+	Current int64
+	Total   int64
+}
+
+// Percent is how far along the step is, or -1 when it cannot tell
+func (e ProgressEvent) Percent() int {
+	if e.Total <= 0 {
+		return -1
+	}
+	return int(e.Current * 100 / e.Total)
+}
+
+//go:generate counterfeiter . ProgressReporter
+
+// ProgressReporter receives the steps providers go through, so that a long
+// replace doesn't look hung
+type ProgressReporter interface {
+	Report(event ProgressEvent)
+}
+
+// ReportStep reports a step without progress to reporter, which may be nil
+func ReportStep(reporter ProgressReporter, step string, subject string) {
+	ReportProgress(reporter, ProgressEvent{Step: step, Subject: subject})
+}
+
+// ReportProgress reports event to reporter, which may be nil
+func ReportProgress(reporter ProgressReporter, event ProgressEvent) {
+	if reporter != nil {
+		reporter.Report(event)
+	}
+}
+
+const (
+	ProgressText = "text"
+	ProgressJSON = "json"
+	ProgressNone = "none"
+)
+
+// NewProgressReporter returns the reporter for a progress format, or nil for
+// none. redact hides secrets in what it writes, and may be nil.
+func NewProgressReporter(format string, w io.Writer, clock clock.Clock, redact func(string) string) ProgressReporter {
+	return NewLabeledProgressReporter(format, "", w, clock, redact)
+}
+
+// NewLabeledProgressReporter returns a reporter that tells its events apart
+// from those of other replaces writing to w by label
+func NewLabeledProgressReporter(format string, label string, w io.Writer, clock clock.Clock, redact func(string) string) ProgressReporter {
+	switch format {
+	case ProgressJSON:
+		return &JSONProgress{Writer: w, Clock: clock, Start: clock.Now(), Label: label, Redact: redact}
+	case ProgressNone:
+		return nil
+	default:
+		return &TextProgress{Writer: w, Clock: clock, Start: clock.Now(), Label: label, Redact: redact}
+	}
+}
+
+// TextProgress writes each event as a timestamped line for people and CI logs
+type TextProgress struct {
+	Writer io.Writer
+	Clock  clock.Clock
+	Start  time.Time
+	Label  string
+	Redact func(string) string
+
+	mutex sync.Mutex
+}
+
+func (p *TextProgress) Report(event ProgressEvent) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	now := p.Clock.Now()
+	prefix := now.UTC().Format(time.RFC3339)
+	if p.Label != "" {
+		prefix += " [" + p.Label + "]"
+	}
+	line := fmt.Sprintf("%s [+%s] %s", prefix, elapsedSince(p.Start, now), event.Step)
+	if event.Subject != "" {
+		line += " " + event.Subject
+	}
+	if percent := event.Percent(); percent >= 0 {
+		line += fmt.Sprintf(" %d%%", percent)
+		if event.Total != 100 {
+			line += fmt.Sprintf(" (%d of %d)", event.Current, event.Total)
+		}
+	}
+	fmt.Fprintln(p.Writer, redactWith(p.Redact, line))
+}
+
+// JSONProgress writes each event as a line of json for other tools
+type JSONProgress struct {
+	Writer io.Writer
+	Clock  clock.Clock
+	Start  time.Time
+	Label  string
+	Redact func(string) string
+
+	mutex sync.Mutex
+}
+
+type progressLine struct {
+	Time           time.Time `json:"time"`
+	Label          string    `json:"label,omitempty"`
+	ElapsedSeconds int64     `json:"elapsed_seconds"`
+	Step           string    `json:"step"`
+	Subject        string    `json:"subject,omitempty"`
+	Current        int64     `json:"current,omitempty"`
+	Total          int64     `json:"total,omitempty"`
+	Percent        *int      `json:"percent,omitempty"`
+}
+
+func (p *JSONProgress) Report(event ProgressEvent) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	now := p.Clock.Now()
+	line := progressLine{
+		Time:           now.UTC(),
+		Label:          p.Label,
+		ElapsedSeconds: int64(elapsedSince(p.Start, now) / time.Second),
+		Step:           event.Step,
+		Subject:        redactWith(p.Redact, event.Subject),
+		Current:        event.Current,
+		Total:          event.Total,
+	}
+	if percent := event.Percent(); percent >= 0 {
+		line.Percent = &percent
+	}
+
+	contents, err := json.Marshal(line)
+	if err != nil {
+		return
+	}
+	fmt.Fprintln(p.Writer, string(contents))
+}
+
+func elapsedSince(start time.Time, now time.Time) time.Duration {
+	return now.Sub(start) / time.Second * time.Second
+}
+
+func redactWith(redact func(string) string, s string) string {
+	if redact == nil {
+		return s
+	}
+	return redact(s)
+}
@@ -0,0 +1,87 @@
+package iaas_test
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/pivotal-cf/cliaas/iaas"
+)
+
+var _ = Describe("Progress", func() {
+	var (
+		output    *bytes.Buffer
+		fakeClock *fakeclock.FakeClock
+		reporter  ProgressReporter
+	)
+
+	BeforeEach(func() {
+		output = new(bytes.Buffer)
+		fakeClock = fakeclock.NewFakeClock(time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC))
+	})
+
+	Context("as text", func() {
+		BeforeEach(func() {
+			reporter = NewProgressReporter(ProgressText, output, fakeClock, nil)
+		})
+
+		It("writes timestamped lines with the elapsed time", func() {
+			reporter.Report(ProgressEvent{Step: StepStopping, Subject: "ops-manager"})
+			fakeClock.Increment(62 * time.Second)
+			reporter.Report(ProgressEvent{Step: StepImportingImage, Subject: "opsman-disk", Current: 40, Total: 100})
+			fakeClock.Increment(time.Minute)
+			reporter.Report(ProgressEvent{Step: StepImportingImage, Subject: "opsman.vhd", Current: 512, Total: 2048})
+
+			Expect(output.String()).To(Equal(
+				"2017-03-01T12:00:00Z [+0s] stopping ops-manager\n" +
+					"2017-03-01T12:01:02Z [+1m2s] importing-image opsman-disk 40%\n" +
+					"2017-03-01T12:02:02Z [+2m2s] importing-image opsman.vhd 25% (512 of 2048)\n"))
+		})
+	})
+
+	Context("as json", func() {
+		BeforeEach(func() {
+			reporter = NewProgressReporter(ProgressJSON, output, fakeClock, nil)
+		})
+
+		It("writes a json line per event", func() {
+			fakeClock.Increment(90 * time.Second)
+			reporter.Report(ProgressEvent{Step: StepImportingImage, Subject: "opsman-disk", Current: 40, Total: 100})
+			reporter.Report(ProgressEvent{Step: StepDone})
+
+			Expect(output.String()).To(Equal(
+				`{"time":"2017-03-01T12:01:30Z","elapsed_seconds":90,"step":"importing-image","subject":"opsman-disk","current":40,"total":100,"percent":40}` + "\n" +
+					`{"time":"2017-03-01T12:01:30Z","elapsed_seconds":90,"step":"done"}` + "\n"))
+		})
+	})
+
+	It("labels the events of one of several replaces", func() {
+		reporter = NewLabeledProgressReporter(ProgressText, "staging", output, fakeClock, nil)
+		reporter.Report(ProgressEvent{Step: StepStopping, Subject: "ops-manager"})
+
+		reporter = NewLabeledProgressReporter(ProgressJSON, "staging", output, fakeClock, nil)
+		reporter.Report(ProgressEvent{Step: StepDone})
+
+		Expect(output.String()).To(Equal(
+			"2017-03-01T12:00:00Z [staging] [+0s] stopping ops-manager\n" +
+				`{"time":"2017-03-01T12:00:00Z","label":"staging","elapsed_seconds":0,"step":"done"}` + "\n"))
+	})
+
+	It("redacts secrets", func() {
+		redact := func(s string) string { return strings.Replace(s, "hunter2", "[REDACTED]", -1) }
+
+		NewProgressReporter(ProgressText, output, fakeClock, redact).Report(ProgressEvent{Step: StepCreating, Subject: "vm-hunter2"})
+		NewProgressReporter(ProgressJSON, output, fakeClock, redact).Report(ProgressEvent{Step: StepCreating, Subject: "vm-hunter2"})
+
+		Expect(output.String()).NotTo(ContainSubstring("hunter2"))
+		Expect(output.String()).To(ContainSubstring("vm-[REDACTED]"))
+	})
+
+	It("reports nothing with none", func() {
+		Expect(NewProgressReporter(ProgressNone, output, fakeClock, nil)).To(BeNil())
+	})
+})
@@ -0,0 +1,56 @@
+package iaas
+
+import "sort"
+
+// VMSpec describes a vm the same way on every iaas, so that it can be kept
+// as yaml and compared with the live vm later. Raw is the provider's own
+// description the spec was made from.
+type VMSpec struct {
+	IaaS         string            `yaml:"iaas"`
+	Name         string            `yaml:"name"`
+	ID           string            `yaml:"id,omitempty"`
+	Image        string            `yaml:"image,omitempty"`
+	InstanceType string            `yaml:"instance_type"`
+	Zone         string            `yaml:"zone,omitempty"`
+	Network      NetworkSpec       `yaml:"network"`
+	Disks        []DiskSpec        `yaml:"disks"`
+	KeyName      string            `yaml:"key_name,omitempty"`
+	Identity     string            `yaml:"identity,omitempty"`
+	Tags         map[string]string `yaml:"tags,omitempty"`
+
+	Raw interface{} `yaml:"-"`
+}
+
+// NetworkSpec is where a vm is attached: the AWS VPC, GCP network or Azure
+// virtual network, its subnet, and the security groups, network tags or
+// network security group that guard it. Interface is the Azure nic.
+type NetworkSpec struct {
+	Network        string   `yaml:"network,omitempty"`
+	Interface      string   `yaml:"interface,omitempty"`
+	Subnet         string   `yaml:"subnet,omitempty"`
+	PrivateIP      string   `yaml:"private_ip,omitempty"`
+	PublicIP       string   `yaml:"public_ip,omitempty"`
+	SecurityGroups []string `yaml:"security_groups,omitempty"`
+}
+
+// DiskSpec is one disk of a vm. Name is the device name on AWS and GCP, and
+// the os disk or lun on Azure. Source is the volume, disk or vhd.
+type DiskSpec struct {
+	Name   string `yaml:"name"`
+	Boot   bool   `yaml:"boot,omitempty"`
+	SizeGB int64  `yaml:"size_gb"`
+	Type   string `yaml:"type,omitempty"`
+	Source string `yaml:"source,omitempty"`
+}
+
+// Normalize sorts the lists of the spec, boot disk first, so that two specs
+// of the same vm are equal and their yaml diffs cleanly
+func (s *VMSpec) Normalize() {
+	sort.Strings(s.Network.SecurityGroups)
+	sort.SliceStable(s.Disks, func(i, j int) bool {
+		if s.Disks[i].Boot != s.Disks[j].Boot {
+			return s.Disks[i].Boot
+		}
+		return s.Disks[i].Name < s.Disks[j].Name
+	})
+}
@@ -0,0 +1,59 @@
+package iaas
+
+import "time"
+
+// Timeouts bound every wait on the IaaS. They can be set in the timeouts
+// section of an iaas config and overridden by flags; zero values fall back to
+// DefaultTimeouts.
+type Timeouts struct {
+	Stop               time.Duration `yaml:"stop" long:"stop-timeout" description:"How long to wait for a VM to stop"`
+	Start              time.Duration `yaml:"start" long:"start-timeout" description:"How long to wait for a stopped VM to start"`
+	Create             time.Duration `yaml:"create" long:"create-timeout" description:"How long to wait for a new VM to be running"`
+	ImageImport        time.Duration `yaml:"image_import" long:"image-import-timeout" description:"How long to wait for an image to be imported or copied"`
+	IPAssociation      time.Duration `yaml:"ip_association" long:"ip-association-timeout" description:"How long to keep trying to associate the public IP with the new VM"`
+	DiskAttachment     time.Duration `yaml:"disk_attachment" long:"disk-attachment-timeout" description:"How long to wait for a data disk to be detached or attached"`
+	OpsManAvailability time.Duration `yaml:"opsman_availability" long:"opsman-availability-timeout" description:"How long to wait for a new Ops Manager to accept the installation import, and to start its authentication after it"`
+	Hook               time.Duration `yaml:"hook" long:"hook-timeout" description:"How long a replace hook may run before it is killed"`
+	APIRetry           time.Duration `yaml:"api_retry" long:"api-retry-timeout" description:"How long to keep retrying an IaaS api call that fails with throttling or a transient error"`
+	PollInterval       time.Duration `yaml:"poll_interval" long:"poll-interval" description:"How long to wait between the first polls of the IaaS"`
+	MaxPollInterval    time.Duration `yaml:"max_poll_interval" long:"max-poll-interval" description:"The longest the poll interval may back off to"`
+}
+
+func DefaultTimeouts() Timeouts {
+	return Timeouts{
+		Stop:               10 * time.Minute,
+		Start:              10 * time.Minute,
+		Create:             10 * time.Minute,
+		ImageImport:        30 * time.Minute,
+		IPAssociation:      time.Minute,
+		DiskAttachment:     5 * time.Minute,
+		OpsManAvailability: 20 * time.Minute,
+		Hook:               10 * time.Minute,
+		APIRetry:           2 * time.Minute,
+		PollInterval:       2 * time.Second,
+		MaxPollInterval:    30 * time.Second,
+	}
+}
+
+// Merge returns t with every non-zero value of override applied on top
+func (t Timeouts) Merge(override Timeouts) Timeouts {
+	merged := t
+	mergeDuration(&merged.Stop, override.Stop)
+	mergeDuration(&merged.Start, override.Start)
+	mergeDuration(&merged.Create, override.Create)
+	mergeDuration(&merged.ImageImport, override.ImageImport)
+	mergeDuration(&merged.IPAssociation, override.IPAssociation)
+	mergeDuration(&merged.DiskAttachment, override.DiskAttachment)
+	mergeDuration(&merged.OpsManAvailability, override.OpsManAvailability)
+	mergeDuration(&merged.Hook, override.Hook)
+	mergeDuration(&merged.APIRetry, override.APIRetry)
+	mergeDuration(&merged.PollInterval, override.PollInterval)
+	mergeDuration(&merged.MaxPollInterval, override.MaxPollInterval)
+	return merged
+}
+
+func mergeDuration(value *time.Duration, override time.Duration) {
+	if override != 0 {
+		*value = override
+	}
+}
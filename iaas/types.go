@@ -3,3 +3,36 @@ package iaas
 type Disk struct {
 	SizeGB int64
 }
+
+// VM identifies a running VM and the image it was created from
+type VM struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Image string `json:"image"`
+}
+
+// PermissionCheck is the outcome of checking one permission that replacing or
+// deleting a VM needs
+type PermissionCheck struct {
+	Permission string
+	Err        error
+	SkipReason string
+}
+
+func (c PermissionCheck) Result() string {
+	switch {
+	case c.Err != nil:
+		return "FAIL"
+	case c.SkipReason != "":
+		return "SKIP"
+	default:
+		return "PASS"
+	}
+}
+
+func (c PermissionCheck) Detail() string {
+	if c.Err != nil {
+		return c.Err.Error()
+	}
+	return c.SkipReason
+}
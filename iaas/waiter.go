@@ -0,0 +1,173 @@
+package iaas
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	errwrap "github.com/pkg/errors"
+)
+
+const (
+	backoffMultiplier = 2
+	jitterFraction    = 0.2
+)
+
+// TimeoutError is returned when a wait runs out of time. Last holds the last
+// error seen while retrying, if any.
+type TimeoutError struct {
+	Description string
+	Timeout     time.Duration
+	Last        error
+}
+
+func (e *TimeoutError) Error() string {
+	message := fmt.Sprintf("timed out after %s %s", e.Timeout, e.Description)
+	if e.Last != nil {
+		message = fmt.Sprintf("%s (last error: %s)", message, e.Last)
+	}
+	return message
+}
+
+// Waiter is the one place cliaas waits on the IaaS. It polls with an
+// exponential backoff between PollInterval and MaxPollInterval, with jitter
+// so that parallel runs don't poll in lockstep.
+type Waiter struct {
+	Clock    clock.Clock
+	Timeouts Timeouts
+
+	// Random returns a value in [0,1) used for jitter; rand.Float64 when nil
+	Random func() float64
+}
+
+func NewWaiter(clock clock.Clock, timeouts Timeouts) Waiter {
+	return Waiter{
+		Clock:    clock,
+		Timeouts: DefaultTimeouts().Merge(timeouts),
+	}
+}
+
+// Wait polls condition until it reports done, returns an error, or timeout
+// passes. The condition is always checked once more at the deadline.
+// Retryable errors from the condition are waited out like a not-done result,
+// backing off for at least as long as the provider asked.
+func (w Waiter) Wait(description string, timeout time.Duration, condition func() (bool, error)) error {
+	deadline := w.Clock.Now().Add(timeout)
+	interval := w.pollInterval()
+	var last error
+
+	for {
+		done, err := condition()
+		if err != nil && ClassOf(err) != Retryable {
+			return err
+		}
+		if err == nil && done {
+			return nil
+		}
+		last = err
+
+		remaining := deadline.Sub(w.Clock.Now())
+		if remaining <= 0 {
+			return &TimeoutError{Description: description, Timeout: timeout, Last: last}
+		}
+
+		delay := w.jitter(interval)
+		if retryAfter := RetryAfterOf(err); retryAfter > delay {
+			delay = retryAfter
+		}
+		if delay > remaining {
+			delay = remaining
+		}
+		w.Clock.Sleep(delay)
+		interval = w.backoff(interval)
+	}
+}
+
+// Retry calls operation until it succeeds or timeout passes, and returns the
+// last error wrapped in a TimeoutError when it never does. Only fatal errors
+// stop it early.
+func (w Waiter) Retry(description string, timeout time.Duration, operation func() error) error {
+	return w.Wait(description, timeout, func() (bool, error) {
+		err := operation()
+		if err != nil && ClassOf(err) != Fatal {
+			return false, &ClassifiedError{Class: Retryable, RetryAfter: RetryAfterOf(err), Err: err}
+		}
+		return err == nil, err
+	})
+}
+
+// Call makes a single IaaS api call, retrying it for up to Timeouts.APIRetry
+// while it fails with a retryable error. Once it gives up the last error is
+// returned, so that its class still reaches the caller.
+func (w Waiter) Call(description string, operation func() error) error {
+	err := w.Wait(description, w.Timeouts.APIRetry, func() (bool, error) {
+		err := operation()
+		return err == nil, err
+	})
+	if timeoutErr, ok := err.(*TimeoutError); ok && timeoutErr.Last != nil {
+		return errwrap.Wrapf(timeoutErr.Last, "gave up %s after %s", description, timeoutErr.Timeout)
+	}
+	return err
+}
+
+// CancelAfter returns a channel that is closed once timeout passes, for sdk
+// calls that poll on their own and accept a cancel channel. Calling stop
+// releases the timer early, and may be called any number of times from any
+// goroutine.
+func (w Waiter) CancelAfter(timeout time.Duration) (cancel <-chan struct{}, stop func()) {
+	cancelCh := make(chan struct{})
+	stopCh := make(chan struct{})
+	timer := w.Clock.NewTimer(timeout)
+
+	go func() {
+		select {
+		case <-timer.C():
+			select {
+			case <-stopCh:
+			default:
+				close(cancelCh)
+			}
+		case <-stopCh:
+			timer.Stop()
+		}
+	}()
+
+	var once sync.Once
+	return cancelCh, func() {
+		once.Do(func() {
+			close(stopCh)
+		})
+	}
+}
+
+func (w Waiter) pollInterval() time.Duration {
+	if w.Timeouts.PollInterval > 0 {
+		return w.Timeouts.PollInterval
+	}
+	return DefaultTimeouts().PollInterval
+}
+
+func (w Waiter) backoff(interval time.Duration) time.Duration {
+	maxInterval := w.Timeouts.MaxPollInterval
+	if maxInterval <= 0 {
+		maxInterval = DefaultTimeouts().MaxPollInterval
+	}
+
+	interval = interval * backoffMultiplier
+	if interval > maxInterval {
+		return maxInterval
+	}
+	return interval
+}
+
+func (w Waiter) jitter(interval time.Duration) time.Duration {
+	random := w.Random
+	if random == nil {
+		random = rand.Float64
+	}
+
+	factor := 1 + jitterFraction*(2*random()-1)
+	return time.Duration(float64(interval) * factor)
+}
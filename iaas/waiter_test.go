@@ -0,0 +1,249 @@
+package iaas_test
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/pivotal-cf/cliaas/iaas"
+)
+
+var _ = Describe("Waiter", func() {
+	var (
+		fakeClock *fakeclock.FakeClock
+		waiter    Waiter
+		start     time.Time
+		polls     []time.Duration
+	)
+
+	BeforeEach(func() {
+		start = time.Now()
+		fakeClock = fakeclock.NewFakeClock(start)
+		waiter = NewWaiter(fakeClock, Timeouts{
+			PollInterval:    time.Second,
+			MaxPollInterval: 4 * time.Second,
+		})
+		waiter.Random = func() float64 { return 0.5 }
+		polls = nil
+	})
+
+	// drive advances the fake clock by whatever the waiter sleeps for until
+	// the wait finishes
+	drive := func(wait func() error) error {
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- wait()
+		}()
+
+		for {
+			select {
+			case err := <-errCh:
+				return err
+			default:
+			}
+
+			if fakeClock.WatcherCount() > 0 {
+				fakeClock.Increment(time.Second)
+			} else {
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}
+
+	Describe("Wait", func() {
+		It("backs off exponentially up to the max poll interval", func() {
+			err := drive(func() error {
+				return waiter.Wait("for something", time.Minute, func() (bool, error) {
+					polls = append(polls, fakeClock.Since(start))
+					return len(polls) == 5, nil
+				})
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(polls).To(Equal([]time.Duration{
+				0,
+				time.Second,
+				3 * time.Second,
+				7 * time.Second,
+				11 * time.Second,
+			}))
+		})
+
+		It("stops at the first error from the condition", func() {
+			controlErr := errors.New("api error")
+			err := waiter.Wait("for something", time.Minute, func() (bool, error) {
+				return false, controlErr
+			})
+			Expect(err).To(Equal(controlErr))
+		})
+
+		It("waits out retryable errors for as long as the provider asks", func() {
+			err := drive(func() error {
+				return waiter.Wait("for something", time.Minute, func() (bool, error) {
+					polls = append(polls, fakeClock.Since(start))
+					if len(polls) == 1 {
+						return false, NewRetryableError(errors.New("throttled"), 10*time.Second)
+					}
+					return true, nil
+				})
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(polls).To(Equal([]time.Duration{0, 10 * time.Second}))
+		})
+
+		It("reports the last retryable error when it times out", func() {
+			err := drive(func() error {
+				return waiter.Wait("waiting for something", 3*time.Second, func() (bool, error) {
+					return false, NewRetryableError(errors.New("throttled"), 0)
+				})
+			})
+			Expect(err).To(MatchError("timed out after 3s waiting for something (last error: throttled)"))
+		})
+
+		It("checks once more at the deadline before timing out", func() {
+			err := drive(func() error {
+				return waiter.Wait("waiting for something", 5*time.Second, func() (bool, error) {
+					polls = append(polls, fakeClock.Since(start))
+					return false, nil
+				})
+			})
+			Expect(err).To(MatchError("timed out after 5s waiting for something"))
+			Expect(polls[len(polls)-1]).To(Equal(5 * time.Second))
+		})
+
+		It("jitters the poll interval", func() {
+			waiter.Random = func() float64 { return 0 }
+			err := drive(func() error {
+				return waiter.Wait("for something", time.Minute, func() (bool, error) {
+					polls = append(polls, fakeClock.Since(start))
+					return len(polls) == 2, nil
+				})
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(polls[1]).To(Equal(time.Second))
+		})
+	})
+
+	Describe("Retry", func() {
+		It("retries until the operation succeeds", func() {
+			var calls int
+			err := drive(func() error {
+				return waiter.Retry("associating the ip", time.Minute, func() error {
+					calls++
+					if calls < 3 {
+						return errors.New("not yet")
+					}
+					return nil
+				})
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(calls).To(Equal(3))
+		})
+
+		It("reports the last error when it times out", func() {
+			err := drive(func() error {
+				return waiter.Retry("associating the ip", 3*time.Second, func() error {
+					return errors.New("InvalidInstanceID")
+				})
+			})
+			Expect(err).To(MatchError("timed out after 3s associating the ip (last error: InvalidInstanceID)"))
+			_, ok := err.(*TimeoutError)
+			Expect(ok).To(BeTrue())
+		})
+
+		It("stops at the first fatal error", func() {
+			var calls int
+			err := waiter.Retry("associating the ip", time.Minute, func() error {
+				calls++
+				return NewFatalError(errors.New("UnauthorizedOperation"))
+			})
+			Expect(err).To(MatchError("UnauthorizedOperation"))
+			Expect(calls).To(Equal(1))
+		})
+	})
+
+	Describe("Call", func() {
+		BeforeEach(func() {
+			waiter.Timeouts.APIRetry = 5 * time.Second
+		})
+
+		It("retries retryable errors until the call succeeds", func() {
+			var calls int
+			err := drive(func() error {
+				return waiter.Call("stopping the vm", func() error {
+					calls++
+					if calls < 3 {
+						return NewRetryableError(errors.New("RequestLimitExceeded"), 0)
+					}
+					return nil
+				})
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(calls).To(Equal(3))
+		})
+
+		It("does not retry other errors", func() {
+			var calls int
+			err := waiter.Call("stopping the vm", func() error {
+				calls++
+				return NewNotFoundError(errors.New("InvalidInstanceID.NotFound"))
+			})
+			Expect(ClassOf(err)).To(Equal(NotFound))
+			Expect(calls).To(Equal(1))
+		})
+
+		It("returns the last error still classified once it gives up", func() {
+			err := drive(func() error {
+				return waiter.Call("stopping the vm", func() error {
+					return NewRetryableError(errors.New("RequestLimitExceeded"), 0)
+				})
+			})
+			Expect(err).To(MatchError("gave up stopping the vm after 5s: RequestLimitExceeded"))
+			Expect(ClassOf(err)).To(Equal(Retryable))
+		})
+	})
+
+	Describe("CancelAfter", func() {
+		It("closes the channel once the timeout passes", func() {
+			cancel, stop := waiter.CancelAfter(time.Minute)
+			defer stop()
+
+			Consistently(cancel).ShouldNot(BeClosed())
+			fakeClock.WaitForWatcherAndIncrement(time.Minute)
+			Eventually(cancel).Should(BeClosed())
+		})
+
+		It("never closes the channel once stopped", func() {
+			cancel, stop := waiter.CancelAfter(time.Minute)
+			stop()
+			stop()
+
+			fakeClock.Increment(time.Minute)
+			Consistently(cancel).ShouldNot(BeClosed())
+		})
+
+		It("can be stopped from several goroutines at once", func() {
+			_, stop := waiter.CancelAfter(time.Minute)
+
+			done := make(chan struct{})
+			for i := 0; i < 10; i++ {
+				go func() {
+					stop()
+					done <- struct{}{}
+				}()
+			}
+			for i := 0; i < 10; i++ {
+				Eventually(done).Should(Receive())
+			}
+		})
+	})
+
+	Describe("Timeouts", func() {
+		It("fills unset values from the defaults", func() {
+			Expect(waiter.Timeouts.Stop).To(Equal(DefaultTimeouts().Stop))
+			Expect(waiter.Timeouts.PollInterval).To(Equal(time.Second))
+		})
+	})
+})
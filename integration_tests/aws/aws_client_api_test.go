@@ -13,6 +13,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/ec2"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/cliaas/iaas"
 	cliaasAWS "github.com/pivotal-cf/cliaas/iaas/aws"
 )
 
@@ -42,7 +43,7 @@ var _ = Describe("AwsClient", func() {
 			Region:      aws.String(region),
 		})
 
-		awsClient = cliaasAWS.NewAWSClient(ec2Client, vpc, clock.NewClock())
+		awsClient = cliaasAWS.NewAWSClient(ec2Client, vpc, iaas.NewWaiter(clock.NewClock(), iaas.Timeouts{}))
 		Expect(awsClient).NotTo(BeNil())
 
 		name = randSeq(10)
@@ -113,9 +114,9 @@ var _ = Describe("AwsClient", func() {
 			})
 			Expect(createErr).NotTo(HaveOccurred())
 
-			client := cliaasAWS.NewAWSClient(ec2Client, vpc, clock.NewClock())
+			client := cliaasAWS.NewAWSClient(ec2Client, vpc, iaas.NewWaiter(clock.NewClock(), iaas.Timeouts{}))
 
-			err := client.WaitForStatus(instanceID, ec2.InstanceStateNameRunning)
+			err := client.WaitForStatus(instanceID, ec2.InstanceStateNameRunning, 5*time.Minute)
 			Expect(err).NotTo(HaveOccurred())
 		})
 
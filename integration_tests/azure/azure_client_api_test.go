@@ -67,8 +67,10 @@ var _ = Describe("Azure API Client", func() {
 		identifier = fmt.Sprintf("%s-vm", prefix)
 		testAzureClient.createVM(prefix, identifier, newImageURL, storageAccountName, containerName, &subnet)
 
-		var err error
-		azureClient, err = azure.NewClient(subscriptionID, clientID, clientSecret, tenantID, prefix, resourceManagerEndpoint)
+		environment, err := azure.NewEnvironment(azure.AzurePublicCloud, resourceManagerEndpoint, "")
+		Expect(err).ShouldNot(HaveOccurred())
+
+		azureClient, err = azure.NewClient(subscriptionID, clientID, clientSecret, tenantID, prefix, environment)
 		Expect(err).ShouldNot(HaveOccurred())
 	})
 
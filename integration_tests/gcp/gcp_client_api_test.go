@@ -179,7 +179,7 @@ var _ = Describe("GCPClientAPI", func() {
 			var finalDiskSizeGB = int64(11)
 
 			JustBeforeEach(func() {
-				err := gcpClientAPI.WaitForStatus(instanceNameGUID, InstanceRunning)
+				err := gcpClientAPI.WaitForStatus(instanceNameGUID, InstanceRunning, 5*time.Minute)
 				Expect(err).ShouldNot(HaveOccurred())
 
 				err = gcpClientAPI.Replace(instanceNameGUID, "ops-manager-us/pcf-gcp-2.0-build.255.tar.gz", finalDiskSizeGB)
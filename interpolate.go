@@ -0,0 +1,203 @@
+package cliaas
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+
+	errwrap "github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const fileReferencePrefix = "file://"
+
+var placeholderRegexp = regexp.MustCompile(`\(\(\s*([^()\s]+)\s*\)\)|\$\{([^{}\s]+)\}`)
+
+// Interpolator resolves ((name)) and ${NAME} placeholders in a yaml document
+// from Vars, then from LookupEnv. Placeholders naming a file:// reference, and
+// values that are themselves a file:// reference, are replaced with the
+// contents of that file.
+type Interpolator struct {
+	Vars      map[string]interface{}
+	LookupEnv func(string) (string, bool)
+	Strict    bool
+}
+
+// Interpolate returns the resolved document together with every resolved
+// value, so callers can keep them out of their output
+func (i Interpolator) Interpolate(contents []byte) ([]byte, []string, error) {
+	var document interface{}
+	err := yaml.Unmarshal(contents, &document)
+	if err != nil {
+		return nil, nil, errwrap.Wrap(err, "failed to parse config for interpolation")
+	}
+
+	state := &interpolation{
+		interpolator: i,
+		unresolved:   map[string]bool{},
+	}
+
+	document, err = state.walk(document)
+	if err != nil {
+		return nil, state.secrets, err
+	}
+
+	if i.Strict && len(state.unresolved) > 0 {
+		var names []string
+		for name := range state.unresolved {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, state.secrets, fmt.Errorf("unresolved placeholders in config: %s", strings.Join(names, ", "))
+	}
+
+	interpolated, err := yaml.Marshal(document)
+	if err != nil {
+		return nil, state.secrets, errwrap.Wrap(err, "failed to render interpolated config")
+	}
+	return interpolated, state.secrets, nil
+}
+
+// LoadVarsFile reads a yaml map of values for ((name)) placeholders
+func LoadVarsFile(path string) (map[string]interface{}, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errwrap.Wrap(err, "failed to read vars file")
+	}
+
+	vars := map[string]interface{}{}
+	err = yaml.Unmarshal(contents, &vars)
+	if err != nil {
+		return nil, errwrap.Wrapf(err, "failed to parse vars file %s", path)
+	}
+	return vars, nil
+}
+
+type interpolation struct {
+	interpolator Interpolator
+	unresolved   map[string]bool
+	secrets      []string
+}
+
+func (s *interpolation) walk(node interface{}) (interface{}, error) {
+	var err error
+	switch typed := node.(type) {
+	case map[interface{}]interface{}:
+		for key, value := range typed {
+			typed[key], err = s.walk(value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return typed, nil
+	case []interface{}:
+		for idx, value := range typed {
+			typed[idx], err = s.walk(value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return typed, nil
+	case string:
+		return s.interpolateString(typed)
+	default:
+		return node, nil
+	}
+}
+
+func (s *interpolation) interpolateString(value string) (interface{}, error) {
+	if strings.HasPrefix(value, fileReferencePrefix) {
+		return s.resolve(value)
+	}
+
+	matches := placeholderRegexp.FindAllStringSubmatchIndex(value, -1)
+	if len(matches) == 0 {
+		return value, nil
+	}
+
+	// a value that is nothing but a placeholder keeps the type of what it
+	// resolves to, so lists and numbers can come from vars files
+	if len(matches) == 1 && matches[0][0] == 0 && matches[0][1] == len(value) {
+		name := placeholderName(value, matches[0])
+		resolved, err := s.resolve(name)
+		if err != nil || resolved == nil {
+			return value, err
+		}
+		return resolved, nil
+	}
+
+	var result string
+	last := 0
+	for _, match := range matches {
+		result += value[last:match[0]]
+		resolved, err := s.resolve(placeholderName(value, match))
+		if err != nil {
+			return nil, err
+		}
+		if resolved == nil {
+			result += value[match[0]:match[1]]
+		} else {
+			result += fmt.Sprint(resolved)
+		}
+		last = match[1]
+	}
+	return result + value[last:], nil
+}
+
+func (s *interpolation) resolve(name string) (interface{}, error) {
+	if strings.HasPrefix(name, fileReferencePrefix) {
+		contents, err := ioutil.ReadFile(strings.TrimPrefix(name, fileReferencePrefix))
+		if err != nil {
+			return nil, errwrap.Wrapf(err, "failed to resolve %s", name)
+		}
+		return s.secret(strings.TrimRight(string(contents), "\r\n")), nil
+	}
+
+	if value, ok := s.interpolator.Vars[name]; ok {
+		s.scalarSecrets(value)
+		return value, nil
+	}
+
+	if s.interpolator.LookupEnv != nil {
+		if value, ok := s.interpolator.LookupEnv(name); ok {
+			return s.secret(value), nil
+		}
+	}
+
+	s.unresolved[name] = true
+	return nil, nil
+}
+
+func (s *interpolation) secret(value string) string {
+	if value != "" {
+		s.secrets = append(s.secrets, value)
+	}
+	return value
+}
+
+// scalarSecrets keeps every string of a vars value as a secret, including
+// those in lists and maps. Numbers and booleans are left alone, as redacting
+// e.g. every 443 or true would garble the output.
+func (s *interpolation) scalarSecrets(value interface{}) {
+	switch typed := value.(type) {
+	case string:
+		s.secret(typed)
+	case map[interface{}]interface{}:
+		for _, item := range typed {
+			s.scalarSecrets(item)
+		}
+	case []interface{}:
+		for _, item := range typed {
+			s.scalarSecrets(item)
+		}
+	}
+}
+
+func placeholderName(value string, match []int) string {
+	if match[2] >= 0 {
+		return value[match[2]:match[3]]
+	}
+	return value[match[4]:match[5]]
+}
@@ -0,0 +1,165 @@
+package cliaas_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/pivotal-cf/cliaas"
+)
+
+var _ = Describe("Interpolator", func() {
+	var (
+		interpolator cliaas.Interpolator
+		env          map[string]string
+		tmpDir       string
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "interpolate")
+		Expect(err).NotTo(HaveOccurred())
+
+		env = map[string]string{}
+		interpolator = cliaas.Interpolator{
+			Vars: map[string]interface{}{},
+			LookupEnv: func(name string) (string, bool) {
+				value, ok := env[name]
+				return value, ok
+			},
+		}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	interpolate := func(document string) (map[string]interface{}, []string, error) {
+		contents, secrets, err := interpolator.Interpolate([]byte(document))
+		if err != nil {
+			return nil, secrets, err
+		}
+
+		result := map[string]interface{}{}
+		Expect(yaml.Unmarshal(contents, &result)).To(Succeed())
+		return result, secrets, nil
+	}
+
+	It("resolves ((placeholders)) from vars before the environment", func() {
+		interpolator.Vars["secret"] = "from-vars"
+		env["secret"] = "from-env"
+
+		result, secrets, err := interpolate(`key: ((secret))`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result["key"]).To(Equal("from-vars"))
+		Expect(secrets).To(ConsistOf("from-vars"))
+	})
+
+	It("resolves ${PLACEHOLDERS} from the environment", func() {
+		env["SECRET_KEY"] = "from-env"
+
+		result, _, err := interpolate(`key: prefix-${SECRET_KEY}-suffix`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result["key"]).To(Equal("prefix-from-env-suffix"))
+	})
+
+	It("keeps the type of vars that make up a whole value", func() {
+		interpolator.Vars["delegates"] = []interface{}{"a@b", "c@d"}
+
+		result, _, err := interpolate(`key: ((delegates))`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result["key"]).To(Equal([]interface{}{"a@b", "c@d"}))
+	})
+
+	It("keeps the strings of vars as secrets, but not numbers and booleans", func() {
+		interpolator.Vars["port"] = 8443
+		interpolator.Vars["enabled"] = true
+		interpolator.Vars["pins"] = []interface{}{12345, "some-pin"}
+
+		_, secrets, err := interpolate("port: ((port))\nenabled: ((enabled))\npins: ((pins))")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(secrets).To(ConsistOf("some-pin"))
+	})
+
+	It("keeps multi-line values intact", func() {
+		env["CREDS"] = "{\n  \"type\": \"service_account\"\n}"
+
+		result, _, err := interpolate("gcp:\n  credentials_json: ${CREDS}\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result["gcp"]).To(HaveKeyWithValue("credentials_json", env["CREDS"]))
+	})
+
+	Context("with file:// references", func() {
+		var secretPath string
+
+		BeforeEach(func() {
+			secretPath = filepath.Join(tmpDir, "secret")
+			Expect(ioutil.WriteFile(secretPath, []byte("from-file\n"), 0600)).To(Succeed())
+		})
+
+		It("replaces a reference value with the file contents", func() {
+			result, secrets, err := interpolate(`key: file://` + secretPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result["key"]).To(Equal("from-file"))
+			Expect(secrets).To(ConsistOf("from-file"))
+		})
+
+		It("replaces a reference placeholder with the file contents", func() {
+			result, _, err := interpolate(`key: ((file://` + secretPath + `))`)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result["key"]).To(Equal("from-file"))
+		})
+
+		It("errors when the file cannot be read", func() {
+			_, _, err := interpolate(`key: file://` + filepath.Join(tmpDir, "missing"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when a placeholder cannot be resolved", func() {
+		It("leaves it in place", func() {
+			result, _, err := interpolate(`key: ((missing))`)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result["key"]).To(Equal("((missing))"))
+		})
+
+		It("errors in strict mode naming every unresolved placeholder", func() {
+			interpolator.Strict = true
+
+			_, _, err := interpolate("a: ((missing))\nb: ${ALSO_MISSING}")
+			Expect(err).To(MatchError("unresolved placeholders in config: ALSO_MISSING, missing"))
+		})
+	})
+
+	Describe("LoadVarsFile", func() {
+		It("reads a yaml map of vars", func() {
+			varsPath := filepath.Join(tmpDir, "vars.yml")
+			Expect(ioutil.WriteFile(varsPath, []byte("secret: value\n"), 0600)).To(Succeed())
+
+			vars, err := cliaas.LoadVarsFile(varsPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(vars).To(Equal(map[string]interface{}{"secret": "value"}))
+		})
+	})
+})
+
+var _ = Describe("Redactor", func() {
+	It("replaces every known secret", func() {
+		redactor := cliaas.NewRedactor("super-secret", "super-secret-longer")
+		Expect(redactor.Redact("a super-secret-longer and a super-secret")).To(Equal("a [REDACTED] and a [REDACTED]"))
+	})
+
+	It("ignores values too short to redact safely", func() {
+		redactor := cliaas.NewRedactor("a")
+		Expect(redactor.Redact("a value")).To(Equal("a value"))
+	})
+
+	It("is safe to use when nil", func() {
+		var redactor *cliaas.Redactor
+		Expect(redactor.Redact("a value")).To(Equal("a value"))
+	})
+})
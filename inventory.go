@@ -0,0 +1,189 @@
+package cliaas
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	errwrap "github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Results of one replace of a batch
+const (
+	ReplaceSucceeded = "succeeded"
+	ReplaceFailed    = "failed"
+	ReplaceSkipped   = "skipped"
+)
+
+// Inventory lists the VMs a replace-many upgrades
+type Inventory struct {
+	Replaces []InventoryEntry `yaml:"replaces"`
+}
+
+// InventoryEntry is one VM to replace. Config is a config file path relative
+// to the inventory, and the config file given with --config when empty.
+type InventoryEntry struct {
+	Name       string `yaml:"name" json:"name"`
+	Config     string `yaml:"config" json:"config,omitempty"`
+	Target     string `yaml:"target" json:"target,omitempty"`
+	Identifier string `yaml:"identifier" json:"identifier"`
+	Image      string `yaml:"image" json:"image,omitempty"`
+	DiskSizeGB int64  `yaml:"disk_size_gb" json:"disk_size_gb"`
+	Canary     bool   `yaml:"canary" json:"canary,omitempty"`
+}
+
+// LoadInventory reads an inventory, names its entries after their target and
+// identifier when they have no name, and resolves their config paths
+func LoadInventory(path string) (*Inventory, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inventory: %s", err)
+	}
+
+	var inventory Inventory
+	err = yaml.Unmarshal(contents, &inventory)
+	if err != nil {
+		return nil, errwrap.Wrap(err, "failed to unmarshal inventory")
+	}
+
+	if len(inventory.Replaces) == 0 {
+		return nil, fmt.Errorf("inventory %s lists no replaces", path)
+	}
+
+	names := map[string]bool{}
+	for i := range inventory.Replaces {
+		entry := &inventory.Replaces[i]
+		if entry.Identifier == "" {
+			return nil, fmt.Errorf("replace %d of the inventory is missing identifier", i+1)
+		}
+
+		if entry.Name == "" {
+			entry.Name = entry.Identifier
+			if entry.Target != "" {
+				entry.Name = entry.Target + "/" + entry.Identifier
+			}
+		}
+		if names[entry.Name] {
+			return nil, fmt.Errorf("inventory lists %s more than once", entry.Name)
+		}
+		names[entry.Name] = true
+
+		if entry.Config != "" && !filepath.IsAbs(entry.Config) {
+			entry.Config = filepath.Join(filepath.Dir(path), entry.Config)
+		}
+		if entry.DiskSizeGB == 0 {
+			entry.DiskSizeGB = 100
+		}
+	}
+
+	return &inventory, nil
+}
+
+// ReplaceResult is how one replace of a batch went
+type ReplaceResult struct {
+	Entry    InventoryEntry
+	Status   string
+	Err      error
+	Duration time.Duration
+}
+
+// ReplaceBatch runs the replaces of an inventory, canaries first. Up to
+// Parallelism replaces run at once. No more replaces are started once
+// MaxFailures have failed, or once any canary has failed.
+type ReplaceBatch struct {
+	Parallelism int
+	MaxFailures int
+	Clock       clock.Clock
+	Replace     func(InventoryEntry) error
+}
+
+// Run returns the result of every entry in the order they were started, with
+// the entries that were never started last as skipped
+func (b ReplaceBatch) Run(entries []InventoryEntry) []ReplaceResult {
+	var canaries, others []InventoryEntry
+	for _, entry := range entries {
+		if entry.Canary {
+			canaries = append(canaries, entry)
+		} else {
+			others = append(others, entry)
+		}
+	}
+
+	var results []ReplaceResult
+	for _, entry := range append(canaries, others...) {
+		results = append(results, ReplaceResult{Entry: entry, Status: ReplaceSkipped})
+	}
+
+	state := &batchState{maxFailures: b.MaxFailures}
+	if state.maxFailures < 1 {
+		state.maxFailures = 1
+	}
+
+	b.runWave(results[:len(canaries)], state)
+	if state.failures > 0 {
+		return results
+	}
+	b.runWave(results[len(canaries):], state)
+	return results
+}
+
+type batchState struct {
+	mutex       sync.Mutex
+	failures    int
+	maxFailures int
+}
+
+func (s *batchState) fail() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.failures++
+}
+
+func (s *batchState) halted() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.failures >= s.maxFailures
+}
+
+func (b ReplaceBatch) runWave(results []ReplaceResult, state *batchState) {
+	parallelism := b.Parallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	c := b.Clock
+	if c == nil {
+		c = clock.NewClock()
+	}
+
+	slots := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i := range results {
+		slots <- struct{}{}
+		if state.halted() {
+			<-slots
+			break
+		}
+
+		wg.Add(1)
+		go func(result *ReplaceResult) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			start := c.Now()
+			err := b.Replace(result.Entry)
+			result.Duration = c.Since(start)
+			if err != nil {
+				result.Status = ReplaceFailed
+				result.Err = err
+				state.fail()
+				return
+			}
+			result.Status = ReplaceSucceeded
+		}(&results[i])
+	}
+	wg.Wait()
+}
@@ -0,0 +1,168 @@
+package cliaas_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/cliaas"
+)
+
+var _ = Describe("Inventory", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "inventory")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	write := func(contents string) string {
+		path := filepath.Join(dir, "inventory.yml")
+		Expect(ioutil.WriteFile(path, []byte(contents), 0644)).To(Succeed())
+		return path
+	}
+
+	It("names entries and resolves their config next to the inventory", func() {
+		inventory, err := cliaas.LoadInventory(write(`
+replaces:
+- target: sandbox
+  identifier: ops-manager
+  config: configs/sandbox.yml
+  canary: true
+- name: prod
+  identifier: ops-manager
+  image: ami-019e4617
+  disk_size_gb: 150
+`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inventory.Replaces).To(Equal([]cliaas.InventoryEntry{
+			{Name: "sandbox/ops-manager", Config: filepath.Join(dir, "configs/sandbox.yml"), Target: "sandbox", Identifier: "ops-manager", DiskSizeGB: 100, Canary: true},
+			{Name: "prod", Identifier: "ops-manager", Image: "ami-019e4617", DiskSizeGB: 150},
+		}))
+	})
+
+	It("rejects entries without an identifier or with the same name", func() {
+		_, err := cliaas.LoadInventory(write("replaces:\n- target: sandbox\n"))
+		Expect(err).To(MatchError("replace 1 of the inventory is missing identifier"))
+
+		_, err = cliaas.LoadInventory(write("replaces:\n- identifier: ops-manager\n- identifier: ops-manager\n"))
+		Expect(err).To(MatchError("inventory lists ops-manager more than once"))
+	})
+})
+
+var _ = Describe("ReplaceBatch", func() {
+	var (
+		entries  []cliaas.InventoryEntry
+		failing  map[string]bool
+		replaced []string
+		mutex    sync.Mutex
+		batch    cliaas.ReplaceBatch
+	)
+
+	statuses := func(results []cliaas.ReplaceResult) map[string]string {
+		s := map[string]string{}
+		for _, result := range results {
+			s[result.Entry.Name] = result.Status
+		}
+		return s
+	}
+
+	BeforeEach(func() {
+		entries = []cliaas.InventoryEntry{
+			{Name: "prod"},
+			{Name: "staging"},
+			{Name: "sandbox", Canary: true},
+		}
+		failing = map[string]bool{}
+		replaced = nil
+		batch = cliaas.ReplaceBatch{
+			Replace: func(entry cliaas.InventoryEntry) error {
+				mutex.Lock()
+				defer mutex.Unlock()
+				replaced = append(replaced, entry.Name)
+				if failing[entry.Name] {
+					return errors.New("replace failed")
+				}
+				return nil
+			},
+		}
+	})
+
+	It("replaces the canaries first", func() {
+		results := batch.Run(entries)
+		Expect(replaced).To(Equal([]string{"sandbox", "prod", "staging"}))
+		Expect(results[0].Entry.Name).To(Equal("sandbox"))
+		Expect(statuses(results)).To(Equal(map[string]string{
+			"sandbox": cliaas.ReplaceSucceeded,
+			"prod":    cliaas.ReplaceSucceeded,
+			"staging": cliaas.ReplaceSucceeded,
+		}))
+	})
+
+	It("stops when a canary fails", func() {
+		failing["sandbox"] = true
+		batch.MaxFailures = 5
+
+		results := batch.Run(entries)
+		Expect(replaced).To(Equal([]string{"sandbox"}))
+		Expect(results[0].Err).To(MatchError("replace failed"))
+		Expect(statuses(results)).To(Equal(map[string]string{
+			"sandbox": cliaas.ReplaceFailed,
+			"prod":    cliaas.ReplaceSkipped,
+			"staging": cliaas.ReplaceSkipped,
+		}))
+	})
+
+	It("stops at the first failure by default", func() {
+		failing["prod"] = true
+
+		results := batch.Run(entries)
+		Expect(replaced).To(Equal([]string{"sandbox", "prod"}))
+		Expect(statuses(results)["staging"]).To(Equal(cliaas.ReplaceSkipped))
+	})
+
+	It("carries on until the failure threshold", func() {
+		failing["prod"] = true
+		batch.MaxFailures = 2
+
+		results := batch.Run(entries)
+		Expect(replaced).To(Equal([]string{"sandbox", "prod", "staging"}))
+		Expect(statuses(results)["staging"]).To(Equal(cliaas.ReplaceSucceeded))
+	})
+
+	It("runs up to the parallelism at once", func() {
+		var running, most int
+		batch.Parallelism = 2
+		batch.Replace = func(entry cliaas.InventoryEntry) error {
+			mutex.Lock()
+			running++
+			if running > most {
+				most = running
+			}
+			mutex.Unlock()
+
+			time.Sleep(50 * time.Millisecond)
+
+			mutex.Lock()
+			running--
+			mutex.Unlock()
+			return nil
+		}
+		entries = []cliaas.InventoryEntry{{Name: "prod"}, {Name: "staging"}, {Name: "dev"}, {Name: "test"}}
+
+		results := batch.Run(entries)
+		Expect(results).To(HaveLen(4))
+		Expect(most).To(Equal(2))
+	})
+})
@@ -0,0 +1,355 @@
+package opsman
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pivotal-cf/cliaas/iaas"
+	errwrap "github.com/pkg/errors"
+)
+
+// Steps an upgrade reports on top of the steps of the replace
+const (
+	StepExportingInstallation = "exporting-installation"
+	StepExportedInstallation  = "exported-installation"
+	StepWaitingForOpsManager  = "waiting-for-opsman"
+	StepImportingInstallation = "importing-installation"
+	StepImportedInstallation  = "imported-installation"
+)
+
+// DefaultClientID is the uaa client Ops Manager sets up for its users
+const DefaultClientID = "opsman"
+
+const installationAssetsPath = "/api/v0/installation_asset_collection"
+
+// DefaultRequestTimeout bounds a request to Ops Manager when the config sets
+// no timeout. It is long, as the export and import stream the installation.
+const DefaultRequestTimeout = time.Hour
+
+// availabilityRequestTimeout bounds one poll of the availability page, so that
+// a hung poll does not outlast the wait for Ops Manager
+const availabilityRequestTimeout = 30 * time.Second
+
+// Config says how to reach and log in to Ops Manager. Either a username and
+// password, or a client id and secret, are needed to export; the import runs
+// before authentication is set up and needs neither.
+type Config struct {
+	URL               string
+	Username          string
+	Password          string
+	ClientID          string
+	ClientSecret      string
+	SkipSSLValidation bool
+	// RequestTimeout bounds each request, including the export download and
+	// the import upload. DefaultRequestTimeout is used when it is not set.
+	RequestTimeout time.Duration
+}
+
+// Client exports the installation from one Ops Manager and imports it into
+// its replacement at the same url
+type Client struct {
+	config     Config
+	httpClient *http.Client
+	waiter     iaas.Waiter
+	progress   iaas.ProgressReporter
+	token      string
+}
+
+func NewClient(config Config, waiter iaas.Waiter, progress iaas.ProgressReporter) *Client {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: config.SkipSSLValidation,
+		},
+	}
+
+	timeout := config.RequestTimeout
+	if timeout <= 0 {
+		timeout = DefaultRequestTimeout
+	}
+
+	return &Client{
+		config: config,
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   timeout,
+			// the availability checks look at the redirects themselves
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		waiter:   waiter,
+		progress: progress,
+	}
+}
+
+// Upgrade exports the installation while the old Ops Manager is running,
+// calls replace, and imports the installation once the new Ops Manager is
+// ready for it. The export is kept at installationPath.
+func (c *Client) Upgrade(installationPath string, passphrase string, replace func() error) error {
+	err := c.ExportInstallation(installationPath)
+	if err != nil {
+		return err
+	}
+
+	err = replace()
+	if err != nil {
+		return err
+	}
+
+	err = c.WaitUntilReadyForImport()
+	if err != nil {
+		return err
+	}
+
+	err = c.ImportInstallation(installationPath, passphrase)
+	if err != nil {
+		return err
+	}
+
+	return c.WaitUntilAuthenticationIsUp()
+}
+
+// Authenticate gets a token from Ops Manager's uaa, with the password grant
+// when a username is configured and the client credentials grant otherwise
+func (c *Client) Authenticate() error {
+	clientID := c.config.ClientID
+	if clientID == "" {
+		clientID = DefaultClientID
+	}
+
+	form := url.Values{}
+	if c.config.Username != "" {
+		form.Set("grant_type", "password")
+		form.Set("username", c.config.Username)
+		form.Set("password", c.config.Password)
+	} else {
+		form.Set("grant_type", "client_credentials")
+	}
+
+	request, err := http.NewRequest("POST", c.url("/uaa/oauth/token"), strings.NewReader(form.Encode()))
+	if err != nil {
+		return errwrap.Wrap(err, "failed building token request")
+	}
+	request.SetBasicAuth(clientID, c.config.ClientSecret)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return errwrap.Wrap(err, "failed requesting a token from ops manager")
+	}
+	defer response.Body.Close()
+
+	err = checkResponse(response, "requesting a token")
+	if err != nil {
+		return err
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	err = json.NewDecoder(response.Body).Decode(&token)
+	if err != nil {
+		return errwrap.Wrap(err, "failed decoding the token response")
+	}
+	if token.AccessToken == "" {
+		return fmt.Errorf("ops manager gave no access token")
+	}
+
+	c.token = token.AccessToken
+	return nil
+}
+
+// ExportInstallation streams the installation to path. The download goes to
+// a temporary file next to it, so that an interrupted export never leaves a
+// partial installation at path.
+func (c *Client) ExportInstallation(path string) error {
+	if c.token == "" {
+		err := c.Authenticate()
+		if err != nil {
+			return err
+		}
+	}
+
+	request, err := http.NewRequest("GET", c.url(installationAssetsPath), nil)
+	if err != nil {
+		return errwrap.Wrap(err, "failed building export request")
+	}
+	request.Header.Set("Authorization", "Bearer "+c.token)
+
+	iaas.ReportStep(c.progress, StepExportingInstallation, path)
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return errwrap.Wrap(err, "failed exporting the installation")
+	}
+	defer response.Body.Close()
+
+	err = checkResponse(response, "exporting the installation")
+	if err != nil {
+		return err
+	}
+
+	partPath := path + ".part"
+	file, err := os.Create(partPath)
+	if err != nil {
+		return errwrap.Wrap(err, "failed creating the installation file")
+	}
+
+	progress := &progressWriter{
+		progress: c.progress,
+		step:     StepExportingInstallation,
+		subject:  path,
+		total:    response.ContentLength,
+	}
+	_, err = io.Copy(io.MultiWriter(file, progress), response.Body)
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil && response.ContentLength >= 0 && progress.written != response.ContentLength {
+		err = fmt.Errorf("got %d of %d bytes", progress.written, response.ContentLength)
+	}
+	if err != nil {
+		os.Remove(partPath)
+		return errwrap.Wrap(err, "failed downloading the installation")
+	}
+
+	err = os.Rename(partPath, path)
+	if err != nil {
+		return errwrap.Wrap(err, "failed saving the installation file")
+	}
+	iaas.ReportStep(c.progress, StepExportedInstallation, path)
+	return nil
+}
+
+// ImportInstallation streams the installation at path into an Ops Manager
+// that has not been set up yet, with the passphrase that decrypts it
+func (c *Client) ImportInstallation(path string, passphrase string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return errwrap.Wrap(err, "failed opening the installation file")
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return errwrap.Wrap(err, "failed reading the installation file")
+	}
+
+	// the multipart body is built around the file rather than in memory, so
+	// its length is known without reading the file
+	var head, tail bytes.Buffer
+	form := multipart.NewWriter(&head)
+	err = form.WriteField("passphrase", passphrase)
+	if err != nil {
+		return errwrap.Wrap(err, "failed building import request")
+	}
+	_, err = form.CreateFormFile("installation[file]", "installation.zip")
+	if err != nil {
+		return errwrap.Wrap(err, "failed building import request")
+	}
+	fmt.Fprintf(&tail, "\r\n--%s--\r\n", form.Boundary())
+
+	body := io.MultiReader(&head, &progressReader{
+		reader: file,
+		progress: progressWriter{
+			progress: c.progress,
+			step:     StepImportingInstallation,
+			subject:  path,
+			total:    info.Size(),
+		},
+	}, &tail)
+
+	request, err := http.NewRequest("POST", c.url(installationAssetsPath), body)
+	if err != nil {
+		return errwrap.Wrap(err, "failed building import request")
+	}
+	request.ContentLength = int64(head.Len()) + info.Size() + int64(tail.Len())
+	request.Header.Set("Content-Type", form.FormDataContentType())
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return errwrap.Wrap(err, "failed importing the installation")
+	}
+	defer response.Body.Close()
+
+	err = checkResponse(response, "importing the installation")
+	if err != nil {
+		return err
+	}
+	iaas.ReportStep(c.progress, StepImportedInstallation, path)
+	return nil
+}
+
+// WaitUntilReadyForImport waits for a new Ops Manager to answer and send its
+// users to the setup page, which is when it accepts an import
+func (c *Client) WaitUntilReadyForImport() error {
+	return c.waitForAvailability("waiting for ops manager to be ready for the import", func(location string) bool {
+		return strings.HasSuffix(location, "/setup")
+	})
+}
+
+// WaitUntilAuthenticationIsUp waits for Ops Manager to start its
+// authentication system after an import, which is when it can be logged in to
+func (c *Client) WaitUntilAuthenticationIsUp() error {
+	return c.waitForAvailability("waiting for ops manager authentication to start", func(location string) bool {
+		return !strings.HasSuffix(location, "/setup")
+	})
+}
+
+// waitForAvailability polls the availability page, which redirects once Ops
+// Manager is up and shows a waiting page while its authentication starts
+func (c *Client) waitForAvailability(description string, ready func(location string) bool) error {
+	iaas.ReportStep(c.progress, StepWaitingForOpsManager, c.config.URL)
+	return c.waiter.Wait(description, c.waiter.Timeouts.OpsManAvailability, func() (bool, error) {
+		request, err := http.NewRequest("GET", c.url("/login/ensure_availability"), nil)
+		if err != nil {
+			return false, errwrap.Wrap(err, "failed building availability request")
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), availabilityRequestTimeout)
+		defer cancel()
+
+		response, err := c.httpClient.Do(request.WithContext(ctx))
+		if err != nil {
+			// the new vm refuses connections until ops manager has started
+			return false, nil
+		}
+		io.Copy(ioutil.Discard, response.Body)
+		response.Body.Close()
+
+		if response.StatusCode != http.StatusFound {
+			return false, nil
+		}
+		return ready(response.Header.Get("Location")), nil
+	})
+}
+
+func (c *Client) url(path string) string {
+	return strings.TrimRight(c.config.URL, "/") + path
+}
+
+// checkResponse turns an unsuccessful response into an error carrying the
+// start of its body, classified by its status code
+func checkResponse(response *http.Response, action string) error {
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return nil
+	}
+
+	body, _ := ioutil.ReadAll(io.LimitReader(response.Body, 1024))
+	return &iaas.ClassifiedError{
+		Class: iaas.ClassifyStatusCode(response.StatusCode),
+		Err:   fmt.Errorf("ops manager failed %s: %s: %s", action, response.Status, strings.TrimSpace(string(body))),
+	}
+}
@@ -0,0 +1,226 @@
+package opsman_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/cliaas/iaas"
+	"github.com/pivotal-cf/cliaas/iaas/iaasfakes"
+	"github.com/pivotal-cf/cliaas/opsman"
+)
+
+const installation = "some-installation-zip-contents"
+
+// fakeOpsManager plays the part of the old Ops Manager until replaced, and of
+// a new one that is booting, then waiting for an import, then starting its
+// authentication system
+type fakeOpsManager struct {
+	mutex      sync.Mutex
+	replaced   bool
+	bootPolls  int
+	imported   string
+	passphrase string
+}
+
+func (f *fakeOpsManager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	switch {
+	case r.URL.Path == "/uaa/oauth/token":
+		username, _, _ := r.BasicAuth()
+		r.ParseForm()
+		if username != "opsman" || r.Form.Get("password") != "some-password" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"access_token":"some-token"}`))
+	case r.URL.Path == "/api/v0/installation_asset_collection" && r.Method == "GET":
+		if r.Header.Get("Authorization") != "Bearer some-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(installation))
+	case r.URL.Path == "/api/v0/installation_asset_collection" && r.Method == "POST":
+		file, _, err := r.FormFile("installation[file]")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		contents, _ := ioutil.ReadAll(file)
+		f.imported = string(contents)
+		f.passphrase = r.FormValue("passphrase")
+	case r.URL.Path == "/login/ensure_availability":
+		switch {
+		case !f.replaced:
+			w.Header().Set("Location", "/auth/cloudfoundry")
+			w.WriteHeader(http.StatusFound)
+		case f.bootPolls < 2:
+			f.bootPolls++
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case f.imported == "":
+			w.Header().Set("Location", "/setup")
+			w.WriteHeader(http.StatusFound)
+		default:
+			w.Header().Set("Location", "/auth/cloudfoundry")
+			w.WriteHeader(http.StatusFound)
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+var _ = Describe("Client", func() {
+	var (
+		opsManager       *fakeOpsManager
+		server           *httptest.Server
+		client           *opsman.Client
+		config           opsman.Config
+		fakeProgress     *iaasfakes.FakeProgressReporter
+		dir              string
+		installationPath string
+	)
+
+	BeforeEach(func() {
+		opsManager = new(fakeOpsManager)
+		server = httptest.NewTLSServer(opsManager)
+		fakeProgress = new(iaasfakes.FakeProgressReporter)
+
+		var err error
+		dir, err = ioutil.TempDir("", "opsman")
+		Expect(err).NotTo(HaveOccurred())
+		installationPath = filepath.Join(dir, "installation.zip")
+
+		config = opsman.Config{
+			URL:               server.URL,
+			Username:          "admin",
+			Password:          "some-password",
+			SkipSSLValidation: true,
+		}
+	})
+
+	JustBeforeEach(func() {
+		waiter := iaas.NewWaiter(clock.NewClock(), iaas.Timeouts{
+			OpsManAvailability: time.Second,
+			PollInterval:       time.Millisecond,
+			MaxPollInterval:    time.Millisecond,
+		})
+		client = opsman.NewClient(config, waiter, fakeProgress)
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(dir)
+	})
+
+	Describe("Upgrade", func() {
+		It("exports before the replace and imports once the new ops manager is ready", func() {
+			err := client.Upgrade(installationPath, "some-passphrase", func() error {
+				contents, err := ioutil.ReadFile(installationPath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(contents)).To(Equal(installation))
+
+				opsManager.mutex.Lock()
+				opsManager.replaced = true
+				opsManager.mutex.Unlock()
+				return nil
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(opsManager.imported).To(Equal(installation))
+			Expect(opsManager.passphrase).To(Equal("some-passphrase"))
+
+			var steps []string
+			for i := 0; i < fakeProgress.ReportCallCount(); i++ {
+				if step := fakeProgress.ReportArgsForCall(i).Step; len(steps) == 0 || steps[len(steps)-1] != step {
+					steps = append(steps, step)
+				}
+			}
+			Expect(steps).To(Equal([]string{
+				opsman.StepExportingInstallation,
+				opsman.StepExportedInstallation,
+				opsman.StepWaitingForOpsManager,
+				opsman.StepImportingInstallation,
+				opsman.StepImportedInstallation,
+				opsman.StepWaitingForOpsManager,
+			}))
+		})
+
+		It("does not replace the vm when the export fails", func() {
+			var replaced bool
+			config.Password = "wrong-password"
+			client = opsman.NewClient(config, iaas.NewWaiter(clock.NewClock(), iaas.Timeouts{}), nil)
+
+			err := client.Upgrade(installationPath, "some-passphrase", func() error {
+				replaced = true
+				return nil
+			})
+			Expect(err).To(MatchError(ContainSubstring("401")))
+			Expect(iaas.ClassOf(err)).To(Equal(iaas.Fatal))
+			Expect(replaced).To(BeFalse())
+		})
+	})
+
+	Describe("ExportInstallation", func() {
+		It("does not leave a partial file behind when the download is cut short", func() {
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/uaa/oauth/token" {
+					w.Write([]byte(`{"access_token":"some-token"}`))
+					return
+				}
+				w.Header().Set("Content-Length", "4096")
+				w.Write([]byte(installation))
+			})
+
+			err := client.ExportInstallation(installationPath)
+			Expect(err).To(HaveOccurred())
+
+			files, _ := ioutil.ReadDir(dir)
+			Expect(files).To(BeEmpty())
+		})
+	})
+
+	It("gives up on a request that takes longer than the request timeout", func() {
+		config.RequestTimeout = 50 * time.Millisecond
+		client = opsman.NewClient(config, iaas.NewWaiter(clock.NewClock(), iaas.Timeouts{}), nil)
+		server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(300 * time.Millisecond)
+		})
+
+		err := client.Authenticate()
+		Expect(err).To(MatchError(ContainSubstring("failed requesting a token")))
+	})
+
+	Describe("WaitUntilReadyForImport", func() {
+		It("times out while ops manager is not up", func() {
+			opsManager.replaced = true
+			opsManager.bootPolls = -1000000
+
+			err := client.WaitUntilReadyForImport()
+			_, ok := err.(*iaas.TimeoutError)
+			Expect(ok).To(BeTrue())
+		})
+	})
+
+	It("reports import errors with the response body", func() {
+		Expect(ioutil.WriteFile(installationPath, []byte(installation), 0600)).To(Succeed())
+		server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"errors":["Decryption passphrase is incorrect"]}`))
+		})
+
+		err := client.ImportInstallation(installationPath, "wrong-passphrase")
+		Expect(err).To(MatchError(ContainSubstring("Decryption passphrase is incorrect")))
+		Expect(strings.Contains(err.Error(), "wrong-passphrase")).To(BeFalse())
+	})
+})
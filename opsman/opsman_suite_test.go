@@ -0,0 +1,13 @@
+package opsman_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestOpsman(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Opsman Suite")
+}
@@ -0,0 +1,57 @@
+package opsman
+
+import (
+	"io"
+
+	"github.com/pivotal-cf/cliaas/iaas"
+)
+
+// unknownTotalReportInterval is how many bytes pass between reports when the
+// size of the installation is not known
+const unknownTotalReportInterval = 64 << 20
+
+// progressWriter counts the bytes of a transfer and reports them once per
+// percent, so that a multi gigabyte installation doesn't flood the output
+type progressWriter struct {
+	progress iaas.ProgressReporter
+	step     string
+	subject  string
+	total    int64
+
+	written  int64
+	reported int64
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	w.written += int64(len(p))
+
+	interval := int64(unknownTotalReportInterval)
+	if w.total > 0 {
+		interval = w.total / 100
+	}
+	if w.written-w.reported >= interval || w.written == w.total {
+		w.reported = w.written
+		iaas.ReportProgress(w.progress, iaas.ProgressEvent{
+			Step:    w.step,
+			Subject: w.subject,
+			Current: w.written,
+			Total:   w.total,
+		})
+	}
+	return len(p), nil
+}
+
+// progressReader reports the bytes read through it
+type progressReader struct {
+	reader   io.Reader
+	progress progressWriter
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.progress.Write(p[:n])
+	return n, err
+}
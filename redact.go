@@ -0,0 +1,54 @@
+package cliaas
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+const Redacted = "[REDACTED]"
+
+// secrets shorter than this are too likely to match unrelated text
+const minRedactedLength = 4
+
+// Redactor replaces known secret values in text before it is shown to a user
+type Redactor struct {
+	mutex   sync.RWMutex
+	secrets []string
+}
+
+func NewRedactor(secrets ...string) *Redactor {
+	r := &Redactor{}
+	r.Add(secrets...)
+	return r
+}
+
+func (r *Redactor) Add(secrets ...string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, secret := range secrets {
+		if len(secret) >= minRedactedLength {
+			r.secrets = append(r.secrets, secret)
+		}
+	}
+
+	// longest first so a secret containing another is replaced whole
+	sort.Slice(r.secrets, func(i, j int) bool {
+		return len(r.secrets[i]) > len(r.secrets[j])
+	})
+}
+
+func (r *Redactor) Redact(s string) string {
+	if r == nil {
+		return s
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, secret := range r.secrets {
+		s = strings.Replace(s, secret, Redacted, -1)
+	}
+	return s
+}
@@ -0,0 +1,335 @@
+package resource
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"code.cloudfoundry.org/clock"
+	"github.com/pivotal-cf/cliaas"
+	"github.com/pivotal-cf/cliaas/iaas"
+	errwrap "github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// DefaultDiskSizeGB is the disk size of the new VM when put does not give one
+const DefaultDiskSizeGB = 100
+
+// Source is the IaaS config of a resource, in the same shape as the config
+// file, and the VM it follows
+type Source struct {
+	cliaas.MultiConfig `yaml:",inline"`
+
+	Identifier string `yaml:"identifier"`
+	LogLevel   string `yaml:"log_level"`
+}
+
+// Version identifies a VM by its IaaS id. The image it was created from and
+// the ops manager version found in it are there for display.
+type Version struct {
+	ID      string `json:"id"`
+	Image   string `json:"image,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+// OutParams are the params of a put, which replaces the VM
+type OutParams struct {
+	// Image overrides the image in the source
+	Image string `json:"image"`
+	// ImageFile is a file in the build, relative to the sources directory,
+	// holding the image to use
+	ImageFile       string `json:"image_file"`
+	DiskSizeGB      int64  `json:"disk_size_gb"`
+	MigrateDataDisk bool   `json:"migrate_data_disk"`
+}
+
+// MetadataField is a name and value shown next to a version in Concourse
+type MetadataField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Response is what in and out write to stdout
+type Response struct {
+	Version  Version         `json:"version"`
+	Metadata []MetadataField `json:"metadata"`
+}
+
+type request struct {
+	Source  json.RawMessage `json:"source"`
+	Version *Version        `json:"version"`
+	Params  json.RawMessage `json:"params"`
+}
+
+// Resource implements the check, in and out scripts of a Concourse resource
+// type that follows an Ops Manager VM
+type Resource struct {
+	// NewClient builds the client of the single complete config in the source
+	NewClient func(cliaas.Config) (cliaas.Client, error)
+	Stderr    io.Writer
+	Clock     clock.Clock
+}
+
+func New(stderr io.Writer) *Resource {
+	return &Resource{
+		NewClient: func(config cliaas.Config) (cliaas.Client, error) {
+			return config.NewClient()
+		},
+		Stderr: stderr,
+		Clock:  clock.NewClock(),
+	}
+}
+
+// Check emits the version of the VM that is running now
+func (r *Resource) Check(stdin io.Reader, stdout io.Writer) error {
+	var req request
+	source, redactor, err := r.readRequest(stdin, &req)
+	if err != nil {
+		return err
+	}
+
+	vm, err := r.getVM(source, redactor)
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(stdout).Encode([]Version{versionOf(vm)})
+}
+
+// In writes the id, name, image and version of the VM to files in dir. Only
+// the running VM can be fetched, so an older version fails.
+func (r *Resource) In(dir string, stdin io.Reader, stdout io.Writer) error {
+	var req request
+	source, redactor, err := r.readRequest(stdin, &req)
+	if err != nil {
+		return err
+	}
+
+	vm, err := r.getVM(source, redactor)
+	if err != nil {
+		return err
+	}
+
+	if req.Version != nil && req.Version.ID != "" && req.Version.ID != vm.ID {
+		return fmt.Errorf("vm %s has been replaced by %s", req.Version.ID, vm.ID)
+	}
+
+	version := versionOf(vm)
+	files := map[string]string{
+		"id":      vm.ID,
+		"name":    vm.Name,
+		"image":   vm.Image,
+		"version": version.Version,
+	}
+	for name, contents := range files {
+		err = ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644)
+		if err != nil {
+			return errwrap.Wrapf(err, "failed writing %s", name)
+		}
+	}
+
+	return json.NewEncoder(stdout).Encode(Response{
+		Version:  version,
+		Metadata: metadataOf(vm),
+	})
+}
+
+// Out replaces the VM, with the image from the params or the source, and
+// emits the version of the new VM. dir is the build's sources directory.
+func (r *Resource) Out(dir string, stdin io.Reader, stdout io.Writer) error {
+	var req request
+	source, redactor, err := r.readRequest(stdin, &req)
+	if err != nil {
+		return err
+	}
+
+	var params OutParams
+	if len(req.Params) > 0 {
+		err = json.Unmarshal(req.Params, &params)
+		if err != nil {
+			return errwrap.Wrap(err, "failed to parse params")
+		}
+	}
+
+	config, err := r.config(source, redactor)
+	if err != nil {
+		return err
+	}
+	config.Options().MigrateDataDisk = params.MigrateDataDisk
+
+	image := config.Image()
+	if params.Image != "" {
+		image = params.Image
+	}
+	if params.ImageFile != "" {
+		contents, err := ioutil.ReadFile(filepath.Join(dir, params.ImageFile))
+		if err != nil {
+			return errwrap.Wrap(err, "failed to read image_file")
+		}
+		image = strings.TrimSpace(string(contents))
+	}
+
+	diskSizeGB := params.DiskSizeGB
+	if diskSizeGB == 0 {
+		diskSizeGB = DefaultDiskSizeGB
+	}
+
+	client, err := r.NewClient(config)
+	if err != nil {
+		return redactError(err, redactor)
+	}
+
+	err = client.Replace(source.Identifier, image, diskSizeGB)
+	if err != nil {
+		return redactError(err, redactor)
+	}
+
+	vm, err := client.GetVM(source.Identifier)
+	if err != nil {
+		return redactError(err, redactor)
+	}
+
+	return json.NewEncoder(stdout).Encode(Response{
+		Version:  versionOf(vm),
+		Metadata: metadataOf(vm),
+	})
+}
+
+// readRequest decodes the request and its source. The source is read as yaml,
+// which json is a subset of, so that it takes the field names of the config
+// file. It is compacted first, as yaml does not allow indenting with tabs.
+func (r *Resource) readRequest(stdin io.Reader, req *request) (Source, *cliaas.Redactor, error) {
+	err := json.NewDecoder(stdin).Decode(req)
+	if err != nil {
+		return Source{}, nil, errwrap.Wrap(err, "failed to parse request")
+	}
+
+	var compacted bytes.Buffer
+	if len(req.Source) > 0 {
+		err = json.Compact(&compacted, req.Source)
+		if err != nil {
+			return Source{}, nil, errwrap.Wrap(err, "failed to parse source")
+		}
+	}
+
+	var source Source
+	err = yaml.Unmarshal(compacted.Bytes(), &source)
+	if err != nil {
+		return Source{}, nil, errwrap.Wrap(err, "failed to parse source")
+	}
+
+	if source.Identifier == "" {
+		return Source{}, nil, errors.New("source is missing identifier")
+	}
+
+	redactor := cliaas.NewRedactor()
+	for _, config := range source.Configs() {
+		redactor.Add(config.Secrets()...)
+	}
+	return source, redactor, nil
+}
+
+// config picks the single complete IaaS config in the source and points its
+// progress and logs at stderr, which Concourse shows in the build
+func (r *Resource) config(source Source, redactor *cliaas.Redactor) (cliaas.Config, error) {
+	configFile := cliaas.ConfigFile{MultiConfig: source.MultiConfig}
+	config, err := configFile.Target("")
+	if err != nil {
+		return nil, errwrap.Wrap(err, "invalid source")
+	}
+
+	level, err := iaas.ParseLogLevel(source.LogLevel)
+	if err != nil {
+		level = iaas.LogInfo
+	}
+
+	options := config.Options()
+	options.Progress = iaas.NewProgressReporter(iaas.ProgressText, r.Stderr, r.Clock, redactor.Redact)
+	options.Logger = &iaas.Logger{
+		Writer:     r.Stderr,
+		Level:      level,
+		Format:     iaas.LogFormatText,
+		Clock:      r.Clock,
+		Redact:     redactor.Redact,
+		AddSecrets: redactor.Add,
+	}
+	return config, nil
+}
+
+func (r *Resource) getVM(source Source, redactor *cliaas.Redactor) (iaas.VM, error) {
+	config, err := r.config(source, redactor)
+	if err != nil {
+		return iaas.VM{}, err
+	}
+
+	client, err := r.NewClient(config)
+	if err != nil {
+		return iaas.VM{}, redactError(err, redactor)
+	}
+
+	vm, err := client.GetVM(source.Identifier)
+	if err != nil {
+		return iaas.VM{}, redactError(err, redactor)
+	}
+	return vm, nil
+}
+
+func versionOf(vm iaas.VM) Version {
+	return Version{
+		ID:      vm.ID,
+		Image:   vm.Image,
+		Version: iaas.OpsManagerVersion(vm.Image),
+	}
+}
+
+func metadataOf(vm iaas.VM) []MetadataField {
+	return []MetadataField{
+		{Name: "name", Value: vm.Name},
+		{Name: "image", Value: vm.Image},
+	}
+}
+
+func redactError(err error, redactor *cliaas.Redactor) error {
+	return errors.New(redactor.Redact(err.Error()))
+}
+
+// Main runs the script named by the binary, or by its first argument when the
+// binary has another name, and returns the exit code
+func Main(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	command := filepath.Base(args[0])
+	args = args[1:]
+	if command != "check" && command != "in" && command != "out" && len(args) > 0 {
+		command = args[0]
+		args = args[1:]
+	}
+
+	r := New(stderr)
+	var err error
+	switch {
+	case command == "check":
+		err = r.Check(stdin, stdout)
+	case (command == "in" || command == "out") && len(args) == 0:
+		err = fmt.Errorf("usage: %s <directory>", command)
+	case command == "in":
+		err = os.MkdirAll(args[0], 0755)
+		if err == nil {
+			err = r.In(args[0], stdin, stdout)
+		}
+	case command == "out":
+		err = r.Out(args[0], stdin, stdout)
+	default:
+		err = fmt.Errorf("unknown command %q, expected check, in or out", command)
+	}
+
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %s\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,173 @@
+package resource_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/cliaas"
+	"github.com/pivotal-cf/cliaas/cliaasfakes"
+	"github.com/pivotal-cf/cliaas/iaas"
+	"github.com/pivotal-cf/cliaas/resource"
+)
+
+const source = `{
+	"identifier": "ops-manager",
+	"aws": {
+		"access_key_id": "some-access-key-id",
+		"secret_access_key": "some-secret-access-key",
+		"region": "us-east-1",
+		"vpc": "vpc-12345678",
+		"ami": "ami-019e4617"
+	}
+}`
+
+var _ = Describe("Resource", func() {
+	var (
+		r          *resource.Resource
+		fakeClient *cliaasfakes.FakeClient
+		config     cliaas.Config
+		stdout     *bytes.Buffer
+		stderr     *bytes.Buffer
+		dir        string
+	)
+
+	BeforeEach(func() {
+		fakeClient = new(cliaasfakes.FakeClient)
+		fakeClient.GetVMReturns(iaas.VM{
+			ID:    "i-1234",
+			Name:  "ops-manager-2017-03-01-12-00-00",
+			Image: "ops-manager-1.10.3",
+		}, nil)
+
+		stdout = new(bytes.Buffer)
+		stderr = new(bytes.Buffer)
+		r = resource.New(stderr)
+		r.NewClient = func(c cliaas.Config) (cliaas.Client, error) {
+			config = c
+			return fakeClient, nil
+		}
+
+		var err error
+		dir, err = ioutil.TempDir("", "resource")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	Describe("Check", func() {
+		It("emits the version of the running vm", func() {
+			err := r.Check(strings.NewReader(`{"source":`+source+`}`), stdout)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(stdout.String()).To(MatchJSON(`[{"id":"i-1234","image":"ops-manager-1.10.3","version":"1.10.3"}]`))
+			Expect(config.IaaS()).To(Equal("aws"))
+			Expect(fakeClient.GetVMArgsForCall(0)).To(Equal("ops-manager"))
+		})
+
+		It("fails without an identifier", func() {
+			err := r.Check(strings.NewReader(`{"source":{"aws":{}}}`), stdout)
+			Expect(err).To(MatchError("source is missing identifier"))
+		})
+
+		It("fails without a complete iaas config", func() {
+			err := r.Check(strings.NewReader(`{"source":{"identifier":"ops-manager","aws":{"region":"us-east-1"}}}`), stdout)
+			Expect(err).To(MatchError(ContainSubstring("zero iaas configurations")))
+		})
+
+		It("keeps secrets out of errors", func() {
+			fakeClient.GetVMReturns(iaas.VM{}, errors.New("bad key some-secret-access-key"))
+
+			err := r.Check(strings.NewReader(`{"source":`+source+`}`), stdout)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).NotTo(ContainSubstring("some-secret-access-key"))
+		})
+	})
+
+	Describe("In", func() {
+		It("writes the vm metadata to files", func() {
+			err := r.In(dir, strings.NewReader(`{"source":`+source+`,"version":{"id":"i-1234"}}`), stdout)
+			Expect(err).NotTo(HaveOccurred())
+
+			for name, expected := range map[string]string{
+				"id":      "i-1234",
+				"name":    "ops-manager-2017-03-01-12-00-00",
+				"image":   "ops-manager-1.10.3",
+				"version": "1.10.3",
+			} {
+				contents, err := ioutil.ReadFile(filepath.Join(dir, name))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(contents)).To(Equal(expected))
+			}
+
+			var response resource.Response
+			Expect(json.Unmarshal(stdout.Bytes(), &response)).To(Succeed())
+			Expect(response.Version.ID).To(Equal("i-1234"))
+			Expect(response.Metadata).To(ContainElement(resource.MetadataField{Name: "name", Value: "ops-manager-2017-03-01-12-00-00"}))
+		})
+
+		It("fails for a vm that has been replaced", func() {
+			err := r.In(dir, strings.NewReader(`{"source":`+source+`,"version":{"id":"i-0000"}}`), stdout)
+			Expect(err).To(MatchError("vm i-0000 has been replaced by i-1234"))
+		})
+	})
+
+	Describe("Out", func() {
+		It("replaces the vm with the image in the source", func() {
+			err := r.Out(dir, strings.NewReader(`{"source":`+source+`,"params":{}}`), stdout)
+			Expect(err).NotTo(HaveOccurred())
+
+			identifier, image, diskSizeGB := fakeClient.ReplaceArgsForCall(0)
+			Expect(identifier).To(Equal("ops-manager"))
+			Expect(image).To(Equal("ami-019e4617"))
+			Expect(diskSizeGB).To(Equal(int64(resource.DefaultDiskSizeGB)))
+			Expect(config.Options().MigrateDataDisk).To(BeFalse())
+
+			Expect(stdout.String()).To(MatchJSON(`{
+				"version": {"id":"i-1234","image":"ops-manager-1.10.3","version":"1.10.3"},
+				"metadata": [
+					{"name":"name","value":"ops-manager-2017-03-01-12-00-00"},
+					{"name":"image","value":"ops-manager-1.10.3"}
+				]
+			}`))
+		})
+
+		It("takes the image, disk size and data disk migration from the params", func() {
+			Expect(os.MkdirAll(filepath.Join(dir, "ami"), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(dir, "ami", "id"), []byte("ami-9876\n"), 0644)).To(Succeed())
+
+			err := r.Out(dir, strings.NewReader(`{"source":`+source+`,"params":{"image_file":"ami/id","disk_size_gb":150,"migrate_data_disk":true}}`), stdout)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, image, diskSizeGB := fakeClient.ReplaceArgsForCall(0)
+			Expect(image).To(Equal("ami-9876"))
+			Expect(diskSizeGB).To(Equal(int64(150)))
+			Expect(config.Options().MigrateDataDisk).To(BeTrue())
+		})
+
+		It("fails when the replace fails", func() {
+			fakeClient.ReplaceReturns(errors.New("replace failed"))
+
+			err := r.Out(dir, strings.NewReader(`{"source":`+source+`}`), stdout)
+			Expect(err).To(MatchError("replace failed"))
+			Expect(stdout.String()).To(BeEmpty())
+		})
+	})
+
+	Describe("Main", func() {
+		It("runs the script named by the first argument", func() {
+			code := resource.Main([]string{"cliaas-resource", "in"}, strings.NewReader(""), stdout, stderr)
+			Expect(code).To(Equal(1))
+			Expect(stderr.String()).To(ContainSubstring("usage: in <directory>"))
+		})
+	})
+})
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"time"
+
+	"github.com/pivotal-cf/cliaas/iaas"
+)
+
+// Job statuses
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCanceled  = "canceled"
+)
+
+// Job operations
+const (
+	OperationReplace = "replace"
+	OperationDelete  = "delete"
+)
+
+// Job is one replace or delete. Jobs on the same VM run one after another in
+// the order they were made.
+type Job struct {
+	ID         string     `json:"id"`
+	Operation  string     `json:"operation"`
+	Target     string     `json:"target,omitempty"`
+	Identifier string     `json:"identifier"`
+	Image      string     `json:"image,omitempty"`
+	DiskSizeGB int64      `json:"disk_size_gb,omitempty"`
+	Status     string     `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	ErrorClass string     `json:"error_class,omitempty"`
+	Events     []Event    `json:"events"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	// vm is the id of the VM the identifier matched when the job was made
+	vm string
+}
+
+// Event is a progress event of a job, as the command line reports them
+type Event struct {
+	Time    time.Time `json:"time"`
+	Step    string    `json:"step"`
+	Subject string    `json:"subject,omitempty"`
+	Current int64     `json:"current,omitempty"`
+	Total   int64     `json:"total,omitempty"`
+}
+
+// Finished says whether the job has stopped for good
+func (j *Job) Finished() bool {
+	return j.Status == JobSucceeded || j.Status == JobFailed || j.Status == JobCanceled
+}
+
+// vmKey names the queue of the job's VM
+func (j *Job) vmKey() string {
+	return j.Target + "/" + j.vm
+}
+
+// copy is a snapshot of the job that is safe to encode without the lock
+func (j *Job) copy() Job {
+	c := *j
+	c.Events = append([]Event{}, j.Events...)
+	return c
+}
+
+// jobProgress records the progress a job's client reports on the job
+type jobProgress struct {
+	server *Server
+	job    *Job
+}
+
+func (p *jobProgress) Report(event iaas.ProgressEvent) {
+	p.server.mutex.Lock()
+	defer p.server.mutex.Unlock()
+
+	p.job.Events = append(p.job.Events, Event{
+		Time:    p.server.Clock.Now(),
+		Step:    event.Step,
+		Subject: event.Subject,
+		Current: event.Current,
+		Total:   event.Total,
+	})
+}
+
+// enqueue adds the job to its VM's queue, and starts working the queue when
+// nothing else is
+func (s *Server) enqueue(job *Job) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.expireJobs()
+
+	s.jobs[job.ID] = job
+	s.jobOrder = append(s.jobOrder, job.ID)
+
+	key := job.vmKey()
+	s.queues[key] = append(s.queues[key], job)
+	if len(s.queues[key]) == 1 {
+		s.workers.Add(1)
+		go s.work(key)
+	}
+}
+
+// work runs the jobs of one VM in order until its queue is empty, so that two
+// jobs never touch the same VM at once
+func (s *Server) work(key string) {
+	defer s.workers.Done()
+
+	for {
+		s.mutex.Lock()
+		if len(s.queues[key]) == 0 {
+			delete(s.queues, key)
+			s.mutex.Unlock()
+			return
+		}
+		job := s.queues[key][0]
+		if job.Status == JobCanceled {
+			s.queues[key] = s.queues[key][1:]
+			s.mutex.Unlock()
+			continue
+		}
+		now := s.Clock.Now()
+		job.Status = JobRunning
+		job.StartedAt = &now
+		s.mutex.Unlock()
+
+		err := s.run(job)
+
+		s.mutex.Lock()
+		now = s.Clock.Now()
+		job.FinishedAt = &now
+		if err != nil {
+			job.Status = JobFailed
+			job.Error = s.redact(err.Error())
+			job.ErrorClass = iaas.ClassOf(err).String()
+		} else {
+			job.Status = JobSucceeded
+		}
+		s.queues[key] = s.queues[key][1:]
+		s.mutex.Unlock()
+	}
+}
+
+func (s *Server) run(job *Job) error {
+	config, err := s.LoadConfig(job.Target)
+	if err != nil {
+		return err
+	}
+	config.Options().Progress = &jobProgress{server: s, job: job}
+
+	client, err := s.NewClient(config)
+	if err != nil {
+		return err
+	}
+
+	switch job.Operation {
+	case OperationReplace:
+		image := job.Image
+		if image == "" {
+			image = config.Image()
+		}
+		return client.Replace(job.Identifier, image, job.DiskSizeGB)
+	default:
+		return client.Delete(job.Identifier)
+	}
+}
+
+// expireJobs forgets the jobs that finished more than JobRetention ago, so
+// that a server running for long does not keep every job it ever ran. The
+// caller holds the mutex.
+func (s *Server) expireJobs() {
+	if s.JobRetention <= 0 {
+		return
+	}
+
+	cutoff := s.Clock.Now().Add(-s.JobRetention)
+	kept := s.jobOrder[:0]
+	for _, id := range s.jobOrder {
+		job := s.jobs[id]
+		if job.Finished() && job.FinishedAt != nil && job.FinishedAt.Before(cutoff) {
+			delete(s.jobs, id)
+			continue
+		}
+		kept = append(kept, id)
+	}
+	s.jobOrder = kept
+}
+
+// cancel stops a job that has not started yet. A running job is not stopped,
+// as a replace cut short could leave the VM stopped or without its disks.
+func (s *Server) cancel(id string) (Job, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.expireJobs()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, errJobNotFound
+	}
+	if job.Status != JobQueued {
+		return job.copy(), errJobStarted
+	}
+
+	now := s.Clock.Now()
+	job.Status = JobCanceled
+	job.FinishedAt = &now
+	return job.copy(), nil
+}
@@ -0,0 +1,311 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"github.com/google/uuid"
+	"github.com/pivotal-cf/cliaas"
+	"github.com/pivotal-cf/cliaas/iaas"
+)
+
+// DefaultDiskSizeGB is the disk size of the new VM when a replace does not
+// give one, as for replace-vm
+const DefaultDiskSizeGB = 100
+
+// DefaultJobRetention is how long a finished job can still be looked up
+const DefaultJobRetention = 24 * time.Hour
+
+var (
+	errJobNotFound = errors.New("job not found")
+	errJobStarted  = errors.New("job has already started, only queued jobs can be canceled")
+)
+
+// Server serves the replace, delete and get-disk operations of cliaas over
+// http, as described in the README. Replaces and deletes run as jobs in the
+// background. Every request needs an Authorization: Bearer header with Token.
+type Server struct {
+	Token string
+	// TargetNames are listed by /v0/targets
+	TargetNames []string
+	// DefaultTarget is the target of requests without ?target=, as --target
+	// is on the command line
+	DefaultTarget string
+	// LoadConfig returns a fresh config of the target for every call, so that
+	// each job reports its own progress
+	LoadConfig func(target string) (cliaas.Config, error)
+	NewClient  func(cliaas.Config) (cliaas.Client, error)
+	Redact     func(string) string
+	Clock      clock.Clock
+	// JobRetention is how long finished jobs are kept, or forever when it is
+	// not positive
+	JobRetention time.Duration
+
+	mutex    sync.Mutex
+	jobs     map[string]*Job
+	jobOrder []string
+	queues   map[string][]*Job
+	workers  sync.WaitGroup
+}
+
+func New(token string, loadConfig func(target string) (cliaas.Config, error)) *Server {
+	return &Server{
+		Token:      token,
+		LoadConfig: loadConfig,
+		NewClient: func(config cliaas.Config) (cliaas.Client, error) {
+			return config.NewClient()
+		},
+		Clock:        clock.NewClock(),
+		JobRetention: DefaultJobRetention,
+		jobs:         map[string]*Job{},
+		queues:       map[string][]*Job{},
+	}
+}
+
+// Wait blocks until every queued job has finished
+func (s *Server) Wait() {
+	s.workers.Wait()
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, errors.New("missing or wrong token"))
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 2 || parts[0] != "v0" {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no route for %s", r.URL.Path))
+		return
+	}
+
+	switch {
+	case len(parts) == 2 && parts[1] == "targets" && r.Method == "GET":
+		writeJSON(w, http.StatusOK, map[string][]string{"targets": s.targetNames()})
+	case len(parts) == 3 && parts[1] == "vms" && r.Method == "GET":
+		s.getVM(w, r, parts[2])
+	case len(parts) == 4 && parts[1] == "vms" && parts[3] == "disk" && r.Method == "GET":
+		s.getDisk(w, r, parts[2])
+	case len(parts) == 4 && parts[1] == "vms" && parts[3] == "replace" && r.Method == "POST":
+		s.replace(w, r, parts[2])
+	case len(parts) == 3 && parts[1] == "vms" && r.Method == "DELETE":
+		s.startJob(w, &Job{Operation: OperationDelete, Target: s.target(r), Identifier: parts[2]})
+	case len(parts) == 2 && parts[1] == "jobs" && r.Method == "GET":
+		writeJSON(w, http.StatusOK, map[string][]Job{"jobs": s.listJobs()})
+	case len(parts) == 3 && parts[1] == "jobs" && r.Method == "GET":
+		s.getJob(w, parts[2])
+	case len(parts) == 3 && parts[1] == "jobs" && r.Method == "DELETE":
+		s.cancelJob(w, parts[2])
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("no route for %s %s", r.Method, r.URL.Path))
+	}
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	header := r.Header.Get("Authorization")
+	if s.Token == "" || !strings.HasPrefix(header, "Bearer ") {
+		return false
+	}
+	token := strings.TrimPrefix(header, "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) == 1
+}
+
+func (s *Server) targetNames() []string {
+	if s.TargetNames == nil {
+		return []string{}
+	}
+	return s.TargetNames
+}
+
+// target is the name of the target the request is for
+func (s *Server) target(r *http.Request) string {
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		return s.DefaultTarget
+	}
+	return target
+}
+
+// client builds a client for the target of a synchronous request
+func (s *Server) client(r *http.Request) (cliaas.Client, error) {
+	config, err := s.LoadConfig(s.target(r))
+	if err != nil {
+		return nil, err
+	}
+	return s.NewClient(config)
+}
+
+func (s *Server) getVM(w http.ResponseWriter, r *http.Request, identifier string) {
+	client, err := s.client(r)
+	if err != nil {
+		s.writeIaaSError(w, err)
+		return
+	}
+
+	vm, err := client.GetVM(identifier)
+	if err != nil {
+		s.writeIaaSError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"id":    vm.ID,
+		"name":  vm.Name,
+		"image": vm.Image,
+	})
+}
+
+func (s *Server) getDisk(w http.ResponseWriter, r *http.Request, identifier string) {
+	client, err := s.client(r)
+	if err != nil {
+		s.writeIaaSError(w, err)
+		return
+	}
+
+	disk, err := client.GetDisk(identifier)
+	if err != nil {
+		s.writeIaaSError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int64{"size_gb": disk.SizeGB})
+}
+
+func (s *Server) replace(w http.ResponseWriter, r *http.Request, identifier string) {
+	var body struct {
+		Image      string `json:"image"`
+		DiskSizeGB int64  `json:"disk_size_gb"`
+	}
+	if r.ContentLength != 0 {
+		err := json.NewDecoder(r.Body).Decode(&body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid replace request: %s", err))
+			return
+		}
+	}
+	if body.DiskSizeGB == 0 {
+		body.DiskSizeGB = DefaultDiskSizeGB
+	}
+
+	s.startJob(w, &Job{
+		Operation:  OperationReplace,
+		Target:     s.target(r),
+		Identifier: identifier,
+		Image:      body.Image,
+		DiskSizeGB: body.DiskSizeGB,
+	})
+}
+
+// startJob checks the job's target and looks up its VM before queueing it,
+// so that a typo is answered right away rather than by a failed job, and so
+// that jobs on the same VM share a queue however they name it
+func (s *Server) startJob(w http.ResponseWriter, job *Job) {
+	config, err := s.LoadConfig(job.Target)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New(s.redact(err.Error())))
+		return
+	}
+
+	client, err := s.NewClient(config)
+	if err != nil {
+		s.writeIaaSError(w, err)
+		return
+	}
+	vm, err := client.GetVM(job.Identifier)
+	if err != nil {
+		s.writeIaaSError(w, err)
+		return
+	}
+	job.vm = vm.ID
+	if job.vm == "" {
+		job.vm = vm.Name
+	}
+
+	job.ID = uuid.New().String()
+	job.Status = JobQueued
+	job.Events = []Event{}
+	job.CreatedAt = s.Clock.Now()
+	snapshot := job.copy()
+	s.enqueue(job)
+
+	w.Header().Set("Location", "/v0/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, snapshot)
+}
+
+func (s *Server) listJobs() []Job {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.expireJobs()
+
+	jobs := []Job{}
+	for _, id := range s.jobOrder {
+		jobs = append(jobs, s.jobs[id].copy())
+	}
+	return jobs
+}
+
+func (s *Server) getJob(w http.ResponseWriter, id string) {
+	s.mutex.Lock()
+	s.expireJobs()
+	job, ok := s.jobs[id]
+	var snapshot Job
+	if ok {
+		snapshot = job.copy()
+	}
+	s.mutex.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, errJobNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, snapshot)
+}
+
+func (s *Server) cancelJob(w http.ResponseWriter, id string) {
+	job, err := s.cancel(id)
+	switch err {
+	case nil:
+		writeJSON(w, http.StatusOK, job)
+	case errJobNotFound:
+		writeError(w, http.StatusNotFound, err)
+	default:
+		writeError(w, http.StatusConflict, err)
+	}
+}
+
+func (s *Server) redact(message string) string {
+	if s.Redact == nil {
+		return message
+	}
+	return s.Redact(message)
+}
+
+// writeIaaSError answers with the status that matches the error's class
+func (s *Server) writeIaaSError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch iaas.ClassOf(err) {
+	case iaas.NotFound:
+		status = http.StatusNotFound
+	case iaas.Retryable:
+		status = http.StatusServiceUnavailable
+	}
+	writeError(w, status, errors.New(s.redact(err.Error())))
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
@@ -0,0 +1,252 @@
+package server_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/pivotal-cf/cliaas"
+	"github.com/pivotal-cf/cliaas/cliaasfakes"
+	"github.com/pivotal-cf/cliaas/iaas"
+	"github.com/pivotal-cf/cliaas/server"
+)
+
+var _ = Describe("Server", func() {
+	var (
+		s          *server.Server
+		fakeClient *cliaasfakes.FakeClient
+		configs    []cliaas.Config
+		mutex      sync.Mutex
+	)
+
+	request := func(method string, path string, body string) *httptest.ResponseRecorder {
+		var reader io.Reader
+		if body != "" {
+			reader = strings.NewReader(body)
+		}
+		r := httptest.NewRequest(method, path, reader)
+		r.Header.Set("Authorization", "Bearer some-token")
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		return w
+	}
+
+	decodeJob := func(w *httptest.ResponseRecorder) server.Job {
+		var job server.Job
+		Expect(json.Unmarshal(w.Body.Bytes(), &job)).To(Succeed())
+		return job
+	}
+
+	BeforeEach(func() {
+		fakeClient = new(cliaasfakes.FakeClient)
+		fakeClient.GetVMStub = func(identifier string) (iaas.VM, error) {
+			return iaas.VM{ID: "id-" + identifier, Name: identifier}, nil
+		}
+		configs = nil
+
+		s = server.New("some-token", func(target string) (cliaas.Config, error) {
+			if target != "" && target != "staging" {
+				return nil, fmt.Errorf("target %q does not exist in config", target)
+			}
+			config := &cliaas.AWSConfig{AMI: "ami-019e4617", SecretAccessKey: "some-secret-key"}
+			mutex.Lock()
+			configs = append(configs, config)
+			mutex.Unlock()
+			return config, nil
+		})
+		s.TargetNames = []string{"staging"}
+		s.Redact = cliaas.NewRedactor("some-secret-key").Redact
+		s.NewClient = func(config cliaas.Config) (cliaas.Client, error) {
+			return fakeClient, nil
+		}
+	})
+
+	It("rejects requests without the token", func() {
+		r := httptest.NewRequest("GET", "/v0/targets", nil)
+		r.Header.Set("Authorization", "Bearer wrong-token")
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+
+		Expect(w.Code).To(Equal(http.StatusUnauthorized))
+		Expect(fakeClient.Invocations()).To(BeEmpty())
+	})
+
+	It("lists the targets", func() {
+		w := request("GET", "/v0/targets", "")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(MatchJSON(`{"targets":["staging"]}`))
+	})
+
+	Describe("get-disk", func() {
+		It("answers with the disk size", func() {
+			fakeClient.GetDiskReturns(iaas.Disk{SizeGB: 150}, nil)
+
+			w := request("GET", "/v0/vms/ops-manager/disk?target=staging", "")
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(MatchJSON(`{"size_gb":150}`))
+			Expect(fakeClient.GetDiskArgsForCall(0)).To(Equal("ops-manager"))
+		})
+
+		It("answers not found for a missing vm", func() {
+			fakeClient.GetDiskReturns(iaas.Disk{}, iaas.NewNotFoundError(errors.New("no matching instances found")))
+
+			w := request("GET", "/v0/vms/ops-manager/disk", "")
+			Expect(w.Code).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("replace", func() {
+		It("runs a job that records its progress", func() {
+			fakeClient.ReplaceStub = func(identifier string, image string, diskSizeGB int64) error {
+				mutex.Lock()
+				config := configs[len(configs)-1]
+				mutex.Unlock()
+				iaas.ReportStep(config.Options().Progress, iaas.StepStopping, identifier)
+				return nil
+			}
+
+			w := request("POST", "/v0/vms/ops-manager/replace", `{"disk_size_gb":150}`)
+			Expect(w.Code).To(Equal(http.StatusAccepted))
+			job := decodeJob(w)
+			Expect(job.Status).To(Equal(server.JobQueued))
+			Expect(w.Header().Get("Location")).To(Equal("/v0/jobs/" + job.ID))
+
+			s.Wait()
+			job = decodeJob(request("GET", "/v0/jobs/"+job.ID, ""))
+			Expect(job.Status).To(Equal(server.JobSucceeded))
+			Expect(job.Events).To(HaveLen(1))
+			Expect(job.Events[0].Step).To(Equal(iaas.StepStopping))
+
+			identifier, image, diskSizeGB := fakeClient.ReplaceArgsForCall(0)
+			Expect(identifier).To(Equal("ops-manager"))
+			Expect(image).To(Equal("ami-019e4617"))
+			Expect(diskSizeGB).To(Equal(int64(150)))
+		})
+
+		It("records a failure without secrets", func() {
+			fakeClient.ReplaceReturns(errors.New("auth failed for some-secret-key"))
+
+			job := decodeJob(request("POST", "/v0/vms/ops-manager/replace", ""))
+			s.Wait()
+
+			job = decodeJob(request("GET", "/v0/jobs/"+job.ID, ""))
+			Expect(job.Status).To(Equal(server.JobFailed))
+			Expect(job.Error).To(Equal("auth failed for " + cliaas.Redacted))
+		})
+
+		It("rejects an unknown target right away", func() {
+			w := request("POST", "/v0/vms/ops-manager/replace?target=prod", "")
+			Expect(w.Code).To(Equal(http.StatusBadRequest))
+		})
+
+		It("answers not found for a missing vm right away", func() {
+			fakeClient.GetVMReturns(iaas.VM{}, iaas.NewNotFoundError(errors.New("no matching instances found")))
+
+			w := request("POST", "/v0/vms/ops-manager/replace", "")
+			Expect(w.Code).To(Equal(http.StatusNotFound))
+			Expect(fakeClient.ReplaceCallCount()).To(Equal(0))
+		})
+	})
+
+	Describe("the queue of a vm", func() {
+		var release chan struct{}
+		var running chan string
+
+		BeforeEach(func() {
+			release = make(chan struct{})
+			running = make(chan string, 10)
+			fakeClient.ReplaceStub = func(identifier string, image string, diskSizeGB int64) error {
+				running <- identifier
+				<-release
+				return nil
+			}
+			fakeClient.DeleteStub = func(identifier string) error {
+				running <- "delete " + identifier
+				return nil
+			}
+		})
+
+		It("runs one job per vm at a time, and cancels queued jobs", func() {
+			first := decodeJob(request("POST", "/v0/vms/ops-manager/replace", ""))
+			Eventually(running).Should(Receive(Equal("ops-manager")))
+
+			second := decodeJob(request("DELETE", "/v0/vms/ops-manager", ""))
+			third := decodeJob(request("POST", "/v0/vms/other-ops-manager/replace", ""))
+			Eventually(running).Should(Receive(Equal("other-ops-manager")))
+			Consistently(running).ShouldNot(Receive())
+
+			Expect(decodeJob(request("GET", "/v0/jobs/"+second.ID, "")).Status).To(Equal(server.JobQueued))
+
+			w := request("DELETE", "/v0/jobs/"+first.ID, "")
+			Expect(w.Code).To(Equal(http.StatusConflict))
+
+			w = request("DELETE", "/v0/jobs/"+second.ID, "")
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(decodeJob(w).Status).To(Equal(server.JobCanceled))
+
+			close(release)
+			s.Wait()
+			Expect(fakeClient.DeleteCallCount()).To(Equal(0))
+
+			var list struct {
+				Jobs []server.Job `json:"jobs"`
+			}
+			Expect(json.Unmarshal(request("GET", "/v0/jobs", "").Body.Bytes(), &list)).To(Succeed())
+			Expect(list.Jobs).To(HaveLen(3))
+			Expect(list.Jobs[0].ID).To(Equal(first.ID))
+			Expect(list.Jobs[0].Status).To(Equal(server.JobSucceeded))
+			Expect(list.Jobs[1].Status).To(Equal(server.JobCanceled))
+			Expect(list.Jobs[2].ID).To(Equal(third.ID))
+		})
+
+		It("queues jobs on the same vm together however they name it and its target", func() {
+			s.DefaultTarget = "staging"
+			fakeClient.GetVMStub = func(identifier string) (iaas.VM, error) {
+				return iaas.VM{ID: "some-vm-id", Name: "ops-manager"}, nil
+			}
+
+			first := decodeJob(request("POST", "/v0/vms/ops-manager/replace", ""))
+			Expect(first.Target).To(Equal("staging"))
+			Eventually(running).Should(Receive(Equal("ops-manager")))
+
+			second := decodeJob(request("POST", "/v0/vms/ops-man.*/replace?target=staging", ""))
+			Consistently(running).ShouldNot(Receive())
+			Expect(decodeJob(request("GET", "/v0/jobs/"+second.ID, "")).Status).To(Equal(server.JobQueued))
+
+			close(release)
+			Eventually(running).Should(Receive(Equal("ops-man.*")))
+			s.Wait()
+		})
+	})
+
+	It("forgets finished jobs once they are older than the retention", func() {
+		fakeClock := fakeclock.NewFakeClock(time.Date(2017, 3, 1, 12, 0, 0, 0, time.UTC))
+		s.Clock = fakeClock
+		s.JobRetention = time.Hour
+
+		job := decodeJob(request("DELETE", "/v0/vms/ops-manager", ""))
+		s.Wait()
+
+		fakeClock.Increment(time.Hour)
+		Expect(request("GET", "/v0/jobs/"+job.ID, "").Code).To(Equal(http.StatusOK))
+
+		fakeClock.Increment(time.Second)
+		Expect(request("GET", "/v0/jobs/"+job.ID, "").Code).To(Equal(http.StatusNotFound))
+		Expect(request("GET", "/v0/jobs", "").Body.String()).To(MatchJSON(`{"jobs":[]}`))
+	})
+
+	It("answers not found for an unknown job", func() {
+		w := request("GET", "/v0/jobs/some-job", "")
+		Expect(w.Code).To(Equal(http.StatusNotFound))
+	})
+})
@@ -0,0 +1,58 @@
+package cliaas
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ConfigFile is the whole of a config file: either a single set of IaaS
+// configs at the top level, or any number of them under named targets
+type ConfigFile struct {
+	MultiConfig `yaml:",inline"`
+	Targets     map[string]*MultiConfig `yaml:"targets"`
+}
+
+func (c *ConfigFile) TargetNames() []string {
+	var names []string
+	for name := range c.Targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Target returns the single complete config of the named target. Without a
+// name the top level config is used, or the only target if there is just one.
+func (c *ConfigFile) Target(name string) (Config, error) {
+	if name == "" {
+		if len(c.Configs()) == 0 && len(c.Targets) == 1 {
+			name = c.TargetNames()[0]
+		} else if len(c.Configs()) == 0 && len(c.Targets) > 1 {
+			return nil, fmt.Errorf("a target is required, choose one of: %s", strings.Join(c.TargetNames(), ", "))
+		} else {
+			return singleCompleteConfig(&c.MultiConfig, "config")
+		}
+	}
+
+	multiConfig, ok := c.Targets[name]
+	if !ok || multiConfig == nil {
+		return nil, fmt.Errorf("target %q does not exist in config, choose one of: %s", name, strings.Join(c.TargetNames(), ", "))
+	}
+
+	return singleCompleteConfig(multiConfig, fmt.Sprintf("target %q", name))
+}
+
+func singleCompleteConfig(multiConfig *MultiConfig, description string) (Config, error) {
+	completeConfigs := multiConfig.CompleteConfigs()
+
+	if len(completeConfigs) == 0 {
+		return nil, fmt.Errorf("zero iaas configurations exists in %s", description)
+	}
+
+	if len(completeConfigs) > 1 {
+		return nil, fmt.Errorf("more than one iaas configuration exists in %s", description)
+	}
+
+	return completeConfigs[0], nil
+}
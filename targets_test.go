@@ -0,0 +1,111 @@
+package cliaas_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/pivotal-cf/cliaas"
+)
+
+var _ = Describe("ConfigFile", func() {
+	var configFile cliaas.ConfigFile
+
+	const awsConfig = `
+    aws:
+      access_key_id: some-access-key-id
+      secret_access_key: some-secret-access-key
+      region: us-east-1
+      vpc: vpc-yyyyyyyy
+      ami: ami-nnnnnnnn`
+
+	const gcpConfig = `
+    gcp:
+      credfile: testdata/fake_gcp_creds.json
+      zone: us-east1-b
+      project: some-project
+      disk_image_url: some-disk-image-url`
+
+	load := func(contents string) {
+		configFile = cliaas.ConfigFile{}
+		Expect(yaml.Unmarshal([]byte(contents), &configFile)).To(Succeed())
+	}
+
+	Context("when the config has a single iaas at the top level", func() {
+		BeforeEach(func() {
+			load(`
+aws:
+  access_key_id: some-access-key-id
+  secret_access_key: some-secret-access-key
+  region: us-east-1
+  vpc: vpc-yyyyyyyy
+  ami: ami-nnnnnnnn`)
+		})
+
+		It("uses it when no target is given", func() {
+			config, err := configFile.Target("")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.IaaS()).To(Equal("aws"))
+		})
+
+		It("errors when a target is given", func() {
+			_, err := configFile.Target("prod-east")
+			Expect(err).To(MatchError(ContainSubstring(`target "prod-east" does not exist`)))
+		})
+	})
+
+	Context("when the config has named targets", func() {
+		BeforeEach(func() {
+			load(`
+targets:
+  prod-east:` + awsConfig + `
+  dev-gcp:` + gcpConfig)
+		})
+
+		It("lists the target names in order", func() {
+			Expect(configFile.TargetNames()).To(Equal([]string{"dev-gcp", "prod-east"}))
+		})
+
+		It("selects the named target", func() {
+			config, err := configFile.Target("dev-gcp")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.IaaS()).To(Equal("gcp"))
+
+			config, err = configFile.Target("prod-east")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.IaaS()).To(Equal("aws"))
+		})
+
+		It("requires a target to be chosen", func() {
+			_, err := configFile.Target("")
+			Expect(err).To(MatchError("a target is required, choose one of: dev-gcp, prod-east"))
+		})
+	})
+
+	Context("when the config has a single named target", func() {
+		BeforeEach(func() {
+			load(`
+targets:
+  prod-east:` + awsConfig)
+		})
+
+		It("uses it when no target is given", func() {
+			config, err := configFile.Target("")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.IaaS()).To(Equal("aws"))
+		})
+	})
+
+	Context("when a target has more than one complete iaas", func() {
+		BeforeEach(func() {
+			load(`
+targets:
+  confused:` + awsConfig + gcpConfig)
+		})
+
+		It("errors naming the target", func() {
+			_, err := configFile.Target("confused")
+			Expect(err).To(MatchError(`more than one iaas configuration exists in target "confused"`))
+		})
+	})
+})
@@ -0,0 +1,381 @@
+package cliaas
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+
+	"github.com/pivotal-cf/cliaas/iaas"
+	"github.com/pivotal-cf/cliaas/iaas/aws"
+	"github.com/pivotal-cf/cliaas/iaas/azure"
+	errwrap "github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+var (
+	amiRegexp                  = regexp.MustCompile(`^ami-([0-9a-f]{8}|[0-9a-f]{17})$`)
+	vpcRegexp                  = regexp.MustCompile(`^vpc-([0-9a-f]{8}|[0-9a-f]{17})$`)
+	awsRegionRegexp            = regexp.MustCompile(`^[a-z]{2}(-gov|-iso[a-z]?)?-[a-z]+-[0-9]$`)
+	roleARNRegexp              = regexp.MustCompile(`^arn:aws[a-z-]*:iam::[0-9]{12}:role/.+$`)
+	gcpZoneRegexp              = regexp.MustCompile(`^[a-z]+-[a-z]+[0-9]+-[a-z]$`)
+	gcpProjectRegexp           = regexp.MustCompile(`^([a-z0-9.-]+:)?[a-z][a-z0-9-]{4,28}[a-z0-9]$`)
+	gcpDiskImageRegexp         = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{1,221}[a-z0-9]/.+\.tar\.gz$`)
+	guidRegexp                 = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	azureStorageAccountRegexp  = regexp.MustCompile(`^[a-z0-9]{3,24}$`)
+	azureContainerRegexp       = regexp.MustCompile(`^[a-z0-9]([a-z0-9]|-[a-z0-9]){2,62}$`)
+	azureResourceGroupNameRule = regexp.MustCompile(`^[-\w._()]{1,90}$`)
+)
+
+// FieldError is a single problem with a single config field
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e FieldError) String() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ValidationError lists every problem found in a config, not just the first
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	var lines []string
+	for _, fieldError := range e.Errors {
+		lines = append(lines, fieldError.String())
+	}
+	return "invalid config:\n  " + strings.Join(lines, "\n  ")
+}
+
+// Prefixed returns a copy of the errors with every field nested under prefix
+func (e *ValidationError) Prefixed(prefix string) []FieldError {
+	var fieldErrors []FieldError
+	for _, fieldError := range e.Errors {
+		if fieldError.Field != "" {
+			fieldError.Field = prefix + "." + fieldError.Field
+		}
+		fieldErrors = append(fieldErrors, fieldError)
+	}
+	return fieldErrors
+}
+
+// UnknownFields reports every field in a config file that none of the configs
+// know about, which usually means it is misspelled
+func UnknownFields(contents []byte) []FieldError {
+	var configFile ConfigFile
+	err := yaml.UnmarshalStrict(contents, &configFile)
+	if err == nil {
+		return nil
+	}
+
+	typeError, ok := err.(*yaml.TypeError)
+	if !ok {
+		return []FieldError{{Message: err.Error()}}
+	}
+
+	var fieldErrors []FieldError
+	for _, message := range typeError.Errors {
+		fieldErrors = append(fieldErrors, FieldError{Message: message})
+	}
+	return fieldErrors
+}
+
+// Validate checks every config in the file, at the top level and under each
+// target
+func (c *ConfigFile) Validate() error {
+	var fieldErrors []FieldError
+
+	if len(c.Configs()) == 0 && len(c.Targets) == 0 {
+		fieldErrors = append(fieldErrors, FieldError{Message: "zero iaas configurations exists in config"})
+	}
+	fieldErrors = append(fieldErrors, validateMultiConfig(&c.MultiConfig, "")...)
+
+	for _, name := range c.TargetNames() {
+		prefix := "targets." + name
+		multiConfig := c.Targets[name]
+		if multiConfig == nil || len(multiConfig.Configs()) == 0 {
+			fieldErrors = append(fieldErrors, FieldError{Field: prefix, Message: "zero iaas configurations exists in target"})
+			continue
+		}
+		fieldErrors = append(fieldErrors, validateMultiConfig(multiConfig, prefix)...)
+	}
+
+	if len(fieldErrors) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: fieldErrors}
+}
+
+func validateMultiConfig(multiConfig *MultiConfig, prefix string) []FieldError {
+	var fieldErrors []FieldError
+	for _, config := range multiConfig.Configs() {
+		err := config.Validate()
+		if err == nil {
+			continue
+		}
+
+		validationError, ok := err.(*ValidationError)
+		if !ok {
+			fieldErrors = append(fieldErrors, FieldError{Field: prefix, Message: err.Error()})
+		} else if prefix == "" {
+			fieldErrors = append(fieldErrors, validationError.Errors...)
+		} else {
+			fieldErrors = append(fieldErrors, validationError.Prefixed(prefix)...)
+		}
+	}
+	return fieldErrors
+}
+
+type validator struct {
+	prefix string
+	errors []FieldError
+}
+
+func (v *validator) add(field string, format string, args ...interface{}) {
+	v.errors = append(v.errors, FieldError{
+		Field:   v.prefix + "." + field,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *validator) required(field string, value string) bool {
+	if value == "" {
+		v.add(field, "is required")
+		return false
+	}
+	return true
+}
+
+func (v *validator) matches(field string, value string, pattern *regexp.Regexp, description string) {
+	if value != "" && !pattern.MatchString(value) {
+		v.add(field, "%q is not %s", value, description)
+	}
+}
+
+func (v *validator) url(field string, value string, schemes ...string) *url.URL {
+	if value == "" {
+		return nil
+	}
+
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		v.add(field, "%q is not an absolute url", value)
+		return nil
+	}
+
+	for _, scheme := range schemes {
+		if u.Scheme == scheme {
+			return u
+		}
+	}
+	v.add(field, "%q must use one of the schemes %s", value, strings.Join(schemes, ", "))
+	return nil
+}
+
+func (v *validator) timeouts(timeouts iaas.Timeouts) {
+	durations := []struct {
+		field string
+		value time.Duration
+	}{
+		{"stop", timeouts.Stop},
+		{"start", timeouts.Start},
+		{"create", timeouts.Create},
+		{"image_import", timeouts.ImageImport},
+		{"ip_association", timeouts.IPAssociation},
+		{"disk_attachment", timeouts.DiskAttachment},
+		{"opsman_availability", timeouts.OpsManAvailability},
+		{"hook", timeouts.Hook},
+		{"api_retry", timeouts.APIRetry},
+		{"poll_interval", timeouts.PollInterval},
+		{"max_poll_interval", timeouts.MaxPollInterval},
+	}
+	for _, duration := range durations {
+		if duration.value < 0 {
+			v.add("timeouts."+duration.field, "%s is negative", duration.value)
+		}
+	}
+}
+
+func (v *validator) nameTemplate(text string) {
+	if text == "" {
+		return
+	}
+
+	err := iaas.ValidateNameTemplate(text)
+	if err != nil {
+		v.add("name_template", "%s", err)
+	}
+}
+
+func (v *validator) err() error {
+	if len(v.errors) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: v.errors}
+}
+
+func (c *AWSConfig) Validate() error {
+	v := &validator{prefix: c.IaaS()}
+
+	v.required("ami", c.AMI)
+	v.matches("ami", c.AMI, amiRegexp, "an AMI id like ami-0123abcd")
+	v.required("region", c.Region)
+	v.matches("region", c.Region, awsRegionRegexp, "a region name like us-east-1")
+	v.required("vpc", c.VPCID)
+	v.matches("vpc", c.VPCID, vpcRegexp, "a VPC id like vpc-0123abcd")
+
+	if c.AuthMode() == aws.AuthModeStatic {
+		v.required("access_key_id", c.AccessKeyID)
+		v.required("secret_access_key", c.SecretAccessKey)
+	} else if c.SessionToken != "" {
+		v.add("session_token", "is only used together with access_key_id and secret_access_key")
+	}
+
+	v.matches("role_arn", c.RoleARN, roleARNRegexp, "an IAM role arn")
+	if c.RoleARN == "" {
+		if c.ExternalID != "" {
+			v.add("external_id", "is only used together with role_arn")
+		}
+		if c.RoleSessionName != "" {
+			v.add("role_session_name", "is only used together with role_arn")
+		}
+	}
+
+	v.timeouts(c.Timeouts)
+	v.nameTemplate(c.NameTemplate)
+
+	return v.err()
+}
+
+func (c *GCPConfig) Validate() error {
+	v := &validator{prefix: c.IaaS()}
+
+	v.required("zone", c.Zone)
+	v.matches("zone", c.Zone, gcpZoneRegexp, "a zone name like us-east1-b")
+	v.required("project", c.Project)
+	v.matches("project", c.Project, gcpProjectRegexp, "a project id")
+	if v.required("disk_image_url", c.DiskImageURL) && !gcpDiskImageRegexp.MatchString(c.DiskImageURL) {
+		v.add("disk_image_url", "%q is not a <bucket>/<path>.tar.gz in google cloud storage, without a scheme or host", c.DiskImageURL)
+	}
+
+	if c.CredfilePath != "" && c.CredentialsJSON != "" {
+		v.add("credentials_json", "cannot be given together with credfile")
+	}
+
+	if c.CredfilePath != "" {
+		if _, err := os.Stat(c.CredfilePath); err != nil {
+			v.add("credfile", "%q cannot be read: %s", c.CredfilePath, err)
+		}
+	}
+
+	if c.CredentialsJSON != "" {
+		var credentials map[string]interface{}
+		if err := json.Unmarshal([]byte(c.CredentialsJSON), &credentials); err != nil {
+			v.add("credentials_json", "is not valid json: %s", err)
+		}
+	}
+
+	if c.ImpersonateServiceAccount == "" && len(c.ImpersonationDelegates) > 0 {
+		v.add("impersonation_delegates", "is only used together with impersonate_service_account")
+	}
+
+	v.timeouts(c.Timeouts)
+	v.nameTemplate(c.NameTemplate)
+
+	return v.err()
+}
+
+func (c *AzureConfig) Validate() error {
+	v := &validator{prefix: c.IaaS()}
+
+	v.required("subscription_id", c.SubscriptionID)
+	v.matches("subscription_id", c.SubscriptionID, guidRegexp, "a guid")
+	v.required("client_id", c.ClientID)
+	v.matches("client_id", c.ClientID, guidRegexp, "a guid")
+	v.required("client_secret", c.ClientSecret)
+	v.required("tenant_id", c.TenantID)
+	v.required("resource_group_name", c.ResourceGroupName)
+	v.matches("resource_group_name", c.ResourceGroupName, azureResourceGroupNameRule, "a valid resource group name")
+	v.required("storage_account_name", c.StorageAccountName)
+	v.matches("storage_account_name", c.StorageAccountName, azureStorageAccountRegexp, "3 to 24 lowercase letters and numbers")
+	v.required("storage_account_key", c.StorageAccountKey)
+	v.required("storage_container_name", c.StorageContainerName)
+	v.matches("storage_container_name", c.StorageContainerName, azureContainerRegexp, "a valid container name")
+
+	if v.required("vhd_image_url", c.VHDImageURL) {
+		vhdURL := v.url("vhd_image_url", c.VHDImageURL, "https", "http")
+		if vhdURL != nil && !strings.HasSuffix(vhdURL.Path, ".vhd") {
+			v.add("vhd_image_url", "%q does not point at a .vhd blob", c.VHDImageURL)
+		}
+	}
+
+	v.url("resource_manager_endpoint", c.ResourceManagerEndpoint, "https", "http")
+	v.url("metadata_endpoint", c.MetadataEndpoint, "https", "http")
+
+	switch {
+	case c.Environment == "":
+	case strings.EqualFold(c.Environment, azure.AzureStack):
+		if c.ResourceManagerEndpoint == "" {
+			v.add("resource_manager_endpoint", "is required for %s", azure.AzureStack)
+		}
+	default:
+		if _, err := azure.NewEnvironment(c.Environment, "", ""); err != nil {
+			v.add("environment", "%s", err)
+		}
+	}
+
+	if c.MetadataEndpoint != "" && !strings.EqualFold(c.Environment, azure.AzureStack) {
+		v.add("metadata_endpoint", "is only used with %s", azure.AzureStack)
+	}
+
+	if strings.Contains(c.StorageURL, "://") {
+		v.add("storage_url", "%q should be a domain suffix like core.windows.net, without a scheme", c.StorageURL)
+	}
+
+	v.timeouts(c.Timeouts)
+	v.nameTemplate(c.NameTemplate)
+
+	return v.err()
+}
+
+// ValidateOnline checks the credentials by looking up the configured VPC
+func (c *AWSConfig) ValidateOnline() error {
+	ec2Client, err := aws.NewEC2Client(c.credentialsConfig(), c.Region, c.Logger)
+	if err != nil {
+		return errwrap.Wrap(err, "failed to make ec2 client")
+	}
+
+	return aws.NewAWSClient(ec2Client, c.VPCID, iaas.NewWaiter(clock.NewClock(), c.Timeouts)).VerifyVPC()
+}
+
+// ValidateOnline checks the credentials by looking up the configured project
+func (c *GCPConfig) ValidateOnline() error {
+	client, err := c.newClient()
+	if err != nil {
+		return err
+	}
+
+	return client.VerifyProject()
+}
+
+// ValidateOnline checks the credentials by looking up the configured resource
+// group
+func (c *AzureConfig) ValidateOnline() error {
+	client, err := c.newClient()
+	if err != nil {
+		return err
+	}
+
+	return client.VerifyResourceGroup()
+}
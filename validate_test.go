@@ -0,0 +1,207 @@
+package cliaas_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/pivotal-cf/cliaas"
+)
+
+var _ = Describe("Validate", func() {
+	fieldsOf := func(err error) []string {
+		Expect(err).To(HaveOccurred())
+		validationError, ok := err.(*cliaas.ValidationError)
+		Expect(ok).To(BeTrue())
+
+		var fields []string
+		for _, fieldError := range validationError.Errors {
+			fields = append(fields, fieldError.Field)
+		}
+		return fields
+	}
+
+	Describe("AWSConfig", func() {
+		var config *cliaas.AWSConfig
+
+		BeforeEach(func() {
+			config = &cliaas.AWSConfig{
+				AccessKeyID:     "some-access-key-id",
+				SecretAccessKey: "some-secret-access-key",
+				Region:          "us-east-1",
+				VPCID:           "vpc-0123abcd",
+				AMI:             "ami-0123456789abcdef0",
+			}
+		})
+
+		It("accepts a valid config", func() {
+			Expect(config.Validate()).To(Succeed())
+		})
+
+		It("accepts gov cloud regions", func() {
+			config.Region = "us-gov-west-1"
+			Expect(config.Validate()).To(Succeed())
+		})
+
+		It("lists every malformed and missing field", func() {
+			config.AMI = "ubuntu-xenial"
+			config.Region = "US East"
+			config.VPCID = ""
+			config.SecretAccessKey = ""
+
+			Expect(fieldsOf(config.Validate())).To(Equal([]string{
+				"aws.ami",
+				"aws.region",
+				"aws.vpc",
+				"aws.secret_access_key",
+			}))
+		})
+
+		It("rejects role options without a role arn", func() {
+			config.ExternalID = "some-external-id"
+			Expect(fieldsOf(config.Validate())).To(Equal([]string{"aws.external_id"}))
+		})
+
+		It("rejects a malformed role arn", func() {
+			config.RoleARN = "some-role"
+			Expect(config.Validate()).To(MatchError(ContainSubstring(`aws.role_arn: "some-role" is not an IAM role arn`)))
+		})
+
+		It("rejects negative timeouts", func() {
+			config.Timeouts.Create = -time.Minute
+			Expect(config.Validate()).To(MatchError(ContainSubstring("aws.timeouts.create: -1m0s is negative")))
+		})
+
+		It("rejects a name template using unknown fields", func() {
+			config.NameTemplate = "{{.Name}}-{{.Timestamp}}"
+			Expect(fieldsOf(config.Validate())).To(Equal([]string{"aws.name_template"}))
+		})
+	})
+
+	Describe("GCPConfig", func() {
+		var config *cliaas.GCPConfig
+
+		BeforeEach(func() {
+			config = &cliaas.GCPConfig{
+				CredfilePath: "testdata/fake_gcp_creds.json",
+				Zone:         "us-east1-b",
+				Project:      "my-gcp-project",
+				DiskImageURL: "ops-manager-us/pcf-gcp-1.9.3.tar.gz",
+			}
+		})
+
+		It("accepts a valid config", func() {
+			Expect(config.Validate()).To(Succeed())
+		})
+
+		It("rejects a disk image url with a scheme", func() {
+			config.DiskImageURL = "https://storage.googleapis.com/ops-manager-us/pcf-gcp-1.9.3.tar.gz"
+			Expect(fieldsOf(config.Validate())).To(Equal([]string{"gcp.disk_image_url"}))
+		})
+
+		It("reports a credfile that does not exist", func() {
+			config.CredfilePath = "testdata/does-not-exist.json"
+			Expect(config.Validate()).To(MatchError(ContainSubstring("gcp.credfile")))
+		})
+
+		It("lists every malformed field", func() {
+			config.Zone = "us-east-1"
+			config.Project = "My Project"
+			config.CredfilePath = ""
+			config.CredentialsJSON = "{not json"
+
+			Expect(fieldsOf(config.Validate())).To(Equal([]string{
+				"gcp.zone",
+				"gcp.project",
+				"gcp.credentials_json",
+			}))
+		})
+	})
+
+	Describe("AzureConfig", func() {
+		var config *cliaas.AzureConfig
+
+		BeforeEach(func() {
+			config = &cliaas.AzureConfig{
+				VHDImageURL:          "https://opsmanagereastus.blob.core.windows.net/images/ops-manager-1.10.3.vhd?sv=2016&sig=abc",
+				SubscriptionID:       "00000000-0000-0000-0000-000000000001",
+				ClientID:             "00000000-0000-0000-0000-000000000002",
+				ClientSecret:         "some-client-secret",
+				TenantID:             "some-tenant",
+				ResourceGroupName:    "some-resource-group",
+				StorageAccountName:   "someaccount",
+				StorageAccountKey:    "some-key",
+				StorageContainerName: "opsmanager",
+			}
+		})
+
+		It("accepts a valid config", func() {
+			Expect(config.Validate()).To(Succeed())
+		})
+
+		It("lists every malformed field", func() {
+			config.VHDImageURL = "opsmanager.vhd"
+			config.SubscriptionID = "not-a-guid"
+			config.StorageAccountName = "Some_Account"
+			config.Environment = "AzureMoonCloud"
+
+			Expect(fieldsOf(config.Validate())).To(Equal([]string{
+				"azure.subscription_id",
+				"azure.storage_account_name",
+				"azure.vhd_image_url",
+				"azure.environment",
+			}))
+		})
+
+		It("requires a resource manager endpoint for azure stack", func() {
+			config.Environment = "AzureStack"
+			Expect(fieldsOf(config.Validate())).To(Equal([]string{"azure.resource_manager_endpoint"}))
+		})
+	})
+
+	Describe("ConfigFile", func() {
+		It("prefixes the fields of targets with the target name", func() {
+			var configFile cliaas.ConfigFile
+			Expect(yaml.Unmarshal([]byte(`
+targets:
+  prod:
+    aws:
+      region: us-east-1
+      vpc: vpc-0123abcd
+      ami: ami-0123abcd
+      profile: some-profile
+  empty: {}
+`), &configFile)).To(Succeed())
+
+			Expect(configFile.Validate()).To(Equal(&cliaas.ValidationError{Errors: []cliaas.FieldError{
+				{Field: "targets.empty", Message: "zero iaas configurations exists in target"},
+			}}))
+
+			configFile.Targets["prod"].AWS.AMI = "ami-nope"
+			Expect(fieldsOf(configFile.Validate())).To(ContainElement("targets.prod.aws.ami"))
+		})
+
+		It("reports a config file without any iaas", func() {
+			var configFile cliaas.ConfigFile
+			Expect(configFile.Validate()).To(MatchError(ContainSubstring("zero iaas configurations exists in config")))
+		})
+	})
+
+	Describe("UnknownFields", func() {
+		It("reports misspelled fields", func() {
+			fieldErrors := cliaas.UnknownFields([]byte(`
+aws:
+  region: us-east-1
+  vcp: vpc-0123abcd
+`))
+			Expect(fieldErrors).To(HaveLen(1))
+			Expect(fieldErrors[0].Message).To(ContainSubstring("vcp"))
+		})
+
+		It("reports nothing for a known config", func() {
+			Expect(cliaas.UnknownFields([]byte("gcp:\n  zone: us-east1-b\n"))).To(BeEmpty())
+		})
+	})
+})